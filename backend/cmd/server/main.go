@@ -19,7 +19,9 @@ import (
 	agentScheduler "github.com/cds-id/pdt/backend/internal/scheduler"
 	"github.com/cds-id/pdt/backend/internal/scheduler/eventbus"
 	"github.com/cds-id/pdt/backend/internal/services/executive"
+	"github.com/cds-id/pdt/backend/internal/services/identity"
 	"github.com/cds-id/pdt/backend/internal/services/report"
+	"github.com/cds-id/pdt/backend/internal/services/reportexport"
 	"github.com/cds-id/pdt/backend/internal/services/storage"
 	tgService "github.com/cds-id/pdt/backend/internal/services/telegram"
 	waService "github.com/cds-id/pdt/backend/internal/services/whatsapp"
@@ -43,6 +45,7 @@ func main() {
 	if err := database.Migrate(db); err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
+	go worker.RunDataMigrations(db)
 
 	encryptor, err := crypto.NewEncryptor(cfg.EncryptionKey)
 	if err != nil {
@@ -53,6 +56,8 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	reportexport.Configure(cfg.ReportTheme, cfg.ReportBrandName, cfg.ReportBrandColor)
+
 	// R2 storage (optional)
 	var r2Client *storage.R2Client
 	if cfg.R2AccountID != "" && cfg.R2AccessKeyID != "" {
@@ -86,10 +91,20 @@ func main() {
 	// Event bus (shared between worker and agent scheduler)
 	eventBus := eventbus.New()
 
+	var miniMaxClient *minimax.Client
+	if cfg.MiniMaxAPIKey != "" {
+		miniMaxClient = minimax.NewClient(cfg.MiniMaxAPIKey, cfg.MiniMaxGroupID)
+	}
+
 	// Worker scheduler
+	worker.CommitBackfillDays = cfg.SyncCommitBackfillDays
+	if cfg.ReportNarrative && miniMaxClient != nil {
+		worker.ReportNarrativeLLM = miniMaxClient
+		worker.ReportNarrativeModel = miniMaxClient.Model
+	}
 	var syncStatus *worker.SyncStatus
 	if cfg.SyncEnabled {
-		scheduler := worker.NewScheduler(db, encryptor, cfg.SyncIntervalCommits, cfg.SyncIntervalJira, cfg.ReportAutoGenerate, cfg.ReportAutoTime, cfg.ReportMonthlyAutoTime, r2Client, weaviateClient)
+		scheduler := worker.NewScheduler(db, encryptor, cfg.SyncIntervalCommits, cfg.SyncIntervalJira, cfg.ReportAutoGenerate, cfg.ReportAutoTime, cfg.ReportMonthlyAutoTime, cfg.ReportWeeklyAutoTime, r2Client, weaviateClient)
 		scheduler.EventBus = eventBus
 		scheduler.Start(ctx)
 		syncStatus = scheduler.Status
@@ -104,17 +119,20 @@ func main() {
 		JWTExpiryHours: cfg.JWTExpiryHours,
 	}
 	userHandler := &handlers.UserHandler{DB: db, Encryptor: encryptor}
-	repoHandler := &handlers.RepoHandler{DB: db}
+	repoHandler := &handlers.RepoHandler{DB: db, Encryptor: encryptor}
+	gitConnHandler := &handlers.GitConnectionHandler{DB: db, Encryptor: encryptor}
+	webhookHandler := &handlers.WebhookHandler{DB: db, Encryptor: encryptor, Weaviate: weaviateClient, EventBus: eventBus}
 	syncHandler := &handlers.SyncHandler{DB: db, Encryptor: encryptor, Status: syncStatus}
 	commitHandler := &handlers.CommitHandler{DB: db}
+	pullRequestHandler := &handlers.PullRequestHandler{DB: db}
+	identityHandler := &handlers.IdentityHandler{DB: db}
 	jiraHandler := &handlers.JiraHandler{DB: db, Encryptor: encryptor}
+	timesheetHandler := &handlers.TimesheetHandler{DB: db, Encryptor: encryptor}
 	reportGen := report.NewGenerator(db, encryptor)
-	reportHandler := &handlers.ReportHandler{DB: db, Generator: reportGen, R2: r2Client}
-
-	var miniMaxClient *minimax.Client
-	if cfg.MiniMaxAPIKey != "" {
-		miniMaxClient = minimax.NewClient(cfg.MiniMaxAPIKey, cfg.MiniMaxGroupID)
+	if miniMaxClient != nil {
+		reportGen.LLM, reportGen.LLMModel = miniMaxClient, miniMaxClient.Model
 	}
+	reportHandler := &handlers.ReportHandler{DB: db, Generator: reportGen, R2: r2Client}
 
 	composioClient := composio.NewClient()
 
@@ -168,7 +186,7 @@ func main() {
 		}
 		scheduleEngine = agentScheduler.NewEngine(db, miniMaxClient, eventBus, notifier,
 			&agent.GitAgent{DB: db, Encryptor: encryptor, Weaviate: weaviateClient},
-			&agent.JiraAgent{DB: db, Weaviate: weaviateClient},
+			&agent.JiraAgent{DB: db, Weaviate: weaviateClient, Encryptor: encryptor},
 			&agent.ReportAgent{DB: db, Generator: reportGen, R2: r2Client},
 			&agent.ProofAgent{DB: db},
 			&agent.BriefingAgent{DB: db},
@@ -178,7 +196,7 @@ func main() {
 		scheduleEngine.SetAgentBuilder(func(userID uint) []agent.Agent {
 			agents := []agent.Agent{
 				&agent.GitAgent{DB: db, UserID: userID, Encryptor: encryptor, Weaviate: weaviateClient},
-				&agent.JiraAgent{DB: db, UserID: userID, Weaviate: weaviateClient},
+				&agent.JiraAgent{DB: db, UserID: userID, Weaviate: weaviateClient, Encryptor: encryptor},
 				&agent.ReportAgent{DB: db, UserID: userID, Generator: reportGen, R2: r2Client},
 				&agent.ProofAgent{DB: db, UserID: userID},
 				&agent.BriefingAgent{DB: db, UserID: userID},
@@ -208,7 +226,8 @@ func main() {
 	}
 
 	// Router
-	r := gin.Default()
+	r := gin.New()
+	r.Use(middleware.Logger(), gin.Recovery())
 
 	// CORS middleware
 	r.Use(cors.New(cors.Config{
@@ -230,6 +249,14 @@ func main() {
 			auth.POST("/login", authHandler.Login)
 		}
 
+		// Authenticated by a per-repository or per-workspace webhook secret instead of a JWT.
+		webhooks := api.Group("/webhooks")
+		{
+			webhooks.POST("/github/:repoId", webhookHandler.GitHub)
+			webhooks.POST("/gitlab/:repoId", webhookHandler.GitLab)
+			webhooks.POST("/jira/:secret", webhookHandler.Jira)
+		}
+
 		protected := api.Group("")
 		protected.Use(middleware.JWTAuth(cfg.JWTSecret))
 		{
@@ -246,6 +273,16 @@ func main() {
 				repos.POST("", repoHandler.Add)
 				repos.DELETE("/:id", repoHandler.Delete)
 				repos.POST("/:id/validate", repoHandler.Validate)
+				repos.POST("/:id/webhook", repoHandler.EnableWebhook)
+				repos.DELETE("/:id/webhook", repoHandler.DisableWebhook)
+			}
+
+			gitConns := protected.Group("/git/connections")
+			{
+				gitConns.GET("", gitConnHandler.List)
+				gitConns.POST("", gitConnHandler.Create)
+				gitConns.PATCH("/:id", gitConnHandler.Update)
+				gitConns.DELETE("/:id", gitConnHandler.Delete)
 			}
 
 			protected.POST("/sync/commits", syncHandler.SyncCommits)
@@ -259,26 +296,79 @@ func main() {
 				commits.POST("/:sha/link", commitHandler.Link)
 			}
 
+			pulls := protected.Group("/pull-requests")
+			{
+				pulls.GET("", pullRequestHandler.List)
+				pulls.GET("/:id", pullRequestHandler.Get)
+			}
+
+			people := protected.Group("/identity")
+			{
+				people.GET("/people", identityHandler.ListPeople)
+				people.POST("/people", identityHandler.CreatePerson)
+				people.GET("/people/:id", identityHandler.GetPerson)
+				people.PATCH("/people/:id", identityHandler.UpdatePerson)
+				people.DELETE("/people/:id", identityHandler.DeletePerson)
+				people.POST("/people/:id/identities", identityHandler.AddIdentity)
+				people.DELETE("/people/:id/identities/:identityId", identityHandler.RemoveIdentity)
+				people.POST("/people/:id/merge", identityHandler.Merge)
+				people.POST("/discover", identityHandler.Discover)
+				people.GET("/suggestions", identityHandler.ListSuggestions)
+				people.POST("/suggestions/:id/accept", identityHandler.AcceptSuggestion)
+				people.POST("/suggestions/:id/reject", identityHandler.RejectSuggestion)
+			}
+
 			jira := protected.Group("/jira")
 			{
 				jira.GET("/workspaces", jiraHandler.ListWorkspaces)
 				jira.POST("/workspaces", jiraHandler.AddWorkspace)
 				jira.PATCH("/workspaces/:id", jiraHandler.UpdateWorkspace)
 				jira.DELETE("/workspaces/:id", jiraHandler.DeleteWorkspace)
+				jira.POST("/workspaces/:id/validate", jiraHandler.ValidateWorkspace)
+				jira.POST("/workspaces/:id/webhook", jiraHandler.EnableWebhook)
+				jira.DELETE("/workspaces/:id/webhook", jiraHandler.DisableWebhook)
 				jira.GET("/sprints", jiraHandler.ListSprints)
 				jira.GET("/sprints/:id", jiraHandler.GetSprint)
+				jira.GET("/sprints/:id/burndown", jiraHandler.SprintBurndown)
+				jira.GET("/sprints/:id/burnup", jiraHandler.SprintBurnup)
+				jira.GET("/sprints/:id/commitment", jiraHandler.SprintCommitment)
+				jira.GET("/sprints/:id/carry-over", jiraHandler.SprintCarryOver)
+				jira.GET("/velocity", jiraHandler.Velocity)
 				jira.GET("/active-sprint", jiraHandler.GetActiveSprint)
 				jira.GET("/cards", jiraHandler.ListCards)
 				jira.GET("/cards/:key", jiraHandler.GetCard)
 				jira.GET("/cards/:key/comments", jiraHandler.GetCardComments)
+				jira.GET("/cards/:key/transitions", jiraHandler.GetCardTransitions)
+				jira.GET("/cards/:key/history", jiraHandler.GetCardHistory)
+				jira.GET("/metrics/cycle-time", jiraHandler.CycleTime)
+				jira.GET("/metrics/lead-time", jiraHandler.LeadTime)
+				jira.GET("/metrics/time-in-status", jiraHandler.TimeInStatus)
+				jira.GET("/metrics/throughput", jiraHandler.Throughput)
+				jira.GET("/outbox", jiraHandler.ListOutbox)
+				jira.POST("/outbox", jiraHandler.CreateOutbox)
+				jira.PATCH("/outbox/:id", jiraHandler.UpdateOutbox)
+				jira.DELETE("/outbox/:id", jiraHandler.DeleteOutbox)
+			}
+
+			timesheets := protected.Group("/timesheet")
+			{
+				timesheets.GET("", timesheetHandler.Get)
+				timesheets.PATCH("/entries", timesheetHandler.UpdateEntry)
+				timesheets.POST("/approve", timesheetHandler.Approve)
 			}
 
 			reports := protected.Group("/reports")
 			{
 				reports.POST("/generate", reportHandler.Generate)
 				reports.POST("/generate/monthly", reportHandler.GenerateMonthly)
+				reports.POST("/generate/range", reportHandler.GenerateRange)
 				reports.GET("", reportHandler.List)
 				reports.GET("/:id", reportHandler.Get)
+				reports.GET("/:id/export", reportHandler.Export)
+				reports.GET("/:id/versions", reportHandler.ListVersions)
+				reports.GET("/:id/versions/:version", reportHandler.GetVersion)
+				reports.POST("/:id/versions/:version/restore", reportHandler.RestoreVersion)
+				reports.GET("/:id/diff", reportHandler.DiffVersions)
 				reports.DELETE("/:id", reportHandler.Delete)
 
 				templates := reports.Group("/templates")
@@ -292,9 +382,12 @@ func main() {
 			}
 
 			execLLM := agent.NewMinimaxExecutiveLLM(miniMaxClient, miniMaxClient.Model)
+			execCorrelator := executive.NewCorrelator(executive.NewWeaviateAdapter(db, weaviateClient))
+			execCorrelator.People = &identity.Resolver{DB: db}
+			execCorrelator.Sprints = executive.NewSprintAdapter(db)
 			execHandler := &handlers.ExecutiveReportHandler{
 				DB:         db,
-				Correlator: executive.NewCorrelator(executive.NewWeaviateAdapter(db, weaviateClient)),
+				Correlator: execCorrelator,
 				Agent:      &agent.ExecutiveReportAgent{LLM: execLLM},
 			}
 			executiveGroup := protected.Group("/reports/executive")
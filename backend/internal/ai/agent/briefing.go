@@ -4,11 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/cds-id/pdt/backend/internal/ai/minimax"
 	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/cardlink"
+	"github.com/cds-id/pdt/backend/internal/services/flow"
+	"github.com/cds-id/pdt/backend/internal/services/identity"
+	"github.com/cds-id/pdt/backend/internal/services/sprintstats"
 	"gorm.io/gorm"
 )
 
@@ -55,6 +60,8 @@ FORMAT (respond in user's language):
 - [KEY] Summary — last commit [date]
 ### 📋 Belum Dimulai (To Do)
 - [KEY] Summary
+### 📊 Sprint Health
+- Committed vs completed points, scope change, remaining vs ideal, carry-over (from sprint_health)
 ### ⚠️ Blocker / Risiko
 - [KEY] Issue — severity, suggestion
 ### 💬 Komentar Eksternal (dari orang lain)
@@ -117,6 +124,18 @@ func (a *BriefingAgent) Tools() []minimax.Tool {
 				}
 			}`),
 		},
+		{
+			Name:        "sprint_analytics",
+			Description: "Sprint health in numbers: committed vs completed story points, scope added or removed since the start, remaining vs ideal burndown, carry-over cards, and velocity over the last closed sprints. Defaults to the active sprints.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"sprint_name": {"type": "string", "description": "Sprint name (e.g., 'BNS Sprint 13')"},
+					"sprint_id": {"type": "integer", "description": "Sprint ID"},
+					"velocity_sprints": {"type": "integer", "description": "How many closed sprints to average velocity over (default 5)"}
+				}
+			}`),
+		},
 		{
 			Name:        "search_comments",
 			Description: "Search Jira comments to find miscommunication, unanswered questions, or escalation from product/PM. Use this to find communication gaps.",
@@ -145,6 +164,8 @@ func (a *BriefingAgent) ExecuteTool(ctx context.Context, name string, args json.
 		return a.auditSprintCards(args)
 	case "find_blockers":
 		return a.findBlockers(args)
+	case "sprint_analytics":
+		return a.sprintAnalytics(args)
 	case "search_comments":
 		return a.searchComments(args)
 	default:
@@ -180,15 +201,47 @@ func (a *BriefingAgent) resolveSprintID(sprintID int, sprintName string) []uint
 	return ids
 }
 
+// getAssignee returns the Jira display name to filter by: the requested
+// person, or the current user when empty. Names the identity map does not
+// know are returned unchanged.
 func (a *BriefingAgent) getAssignee(assignee string) string {
-	if assignee != "" {
-		return assignee
+	if assignee == "" {
+		var user models.User
+		if a.DB.First(&user, a.UserID).Error != nil {
+			return ""
+		}
+		if user.JiraUsername == "" {
+			// The user's own email may still be linked to their Jira name.
+			if person, _ := identity.Find(a.DB, a.UserID, user.Email); person != nil {
+				return person.JiraDisplayName
+			}
+			return ""
+		}
+		assignee = user.JiraUsername
 	}
-	var user models.User
-	if a.DB.First(&user, a.UserID).Error == nil && user.JiraUsername != "" {
-		return user.JiraUsername
+	if person, _ := identity.Find(a.DB, a.UserID, assignee); person != nil && person.JiraDisplayName != "" {
+		return person.JiraDisplayName
+	}
+	return assignee
+}
+
+// assigneeNames returns every name the assignee is known under, or nil when
+// the identity map does not know them.
+func (a *BriefingAgent) assigneeNames(assignee string) []string {
+	if person, _ := identity.Find(a.DB, a.UserID, assignee); person != nil {
+		return identity.AliasesOf(person).Names
+	}
+	return nil
+}
+
+func (a *BriefingAgent) whereAssignee(query *gorm.DB, assignee string) *gorm.DB {
+	if assignee == "" {
+		return query
+	}
+	if names := a.assigneeNames(assignee); names != nil {
+		return query.Where("assignee IN ?", names)
 	}
-	return ""
+	return query.Where("assignee LIKE ?", "%"+assignee+"%")
 }
 
 func (a *BriefingAgent) fullReport(args json.RawMessage) (any, error) {
@@ -209,6 +262,7 @@ func (a *BriefingAgent) fullReport(args json.RawMessage) (any, error) {
 	briefing, _ := a.generateBriefing(subArgs)
 	audit, _ := a.auditSprintCards(subArgs)
 	blockers, _ := a.findBlockers(subArgs)
+	sprintHealth, _ := a.sprintAnalytics(subArgs)
 
 	// Search all comments in the time window
 	assignee := a.getAssignee(params.Assignee)
@@ -227,12 +281,14 @@ func (a *BriefingAgent) fullReport(args json.RawMessage) (any, error) {
 		Body    string `json:"body"`
 	}
 	var externalComments, myComments []commentEntry
+	myNames := a.assigneeNames(assignee)
 	// Convert through JSON to handle the local type
 	if raw, err := json.Marshal(allComments); err == nil {
 		var commentList []commentEntry
 		if json.Unmarshal(raw, &commentList) == nil {
 			for _, c := range commentList {
-				if assignee != "" && strings.Contains(strings.ToLower(c.Author), strings.ToLower(assignee)) {
+				own := slices.ContainsFunc(myNames, func(n string) bool { return strings.EqualFold(n, c.Author) })
+				if assignee != "" && (own || strings.Contains(strings.ToLower(c.Author), strings.ToLower(assignee))) {
 					myComments = append(myComments, c)
 				} else {
 					externalComments = append(externalComments, c)
@@ -245,6 +301,7 @@ func (a *BriefingAgent) fullReport(args json.RawMessage) (any, error) {
 		"briefing":           briefing,
 		"audit":              audit,
 		"blockers":           blockers,
+		"sprint_health":      sprintHealth,
 		"external_comments":  externalComments,
 		"my_comments":        myComments,
 		"assignee":           assignee,
@@ -274,9 +331,7 @@ func (a *BriefingAgent) generateBriefing(args json.RawMessage) (any, error) {
 
 	// Get cards
 	query := a.DB.Where("user_id = ? AND sprint_id IN ?", a.UserID, sprintIDs)
-	if assignee != "" {
-		query = query.Where("assignee LIKE ?", "%"+assignee+"%")
-	}
+	query = a.whereAssignee(query, assignee)
 	var cards []models.JiraCard
 	query.Find(&cards)
 
@@ -299,7 +354,7 @@ func (a *BriefingAgent) generateBriefing(args json.RawMessage) (any, error) {
 		// Find recent commits for this card (within days_back window)
 		var commits []models.Commit
 		a.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
-			Where("repositories.user_id = ? AND commits.jira_card_key = ? AND commits.date >= ?",
+			Where("repositories.user_id = ? AND "+cardlink.OnCard+" AND commits.date >= ?",
 				a.UserID, c.Key, since).
 			Order("commits.date desc").Limit(5).Find(&commits)
 
@@ -314,41 +369,19 @@ func (a *BriefingAgent) generateBriefing(args json.RawMessage) (any, error) {
 		// Get last commit date for this card (any time)
 		var lastCommit models.Commit
 		if a.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
-			Where("repositories.user_id = ? AND commits.jira_card_key = ?", a.UserID, c.Key).
+			Where("repositories.user_id = ? AND "+cardlink.OnCard, a.UserID, c.Key).
 			Order("commits.date desc").First(&lastCommit).Error == nil {
 			entry.LastCommitDate = lastCommit.Date.Format("2006-01-02")
 		}
 
-		// Determine completion date from changelog
+		// Determine completion date from the stored status transitions
 		status := strings.ToLower(c.Status)
 		isDone := status == "done" || status == "ready to test" || status == "in review"
-		if isDone && c.DetailsJSON != "" {
-			var details struct {
-				Changelog []struct {
-					Created string `json:"created"`
-					Items   []struct {
-						Field      string `json:"field"`
-						FromString string `json:"from_string"`
-						ToString   string `json:"to_string"`
-					} `json:"items"`
-				} `json:"changelog"`
-			}
-			if json.Unmarshal([]byte(c.DetailsJSON), &details) == nil {
-				var latestTransitionTime time.Time
-				for _, h := range details.Changelog {
-					for _, item := range h.Items {
-						if item.Field == "status" {
-							if t, err := time.Parse("2006-01-02T15:04:05.000-0700", h.Created); err == nil {
-								if t.After(latestTransitionTime) {
-									latestTransitionTime = t
-									entry.LastTransition = fmt.Sprintf("%s → %s on %s", item.FromString, item.ToString, t.Format("2006-01-02"))
-								}
-								if item.ToString == c.Status {
-									entry.CompletedDate = t.Format("2006-01-02")
-								}
-							}
-						}
-					}
+		if isDone {
+			for _, t := range flow.History(a.DB, a.UserID, c.Key) {
+				entry.LastTransition = fmt.Sprintf("%s → %s on %s", t.FromStatus, t.ToStatus, t.TransitionedAt.Format("2006-01-02"))
+				if t.ToStatus == c.Status {
+					entry.CompletedDate = t.TransitionedAt.Format("2006-01-02")
 				}
 			}
 		}
@@ -403,9 +436,7 @@ func (a *BriefingAgent) auditSprintCards(args json.RawMessage) (any, error) {
 	assignee := a.getAssignee(params.Assignee)
 
 	query := a.DB.Where("user_id = ? AND sprint_id IN ?", a.UserID, sprintIDs)
-	if assignee != "" {
-		query = query.Where("assignee LIKE ?", "%"+assignee+"%")
-	}
+	query = a.whereAssignee(query, assignee)
 	var cards []models.JiraCard
 	query.Find(&cards)
 
@@ -440,7 +471,7 @@ func (a *BriefingAgent) auditSprintCards(args json.RawMessage) (any, error) {
 		var commitCount int64
 		a.DB.Model(&models.Commit{}).
 			Joins("JOIN repositories ON repositories.id = commits.repo_id").
-			Where("repositories.user_id = ? AND commits.jira_card_key = ?", a.UserID, c.Key).
+			Where("repositories.user_id = ? AND "+cardlink.OnCard, a.UserID, c.Key).
 			Count(&commitCount)
 
 		if c.Status == "In Progress" && commitCount == 0 {
@@ -459,7 +490,7 @@ func (a *BriefingAgent) auditSprintCards(args json.RawMessage) (any, error) {
 		if c.Status == "In Progress" && commitCount > 0 {
 			var lastCommit models.Commit
 			a.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
-				Where("repositories.user_id = ? AND commits.jira_card_key = ?", a.UserID, c.Key).
+				Where("repositories.user_id = ? AND "+cardlink.OnCard, a.UserID, c.Key).
 				Order("commits.date desc").First(&lastCommit)
 			if time.Since(lastCommit.Date) > 3*24*time.Hour {
 				risks = append(risks, fmt.Sprintf("stale — last commit was %s, %d days ago",
@@ -518,12 +549,15 @@ func (a *BriefingAgent) findBlockers(args json.RawMessage) (any, error) {
 	assignee := a.getAssignee(params.Assignee)
 
 	query := a.DB.Where("user_id = ? AND sprint_id IN ?", a.UserID, sprintIDs)
-	if assignee != "" {
-		query = query.Where("assignee LIKE ?", "%"+assignee+"%")
-	}
+	query = a.whereAssignee(query, assignee)
 	var cards []models.JiraCard
 	query.Find(&cards)
 
+	ownNames := a.assigneeNames(assignee)
+	if ownNames == nil {
+		ownNames = []string{assignee}
+	}
+
 	type blocker struct {
 		Key         string `json:"key"`
 		Summary     string `json:"summary"`
@@ -545,7 +579,7 @@ func (a *BriefingAgent) findBlockers(args json.RawMessage) (any, error) {
 		threeDaysAgo := time.Now().AddDate(0, 0, -3)
 		a.DB.Model(&models.Commit{}).
 			Joins("JOIN repositories ON repositories.id = commits.repo_id").
-			Where("repositories.user_id = ? AND commits.jira_card_key = ? AND commits.date >= ?",
+			Where("repositories.user_id = ? AND "+cardlink.OnCard+" AND commits.date >= ?",
 				a.UserID, c.Key, threeDaysAgo).
 			Count(&recentCommitCount)
 
@@ -575,8 +609,8 @@ func (a *BriefingAgent) findBlockers(args json.RawMessage) (any, error) {
 		// Check: comments asking for updates or raising concerns
 		var recentComments []models.JiraComment
 		weekAgo := time.Now().AddDate(0, 0, -7)
-		a.DB.Where("user_id = ? AND card_key = ? AND commented_at >= ? AND author != ?",
-			a.UserID, c.Key, weekAgo, assignee).
+		a.DB.Where("user_id = ? AND card_key = ? AND commented_at >= ? AND author NOT IN ?",
+			a.UserID, c.Key, weekAgo, ownNames).
 			Order("commented_at desc").Limit(5).Find(&recentComments)
 
 		for _, comment := range recentComments {
@@ -619,6 +653,54 @@ func (a *BriefingAgent) findBlockers(args json.RawMessage) (any, error) {
 	}, nil
 }
 
+func (a *BriefingAgent) sprintAnalytics(args json.RawMessage) (any, error) {
+	var params struct {
+		SprintID        int    `json:"sprint_id"`
+		SprintName      string `json:"sprint_name"`
+		VelocitySprints int    `json:"velocity_sprints"`
+	}
+	json.Unmarshal(args, &params)
+	if params.VelocitySprints <= 0 {
+		params.VelocitySprints = 5
+	}
+
+	now := time.Now()
+	var sprints []map[string]any
+	for _, id := range a.resolveSprintID(params.SprintID, params.SprintName) {
+		series, err := sprintstats.Build(a.DB, a.UserID, id, now)
+		if err != nil {
+			continue
+		}
+		carried, err := sprintstats.CarryOver(a.DB, series)
+		if err != nil {
+			continue
+		}
+		entry := map[string]any{
+			"sprint":     series.Sprint.Name,
+			"state":      series.Sprint.State,
+			"commitment": series.Commitment(),
+			"carried_in": carried.In,
+			"not_done":   carried.NotDone,
+		}
+		if len(series.Days) > 0 {
+			today := series.Days[len(series.Days)-1]
+			entry["remaining"] = today.Remaining
+			entry["ideal_remaining_points"] = today.Ideal
+		}
+		sprints = append(sprints, entry)
+	}
+
+	velocity, err := sprintstats.Velocity(a.DB, a.UserID, nil, params.VelocitySprints, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"sprints":  sprints,
+		"velocity": velocity,
+	}, nil
+}
+
 func (a *BriefingAgent) searchComments(args json.RawMessage) (any, error) {
 	var params struct {
 		Author  string `json:"author"`
@@ -43,6 +43,7 @@ func buildExecutiveSystemPrompt(r executive.DateRange) string {
 		r.Start.Format(time.DateOnly) + " and " + r.End.Format(time.DateOnly) +
 		". Produce sections in exactly this order: ## Summary, ## Topics, ## Gaps, ## Stale Work, ## Next Steps. " +
 		"Cite evidence inline as [jira:KEY], [commit:sha], [wa:sender@time]. " +
+		"When the dataset has sprints, open ## Summary with committed vs completed points, scope added or removed, and velocity against the recent average. " +
 		"Whenever you identify a gap, stale item, or next-step recommendation, call the emit_suggestion tool with kind=gap|stale|next_step."
 }
 
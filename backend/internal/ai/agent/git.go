@@ -10,8 +10,9 @@ import (
 	"github.com/cds-id/pdt/backend/internal/crypto"
 	"github.com/cds-id/pdt/backend/internal/models"
 	"github.com/cds-id/pdt/backend/internal/services"
-	githubsvc "github.com/cds-id/pdt/backend/internal/services/github"
-	gitlabsvc "github.com/cds-id/pdt/backend/internal/services/gitlab"
+	"github.com/cds-id/pdt/backend/internal/services/cardlink"
+	"github.com/cds-id/pdt/backend/internal/services/gitprovider"
+	"github.com/cds-id/pdt/backend/internal/services/identity"
 	wvClient "github.com/cds-id/pdt/backend/internal/services/weaviate"
 	"gorm.io/gorm"
 )
@@ -47,6 +48,8 @@ func (a *GitAgent) Tools() []minimax.Tool {
 				"properties": {
 					"keyword": {"type": "string", "description": "Search keyword in commit message"},
 					"repo": {"type": "string", "description": "Repository name filter"},
+					"author": {"type": "string", "description": "Author name, email or username (default: the user's own commits)"},
+					"all_authors": {"type": "boolean", "description": "Include commits by every author, not just the user's own"},
 					"since": {"type": "string", "description": "Start date (YYYY-MM-DD)"},
 					"until": {"type": "string", "description": "End date (YYYY-MM-DD)"},
 					"limit": {"type": "integer", "description": "Max results (default 20)"}
@@ -141,11 +144,13 @@ func (a *GitAgent) ExecuteTool(ctx context.Context, name string, args json.RawMe
 
 func (a *GitAgent) searchCommits(args json.RawMessage) (any, error) {
 	var params struct {
-		Keyword string `json:"keyword"`
-		Repo    string `json:"repo"`
-		Since   string `json:"since"`
-		Until   string `json:"until"`
-		Limit   int    `json:"limit"`
+		Keyword    string `json:"keyword"`
+		Repo       string `json:"repo"`
+		Author     string `json:"author"`
+		AllAuthors bool   `json:"all_authors"`
+		Since      string `json:"since"`
+		Until      string `json:"until"`
+		Limit      int    `json:"limit"`
 	}
 	json.Unmarshal(args, &params)
 	if params.Limit == 0 {
@@ -156,6 +161,17 @@ func (a *GitAgent) searchCommits(args json.RawMessage) (any, error) {
 		Where("repositories.user_id = ?", a.UserID).
 		Preload("Repository")
 
+	if params.Author != "" {
+		query = identity.ScopeCommitAuthor(a.DB, a.UserID, params.Author, query)
+	} else if !params.AllAuthors {
+		var user models.User
+		if a.DB.First(&user, a.UserID).Error == nil {
+			if mine, ok := identity.AuthorFilter(a.DB, user); ok {
+				query = mine.ScopeCommits(query)
+			}
+		}
+	}
+
 	if params.Keyword != "" {
 		query = query.Where("commits.message LIKE ?", "%"+params.Keyword+"%")
 	}
@@ -254,10 +270,11 @@ func (a *GitAgent) getRepoStats(args json.RawMessage) (any, error) {
 		Count  int64  `json:"count"`
 	}
 	var branches []branchStat
-	a.DB.Model(&models.Commit{}).
-		Select("branch, count(*) as count").
-		Where("repo_id = ? AND date >= ?", repo.ID, since).
-		Group("branch").Order("count desc").Limit(10).
+	a.DB.Model(&models.CommitBranch{}).
+		Select("commit_branches.branch, count(*) as count").
+		Joins("JOIN commits ON commits.id = commit_branches.commit_id").
+		Where("commits.repo_id = ? AND commits.date >= ?", repo.ID, since).
+		Group("commit_branches.branch").Order("count desc").Limit(10).
 		Scan(&branches)
 
 	return map[string]any{
@@ -309,26 +326,11 @@ func (a *GitAgent) fetchDiffForCommit(commit models.Commit) (*services.CommitDif
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
-	var provider services.DiffProvider
-	switch repo.Provider {
-	case models.ProviderGitHub:
-		token, err := a.Encryptor.Decrypt(user.GithubToken)
-		if err != nil {
-			return nil, fmt.Errorf("decrypt github token: %w", err)
-		}
-		client := githubsvc.New()
-		return client.FetchCommitDiff(repo.Owner, repo.Name, commit.SHA, token)
-	case models.ProviderGitLab:
-		token, err := a.Encryptor.Decrypt(user.GitlabToken)
-		if err != nil {
-			return nil, fmt.Errorf("decrypt gitlab token: %w", err)
-		}
-		client := gitlabsvc.New(user.GitlabURL)
-		provider = client
-		return provider.FetchCommitDiff(repo.Owner, repo.Name, commit.SHA, token)
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", repo.Provider)
+	client, token, err := gitprovider.ForRepository(a.DB, a.Encryptor, user, repo)
+	if err != nil {
+		return nil, err
 	}
+	return client.FetchCommitDiff(repo.Owner, repo.Name, commit.SHA, token)
 }
 
 func (a *GitAgent) getCommitChanges(args json.RawMessage) (any, error) {
@@ -361,7 +363,7 @@ func (a *GitAgent) analyzeCardChanges(args json.RawMessage) (any, error) {
 
 	var commits []models.Commit
 	if err := a.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
-		Where("repositories.user_id = ? AND commits.jira_card_key = ?", a.UserID, params.CardKey).
+		Where("repositories.user_id = ? AND "+cardlink.OnCard, a.UserID, params.CardKey).
 		Preload("Repository").
 		Find(&commits).Error; err != nil {
 		return nil, fmt.Errorf("query commits: %w", err)
@@ -8,16 +8,21 @@ import (
 	"time"
 
 	"github.com/cds-id/pdt/backend/internal/ai/minimax"
+	"github.com/cds-id/pdt/backend/internal/crypto"
 	"github.com/cds-id/pdt/backend/internal/helpers"
 	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/cardlink"
+	"github.com/cds-id/pdt/backend/internal/services/flow"
+	"github.com/cds-id/pdt/backend/internal/services/jiraoutbox"
 	wvClient "github.com/cds-id/pdt/backend/internal/services/weaviate"
 	"gorm.io/gorm"
 )
 
 type JiraAgent struct {
-	DB       *gorm.DB
-	UserID   uint
-	Weaviate *wvClient.Client
+	DB        *gorm.DB
+	UserID    uint
+	Weaviate  *wvClient.Client
+	Encryptor *crypto.Encryptor // needed to look up transitions in Jira
 }
 
 func (a *JiraAgent) Name() string { return "jira" }
@@ -40,7 +45,12 @@ func (a *JiraAgent) SystemPrompt() string {
 
 WORKSPACES:%s
 
-When the user asks about a specific workspace or project, use the workspace_id filter. When not specified, results come from all workspaces.`, today, wsList)
+When the user asks about a specific workspace or project, use the workspace_id filter. When not specified, results come from all workspaces.
+
+CHANGING JIRA:
+- You cannot change Jira directly. propose_comment, propose_transition and propose_worklog only queue a pending entry in the Jira outbox; nothing reaches Jira until the user approves it there.
+- ALWAYS explain in 'context' why you are proposing the change, and tell the user it is waiting for their approval.
+- Use get_card_transitions to see which statuses a card can move to before proposing a transition.`, today, wsList)
 }
 
 func (a *JiraAgent) Tools() []minimax.Tool {
@@ -58,12 +68,13 @@ func (a *JiraAgent) Tools() []minimax.Tool {
 		},
 		{
 			Name:        "get_cards",
-			Description: "List Jira cards, optionally filtered by sprint, status, assignee, or workspace",
+			Description: "List Jira cards, optionally filtered by sprint, status, assignee, or workspace. Backlog and Kanban cards have no sprint",
 			InputSchema: json.RawMessage(`{
 				"type": "object",
 				"properties": {
 					"sprint_id": {"type": "integer", "description": "Filter by sprint ID"},
 					"sprint_name": {"type": "string", "description": "Filter by sprint name (e.g., 'BNS Sprint 13')"},
+					"backlog": {"type": "boolean", "description": "Only cards in the backlog, for planning questions"},
 					"status": {"type": "string", "description": "Filter by card status (case-insensitive, e.g., 'Done', 'In Progress', 'READY TO TEST')"},
 					"assignee": {"type": "string", "description": "Filter by assignee name (partial match)"},
 					"keyword": {"type": "string", "description": "Search keyword in card summary"},
@@ -120,6 +131,59 @@ func (a *JiraAgent) Tools() []minimax.Tool {
 				"required": ["sha", "card_key"]
 			}`),
 		},
+		{
+			Name:        "get_card_transitions",
+			Description: "List the workflow transitions currently available on a Jira card, fetched live from Jira. Each has an id, a name and the status it moves the card to.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"key": {"type": "string", "description": "Jira card key (e.g., PDT-123)"}
+				},
+				"required": ["key"]
+			}`),
+		},
+		{
+			Name:        "propose_comment",
+			Description: "Queue a comment on a Jira card for the user's approval. Does NOT post it. Set include_commits=true to append a summary of the commits linked to the card.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"card_key": {"type": "string", "description": "Jira card key (e.g., PDT-123)"},
+					"body": {"type": "string", "description": "Comment text"},
+					"include_commits": {"type": "boolean", "description": "Append the list of commits linked to the card"},
+					"context": {"type": "string", "description": "Why this comment is proposed"}
+				},
+				"required": ["card_key", "body", "context"]
+			}`),
+		},
+		{
+			Name:        "propose_transition",
+			Description: "Queue moving a Jira card through its workflow for the user's approval. Does NOT transition it. The transition is matched by id, transition name or target status when approved.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"card_key": {"type": "string", "description": "Jira card key (e.g., PDT-123)"},
+					"transition": {"type": "string", "description": "Transition id or name, or the target status (e.g., 'Done')"},
+					"context": {"type": "string", "description": "Why this transition is proposed"}
+				},
+				"required": ["card_key", "transition", "context"]
+			}`),
+		},
+		{
+			Name:        "propose_worklog",
+			Description: "Queue a worklog on a Jira card for the user's approval. Does NOT log the time.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"card_key": {"type": "string", "description": "Jira card key (e.g., PDT-123)"},
+					"minutes": {"type": "integer", "description": "Time spent in minutes"},
+					"started": {"type": "string", "description": "When the work started (YYYY-MM-DD HH:MM, local time). Defaults to now minus the time spent."},
+					"comment": {"type": "string", "description": "Optional worklog comment"},
+					"context": {"type": "string", "description": "Why this worklog is proposed"}
+				},
+				"required": ["card_key", "minutes", "context"]
+			}`),
+		},
 	}
 }
 
@@ -137,6 +201,14 @@ func (a *JiraAgent) ExecuteTool(ctx context.Context, name string, args json.RawM
 		return a.semanticSearchCards(ctx, args)
 	case "link_commit_to_card":
 		return a.linkCommitToCard(args)
+	case "get_card_transitions":
+		return a.getCardTransitions(args)
+	case "propose_comment":
+		return a.proposeComment(args)
+	case "propose_transition":
+		return a.proposeTransition(args)
+	case "propose_worklog":
+		return a.proposeWorklog(args)
 	default:
 		return nil, fmt.Errorf("unknown tool: %s", name)
 	}
@@ -193,6 +265,7 @@ func (a *JiraAgent) getCards(args json.RawMessage) (any, error) {
 	var params struct {
 		SprintID    int    `json:"sprint_id"`
 		SprintName  string `json:"sprint_name"`
+		Backlog     bool   `json:"backlog"`
 		Status      string `json:"status"`
 		Assignee    string `json:"assignee"`
 		Keyword     string `json:"keyword"`
@@ -220,6 +293,9 @@ func (a *JiraAgent) getCards(args json.RawMessage) (any, error) {
 	if params.SprintID > 0 {
 		query = query.Where("sprint_id = ?", params.SprintID)
 	}
+	if params.Backlog {
+		query = query.Where("in_backlog = ?", true)
+	}
 	if params.Status != "" {
 		query = query.Where("LOWER(status) = LOWER(?)", params.Status)
 	}
@@ -283,41 +359,17 @@ func (a *JiraAgent) getCardDetail(args json.RawMessage) (any, error) {
 	return result, nil
 }
 
-// extractTransitions pulls status transitions from DetailsJSON changelog
-func (a *JiraAgent) extractTransitions(detailsJSON string) []map[string]string {
-	if detailsJSON == "" {
-		return nil
-	}
-	var details struct {
-		Changelog []struct {
-			Author  string `json:"author"`
-			Created string `json:"created"`
-			Items   []struct {
-				Field      string `json:"field"`
-				FromString string `json:"from_string"`
-				ToString   string `json:"to_string"`
-			} `json:"items"`
-		} `json:"changelog"`
-	}
-	if json.Unmarshal([]byte(detailsJSON), &details) != nil {
-		return nil
-	}
-
+// extractTransitions returns the stored status transitions of a card
+func (a *JiraAgent) extractTransitions(cardKey string) []map[string]string {
 	var transitions []map[string]string
-	for _, h := range details.Changelog {
-		date := h.Created
-		if t, err := time.Parse("2006-01-02T15:04:05.000-0700", h.Created); err == nil {
-			date = t.Format("2006-01-02 15:04")
-		}
-		for _, item := range h.Items {
-			transitions = append(transitions, map[string]string{
-				"field": item.Field,
-				"from":  item.FromString,
-				"to":    item.ToString,
-				"by":    h.Author,
-				"date":  date,
-			})
-		}
+	for _, t := range flow.History(a.DB, a.UserID, cardKey) {
+		transitions = append(transitions, map[string]string{
+			"field": "status",
+			"from":  t.FromStatus,
+			"to":    t.ToStatus,
+			"by":    t.Author,
+			"date":  t.TransitionedAt.Local().Format("2006-01-02 15:04"),
+		})
 	}
 	return transitions
 }
@@ -347,7 +399,7 @@ func (a *JiraAgent) buildCardContext(card models.JiraCard) map[string]any {
 	// Commits
 	var commits []models.Commit
 	a.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
-		Where("repositories.user_id = ? AND commits.jira_card_key = ?", a.UserID, card.Key).
+		Where("repositories.user_id = ? AND "+cardlink.OnCard, a.UserID, card.Key).
 		Order("commits.date desc").Limit(10).Find(&commits)
 
 	var linkedCommits []commitInfo
@@ -384,7 +436,7 @@ func (a *JiraAgent) buildCardContext(card models.JiraCard) map[string]any {
 		"assignee":    card.Assignee,
 		"commits":     linkedCommits,
 		"comments":    cardComments,
-		"transitions": a.extractTransitions(card.DetailsJSON),
+		"transitions": a.extractTransitions(card.Key),
 	}
 
 	// Parse DetailsJSON for description, parent, subtasks
@@ -425,7 +477,7 @@ func (a *JiraAgent) buildCardContext(card models.JiraCard) map[string]any {
 					// Get subtask commits
 					var stCommits []models.Commit
 					a.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
-						Where("repositories.user_id = ? AND commits.jira_card_key = ?", a.UserID, stKey).
+						Where("repositories.user_id = ? AND "+cardlink.OnCard, a.UserID, stKey).
 						Order("commits.date desc").Limit(5).Find(&stCommits)
 					for _, c := range stCommits {
 						child.Commits = append(child.Commits, commitInfo{
@@ -457,8 +509,8 @@ func (a *JiraAgent) buildCardContext(card models.JiraCard) map[string]any {
 									child.Description = desc
 								}
 							}
-							child.Transitions = a.extractTransitions(stCard.DetailsJSON)
 						}
+						child.Transitions = a.extractTransitions(stCard.Key)
 					}
 
 					children = append(children, child)
@@ -594,9 +646,9 @@ func (a *JiraAgent) linkCommitToCard(args json.RawMessage) (any, error) {
 		return nil, fmt.Errorf("commit not found: %s", params.SHA)
 	}
 
-	commit.JiraCardKey = params.CardKey
-	commit.HasLink = true
-	a.DB.Save(&commit)
+	if _, err := cardlink.Link(a.DB, &commit, []string{params.CardKey}, models.LinkSourceAgent); err != nil {
+		return nil, fmt.Errorf("failed to link commit: %w", err)
+	}
 
 	return map[string]string{
 		"status":   "linked",
@@ -604,3 +656,130 @@ func (a *JiraAgent) linkCommitToCard(args json.RawMessage) (any, error) {
 		"card_key": params.CardKey,
 	}, nil
 }
+
+func (a *JiraAgent) getCardTransitions(args json.RawMessage) (any, error) {
+	var params struct {
+		Key string `json:"key"`
+	}
+	json.Unmarshal(args, &params)
+
+	if a.Encryptor == nil {
+		return nil, fmt.Errorf("jira access is not available")
+	}
+	key := strings.ToUpper(params.Key)
+
+	var workspaceID *uint
+	var card models.JiraCard
+	if a.DB.Where("user_id = ? AND card_key = ?", a.UserID, key).First(&card).Error == nil {
+		workspaceID = card.WorkspaceID
+	}
+	client, _, err := jiraoutbox.Client(a.DB, a.Encryptor, a.UserID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	transitions, err := client.FetchTransitions(key)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"card_key":       key,
+		"current_status": card.Status,
+		"transitions":    transitions,
+	}, nil
+}
+
+func (a *JiraAgent) proposeComment(args json.RawMessage) (any, error) {
+	var params struct {
+		CardKey        string `json:"card_key"`
+		Body           string `json:"body"`
+		IncludeCommits bool   `json:"include_commits"`
+		Context        string `json:"context"`
+	}
+	json.Unmarshal(args, &params)
+
+	body := params.Body
+	if params.IncludeCommits {
+		var commits []models.Commit
+		a.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
+			Where("repositories.user_id = ? AND "+cardlink.OnCard, a.UserID, strings.ToUpper(params.CardKey)).
+			Order("commits.date asc").
+			Find(&commits)
+		if len(commits) > 0 {
+			lines := []string{"Linked commits:"}
+			for _, c := range commits {
+				lines = append(lines, fmt.Sprintf("- %s %s (%s)", shortSHA(c.SHA), strings.SplitN(c.Message, "\n", 2)[0], c.Author))
+			}
+			body = strings.TrimSpace(body + "\n\n" + strings.Join(lines, "\n"))
+		}
+	}
+
+	return a.queue(models.JiraOutbox{
+		CardKey: params.CardKey,
+		Action:  models.JiraActionComment,
+		Body:    body,
+		Context: params.Context,
+	})
+}
+
+func (a *JiraAgent) proposeTransition(args json.RawMessage) (any, error) {
+	var params struct {
+		CardKey    string `json:"card_key"`
+		Transition string `json:"transition"`
+		Context    string `json:"context"`
+	}
+	json.Unmarshal(args, &params)
+
+	return a.queue(models.JiraOutbox{
+		CardKey:    params.CardKey,
+		Action:     models.JiraActionTransition,
+		Transition: params.Transition,
+		Context:    params.Context,
+	})
+}
+
+func (a *JiraAgent) proposeWorklog(args json.RawMessage) (any, error) {
+	var params struct {
+		CardKey string `json:"card_key"`
+		Minutes int    `json:"minutes"`
+		Started string `json:"started"`
+		Comment string `json:"comment"`
+		Context string `json:"context"`
+	}
+	json.Unmarshal(args, &params)
+
+	item := models.JiraOutbox{
+		CardKey:          params.CardKey,
+		Action:           models.JiraActionWorklog,
+		Body:             params.Comment,
+		TimeSpentSeconds: params.Minutes * 60,
+		Context:          params.Context,
+	}
+	if params.Started != "" {
+		started, err := time.ParseInLocation("2006-01-02 15:04", params.Started, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("started must be YYYY-MM-DD HH:MM")
+		}
+		item.StartedAt = &started
+	}
+	return a.queue(item)
+}
+
+// queue stores a proposed change as a pending Jira outbox entry.
+func (a *JiraAgent) queue(item models.JiraOutbox) (any, error) {
+	if strings.TrimSpace(item.Context) == "" {
+		return nil, fmt.Errorf("context is required")
+	}
+	item.UserID = a.UserID
+	item.RequestedBy = "agent"
+	if err := jiraoutbox.Queue(a.DB, &item); err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"outbox_id": item.ID,
+		"card_key":  item.CardKey,
+		"action":    item.Action,
+		"status":    item.Status,
+		"note":      "Queued for approval. Nothing changes in Jira until the user approves it in the Jira outbox.",
+	}, nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/cds-id/pdt/backend/internal/ai/minimax"
 	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/cardlink"
 	"gorm.io/gorm"
 )
 
@@ -136,7 +137,7 @@ func (a *ProofAgent) searchComments(args json.RawMessage) (any, error) {
 
 	query := a.DB.Where("user_id = ?", a.UserID)
 	if params.Keyword != "" {
-		query = query.Where("body LIKE ?", "%"+params.Keyword+"%")
+		query = whereCommentMentions(query, params.Keyword)
 	}
 	if params.Author != "" {
 		query = query.Where("author LIKE ?", "%"+params.Author+"%")
@@ -227,7 +228,7 @@ func (a *ProofAgent) findPersonStatements(args json.RawMessage) (any, error) {
 	likePattern := "%" + params.Person + "%"
 	query := a.DB.Where("user_id = ? AND (author LIKE ? OR author_email LIKE ?)", a.UserID, likePattern, likePattern)
 	if params.Keyword != "" {
-		query = query.Where("body LIKE ?", "%"+params.Keyword+"%")
+		query = whereCommentMentions(query, params.Keyword)
 	}
 
 	var comments []models.JiraComment
@@ -452,7 +453,7 @@ func (a *ProofAgent) checkRequirementCoverage(args json.RawMessage) (any, error)
 	// Fetch linked commits
 	var commits []models.Commit
 	a.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
-		Where("repositories.user_id = ? AND commits.jira_card_key = ?", a.UserID, params.CardKey).
+		Where("repositories.user_id = ? AND "+cardlink.OnCard, a.UserID, params.CardKey).
 		Find(&commits)
 
 	type commitInfo struct {
@@ -478,3 +479,11 @@ func (a *ProofAgent) checkRequirementCoverage(args json.RawMessage) (any, error)
 		"commits":     linkedCommits,
 	}, nil
 }
+
+// whereCommentMentions matches comments containing keyword. Bodies are
+// Markdown, where escapes and formatting can split the words, so the raw
+// ADF text is searched too.
+func whereCommentMentions(query *gorm.DB, keyword string) *gorm.DB {
+	like := "%" + keyword + "%"
+	return query.Where("(body LIKE ? OR body_adf LIKE ?)", like, like)
+}
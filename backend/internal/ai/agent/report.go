@@ -24,7 +24,7 @@ func (a *ReportAgent) Name() string { return "report" }
 
 func (a *ReportAgent) SystemPrompt() string {
 	today := time.Now().Format("2006-01-02")
-	return fmt.Sprintf(`You are a Report assistant for PDT. Today is %s. You help users generate daily and monthly reports, view existing reports, and manage report templates. Use the available tools to fetch and generate reports. When generating reports, confirm the date/month with the user first.`, today)
+	return fmt.Sprintf(`You are a Report assistant for PDT. Today is %s. You help users generate daily, weekly, monthly and date-range (e.g. sprint) reports, view existing reports, and manage report templates. Use the available tools to fetch and generate reports. When generating reports, confirm the date/month with the user first.`, today)
 }
 
 func (a *ReportAgent) Tools() []minimax.Tool {
@@ -51,13 +51,26 @@ func (a *ReportAgent) Tools() []minimax.Tool {
 				"required": ["month", "year"]
 			}`),
 		},
+		{
+			Name:        "generate_range_report",
+			Description: "Generate a weekly report, or a report for a date range or sprint with commits grouped per card and per day",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"report_type": {"type": "string", "enum": ["weekly", "range"], "description": "weekly covers the Monday-Sunday week containing start_date (default: range)"},
+					"start_date": {"type": "string", "description": "Start date in YYYY-MM-DD format (weekly default: this week)"},
+					"end_date": {"type": "string", "description": "End date in YYYY-MM-DD format, inclusive (range only)"},
+					"sprint_id": {"type": "integer", "description": "Sprint ID whose start and end dates to use instead of start_date/end_date"}
+				}
+			}`),
+		},
 		{
 			Name:        "list_reports",
 			Description: "List existing reports, optionally filtered by type",
 			InputSchema: json.RawMessage(`{
 				"type": "object",
 				"properties": {
-					"report_type": {"type": "string", "enum": ["daily", "monthly"], "description": "Filter by report type"},
+					"report_type": {"type": "string", "enum": ["daily", "weekly", "monthly", "range"], "description": "Filter by report type"},
 					"limit": {"type": "integer", "description": "Max results (default 20)"}
 				}
 			}`),
@@ -93,6 +106,8 @@ func (a *ReportAgent) ExecuteTool(ctx context.Context, name string, args json.Ra
 		return a.generateDaily(args)
 	case "generate_monthly_report":
 		return a.generateMonthly(args)
+	case "generate_range_report":
+		return a.generateRange(args)
 	case "list_reports":
 		return a.listReports(args)
 	case "get_report":
@@ -129,8 +144,6 @@ func (a *ReportAgent) generateDaily(args json.RawMessage) (any, error) {
 		return nil, fmt.Errorf("render report: %w", err)
 	}
 
-	// Upsert report
-	var existing models.Report
 	rpt := models.Report{
 		UserID:     a.UserID,
 		TemplateID: templateID,
@@ -139,14 +152,8 @@ func (a *ReportAgent) generateDaily(args json.RawMessage) (any, error) {
 		Content:    rendered,
 		ReportType: "daily",
 	}
-
-	if a.DB.Where("user_id = ? AND date = ? AND report_type = ?", a.UserID, params.Date, "daily").First(&existing).Error == nil {
-		existing.Content = rendered
-		existing.Title = rpt.Title
-		a.DB.Save(&existing)
-		rpt.ID = existing.ID
-	} else {
-		a.DB.Create(&rpt)
+	if _, err := report.Save(a.DB, &rpt, data); err != nil {
+		return nil, fmt.Errorf("save report: %w", err)
 	}
 
 	return map[string]any{
@@ -191,15 +198,8 @@ func (a *ReportAgent) generateMonthly(args json.RawMessage) (any, error) {
 		Month:      &month,
 		Year:       &year,
 	}
-
-	var existing models.Report
-	if a.DB.Where("user_id = ? AND report_type = ? AND month = ? AND year = ?", a.UserID, "monthly", month, year).First(&existing).Error == nil {
-		existing.Content = rendered
-		existing.Title = rpt.Title
-		a.DB.Save(&existing)
-		rpt.ID = existing.ID
-	} else {
-		a.DB.Create(&rpt)
+	if _, err := report.Save(a.DB, &rpt, data); err != nil {
+		return nil, fmt.Errorf("save monthly report: %w", err)
 	}
 
 	return map[string]any{
@@ -211,6 +211,78 @@ func (a *ReportAgent) generateMonthly(args json.RawMessage) (any, error) {
 	}, nil
 }
 
+func (a *ReportAgent) generateRange(args json.RawMessage) (any, error) {
+	var params struct {
+		ReportType string `json:"report_type"`
+		StartDate  string `json:"start_date"`
+		EndDate    string `json:"end_date"`
+		SprintID   *uint  `json:"sprint_id"`
+	}
+	json.Unmarshal(args, &params)
+	if params.ReportType == "" {
+		params.ReportType = "range"
+	}
+
+	var data *report.RangeReportData
+	var err error
+	switch {
+	case params.ReportType == "weekly":
+		day := time.Now()
+		if params.StartDate != "" {
+			if day, err = time.ParseInLocation("2006-01-02", params.StartDate, time.Local); err != nil {
+				return nil, fmt.Errorf("invalid date format: %s", params.StartDate)
+			}
+		}
+		data, err = a.Generator.BuildWeeklyReportData(a.UserID, day)
+	case params.ReportType != "range":
+		return nil, fmt.Errorf("report_type must be weekly or range")
+	case params.SprintID != nil:
+		data, err = a.Generator.BuildSprintReportData(a.UserID, *params.SprintID)
+	default:
+		start, startErr := time.ParseInLocation("2006-01-02", params.StartDate, time.Local)
+		end, endErr := time.ParseInLocation("2006-01-02", params.EndDate, time.Local)
+		if startErr != nil || endErr != nil {
+			return nil, fmt.Errorf("start_date and end_date are required in YYYY-MM-DD format")
+		}
+		data, err = a.Generator.BuildRangeReportData(a.UserID, start, end)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("build report data: %w", err)
+	}
+
+	templateContent, templateID := a.Generator.GetWeeklyTemplateContent(a.UserID, nil)
+	rendered, err := a.Generator.RenderRange(templateContent, data)
+	if err != nil {
+		return nil, fmt.Errorf("render report: %w", err)
+	}
+
+	rpt := models.Report{
+		UserID:     a.UserID,
+		TemplateID: templateID,
+		Date:       data.StartDate,
+		EndDate:    data.EndDate,
+		Title:      data.Title,
+		Content:    rendered,
+		ReportType: params.ReportType,
+	}
+	if _, err := report.Save(a.DB, &rpt, data); err != nil {
+		return nil, fmt.Errorf("save report: %w", err)
+	}
+
+	return map[string]any{
+		"id":         rpt.ID,
+		"title":      rpt.Title,
+		"start_date": data.StartDate,
+		"end_date":   data.EndDate,
+		"content":    rendered,
+		"stats": map[string]any{
+			"total_commits": data.Stats.TotalCommits,
+			"total_cards":   data.Stats.TotalCards,
+			"active_days":   data.ActiveDays,
+		},
+	}, nil
+}
+
 func (a *ReportAgent) listReports(args json.RawMessage) (any, error) {
 	var params struct {
 		ReportType string `json:"report_type"`
@@ -44,7 +44,7 @@ TRIGGER TYPES:
 - "cron": Standard 5-field cron expressions (minute hour day-of-month month day-of-week)
   Examples: "0 8 * * 1-5" (weekdays 8am), "0 9 * * 1" (Monday 9am), "0 * * * *" (every hour)
 - "interval": Run every N seconds. Common values: 900 (15min), 1800 (30min), 3600 (1hr)
-- "event": Triggered by system events. Available events: commit_synced, jira_synced, report_generated, schedule_completed
+- "event": Triggered by system events. Available events: commit_synced, commit_pushed, jira_synced, jira_card_transitioned, jira_comment_added, report_generated, schedule_completed
 
 AVAILABLE AGENTS:
 - "briefing": Morning briefing, standup prep, blocker analysis
@@ -84,7 +84,7 @@ func (a *SchedulerAgent) Tools() []minimax.Tool {
 					"trigger_type": {"type": "string", "enum": ["cron", "interval", "event", "once"], "description": "Type of trigger. Use 'once' to run immediately one time."},
 					"cron_expr": {"type": "string", "description": "Cron expression (for cron trigger type)"},
 					"interval_seconds": {"type": "integer", "description": "Interval in seconds (for interval trigger type)"},
-					"event_name": {"type": "string", "description": "Event name (for event trigger type): commit_synced, jira_synced, report_generated, schedule_completed"},
+					"event_name": {"type": "string", "description": "Event name (for event trigger type): commit_synced, commit_pushed, jira_synced, jira_card_transitioned, jira_comment_added, report_generated, schedule_completed"},
 					"chain_config": {
 						"type": "array",
 						"description": "Optional chain steps to run after the main agent",
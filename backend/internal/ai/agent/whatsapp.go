@@ -275,7 +275,7 @@ func (a *WhatsAppAgent) Tools() []minimax.Tool {
 				"properties": {
 					"report_id": {"type": "integer", "description": "The report ID to send. Use 0 to send the latest report."},
 					"target_jid": {"type": "string", "description": "Target WhatsApp JID to send the report to"},
-					"report_date": {"type": "string", "description": "Optional: find report by date (YYYY-MM-DD) instead of ID"}
+					"report_date": {"type": "string", "description": "Optional: find the daily report for this date (YYYY-MM-DD) instead of ID"}
 				},
 				"required": ["target_jid"]
 			}`),
@@ -1241,7 +1241,7 @@ func (a *WhatsAppAgent) sendReport(args json.RawMessage) (any, error) {
 			return map[string]any{"error": "Report not found"}, nil
 		}
 	} else if params.ReportDate != "" {
-		if err := a.DB.Where("user_id = ? AND date = ?", a.UserID, params.ReportDate).Order("created_at desc").First(&report).Error; err != nil {
+		if err := a.DB.Where("user_id = ? AND date = ? AND report_type = ?", a.UserID, params.ReportDate, "daily").Order("created_at desc").First(&report).Error; err != nil {
 			return map[string]any{"error": fmt.Sprintf("No report found for date %s", params.ReportDate)}, nil
 		}
 	} else {
@@ -13,6 +13,9 @@ import (
 
 const defaultBaseURL = "https://api.minimax.io/anthropic"
 
+// Provider is the provider name recorded in AI usage rows.
+const Provider = "minimax"
+
 // Client wraps the Anthropic SDK, pointing at MiniMax's Anthropic-compatible endpoint.
 type Client struct {
 	sdk   anthropic.Client
@@ -27,7 +30,7 @@ func NewClient(apiKey, groupID string) *Client {
 	)
 	return &Client{
 		sdk:   sdk,
-		Model:   "MiniMax-M2.7",
+		Model: "MiniMax-M2.7",
 	}
 }
 
@@ -132,7 +135,9 @@ func (c *Client) Chat(req ChatRequest) (*ChatResponse, error) {
 
 	// Convert response
 	result := &ChatResponse{
-		ID: resp.ID,
+		ID:       resp.ID,
+		Provider: Provider,
+		Model:    model,
 		Usage: Usage{
 			PromptTokens:     int(resp.Usage.InputTokens),
 			CompletionTokens: int(resp.Usage.OutputTokens),
@@ -7,7 +7,7 @@ import "encoding/json"
 
 // Message represents a conversation message used by the agent framework.
 type Message struct {
-	Role       string     `json:"role"`    // "user", "assistant", "system", "tool"
+	Role       string     `json:"role"` // "user", "assistant", "system", "tool"
 	Content    string     `json:"content"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
@@ -45,10 +45,13 @@ type ChatRequest struct {
 
 // ChatResponse is the internal response format for non-streaming calls.
 type ChatResponse struct {
-	ID      string  `json:"id"`
-	Content string  `json:"content"`
-	Choices []Choice `json:"choices"`
-	Usage   Usage   `json:"usage"`
+	ID string `json:"id"`
+	// Provider and Model name who answered, for usage records.
+	Provider string   `json:"provider"`
+	Model    string   `json:"model"`
+	Content  string   `json:"content"`
+	Choices  []Choice `json:"choices"`
+	Usage    Usage    `json:"usage"`
 }
 
 // Choice represents a response choice (maps from Anthropic content blocks).
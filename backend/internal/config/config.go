@@ -22,9 +22,15 @@ type Config struct {
 	SyncEnabled         bool
 	SyncIntervalCommits time.Duration
 	SyncIntervalJira    time.Duration
+	SyncCommitBackfillDays int
 	ReportAutoGenerate     bool
 	ReportAutoTime         string
 	ReportMonthlyAutoTime  string
+	ReportWeeklyAutoTime   string
+	ReportTheme            string
+	ReportBrandName        string
+	ReportBrandColor       string
+	ReportNarrative        bool
 	R2AccountID         string
 	R2AccessKeyID       string
 	R2SecretAccessKey   string
@@ -76,10 +82,22 @@ func Load() (*Config, error) {
 	}
 	cfg.SyncIntervalJira = jiraInterval
 
+	backfillDays, err := strconv.Atoi(getEnv("SYNC_COMMIT_BACKFILL_DAYS", "30"))
+	if err != nil || backfillDays <= 0 {
+		backfillDays = 30
+	}
+	cfg.SyncCommitBackfillDays = backfillDays
+
 	reportAutoGen := getEnv("REPORT_AUTO_GENERATE", "true")
 	cfg.ReportAutoGenerate = reportAutoGen == "true" || reportAutoGen == "1"
 	cfg.ReportAutoTime = getEnv("REPORT_AUTO_TIME", "23:00")
 	cfg.ReportMonthlyAutoTime = getEnv("REPORT_MONTHLY_AUTO_TIME", "08:00")
+	cfg.ReportWeeklyAutoTime = getEnv("REPORT_WEEKLY_AUTO_TIME", "08:00")
+	cfg.ReportTheme = getEnv("REPORT_THEME", "default")
+	cfg.ReportBrandName = getEnv("REPORT_BRAND_NAME", "")
+	cfg.ReportBrandColor = getEnv("REPORT_BRAND_COLOR", "")
+	reportNarrative := getEnv("REPORT_NARRATIVE", "false")
+	cfg.ReportNarrative = reportNarrative == "true" || reportNarrative == "1"
 
 	cfg.R2AccountID = getEnv("R2_ACCOUNT_ID", "")
 	cfg.R2AccessKeyID = getEnv("R2_ACCESS_KEY_ID", "")
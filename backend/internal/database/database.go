@@ -1,9 +1,13 @@
 package database
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/cds-id/pdt/backend/internal/models"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func Connect(dsn string) (*gorm.DB, error) {
@@ -24,25 +28,72 @@ func Connect(dsn string) (*gorm.DB, error) {
 }
 
 func Migrate(db *gorm.DB) error {
+	// Manual links could be created twice before (commit_id, jira_card_key)
+	// became unique; keep the oldest so the index can be added.
+	if db.Migrator().HasTable(&models.CommitCardLink{}) && !db.Migrator().HasIndex(&models.CommitCardLink{}, "idx_commit_card") {
+		db.Exec(`DELETE FROM commit_card_links WHERE id NOT IN (
+			SELECT id FROM (SELECT MIN(id) AS id FROM commit_card_links GROUP BY commit_id, jira_card_key) AS keep)`)
+	}
+
+	if err := mergeDuplicateReports(db); err != nil {
+		return fmt.Errorf("merge duplicate reports: %w", err)
+	}
+
+	if m := db.Migrator(); m.HasTable(&models.ReportTemplate{}) && !m.HasColumn(&models.ReportTemplate{}, "ReportType") {
+		if err := typeReportTemplates(db); err != nil {
+			return fmt.Errorf("type report templates: %w", err)
+		}
+	}
+
+	if m := db.Migrator(); m.HasTable(&models.Commit{}) && !m.HasTable(&models.CommitBranch{}) {
+		if err := splitCommitBranches(db); err != nil {
+			return fmt.Errorf("split commit branches: %w", err)
+		}
+	}
+
+	// Jira sprint and comment IDs were unique across all users; they are
+	// only unique per Jira site, so the indexes now include the user and
+	// workspace.
+	for _, idx := range []struct {
+		model any
+		name  string
+	}{{&models.Sprint{}, "idx_sprints_jira_sprint_id"}, {&models.JiraComment{}, "idx_jira_comments_comment_id"}} {
+		if db.Migrator().HasIndex(idx.model, idx.name) {
+			db.Migrator().DropIndex(idx.model, idx.name)
+		}
+	}
+
 	if err := db.AutoMigrate(
 		&models.User{},
+		&models.GitConnection{},
+		&models.ProviderAccount{},
 		&models.Repository{},
 		&models.Commit{},
+		&models.CommitBranch{},
+		&models.RepoSyncCursor{},
+		&models.RepoSyncHealth{},
+		&models.PullRequest{},
+		&models.PullRequestReview{},
 		&models.CommitCardLink{},
 		&models.JiraWorkspaceConfig{},
 		&models.Sprint{},
 		&models.JiraCard{},
 		&models.ReportTemplate{},
 		&models.Report{},
+		&models.ReportVersion{},
 		&models.Conversation{},
 		&models.ChatMessage{},
 		&models.AIUsage{},
 		&models.JiraComment{},
+		&models.JiraCardTransition{},
+		&models.SprintSnapshot{},
 		&models.WaNumber{},
 		&models.WaListener{},
 		&models.WaMessage{},
 		&models.WaMedia{},
 		&models.WaOutbox{},
+		&models.JiraOutbox{},
+		&models.TimesheetEntry{},
 		&models.TelegramConfig{},
 		&models.TelegramWhitelist{},
 		&models.AgentSchedule{},
@@ -51,6 +102,10 @@ func Migrate(db *gorm.DB) error {
 		&models.ComposioConfig{},
 		&models.ComposioConnection{},
 		&models.ExecutiveReport{},
+		&models.Person{},
+		&models.PersonIdentity{},
+		&models.PersonMergeSuggestion{},
+		&models.DataMigration{},
 	); err != nil {
 		return err
 	}
@@ -65,8 +120,154 @@ func Migrate(db *gorm.DB) error {
 	return nil
 }
 
+// typeReportTemplates adds report_type to report templates. Templates used to
+// be told apart by name: "Monthly Default" and "Weekly Default" rendered
+// monthly and weekly reports and everything else daily ones.
+func typeReportTemplates(db *gorm.DB) error {
+	if err := db.Migrator().AddColumn(&models.ReportTemplate{}, "ReportType"); err != nil {
+		return err
+	}
+	for name, reportType := range map[string]string{"Monthly Default": "monthly", "Weekly Default": "weekly"} {
+		if err := db.Exec("UPDATE report_templates SET report_type = ? WHERE name = ?", reportType, name).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeDuplicateReports prepares reports for the unique index on user, type,
+// date and end date. Concurrent generation could store a report twice before
+// it existed; the newest row of each key is kept and the others become its
+// earlier versions, so no generated content is lost.
+func mergeDuplicateReports(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&models.Report{}) || m.HasIndex(&models.Report{}, "idx_report_key") {
+		return nil
+	}
+	for _, field := range []string{"EndDate", "Version"} {
+		if !m.HasColumn(&models.Report{}, field) {
+			if err := m.AddColumn(&models.Report{}, field); err != nil {
+				return err
+			}
+		}
+	}
+	if !m.HasTable(&models.ReportVersion{}) {
+		if err := m.CreateTable(&models.ReportVersion{}); err != nil {
+			return err
+		}
+	}
+	if err := db.Exec("UPDATE reports SET end_date = '' WHERE end_date IS NULL").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("UPDATE reports SET report_type = 'daily' WHERE report_type IS NULL OR report_type = ''").Error; err != nil {
+		return err
+	}
+
+	var keys []struct {
+		UserID     uint
+		ReportType string
+		Date       string
+		EndDate    string
+	}
+	if err := db.Model(&models.Report{}).Select("user_id, report_type, date, end_date").
+		Group("user_id, report_type, date, end_date").Having("COUNT(*) > 1").Scan(&keys).Error; err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var rows []models.Report
+			if err := tx.Where("user_id = ? AND report_type = ? AND date = ? AND end_date = ?", key.UserID, key.ReportType, key.Date, key.EndDate).
+				Order("id").Find(&rows).Error; err != nil {
+				return err
+			}
+			keep := rows[len(rows)-1]
+
+			// Each row contributes its own versions, or its content as a
+			// single version when it predates versioning, numbered after
+			// the rows before it.
+			var history []models.ReportVersion
+			ids := make([]uint, 0, len(rows))
+			for _, r := range rows {
+				ids = append(ids, r.ID)
+				var versions []models.ReportVersion
+				if err := tx.Where("report_id = ?", r.ID).Order("version").Find(&versions).Error; err != nil {
+					return err
+				}
+				if len(versions) == 0 {
+					versions = []models.ReportVersion{{Version: 1, TemplateID: r.TemplateID, Title: r.Title,
+						Content: r.Content, FileURL: r.FileURL, CreatedAt: r.CreatedAt}}
+				}
+				offset := len(history)
+				for _, v := range versions {
+					v.ID = 0
+					v.ReportID = keep.ID
+					v.Version += offset
+					if v.RestoredFrom != nil {
+						from := *v.RestoredFrom + offset
+						v.RestoredFrom = &from
+					}
+					history = append(history, v)
+				}
+			}
+
+			if err := tx.Where("report_id IN ?", ids).Delete(&models.ReportVersion{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&history).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ? AND id <> ?", ids, keep.ID).Delete(&models.Report{}).Error; err != nil {
+				return err
+			}
+			return tx.Model(&keep).Update("version", len(history)).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitCommitBranches moves the branches of each commit into
+// commit_branches. commits.branch held a comma-separated list of every branch
+// a commit was seen on; it keeps only the first, which also lets the column
+// shrink back.
+func splitCommitBranches(db *gorm.DB) error {
+	if err := db.Migrator().CreateTable(&models.CommitBranch{}); err != nil {
+		return err
+	}
+	if err := db.Exec(`INSERT INTO commit_branches (commit_id, branch, seen_at)
+		SELECT id, branch, created_at FROM commits WHERE branch <> '' AND branch NOT LIKE ?`, "%,%").Error; err != nil {
+		return err
+	}
+
+	var commits []models.Commit
+	return db.Select("id, branch, created_at").Where("branch LIKE ?", "%,%").
+		FindInBatches(&commits, 500, func(_ *gorm.DB, _ int) error {
+			for _, c := range commits {
+				branches := strings.Split(c.Branch, ",")
+				for _, b := range branches {
+					if b == "" {
+						continue
+					}
+					if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+						Create(&models.CommitBranch{CommitID: c.ID, Branch: b, SeenAt: c.CreatedAt}).Error; err != nil {
+						return err
+					}
+				}
+				if err := db.Model(&models.Commit{}).Where("id = ?", c.ID).Update("branch", branches[0]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
 // migrateJiraWorkspaces creates JiraWorkspaceConfig entries for users
 // that have Jira configured on the User model but no workspace entries yet.
+// Workspaces are left without credentials of their own, so jira.Credentials
+// keeps using the user's current ones.
 func migrateJiraWorkspaces(db *gorm.DB) {
 	var users []models.User
 	db.Where("jira_workspace != '' AND jira_token != ''").Find(&users)
@@ -82,6 +283,7 @@ func migrateJiraWorkspaces(db *gorm.DB) {
 			UserID:      user.ID,
 			Workspace:   user.JiraWorkspace,
 			Name:        user.JiraWorkspace,
+			AuthMode:    models.JiraAuthBasic,
 			ProjectKeys: user.JiraProjectKeys,
 			IsActive:    true,
 		}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+// baselineReport is the reports table before versioning and end dates.
+type baselineReport struct {
+	ID         uint   `gorm:"primarykey"`
+	UserID     uint   `gorm:"index;not null"`
+	TemplateID *uint  `gorm:"index"`
+	Date       string `gorm:"type:varchar(10);index;not null"`
+	Title      string `gorm:"type:varchar(500)"`
+	Content    string `gorm:"type:text"`
+	FileURL    string `gorm:"type:varchar(500)"`
+	ReportType string `gorm:"type:varchar(10);default:daily"`
+	Month      *int
+	Year       *int
+	CreatedAt  time.Time
+}
+
+func (baselineReport) TableName() string { return "reports" }
+
+func TestMigrate_MergesDuplicateReportsIntoVersions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&baselineReport{}); err != nil {
+		t.Fatalf("baseline schema: %v", err)
+	}
+	day := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	for i, content := range []string{"first", "second", "third"} {
+		db.Create(&baselineReport{UserID: 1, Date: "2026-10-12", ReportType: "daily", Title: "Daily", Content: content,
+			CreatedAt: day.Add(time.Duration(i) * time.Hour)})
+	}
+	db.Create(&baselineReport{UserID: 1, Date: "2026-10-13", ReportType: "daily", Content: "alone"})
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	var reports []models.Report
+	db.Order("date").Find(&reports)
+	if len(reports) != 2 {
+		t.Fatalf("reports = %d, want 2", len(reports))
+	}
+	kept := reports[0]
+	if kept.Content != "third" || kept.Version != 3 || kept.EndDate != "" {
+		t.Errorf("kept = %+v, want the newest row at version 3", kept)
+	}
+	var versions []models.ReportVersion
+	db.Where("report_id = ?", kept.ID).Order("version").Find(&versions)
+	if len(versions) != 3 || versions[0].Content != "first" || versions[1].Content != "second" || versions[2].Content != "third" {
+		t.Errorf("versions = %+v, want the three generations in order", versions)
+	}
+	if reports[1].Version != 0 {
+		t.Errorf("a report without duplicates keeps version 0 until it is regenerated, got %d", reports[1].Version)
+	}
+
+	if err := db.Create(&models.Report{UserID: 1, Date: "2026-10-13", ReportType: "daily"}).Error; err == nil {
+		t.Error("the unique report key was not enforced")
+	}
+}
+
+func TestMigrate_SplitsCommitBranches(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	type commit struct {
+		ID        uint   `gorm:"primarykey"`
+		RepoID    uint   `gorm:"index;not null"`
+		SHA       string `gorm:"type:varchar(64);uniqueIndex;not null"`
+		Branch    string `gorm:"type:varchar(700);index"`
+		CreatedAt time.Time
+	}
+	if err := db.Table("commits").AutoMigrate(&commit{}); err != nil {
+		t.Fatalf("old schema: %v", err)
+	}
+	db.Table("commits").Create(&commit{RepoID: 1, SHA: "a1", Branch: "main,feature/x"})
+	db.Table("commits").Create(&commit{RepoID: 1, SHA: "b2", Branch: "dev"})
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	var a1 models.Commit
+	db.Where("sha = ?", "a1").First(&a1)
+	var branches []string
+	db.Model(&models.CommitBranch{}).Where("commit_id = ?", a1.ID).Order("branch").Pluck("branch", &branches)
+	if a1.Branch != "main" || len(branches) != 2 || branches[0] != "feature/x" || branches[1] != "main" {
+		t.Errorf("a1 branch=%q branches=%v", a1.Branch, branches)
+	}
+	var count int64
+	db.Model(&models.CommitBranch{}).Count(&count)
+	if count != 3 {
+		t.Errorf("commit_branches = %d, want 3", count)
+	}
+}
+
+// baselineTemplate is the report_templates table before templates had a type.
+type baselineTemplate struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	Name      string `gorm:"type:varchar(255);not null"`
+	Content   string `gorm:"type:text;not null"`
+	IsDefault bool   `gorm:"default:false"`
+}
+
+func (baselineTemplate) TableName() string { return "report_templates" }
+
+func TestMigrate_TypesReportTemplatesByName(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&baselineTemplate{}); err != nil {
+		t.Fatalf("baseline schema: %v", err)
+	}
+	for _, name := range []string{"Monthly Default", "Weekly Default", "Mine"} {
+		db.Create(&baselineTemplate{UserID: 1, Name: name, Content: name, IsDefault: true})
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	got := map[string]string{}
+	var templates []models.ReportTemplate
+	db.Find(&templates)
+	for _, tmpl := range templates {
+		got[tmpl.Name] = tmpl.ReportType
+	}
+	if got["Monthly Default"] != "monthly" || got["Weekly Default"] != "weekly" || got["Mine"] != "daily" {
+		t.Errorf("report types = %v", got)
+	}
+}
@@ -129,7 +129,7 @@ func (h *ChatHandler) HandleWebSocket(c *gin.Context) {
 	// Build base agents
 	agents := []agent.Agent{
 		&agent.GitAgent{DB: h.DB, UserID: userID, Encryptor: h.Encryptor, Weaviate: h.WeaviateClient},
-		&agent.JiraAgent{DB: h.DB, UserID: userID, Weaviate: h.WeaviateClient},
+		&agent.JiraAgent{DB: h.DB, UserID: userID, Weaviate: h.WeaviateClient, Encryptor: h.Encryptor},
 		&agent.ReportAgent{DB: h.DB, UserID: userID, Generator: h.ReportGenerator, R2: h.R2},
 		&agent.ProofAgent{DB: h.DB, UserID: userID},
 		&agent.BriefingAgent{DB: h.DB, UserID: userID},
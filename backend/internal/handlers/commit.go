@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/cardlink"
+	"github.com/cds-id/pdt/backend/internal/services/identity"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -23,11 +26,37 @@ func (h *CommitHandler) List(c *gin.Context) {
 		query = query.Where("commits.repo_id = ?", repoID)
 	}
 	if cardKey := c.Query("jira_card_key"); cardKey != "" {
-		query = query.Where("commits.jira_card_key = ?", cardKey)
+		query = query.Where(cardlink.OnCard, cardKey)
 	}
 	if hasLink := c.Query("has_link"); hasLink != "" {
 		query = query.Where("commits.has_link = ?", hasLink == "true")
 	}
+	if personID := c.Query("person_id"); personID != "" {
+		id, err := strconv.ParseUint(personID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid person_id"})
+			return
+		}
+		person, err := identity.Get(h.DB, userID, uint(id))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "person not found"})
+			return
+		}
+		query = identity.AliasesOf(person).ScopeCommits(query)
+	}
+	if author := c.Query("author"); author != "" {
+		query = identity.ScopeCommitAuthor(h.DB, userID, author, query)
+	}
+	// Without an explicit author, list only the user's own commits unless
+	// all authors are asked for.
+	if c.Query("person_id") == "" && c.Query("author") == "" && c.Query("all_authors") != "true" {
+		var user models.User
+		if h.DB.First(&user, userID).Error == nil {
+			if mine, ok := identity.AuthorFilter(h.DB, user); ok {
+				query = mine.ScopeCommits(query)
+			}
+		}
+	}
 
 	var commits []models.Commit
 	if err := query.Order("commits.date desc").Find(&commits).Error; err != nil {
@@ -75,19 +104,14 @@ func (h *CommitHandler) Link(c *gin.Context) {
 		return
 	}
 
-	link := models.CommitCardLink{
-		CommitID:    commit.ID,
-		JiraCardKey: req.JiraCardKey,
-		LinkedAt:    time.Now(),
-	}
-
-	if err := h.DB.Create(&link).Error; err != nil {
+	key := strings.ToUpper(strings.TrimSpace(req.JiraCardKey))
+	if _, err := cardlink.Link(h.DB, &commit, []string{key}, models.LinkSourceManual); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create link"})
 		return
 	}
 
-	// Update has_link on commit
-	h.DB.Model(&commit).Update("has_link", true)
+	var link models.CommitCardLink
+	h.DB.Where("commit_id = ? AND jira_card_key = ?", commit.ID, key).First(&link)
 
 	c.JSON(http.StatusCreated, link)
 }
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type GitConnectionHandler struct {
+	DB        *gorm.DB
+	Encryptor *crypto.Encryptor
+}
+
+func validGitProvider(p models.Provider) bool {
+	switch p {
+	case models.ProviderGitHub, models.ProviderGitLab, models.ProviderBitbucket, models.ProviderGitea:
+		return true
+	}
+	return false
+}
+
+func (h *GitConnectionHandler) List(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var conns []models.GitConnection
+	h.DB.Where("user_id = ?", userID).Order("created_at").Find(&conns)
+
+	c.JSON(http.StatusOK, conns)
+}
+
+func (h *GitConnectionHandler) Create(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req struct {
+		Name     string          `json:"name"`
+		Provider models.Provider `json:"provider" binding:"required"`
+		BaseURL  string          `json:"base_url" binding:"required,url"`
+		Username string          `json:"username"`
+		Token    string          `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !validGitProvider(req.Provider) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported provider"})
+		return
+	}
+
+	encrypted, err := h.Encryptor.Encrypt(req.Token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encrypt token"})
+		return
+	}
+
+	if req.Name == "" {
+		req.Name = req.BaseURL
+	}
+
+	conn := models.GitConnection{
+		UserID:   userID,
+		Name:     req.Name,
+		Provider: req.Provider,
+		BaseURL:  strings.TrimRight(req.BaseURL, "/"),
+		Username: req.Username,
+		Token:    encrypted,
+	}
+	if err := h.DB.Create(&conn).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create connection"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, conn)
+}
+
+func (h *GitConnectionHandler) Update(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	connID := c.Param("id")
+
+	var conn models.GitConnection
+	if err := h.DB.Where("id = ? AND user_id = ?", connID, userID).First(&conn).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "connection not found"})
+		return
+	}
+
+	var req struct {
+		Name     *string `json:"name"`
+		BaseURL  *string `json:"base_url"`
+		Username *string `json:"username"`
+		Token    *string `json:"token"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.BaseURL != nil {
+		updates["base_url"] = strings.TrimRight(*req.BaseURL, "/")
+	}
+	if req.Username != nil {
+		updates["username"] = *req.Username
+	}
+	if req.Token != nil {
+		encrypted, err := h.Encryptor.Encrypt(*req.Token)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encrypt token"})
+			return
+		}
+		updates["token"] = encrypted
+	}
+
+	h.DB.Model(&conn).Updates(updates)
+	h.DB.First(&conn, conn.ID)
+
+	c.JSON(http.StatusOK, conn)
+}
+
+func (h *GitConnectionHandler) Delete(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	connID := c.Param("id")
+
+	var conn models.GitConnection
+	if err := h.DB.Where("id = ? AND user_id = ?", connID, userID).First(&conn).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "connection not found"})
+		return
+	}
+
+	var repoCount int64
+	h.DB.Model(&models.Repository{}).Where("connection_id = ?", conn.ID).Count(&repoCount)
+	if repoCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "connection is used by tracked repositories"})
+		return
+	}
+
+	h.DB.Delete(&conn)
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
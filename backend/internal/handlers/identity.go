@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/identity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type IdentityHandler struct {
+	DB *gorm.DB
+}
+
+type identityRequest struct {
+	Kind  models.IdentityKind `json:"kind" binding:"required"`
+	Value string              `json:"value" binding:"required"`
+}
+
+func (h *IdentityHandler) ListPeople(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var people []models.Person
+	h.DB.Preload("Identities").Where("user_id = ?", userID).Order("display_name").Find(&people)
+
+	c.JSON(http.StatusOK, people)
+}
+
+func (h *IdentityHandler) GetPerson(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	person, ok := h.loadPerson(c, userID, c.Param("id"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, person)
+}
+
+func (h *IdentityHandler) CreatePerson(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req struct {
+		DisplayName     string            `json:"display_name" binding:"required"`
+		JiraAccountID   string            `json:"jira_account_id"`
+		JiraDisplayName string            `json:"jira_display_name"`
+		Identities      []identityRequest `json:"identities"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, id := range req.Identities {
+		if !identity.ValidKind(id.Kind) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported identity kind: " + string(id.Kind)})
+			return
+		}
+	}
+
+	person := models.Person{
+		UserID:          userID,
+		DisplayName:     strings.TrimSpace(req.DisplayName),
+		JiraAccountID:   strings.TrimSpace(req.JiraAccountID),
+		JiraDisplayName: strings.TrimSpace(req.JiraDisplayName),
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&person).Error; err != nil {
+			return err
+		}
+		for _, id := range req.Identities {
+			if err := addIdentity(tx, userID, person.ID, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errIdentityTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create person"})
+		return
+	}
+
+	created, _ := identity.Get(h.DB, userID, person.ID)
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *IdentityHandler) UpdatePerson(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	person, ok := h.loadPerson(c, userID, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req struct {
+		DisplayName     *string `json:"display_name"`
+		JiraAccountID   *string `json:"jira_account_id"`
+		JiraDisplayName *string `json:"jira_display_name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.DisplayName != nil {
+		if strings.TrimSpace(*req.DisplayName) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "display_name cannot be empty"})
+			return
+		}
+		updates["display_name"] = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.JiraAccountID != nil {
+		updates["jira_account_id"] = strings.TrimSpace(*req.JiraAccountID)
+	}
+	if req.JiraDisplayName != nil {
+		updates["jira_display_name"] = strings.TrimSpace(*req.JiraDisplayName)
+	}
+
+	h.DB.Model(person).Updates(updates)
+	person, _ = identity.Get(h.DB, userID, person.ID)
+
+	c.JSON(http.StatusOK, person)
+}
+
+func (h *IdentityHandler) DeletePerson(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	person, ok := h.loadPerson(c, userID, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	h.DB.Where("person_id = ?", person.ID).Delete(&models.PersonIdentity{})
+	h.DB.Where("user_id = ? AND (person_id = ? OR other_person_id = ?)", userID, person.ID, person.ID).
+		Delete(&models.PersonMergeSuggestion{})
+	h.DB.Delete(person)
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+func (h *IdentityHandler) AddIdentity(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	person, ok := h.loadPerson(c, userID, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req identityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !identity.ValidKind(req.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported identity kind: " + string(req.Kind)})
+		return
+	}
+
+	if err := addIdentity(h.DB, userID, person.ID, req); err != nil {
+		if errors.Is(err, errIdentityTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add identity"})
+		return
+	}
+
+	person, _ = identity.Get(h.DB, userID, person.ID)
+	c.JSON(http.StatusCreated, person)
+}
+
+func (h *IdentityHandler) RemoveIdentity(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	person, ok := h.loadPerson(c, userID, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	res := h.DB.Where("id = ? AND person_id = ?", c.Param("identityId"), person.ID).Delete(&models.PersonIdentity{})
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "identity not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// Merge folds the person in the body into the one in the path.
+func (h *IdentityHandler) Merge(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	keepID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid person id"})
+		return
+	}
+	var req struct {
+		PersonID uint `json:"person_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondMerge(c, userID, uint(keepID), req.PersonID)
+}
+
+func (h *IdentityHandler) Discover(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	res, err := identity.Discover(h.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "identity discovery failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *IdentityHandler) ListSuggestions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	status := c.DefaultQuery("status", string(models.MergePending))
+
+	var suggestions []models.PersonMergeSuggestion
+	h.DB.Preload("Person.Identities").Preload("OtherPerson.Identities").
+		Where("user_id = ? AND status = ?", userID, status).
+		Order("created_at desc").Find(&suggestions)
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// AcceptSuggestion merges the suggested pair, keeping the person that was
+// seen first unless keep_person_id picks the other one.
+func (h *IdentityHandler) AcceptSuggestion(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	suggestion, ok := h.loadPendingSuggestion(c, userID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		KeepPersonID uint `json:"keep_person_id"`
+	}
+	c.ShouldBindJSON(&req)
+
+	keepID, dropID := suggestion.PersonID, suggestion.OtherPersonID
+	switch req.KeepPersonID {
+	case 0, suggestion.PersonID:
+	case suggestion.OtherPersonID:
+		keepID, dropID = dropID, keepID
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keep_person_id must be one of the suggested people"})
+		return
+	}
+
+	h.respondMerge(c, userID, keepID, dropID)
+}
+
+func (h *IdentityHandler) RejectSuggestion(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	suggestion, ok := h.loadPendingSuggestion(c, userID)
+	if !ok {
+		return
+	}
+
+	h.DB.Model(suggestion).Update("status", models.MergeRejected)
+	c.JSON(http.StatusOK, suggestion)
+}
+
+func (h *IdentityHandler) respondMerge(c *gin.Context, userID, keepID, dropID uint) {
+	person, err := identity.Merge(h.DB, userID, keepID, dropID)
+	switch {
+	case errors.Is(err, identity.ErrPersonNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "person not found"})
+	case err != nil && keepID == dropID:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to merge people"})
+	default:
+		c.JSON(http.StatusOK, person)
+	}
+}
+
+func (h *IdentityHandler) loadPerson(c *gin.Context, userID uint, rawID string) (*models.Person, bool) {
+	id, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid person id"})
+		return nil, false
+	}
+	person, err := identity.Get(h.DB, userID, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "person not found"})
+		return nil, false
+	}
+	return person, true
+}
+
+func (h *IdentityHandler) loadPendingSuggestion(c *gin.Context, userID uint) (*models.PersonMergeSuggestion, bool) {
+	var suggestion models.PersonMergeSuggestion
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&suggestion).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "suggestion not found"})
+		return nil, false
+	}
+	if suggestion.Status != models.MergePending {
+		c.JSON(http.StatusConflict, gin.H{"error": "suggestion already " + string(suggestion.Status)})
+		return nil, false
+	}
+	return &suggestion, true
+}
+
+var errIdentityTaken = errors.New("identity already belongs to a person")
+
+func addIdentity(db *gorm.DB, userID, personID uint, req identityRequest) error {
+	value := identity.Normalize(req.Kind, req.Value)
+
+	var existing models.PersonIdentity
+	if db.Where("user_id = ? AND kind = ? AND value = ?", userID, req.Kind, value).Limit(1).Find(&existing); existing.ID != 0 {
+		if existing.PersonID == personID {
+			return nil
+		}
+		return errIdentityTaken
+	}
+
+	return db.Create(&models.PersonIdentity{
+		UserID:   userID,
+		PersonID: personID,
+		Kind:     req.Kind,
+		Value:    value,
+		Source:   "manual",
+	}).Error
+}
@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/cds-id/pdt/backend/internal/crypto"
 	"github.com/cds-id/pdt/backend/internal/helpers"
 	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/cardlink"
 	"github.com/cds-id/pdt/backend/internal/services/jira"
+	"github.com/cds-id/pdt/backend/internal/worker"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -18,28 +21,24 @@ type JiraHandler struct {
 	Encryptor *crypto.Encryptor
 }
 
-// getClientForWorkspace creates a Jira client for a specific workspace.
+// getClientForWorkspace creates a Jira client for a specific workspace,
+// signed in with the workspace's credentials or the user's.
 func (h *JiraHandler) getClientForWorkspace(userID uint, workspaceID uint) (*jira.Client, *models.JiraWorkspaceConfig, error) {
 	var user models.User
 	if err := h.DB.First(&user, userID).Error; err != nil {
 		return nil, nil, fmt.Errorf("user not found")
 	}
 
-	if user.JiraToken == "" || user.JiraEmail == "" {
-		return nil, nil, fmt.Errorf("jira credentials not configured")
-	}
-
 	var ws models.JiraWorkspaceConfig
 	if err := h.DB.Where("id = ? AND user_id = ?", workspaceID, userID).First(&ws).Error; err != nil {
 		return nil, nil, fmt.Errorf("workspace not found")
 	}
 
-	token, err := h.Encryptor.Decrypt(user.JiraToken)
+	client, err := jira.ClientFor(ws, user, h.Encryptor)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decrypt jira token")
+		return nil, nil, err
 	}
-
-	return jira.New(ws.Workspace, user.JiraEmail, token), &ws, nil
+	return client, &ws, nil
 }
 
 // getDefaultClient creates a Jira client using the first active workspace.
@@ -100,9 +99,13 @@ func (h *JiraHandler) AddWorkspace(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	var req struct {
-		Workspace   string `json:"workspace" binding:"required"`
-		Name        string `json:"name"`
-		ProjectKeys string `json:"project_keys"`
+		Workspace   string                `json:"workspace" binding:"required"`
+		Name        string                `json:"name"`
+		ProjectKeys string                `json:"project_keys"`
+		Deployment  models.JiraDeployment `json:"deployment"`
+		AuthMode    models.JiraAuthMode   `json:"auth_mode"`
+		Email       string                `json:"email"`
+		Token       string                `json:"token"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -112,14 +115,36 @@ func (h *JiraHandler) AddWorkspace(c *gin.Context) {
 	if req.Name == "" {
 		req.Name = req.Workspace
 	}
+	if req.Deployment == "" {
+		req.Deployment = models.JiraCloud
+	}
+	if req.AuthMode == "" {
+		req.AuthMode = models.JiraAuthBasic
+	}
+	if err := validateJiraAccess(req.Deployment, req.AuthMode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	ws := models.JiraWorkspaceConfig{
 		UserID:      userID,
-		Workspace:   req.Workspace,
+		Workspace:   strings.TrimRight(strings.TrimSpace(req.Workspace), "/"),
 		Name:        req.Name,
 		ProjectKeys: req.ProjectKeys,
+		Deployment:  req.Deployment,
+		AuthMode:    req.AuthMode,
+		Email:       strings.TrimSpace(req.Email),
 		IsActive:    true,
 	}
+	if req.Token != "" {
+		encrypted, err := h.Encryptor.Encrypt(req.Token)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encrypt token"})
+			return
+		}
+		ws.Token = encrypted
+		ws.HasToken = true
+	}
 	if err := h.DB.Create(&ws).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create workspace"})
 		return
@@ -139,19 +164,60 @@ func (h *JiraHandler) UpdateWorkspace(c *gin.Context) {
 	}
 
 	var req struct {
-		Workspace   *string `json:"workspace"`
-		Name        *string `json:"name"`
-		ProjectKeys *string `json:"project_keys"`
-		IsActive    *bool   `json:"is_active"`
+		Workspace         *string                `json:"workspace"`
+		Name              *string                `json:"name"`
+		ProjectKeys       *string                `json:"project_keys"`
+		IsActive          *bool                  `json:"is_active"`
+		StoryPointsField  *string                `json:"story_points_field"`
+		SyncBacklog       *bool                  `json:"sync_backlog"`
+		SyncFutureSprints *bool                  `json:"sync_future_sprints"`
+		Deployment        *models.JiraDeployment `json:"deployment"`
+		AuthMode          *models.JiraAuthMode   `json:"auth_mode"`
+		// Email and Token override the user's credentials; empty strings
+		// clear them so the user's are used again.
+		Email *string `json:"email"`
+		Token *string `json:"token"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	deployment, authMode := ws.Deployment, ws.AuthMode
+	if req.Deployment != nil {
+		deployment = *req.Deployment
+	}
+	if req.AuthMode != nil {
+		authMode = *req.AuthMode
+	}
+	if err := validateJiraAccess(deployment, authMode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	updates := map[string]interface{}{}
 	if req.Workspace != nil {
-		updates["workspace"] = *req.Workspace
+		updates["workspace"] = strings.TrimRight(strings.TrimSpace(*req.Workspace), "/")
+	}
+	if req.Deployment != nil {
+		updates["deployment"] = deployment
+	}
+	if req.AuthMode != nil {
+		updates["auth_mode"] = authMode
+	}
+	if req.Email != nil {
+		updates["email"] = strings.TrimSpace(*req.Email)
+	}
+	if req.Token != nil {
+		updates["token"] = ""
+		if *req.Token != "" {
+			encrypted, err := h.Encryptor.Encrypt(*req.Token)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encrypt token"})
+				return
+			}
+			updates["token"] = encrypted
+		}
 	}
 	if req.Name != nil {
 		updates["name"] = *req.Name
@@ -162,6 +228,23 @@ func (h *JiraHandler) UpdateWorkspace(c *gin.Context) {
 	if req.IsActive != nil {
 		updates["is_active"] = *req.IsActive
 	}
+	if req.StoryPointsField != nil && strings.TrimSpace(*req.StoryPointsField) != ws.StoryPointsField {
+		updates["story_points_field"] = strings.TrimSpace(*req.StoryPointsField)
+		// Refetch every card so its points are read from the new field.
+		worker.ResetJiraSync(h.DB, userID, ws.ID)
+	}
+	if req.SyncBacklog != nil {
+		updates["sync_backlog"] = *req.SyncBacklog
+	}
+	if req.SyncFutureSprints != nil {
+		updates["sync_future_sprints"] = *req.SyncFutureSprints
+	}
+	if (req.SyncBacklog != nil && *req.SyncBacklog && !ws.SyncBacklog) ||
+		(req.SyncFutureSprints != nil && *req.SyncFutureSprints && !ws.SyncFutureSprints) {
+		// Cards that changed before the opt-in are behind the high-water
+		// mark, so the next sync starts over.
+		worker.ResetJiraSync(h.DB, userID, ws.ID)
+	}
 
 	h.DB.Model(&ws).Updates(updates)
 	h.DB.First(&ws, ws.ID)
@@ -169,6 +252,54 @@ func (h *JiraHandler) UpdateWorkspace(c *gin.Context) {
 	c.JSON(http.StatusOK, ws)
 }
 
+// validateJiraAccess checks a workspace's deployment and auth mode.
+func validateJiraAccess(deployment models.JiraDeployment, authMode models.JiraAuthMode) error {
+	switch deployment {
+	case models.JiraCloud, models.JiraDataCenter:
+	default:
+		return fmt.Errorf("deployment must be cloud or datacenter")
+	}
+	switch authMode {
+	case models.JiraAuthBasic, models.JiraAuthBearer:
+	default:
+		return fmt.Errorf("auth_mode must be basic or bearer")
+	}
+	return nil
+}
+
+// ValidateWorkspace POST /jira/workspaces/:id/validate checks that the
+// workspace's credentials (or the user's, where it has none) sign in.
+func (h *JiraHandler) ValidateWorkspace(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	wsID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+
+	var ws models.JiraWorkspaceConfig
+	if err := h.DB.Where("id = ? AND user_id = ?", wsID, userID).First(&ws).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "workspace not found"})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	client, err := jira.ClientFor(ws, user, h.Encryptor)
+	if err == nil {
+		err = client.Validate()
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"valid": true})
+}
+
 func (h *JiraHandler) DeleteWorkspace(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	wsID := c.Param("id")
@@ -219,8 +350,11 @@ func (h *JiraHandler) syncSprintsFromJira(client *jira.Client, userID uint, ws *
 		return
 	}
 
-	for _, boardID := range boards {
-		sprints, err := client.FetchSprints(boardID)
+	for _, board := range boards {
+		if board.Type == jira.BoardKanban {
+			continue
+		}
+		sprints, err := client.FetchSprints(board.ID)
 		if err != nil {
 			continue
 		}
@@ -233,11 +367,12 @@ func (h *JiraHandler) syncSprintsFromJira(client *jira.Client, userID uint, ws *
 				StartDate:    s.StartDate,
 				EndDate:      s.EndDate,
 			}
+			query := h.DB.Where("user_id = ? AND jira_sprint_id = ?", userID, sprint.JiraSprintID)
 			if ws != nil {
 				sprint.WorkspaceID = &ws.ID
+				query = query.Where("workspace_id = ?", ws.ID)
 			}
-			h.DB.Where("jira_sprint_id = ?", sprint.JiraSprintID).
-				Assign(sprint).FirstOrCreate(&sprint)
+			query.Assign(sprint).FirstOrCreate(&sprint)
 		}
 	}
 }
@@ -301,6 +436,11 @@ func (h *JiraHandler) ListCards(c *gin.Context) {
 	sprintIDParam := c.Query("sprint_id")
 	wsID := resolveWorkspaceID(c)
 
+	if sprintIDParam == "none" || c.Query("backlog") == "true" {
+		h.listUnsprintedCards(c, userID, wsID)
+		return
+	}
+
 	var sprint models.Sprint
 	if sprintIDParam != "" {
 		if err := h.DB.Where("id = ? AND user_id = ?", sprintIDParam, userID).First(&sprint).Error; err != nil {
@@ -370,6 +510,31 @@ func (h *JiraHandler) ListCards(c *gin.Context) {
 	c.JSON(http.StatusOK, dbCards)
 }
 
+// listUnsprintedCards lists synced cards outside any sprint: Kanban and
+// backlog cards with sprint_id=none, or only backlog cards with
+// backlog=true. Nothing is fetched from Jira here.
+func (h *JiraHandler) listUnsprintedCards(c *gin.Context, userID uint, wsID *uint) {
+	cardQuery := h.DB.Where("user_id = ?", userID)
+	if c.Query("backlog") == "true" {
+		cardQuery = cardQuery.Where("in_backlog = ?", true)
+	} else {
+		cardQuery = cardQuery.Where("sprint_id IS NULL")
+	}
+	if wsID != nil {
+		cardQuery = cardQuery.Where("workspace_id = ?", *wsID)
+		var ws models.JiraWorkspaceConfig
+		if h.DB.First(&ws, *wsID).Error == nil {
+			if clause, args := helpers.BuildProjectKeyWhereClauses(ws.ProjectKeys, "card_key"); clause != "" {
+				cardQuery = cardQuery.Where(clause, args...)
+			}
+		}
+	}
+
+	var cards []models.JiraCard
+	cardQuery.Order("jira_updated_at DESC").Find(&cards)
+	c.JSON(http.StatusOK, cards)
+}
+
 func (h *JiraHandler) GetCard(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	cardKey := c.Param("key")
@@ -377,8 +542,7 @@ func (h *JiraHandler) GetCard(c *gin.Context) {
 	findCommits := func(key string) []models.Commit {
 		var commits []models.Commit
 		h.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
-			Where("repositories.user_id = ? AND (commits.jira_card_key = ? OR commits.id IN (SELECT commit_id FROM commit_card_links WHERE jira_card_key = ?))",
-				userID, key, key).
+			Where("repositories.user_id = ? AND "+cardlink.OnCard, userID, key).
 			Order("commits.date desc").
 			Find(&commits)
 		return commits
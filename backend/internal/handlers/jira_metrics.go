@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/flow"
+	"github.com/gin-gonic/gin"
+)
+
+// GetCardHistory GET /jira/cards/:key/history
+func (h *JiraHandler) GetCardHistory(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	c.JSON(http.StatusOK, flow.History(h.DB, userID, strings.ToUpper(c.Param("key"))))
+}
+
+// CycleTime GET /jira/metrics/cycle-time
+// Time from a card's first move out of a to-do status until it was done.
+func (h *JiraHandler) CycleTime(c *gin.Context) {
+	h.flowTimes(c, func(cf flow.CardFlow) *float64 { return cf.CycleHours })
+}
+
+// LeadTime GET /jira/metrics/lead-time
+// Time from a card's creation until it was done.
+func (h *JiraHandler) LeadTime(c *gin.Context) {
+	h.flowTimes(c, func(cf flow.CardFlow) *float64 { return cf.LeadHours })
+}
+
+func (h *JiraHandler) flowTimes(c *gin.Context, metric func(flow.CardFlow) *float64) {
+	filter, ok := h.flowFilter(c, true)
+	if !ok {
+		return
+	}
+
+	flows, err := flow.Flows(h.DB, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute metrics"})
+		return
+	}
+
+	sprintNames := map[uint]string{}
+	var sprints []models.Sprint
+	h.DB.Select("id, name").Where("user_id = ?", filter.UserID).Find(&sprints)
+	for _, s := range sprints {
+		sprintNames[s.ID] = s.Name
+	}
+
+	var all []float64
+	byAssignee := map[string][]float64{}
+	bySprint := map[string][]float64{}
+	cards := []flow.CardFlow{}
+	for _, cf := range flows {
+		v := metric(cf)
+		if v == nil {
+			continue
+		}
+		cards = append(cards, cf)
+		all = append(all, *v)
+		byAssignee[cf.Assignee] = append(byAssignee[cf.Assignee], *v)
+		if cf.SprintID != nil {
+			name := sprintNames[*cf.SprintID]
+			bySprint[name] = append(bySprint[name], *v)
+		}
+	}
+
+	group := func(m map[string][]float64) map[string]flow.Stats {
+		out := make(map[string]flow.Stats, len(m))
+		for k, v := range m {
+			out[k] = flow.Summarize(v)
+		}
+		return out
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":        filter.From,
+		"to":          filter.To,
+		"summary":     flow.Summarize(all),
+		"by_assignee": group(byAssignee),
+		"by_sprint":   group(bySprint),
+		"cards":       cards,
+	})
+}
+
+// TimeInStatus GET /jira/metrics/time-in-status
+func (h *JiraHandler) TimeInStatus(c *gin.Context) {
+	filter, ok := h.flowFilter(c, false)
+	if !ok {
+		return
+	}
+
+	statuses, err := flow.TimeInStatus(h.DB, filter, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute metrics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     filter.From,
+		"to":       filter.To,
+		"statuses": statuses,
+	})
+}
+
+// Throughput GET /jira/metrics/throughput?group_by=week|sprint|assignee
+func (h *JiraHandler) Throughput(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", "week")
+	if groupBy != "week" && groupBy != "sprint" && groupBy != "assignee" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be week, sprint or assignee"})
+		return
+	}
+
+	filter, ok := h.flowFilter(c, true)
+	if !ok {
+		return
+	}
+
+	buckets, err := flow.Throughput(h.DB, filter, groupBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute metrics"})
+		return
+	}
+
+	total := 0
+	for _, b := range buckets {
+		total += b.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     filter.From,
+		"to":       filter.To,
+		"group_by": groupBy,
+		"total":    total,
+		"buckets":  buckets,
+	})
+}
+
+// flowFilter reads the metric filters from the query: workspace_id,
+// sprint_id, assignee, and from/to dates (to is inclusive). With
+// defaultRange and neither a sprint nor dates, the last 30 days are used.
+func (h *JiraHandler) flowFilter(c *gin.Context, defaultRange bool) (flow.Filter, bool) {
+	f := flow.Filter{UserID: c.GetUint("user_id"), Assignee: c.Query("assignee")}
+
+	for param, dst := range map[string]**uint{"workspace_id": &f.WorkspaceID, "sprint_id": &f.SprintID} {
+		if v := c.Query(param); v != "" {
+			id, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s", param)})
+				return f, false
+			}
+			u := uint(id)
+			*dst = &u
+		}
+	}
+
+	if v := c.Query("from"); v != "" {
+		from, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+			return f, false
+		}
+		f.From = &from
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD"})
+			return f, false
+		}
+		to = to.AddDate(0, 0, 1)
+		f.To = &to
+	}
+
+	if defaultRange && f.SprintID == nil && f.From == nil && f.To == nil {
+		from := time.Now().AddDate(0, 0, -30)
+		f.From = &from
+	}
+	return f, true
+}
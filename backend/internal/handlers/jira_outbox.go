@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/jiraoutbox"
+	"github.com/gin-gonic/gin"
+)
+
+type createJiraOutboxRequest struct {
+	CardKey          string                  `json:"card_key" binding:"required"`
+	Action           models.JiraOutboxAction `json:"action" binding:"required"` // comment | transition | worklog
+	Body             string                  `json:"body"`
+	Transition       string                  `json:"transition"`
+	TimeSpentSeconds int                     `json:"time_spent_seconds"`
+	StartedAt        *time.Time              `json:"started_at"`
+	Context          string                  `json:"context"`
+	AutoApprove      bool                    `json:"auto_approve"`
+}
+
+type updateJiraOutboxRequest struct {
+	Status string `json:"status"` // approved | rejected
+	Body   string `json:"body"`   // optional edited comment
+}
+
+// GetCardTransitions GET /jira/cards/:key/transitions
+func (h *JiraHandler) GetCardTransitions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	cardKey := strings.ToUpper(c.Param("key"))
+
+	var workspaceID *uint
+	var card models.JiraCard
+	if h.DB.Where("user_id = ? AND card_key = ?", userID, cardKey).First(&card).Error == nil {
+		workspaceID = card.WorkspaceID
+	}
+
+	client, _, err := jiraoutbox.Client(h.DB, h.Encryptor, userID, workspaceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transitions, err := client.FetchTransitions(cardKey)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, transitions)
+}
+
+// ListOutbox GET /jira/outbox
+func (h *JiraHandler) ListOutbox(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	query := h.DB.Where("user_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if cardKey := c.Query("card_key"); cardKey != "" {
+		query = query.Where("card_key = ?", strings.ToUpper(cardKey))
+	}
+
+	var outbox []models.JiraOutbox
+	query.Order("created_at desc").Find(&outbox)
+
+	c.JSON(http.StatusOK, outbox)
+}
+
+// CreateOutbox POST /jira/outbox
+func (h *JiraHandler) CreateOutbox(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req createJiraOutboxRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item := models.JiraOutbox{
+		UserID:           userID,
+		CardKey:          req.CardKey,
+		Action:           req.Action,
+		Body:             req.Body,
+		Transition:       req.Transition,
+		TimeSpentSeconds: req.TimeSpentSeconds,
+		StartedAt:        req.StartedAt,
+		RequestedBy:      "user",
+		Context:          req.Context,
+	}
+	if err := jiraoutbox.Queue(h.DB, &item); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.AutoApprove {
+		if err := jiraoutbox.Approve(h.DB, h.Encryptor, &item); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "item": item})
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, item)
+}
+
+// UpdateOutbox PATCH /jira/outbox/:id
+// Approving sends the change to Jira straight away; the response carries
+// the resulting sent or failed entry.
+func (h *JiraHandler) UpdateOutbox(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var item models.JiraOutbox
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "outbox item not found"})
+		return
+	}
+
+	var req updateJiraOutboxRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if item.Status != jiraoutbox.StatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only pending outbox items can be changed"})
+		return
+	}
+
+	if req.Body != "" {
+		if err := h.DB.Model(&item).Update("body", req.Body).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update outbox item"})
+			return
+		}
+	}
+
+	switch req.Status {
+	case "":
+	case jiraoutbox.StatusApproved:
+		if err := jiraoutbox.Approve(h.DB, h.Encryptor, &item); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "item": item})
+			return
+		}
+	case jiraoutbox.StatusRejected:
+		if err := h.DB.Model(&item).Update("status", jiraoutbox.StatusRejected).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update outbox item"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be approved or rejected"})
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// DeleteOutbox DELETE /jira/outbox/:id  (only pending)
+func (h *JiraHandler) DeleteOutbox(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var item models.JiraOutbox
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "outbox item not found"})
+		return
+	}
+
+	if item.Status != jiraoutbox.StatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only pending outbox items can be deleted"})
+		return
+	}
+
+	if err := h.DB.Delete(&item).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete outbox item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "outbox item deleted"})
+}
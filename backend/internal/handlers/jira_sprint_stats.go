@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/services/sprintstats"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type burndownPoint struct {
+	Date            string   `json:"date"`
+	RemainingPoints float64  `json:"remaining_points"`
+	RemainingCards  int      `json:"remaining_cards"`
+	IdealPoints     *float64 `json:"ideal_points"`
+	Estimated       bool     `json:"estimated"`
+}
+
+type burnupPoint struct {
+	Date        string   `json:"date"`
+	ScopePoints float64  `json:"scope_points"`
+	ScopeCards  int      `json:"scope_cards"`
+	DonePoints  float64  `json:"done_points"`
+	DoneCards   int      `json:"done_cards"`
+	Added       []string `json:"added,omitempty"`
+	Removed     []string `json:"removed,omitempty"`
+	Estimated   bool     `json:"estimated"`
+}
+
+// SprintBurndown GET /jira/sprints/:id/burndown
+func (h *JiraHandler) SprintBurndown(c *gin.Context) {
+	series, ok := h.sprintSeries(c)
+	if !ok {
+		return
+	}
+	points := make([]burndownPoint, 0, len(series.Days))
+	for _, d := range series.Days {
+		points = append(points, burndownPoint{
+			Date:            d.Date,
+			RemainingPoints: d.Remaining.Points,
+			RemainingCards:  d.Remaining.Cards,
+			IdealPoints:     d.Ideal,
+			Estimated:       d.Estimated,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"sprint": series.Sprint, "days": points})
+}
+
+// SprintBurnup GET /jira/sprints/:id/burnup
+func (h *JiraHandler) SprintBurnup(c *gin.Context) {
+	series, ok := h.sprintSeries(c)
+	if !ok {
+		return
+	}
+	points := make([]burnupPoint, 0, len(series.Days))
+	for _, d := range series.Days {
+		points = append(points, burnupPoint{
+			Date:        d.Date,
+			ScopePoints: d.Scope.Points,
+			ScopeCards:  d.Scope.Cards,
+			DonePoints:  d.Done.Points,
+			DoneCards:   d.Done.Cards,
+			Added:       d.Added,
+			Removed:     d.Removed,
+			Estimated:   d.Estimated,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"sprint": series.Sprint, "days": points})
+}
+
+// SprintCommitment GET /jira/sprints/:id/commitment
+func (h *JiraHandler) SprintCommitment(c *gin.Context) {
+	series, ok := h.sprintSeries(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sprint": series.Sprint, "commitment": series.Commitment()})
+}
+
+// SprintCarryOver GET /jira/sprints/:id/carry-over
+func (h *JiraHandler) SprintCarryOver(c *gin.Context) {
+	series, ok := h.sprintSeries(c)
+	if !ok {
+		return
+	}
+	carried, err := sprintstats.CarryOver(h.DB, series)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute carry-over"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sprint":     series.Sprint,
+		"carried_in": carried.In,
+		"not_done":   carried.NotDone,
+	})
+}
+
+// Velocity GET /jira/velocity?sprints=6&workspace_id=
+func (h *JiraHandler) Velocity(c *gin.Context) {
+	n := 6
+	if v := c.Query("sprints"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sprints must be between 1 and 50"})
+			return
+		}
+		n = parsed
+	}
+
+	report, err := sprintstats.Velocity(h.DB, c.GetUint("user_id"), resolveWorkspaceID(c), n, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute velocity"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *JiraHandler) sprintSeries(c *gin.Context) (*sprintstats.Series, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sprint id"})
+		return nil, false
+	}
+	series, err := sprintstats.Build(h.DB, c.GetUint("user_id"), uint(id), time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "sprint not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute sprint series"})
+		return nil, false
+	}
+	return series, true
+}
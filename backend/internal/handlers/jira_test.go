@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
+)
+
+func TestValidateWorkspace_UsesWorkspaceCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.JiraWorkspaceConfig{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	enc, err := crypto.NewEncryptor(strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatalf("encryptor: %v", err)
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if email, token, _ := r.BasicAuth(); email != "ana@client.com" || token != "client-tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"displayName":"Ana"}`)
+	}))
+	defer srv.Close()
+	orig := httpclient.Client
+	httpclient.Client = srv.Client()
+	defer func() { httpclient.Client = orig }()
+
+	userToken, _ := enc.Encrypt("user-tok")
+	user := models.User{Email: "ana@corp.com", Password: "x", JiraEmail: "ana@corp.com", JiraToken: userToken}
+	db.Create(&user)
+
+	h := &JiraHandler{DB: db, Encryptor: enc}
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", user.ID) })
+	r.POST("/jira/workspaces", h.AddWorkspace)
+	r.PATCH("/jira/workspaces/:id", h.UpdateWorkspace)
+	r.POST("/jira/workspaces/:id/validate", h.ValidateWorkspace)
+
+	send := func(method, path, body string) map[string]any {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var out map[string]any
+		json.Unmarshal(w.Body.Bytes(), &out)
+		return out
+	}
+
+	created := send(http.MethodPost, "/jira/workspaces",
+		fmt.Sprintf(`{"workspace":%q,"email":"ana@client.com","token":"client-tok"}`, srv.Listener.Addr().String()))
+	if created["has_token"] != true || created["token"] != nil {
+		t.Fatalf("created = %v, want has_token and no token", created)
+	}
+	path := fmt.Sprintf("/jira/workspaces/%v", created["id"])
+
+	if out := send(http.MethodPost, path+"/validate", ""); out["valid"] != true {
+		t.Errorf("own credentials: %v", out)
+	}
+
+	// Clearing the workspace's credentials falls back to the user's, which
+	// this site rejects.
+	send(http.MethodPatch, path, `{"email":"","token":""}`)
+	if out := send(http.MethodPost, path+"/validate", ""); out["valid"] != false || out["error"] == nil {
+		t.Errorf("user credentials: %v", out)
+	}
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/helpers"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/flow"
+	"github.com/cds-id/pdt/backend/internal/services/jira"
+	wvClient "github.com/cds-id/pdt/backend/internal/services/weaviate"
+	"github.com/cds-id/pdt/backend/internal/worker"
+	"github.com/gin-gonic/gin"
+)
+
+// jiraUser is the user object Jira Cloud embeds in webhook payloads.
+type jiraUser struct {
+	DisplayName  string `json:"displayName"`
+	EmailAddress string `json:"emailAddress"`
+}
+
+// jiraWebhookPayload holds the fields used from Jira Cloud issue, comment
+// and sprint webhooks.
+type jiraWebhookPayload struct {
+	WebhookEvent string   `json:"webhookEvent"`
+	Timestamp    int64    `json:"timestamp"` // milliseconds since epoch
+	User         jiraUser `json:"user"`
+	Issue        *struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary     string          `json:"summary"`
+			Description json.RawMessage `json:"description"`
+			Status      struct {
+				Name           string `json:"name"`
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"status"`
+			Assignee *jiraUser `json:"assignee"`
+		} `json:"fields"`
+	} `json:"issue"`
+	Changelog *struct {
+		Items []struct {
+			Field      string `json:"field"`
+			FromString string `json:"fromString"`
+			ToString   string `json:"toString"`
+		} `json:"items"`
+	} `json:"changelog"`
+	Comment *struct {
+		ID      string          `json:"id"`
+		Author  jiraUser        `json:"author"`
+		Body    json.RawMessage `json:"body"`
+		Created string          `json:"created"`
+	} `json:"comment"`
+	Sprint *jira.SprintInfo `json:"sprint"`
+}
+
+// Jira receives Jira Cloud webhooks. The workspace is identified by the
+// secret in the URL, which is compared by its hash.
+func (h *WebhookHandler) Jira(c *gin.Context) {
+	var ws models.JiraWorkspaceConfig
+	if err := h.DB.Where("webhook_secret_hash = ? AND webhook_enabled = ?", hashWebhookSecret(c.Param("secret")), true).
+		First(&ws).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+	var payload jiraWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	switch payload.WebhookEvent {
+	case "jira:issue_updated":
+		h.handleJiraIssue(c, ws, payload)
+	case "comment_created", "comment_updated":
+		h.handleJiraComment(c, ws, payload)
+	case "sprint_started", "sprint_closed":
+		h.handleJiraSprint(c, ws, payload)
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"message": "event ignored"})
+	}
+}
+
+// handleJiraIssue updates a synced card of one of the workspace's projects in
+// place and records the change in its stored changelog. Cards not synced yet are left to the next sync,
+// which also places them in their sprint.
+func (h *WebhookHandler) handleJiraIssue(c *gin.Context, ws models.JiraWorkspaceConfig, payload jiraWebhookPayload) {
+	if payload.Issue == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing issue"})
+		return
+	}
+	issue := payload.Issue
+	if !helpers.FilterByProjectKeys(issue.Key, ws.ProjectKeys) {
+		c.JSON(http.StatusAccepted, gin.H{"message": "project not tracked"})
+		return
+	}
+
+	var card models.JiraCard
+	if err := h.DB.Where("user_id = ? AND card_key = ?", ws.UserID, issue.Key).First(&card).Error; err != nil {
+		c.JSON(http.StatusAccepted, gin.H{"message": "card not synced yet"})
+		return
+	}
+
+	fromStatus := card.Status
+	card.Summary = issue.Fields.Summary
+	card.Status = issue.Fields.Status.Name
+	card.Assignee = ""
+	if issue.Fields.Assignee != nil {
+		card.Assignee = issue.Fields.Assignee.DisplayName
+	}
+
+	var detail jira.IssueDetail
+	json.Unmarshal([]byte(card.DetailsJSON), &detail)
+	detail.Key = issue.Key
+	detail.Summary = card.Summary
+	detail.Status = card.Status
+	detail.Assignee = card.Assignee
+	detail.Description = jira.DescriptionText(issue.Fields.Description)
+	detail.DescriptionADF = jira.RawADF(issue.Fields.Description)
+	at := time.Now()
+	if payload.Timestamp > 0 {
+		at = time.UnixMilli(payload.Timestamp)
+	}
+	if payload.Changelog != nil && len(payload.Changelog.Items) > 0 {
+		history := jira.ChangeHistory{Author: payload.User.DisplayName, Created: at.Format("2006-01-02T15:04:05.000-0700")}
+		for _, item := range payload.Changelog.Items {
+			history.Items = append(history.Items, jira.ChangeItem{Field: item.Field, FromString: item.FromString, ToString: item.ToString})
+			if item.Field == "status" && item.FromString != "" {
+				fromStatus = item.FromString
+			}
+		}
+		detail.Changelog = append(detail.Changelog, history)
+	}
+	detailJSON, _ := json.Marshal(detail)
+	card.DetailsJSON = string(detailJSON)
+
+	if err := h.DB.Model(&card).Updates(map[string]interface{}{
+		"summary":      card.Summary,
+		"status":       card.Status,
+		"assignee":     card.Assignee,
+		"details_json": card.DetailsJSON,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update card"})
+		return
+	}
+
+	if h.Weaviate != nil {
+		content := wvClient.JiraCardContent(card.Summary, detail.Description)
+		go func() {
+			if err := h.Weaviate.UpsertJiraCard(context.Background(), card.Key, int(ws.UserID), int(ws.ID), content, card.Status, card.Assignee); err != nil {
+				log.Printf("[jira-webhook] embed card %s error: %v", card.Key, err)
+			}
+		}()
+	}
+
+	transitioned := fromStatus != card.Status
+	if transitioned {
+		// The next sync of the card replaces this row with its changelog.
+		known := map[string]string{strings.ToLower(card.Status): issue.Fields.Status.StatusCategory.Key}
+		h.DB.Create(&models.JiraCardTransition{
+			UserID:         ws.UserID,
+			WorkspaceID:    card.WorkspaceID,
+			CardKey:        card.Key,
+			FromStatus:     fromStatus,
+			ToStatus:       card.Status,
+			FromCategory:   flow.Category(fromStatus, nil),
+			ToCategory:     flow.Category(card.Status, known),
+			Author:         payload.User.DisplayName,
+			TransitionedAt: at,
+		})
+	}
+	if transitioned && h.EventBus != nil {
+		h.EventBus.Publish("jira_card_transitioned", map[string]any{
+			"user_id":      ws.UserID,
+			"workspace_id": ws.ID,
+			"card_key":     card.Key,
+			"summary":      card.Summary,
+			"from_status":  fromStatus,
+			"to_status":    card.Status,
+			"assignee":     card.Assignee,
+			"changed_by":   payload.User.DisplayName,
+		})
+	}
+
+	log.Printf("[jira-webhook] ws=%s card=%s status=%s transitioned=%v", ws.Workspace, card.Key, card.Status, transitioned)
+	c.JSON(http.StatusOK, gin.H{"card_key": card.Key, "status": card.Status, "transitioned": transitioned})
+}
+
+// handleJiraComment upserts a comment on a card of one of the workspace's
+// projects.
+func (h *WebhookHandler) handleJiraComment(c *gin.Context, ws models.JiraWorkspaceConfig, payload jiraWebhookPayload) {
+	if payload.Comment == nil || payload.Issue == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing comment"})
+		return
+	}
+	cardKey := payload.Issue.Key
+	if !helpers.FilterByProjectKeys(cardKey, ws.ProjectKeys) {
+		c.JSON(http.StatusAccepted, gin.H{"message": "project not tracked"})
+		return
+	}
+
+	created, _ := time.Parse("2006-01-02T15:04:05.000-0700", payload.Comment.Created)
+	wsID := ws.ID
+	comment := models.JiraComment{
+		UserID:      ws.UserID,
+		WorkspaceID: &wsID,
+		CardKey:     cardKey,
+		CommentID:   payload.Comment.ID,
+		Author:      payload.Comment.Author.DisplayName,
+		AuthorEmail: payload.Comment.Author.EmailAddress,
+		Body:        jira.DescriptionText(payload.Comment.Body),
+		BodyADF:     string(jira.RawADF(payload.Comment.Body)),
+		CommentedAt: created,
+	}
+	if err := h.DB.Where("user_id = ? AND workspace_id = ? AND comment_id = ?", ws.UserID, ws.ID, comment.CommentID).
+		Assign(comment).FirstOrCreate(&comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store comment"})
+		return
+	}
+
+	if h.Weaviate != nil {
+		go func() {
+			if err := h.Weaviate.UpsertJiraComment(context.Background(), comment.CommentID, cardKey, int(ws.UserID), int(ws.ID), comment.Body, comment.Author); err != nil {
+				log.Printf("[jira-webhook] embed comment %s error: %v", comment.CommentID, err)
+			}
+		}()
+	}
+
+	if payload.WebhookEvent == "comment_created" && h.EventBus != nil {
+		h.EventBus.Publish("jira_comment_added", map[string]any{
+			"user_id":      ws.UserID,
+			"workspace_id": ws.ID,
+			"card_key":     cardKey,
+			"comment_id":   comment.CommentID,
+			"author":       comment.Author,
+			"body":         comment.Body,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"card_key": cardKey, "comment_id": comment.CommentID})
+}
+
+func (h *WebhookHandler) handleJiraSprint(c *gin.Context, ws models.JiraWorkspaceConfig, payload jiraWebhookPayload) {
+	if payload.Sprint == nil || payload.Sprint.ID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing sprint"})
+		return
+	}
+	sprint := *payload.Sprint
+	if sprint.State == "" {
+		sprint.State = string(models.SprintActive)
+		if payload.WebhookEvent == "sprint_closed" {
+			sprint.State = string(models.SprintClosed)
+		}
+	}
+
+	id := worker.UpsertSprint(h.DB, ws.UserID, ws.ID, sprint)
+	c.JSON(http.StatusOK, gin.H{"sprint_id": id, "state": sprint.State})
+}
+
+// EnableWebhook generates (or rotates) the workspace's webhook secret. The
+// plaintext is only returned here, as part of the URL to register in Jira.
+func (h *JiraHandler) EnableWebhook(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var ws models.JiraWorkspaceConfig
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&ws).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "workspace not found"})
+		return
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret"})
+		return
+	}
+	secret := hex.EncodeToString(raw)
+
+	h.DB.Model(&ws).Updates(map[string]interface{}{
+		"webhook_secret_hash": hashWebhookSecret(secret),
+		"webhook_enabled":     true,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":           ws.ID,
+		"webhook_path": fmt.Sprintf("/api/webhooks/jira/%s", secret),
+		"events":       []string{"jira:issue_updated", "comment_created", "comment_updated", "sprint_started", "sprint_closed"},
+	})
+}
+
+func (h *JiraHandler) DisableWebhook(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var ws models.JiraWorkspaceConfig
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&ws).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "workspace not found"})
+		return
+	}
+
+	h.DB.Model(&ws).Updates(map[string]interface{}{
+		"webhook_secret_hash": "",
+		"webhook_enabled":     false,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook disabled"})
+}
+
+func hashWebhookSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
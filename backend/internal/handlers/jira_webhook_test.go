@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/scheduler/eventbus"
+)
+
+func TestJiraWebhook_UpdatesCardAndPublishes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.JiraWorkspaceConfig{}, &models.Sprint{}, &models.JiraCard{}, &models.JiraComment{}, &models.JiraCardTransition{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	ws := models.JiraWorkspaceConfig{UserID: 7, Workspace: "corp.atlassian.net", ProjectKeys: "CORE", IsActive: true,
+		WebhookSecretHash: hashWebhookSecret("s3cret"), WebhookEnabled: true}
+	db.Create(&ws)
+	db.Create(&models.JiraCard{UserID: 7, WorkspaceID: &ws.ID, Key: "CORE-1", Summary: "Login", Status: "To Do"})
+
+	h := &WebhookHandler{DB: db, EventBus: eventbus.New()}
+	r := gin.New()
+	r.POST("/webhooks/jira/:secret", h.Jira)
+
+	transitions := make(chan map[string]any, 1)
+	comments := make(chan map[string]any, 1)
+	h.EventBus.Subscribe("jira_card_transitioned", func(p map[string]any) { transitions <- p })
+	h.EventBus.Subscribe("jira_comment_added", func(p map[string]any) { comments <- p })
+
+	send := func(secret, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/jira/"+secret, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("wrong", `{"webhookEvent":"jira:issue_updated"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown secret: status %d", w.Code)
+	}
+
+	w := send("s3cret", `{"webhookEvent":"jira:issue_updated","timestamp":1760000000000,"user":{"displayName":"Ana"},
+		"issue":{"key":"CORE-1","fields":{"summary":"Login","status":{"name":"In Progress","statusCategory":{"key":"indeterminate"}},"assignee":{"displayName":"Ana"}}},
+		"changelog":{"items":[{"field":"status","fromString":"To Do","toString":"In Progress"}]}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("issue_updated: status %d body %s", w.Code, w.Body)
+	}
+	var card models.JiraCard
+	db.Where("card_key = ?", "CORE-1").First(&card)
+	if card.Status != "In Progress" || card.Assignee != "Ana" || !strings.Contains(card.DetailsJSON, `"to_string":"In Progress"`) {
+		t.Errorf("card = %+v", card)
+	}
+	var moved models.JiraCardTransition
+	if db.Where("card_key = ?", "CORE-1").First(&moved).Error != nil || moved.FromStatus != "To Do" ||
+		moved.ToCategory != models.StatusCategoryInProgress || moved.TransitionedAt.UnixMilli() != 1760000000000 {
+		t.Errorf("transition = %+v", moved)
+	}
+	select {
+	case p := <-transitions:
+		if p["from_status"] != "To Do" || p["to_status"] != "In Progress" || p["user_id"] != uint(7) {
+			t.Errorf("transition event = %v", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no jira_card_transitioned event")
+	}
+
+	w = send("s3cret", `{"webhookEvent":"comment_created","issue":{"key":"CORE-1"},
+		"comment":{"id":"100","author":{"displayName":"Bob"},"body":"Looks good","created":"2026-10-12T09:00:00.000+0000"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("comment_created: status %d body %s", w.Code, w.Body)
+	}
+	var comment models.JiraComment
+	if db.Where("comment_id = ?", "100").First(&comment).Error != nil || comment.Body != "Looks good" {
+		t.Errorf("comment = %+v", comment)
+	}
+	select {
+	case p := <-comments:
+		if p["card_key"] != "CORE-1" || p["author"] != "Bob" {
+			t.Errorf("comment event = %v", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no jira_comment_added event")
+	}
+
+	w = send("s3cret", `{"webhookEvent":"sprint_closed","sprint":{"id":9,"name":"S9","state":"closed"}}`)
+	var sprint models.Sprint
+	if w.Code != http.StatusOK || db.Where("jira_sprint_id = ?", "9").First(&sprint).Error != nil || sprint.State != models.SprintClosed {
+		t.Errorf("sprint_closed: status %d sprint %+v", w.Code, sprint)
+	}
+
+	// Comment and sprint IDs are per Jira site: another user's workspace
+	// sending the same IDs gets its own rows.
+	other := models.JiraWorkspaceConfig{UserID: 8, Workspace: "other.atlassian.net", ProjectKeys: "CORE", IsActive: true,
+		WebhookSecretHash: hashWebhookSecret("0ther"), WebhookEnabled: true}
+	db.Create(&other)
+	send("0ther", `{"webhookEvent":"comment_updated","issue":{"key":"CORE-1"},
+		"comment":{"id":"100","author":{"displayName":"Eve"},"body":"Theirs","created":"2026-10-12T09:00:00.000+0000"}}`)
+	send("0ther", `{"webhookEvent":"sprint_started","sprint":{"id":9,"name":"Their S9","state":"active"}}`)
+	db.Where("user_id = ? AND comment_id = ?", 7, "100").First(&comment)
+	sprint = models.Sprint{}
+	db.Where("user_id = ? AND jira_sprint_id = ?", 7, "9").First(&sprint)
+	if comment.Body != "Looks good" || sprint.Name != "S9" {
+		t.Errorf("another workspace overwrote comment %q / sprint %q", comment.Body, sprint.Name)
+	}
+	var count int64
+	db.Model(&models.JiraComment{}).Where("comment_id = ?", "100").Count(&count)
+	if count != 2 {
+		t.Errorf("comment rows = %d, want 2", count)
+	}
+
+	if w := send("s3cret", `{"webhookEvent":"jira:issue_updated","issue":{"key":"OPS-1","fields":{"status":{"name":"Done"}}}}`); w.Code != http.StatusAccepted {
+		t.Errorf("untracked project: status %d", w.Code)
+	}
+}
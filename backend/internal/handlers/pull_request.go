@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PullRequestHandler struct {
+	DB *gorm.DB
+}
+
+func (h *PullRequestHandler) List(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	query := h.DB.Joins("JOIN repositories ON repositories.id = pull_requests.repo_id").
+		Where("repositories.user_id = ?", userID)
+
+	if repoID := c.Query("repo_id"); repoID != "" {
+		query = query.Where("pull_requests.repo_id = ?", repoID)
+	}
+	if state := c.Query("state"); state != "" {
+		query = query.Where("pull_requests.state = ?", state)
+	}
+	if cardKey := c.Query("jira_card_key"); cardKey != "" {
+		query = query.Where("pull_requests.jira_card_key = ?", cardKey)
+	}
+	if author := c.Query("author"); author != "" {
+		query = query.Where("pull_requests.author = ?", author)
+	}
+
+	var prs []models.PullRequest
+	if err := query.Order("pull_requests.activity_at desc").Find(&prs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch pull requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, prs)
+}
+
+func (h *PullRequestHandler) Get(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var pr models.PullRequest
+	if err := h.DB.Joins("JOIN repositories ON repositories.id = pull_requests.repo_id").
+		Where("repositories.user_id = ? AND pull_requests.id = ?", userID, c.Param("id")).
+		Preload("Reviews", func(db *gorm.DB) *gorm.DB { return db.Order("submitted_at asc") }).
+		First(&pr).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "pull request not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pr)
+}
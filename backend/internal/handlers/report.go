@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/cds-id/pdt/backend/internal/models"
 	"github.com/cds-id/pdt/backend/internal/services/report"
+	"github.com/cds-id/pdt/backend/internal/services/reportexport"
 	"github.com/cds-id/pdt/backend/internal/services/storage"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -20,15 +24,15 @@ type ReportHandler struct {
 	R2        *storage.R2Client // nil if R2 not configured
 }
 
-// uploadToR2 uploads the report markdown to R2 and returns the URL.
+// uploadToR2 uploads the report markdown, and its HTML, PDF and DOCX exports,
+// to R2 and returns the markdown's URL.
 // Returns empty string if R2 is not configured (non-fatal).
-func (h *ReportHandler) uploadToR2(userID uint, date string, content string) string {
+func (h *ReportHandler) uploadToR2(userID uint, name, title, content string) string {
 	if h.R2 == nil {
 		return ""
 	}
 
-	key := fmt.Sprintf("reports/%d/%s.md", userID, date)
-	url, err := h.R2.Upload(context.Background(), key, []byte(content), "text/markdown; charset=utf-8")
+	url, err := reportexport.Upload(context.Background(), h.R2, userID, name, title, content)
 	if err != nil {
 		log.Printf("[report] R2 upload failed: %v", err)
 		return ""
@@ -42,13 +46,19 @@ func (h *ReportHandler) Generate(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	var req struct {
-		Date       string `json:"date"`
-		TemplateID *uint  `json:"template_id"`
+		Date              string `json:"date"`
+		TemplateID        *uint  `json:"template_id"`
+		IncludeAllAuthors bool   `json:"include_all_authors"`
+		Narrative         bool   `json:"narrative"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
+	if req.Narrative && h.Generator.LLM == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "narratives are not available: no AI model is configured"})
+		return
+	}
 
 	if req.Date == "" {
 		req.Date = time.Now().Format("2006-01-02")
@@ -60,12 +70,22 @@ func (h *ReportHandler) Generate(c *gin.Context) {
 		return
 	}
 
-	data, err := h.Generator.BuildReportData(userID, date)
+	data, err := h.Generator.BuildReportData(userID, date, report.BuildOptions{AllAuthors: req.IncludeAllAuthors})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// The report for this date, if any, holds the cached narrative.
+	var existing models.Report
+	h.DB.Where("user_id = ? AND date = ? AND report_type = ?", userID, req.Date, "daily").First(&existing)
+	existing.UserID = userID
+	if req.Narrative {
+		if err := h.Generator.Narrate(&existing, data); err != nil {
+			log.Printf("[report] user %d: %v", userID, err)
+		}
+	}
+
 	templateContent, templateID := h.Generator.GetTemplateContent(userID, req.TemplateID)
 
 	rendered, err := h.Generator.Render(templateContent, data)
@@ -74,51 +94,56 @@ func (h *ReportHandler) Generate(c *gin.Context) {
 		return
 	}
 
-	fileURL := h.uploadToR2(userID, req.Date, rendered)
+	title := "Daily Report — " + date.Format("Monday, 02 January 2006")
 
 	rpt := models.Report{
-		UserID:     userID,
-		TemplateID: templateID,
-		Date:       req.Date,
-		Title:      "Daily Report — " + date.Format("Monday, 02 January 2006"),
-		Content:    rendered,
-		FileURL:    fileURL,
-	}
-
-	// Upsert: if report for this date already exists, update it
-	var existing models.Report
-	if err := h.DB.Where("user_id = ? AND date = ?", userID, req.Date).First(&existing).Error; err == nil {
-		existing.Content = rendered
-		existing.Title = rpt.Title
-		existing.TemplateID = templateID
-		existing.FileURL = fileURL
-		h.DB.Save(&existing)
-		c.JSON(http.StatusOK, existing)
-		return
-	}
-
-	h.DB.Create(&rpt)
-	c.JSON(http.StatusCreated, rpt)
+		UserID:        userID,
+		TemplateID:    templateID,
+		Date:          req.Date,
+		Title:         title,
+		Content:       rendered,
+		ReportType:    "daily",
+		Narrative:     existing.Narrative,
+		NarrativeHash: existing.NarrativeHash,
+	}
+	h.saveReport(c, &rpt, data)
 }
 
 func (h *ReportHandler) GenerateMonthly(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	var req struct {
-		Month int `json:"month" binding:"required"`
-		Year  int `json:"year" binding:"required"`
+		Month             int  `json:"month" binding:"required"`
+		Year              int  `json:"year" binding:"required"`
+		IncludeAllAuthors bool `json:"include_all_authors"`
+		Narrative         bool `json:"narrative"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "month and year are required"})
 		return
 	}
+	if req.Narrative && h.Generator.LLM == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "narratives are not available: no AI model is configured"})
+		return
+	}
 
-	data, err := h.Generator.BuildMonthlyReportData(userID, req.Month, req.Year)
+	data, err := h.Generator.BuildMonthlyReportData(userID, req.Month, req.Year, report.BuildOptions{AllAuthors: req.IncludeAllAuthors})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	month := req.Month
+	year := req.Year
+	var existing models.Report
+	h.DB.Where("user_id = ? AND report_type = ? AND month = ? AND year = ?", userID, "monthly", month, year).First(&existing)
+	existing.UserID = userID
+	if req.Narrative {
+		if err := h.Generator.NarrateMonthly(&existing, data); err != nil {
+			log.Printf("[report] user %d: %v", userID, err)
+		}
+	}
+
 	templateContent := h.Generator.GetMonthlyTemplateContent(userID)
 	rendered, err := h.Generator.RenderMonthly(templateContent, data)
 	if err != nil {
@@ -127,36 +152,120 @@ func (h *ReportHandler) GenerateMonthly(c *gin.Context) {
 	}
 
 	dateStr := fmt.Sprintf("%04d-%02d", req.Year, req.Month)
-	fileURL := h.uploadToR2(userID, "monthly-"+dateStr, rendered)
+	title := fmt.Sprintf("Monthly Report — %s %d", time.Month(month).String(), year)
+
+	rpt := models.Report{
+		UserID:        userID,
+		Date:          dateStr,
+		Title:         title,
+		Content:       rendered,
+		ReportType:    "monthly",
+		Month:         &month,
+		Year:          &year,
+		Narrative:     existing.Narrative,
+		NarrativeHash: existing.NarrativeHash,
+	}
+	h.saveReport(c, &rpt, data)
+}
+
+// GenerateRange generates a report covering several days: a week, a sprint or
+// an arbitrary range. Commits are grouped per card and per day.
+func (h *ReportHandler) GenerateRange(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req struct {
+		ReportType        string `json:"report_type"`
+		StartDate         string `json:"start_date"`
+		EndDate           string `json:"end_date"`
+		SprintID          *uint  `json:"sprint_id"`
+		TemplateID        *uint  `json:"template_id"`
+		IncludeAllAuthors bool   `json:"include_all_authors"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if req.ReportType == "" {
+		req.ReportType = "range"
+	}
+	opts := report.BuildOptions{AllAuthors: req.IncludeAllAuthors}
+
+	var data *report.RangeReportData
+	var err error
+	switch {
+	case req.ReportType != "range" && req.ReportType != "weekly":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "report_type must be range or weekly"})
+		return
+	case req.ReportType == "weekly":
+		day := time.Now()
+		if req.StartDate != "" {
+			if day, err = time.ParseInLocation("2006-01-02", req.StartDate, time.Local); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
+				return
+			}
+		}
+		data, err = h.Generator.BuildWeeklyReportData(userID, day, opts)
+	case req.SprintID != nil:
+		data, err = h.Generator.BuildSprintReportData(userID, *req.SprintID, opts)
+	default:
+		start, startErr := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+		end, endErr := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+		if startErr != nil || endErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_date and end_date are required, use YYYY-MM-DD"})
+			return
+		}
+		data, err = h.Generator.BuildRangeReportData(userID, start, end, opts)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	templateContent, templateID := h.Generator.GetWeeklyTemplateContent(userID, req.TemplateID)
+	rendered, err := h.Generator.RenderRange(templateContent, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "template error: " + err.Error()})
+		return
+	}
 
-	month := req.Month
-	year := req.Year
 	rpt := models.Report{
 		UserID:     userID,
-		Date:       dateStr,
-		Title:      fmt.Sprintf("Monthly Report — %s %d", time.Month(month).String(), year),
+		TemplateID: templateID,
+		Date:       data.StartDate,
+		EndDate:    data.EndDate,
+		Title:      data.Title,
 		Content:    rendered,
-		FileURL:    fileURL,
-		ReportType: "monthly",
-		Month:      &month,
-		Year:       &year,
+		ReportType: req.ReportType,
 	}
+	h.saveReport(c, &rpt, data)
+}
 
-	var existing models.Report
-	if h.DB.Where("user_id = ? AND report_type = ? AND month = ? AND year = ?", userID, "monthly", month, year).First(&existing).Error == nil {
-		existing.Content = rendered
-		existing.Title = rpt.Title
-		existing.FileURL = fileURL
-		h.DB.Save(&existing)
-		c.JSON(http.StatusOK, existing)
+// saveReport stores rpt as a new version of its report and responds with
+// the report: 201 when it is new, 200 when it was regenerated.
+func (h *ReportHandler) saveReport(c *gin.Context, rpt *models.Report, data any) {
+	created, err := report.Save(h.DB, rpt, data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.uploadVersion(rpt)
+	if created {
+		c.JSON(http.StatusCreated, rpt)
+		return
+	}
+	c.JSON(http.StatusOK, rpt)
+}
 
-	if err := h.DB.Create(&rpt).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// uploadVersion uploads the exports of rpt's latest version to R2, if
+// configured, and records where.
+func (h *ReportHandler) uploadVersion(rpt *models.Report) {
+	url := h.uploadToR2(rpt.UserID, report.FileName(*rpt), rpt.Title, rpt.Content)
+	if url == "" {
 		return
 	}
-	c.JSON(http.StatusCreated, rpt)
+	if err := report.SetFileURL(h.DB, rpt, url); err != nil {
+		log.Printf("[report] record file URL of report %d: %v", rpt.ID, err)
+	}
 }
 
 func (h *ReportHandler) List(c *gin.Context) {
@@ -191,6 +300,43 @@ func (h *ReportHandler) Get(c *gin.Context) {
 	c.JSON(http.StatusOK, rpt)
 }
 
+// Export returns a report as Markdown, HTML, PDF or DOCX. HTML, PDF and DOCX
+// use the named theme, or the configured default.
+func (h *ReportHandler) Export(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	id := c.Param("id")
+
+	format, err := reportexport.ParseFormat(c.DefaultQuery("format", "pdf"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	theme, ok := reportexport.ThemeByName(c.Query("theme"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown theme"})
+		return
+	}
+
+	var rpt models.Report
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&rpt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+
+	content, err := reportexport.Render(format, rpt.Title, rpt.Content, theme)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := rpt.ReportType + "-report-" + rpt.Date
+	if rpt.EndDate != "" && rpt.EndDate != rpt.Date {
+		name += "_" + rpt.EndDate
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	c.Data(http.StatusOK, format.ContentType(), content)
+}
+
 func (h *ReportHandler) Delete(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	id := c.Param("id")
@@ -200,10 +346,148 @@ func (h *ReportHandler) Delete(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
 		return
 	}
+	h.DB.Where("report_id = ?", id).Delete(&models.ReportVersion{})
 
 	c.JSON(http.StatusOK, gin.H{"message": "report deleted"})
 }
 
+// --- Versions ---
+
+// ListVersions lists a report's versions, newest first, without their content.
+func (h *ReportHandler) ListVersions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	id := c.Param("id")
+
+	var rpt models.Report
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&rpt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+
+	var versions []models.ReportVersion
+	h.DB.Select("id, report_id, version, template_id, title, file_url, restored_from, created_at").
+		Where("report_id = ?", rpt.ID).Order("version DESC").Find(&versions)
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// GetVersion returns one version of a report with its content and the data
+// it was rendered from.
+func (h *ReportHandler) GetVersion(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	id := c.Param("id")
+
+	var rpt models.Report
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&rpt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+
+	v, ok := h.findVersion(c, rpt.ID, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	var data json.RawMessage
+	if v.Data != "" {
+		data = json.RawMessage(v.Data)
+	}
+	c.JSON(http.StatusOK, struct {
+		models.ReportVersion
+		Data json.RawMessage `json:"data"`
+	}{v, data})
+}
+
+// DiffVersions returns a unified diff between two versions of a report's
+// content. to defaults to the latest version and from to the one before it.
+func (h *ReportHandler) DiffVersions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	id := c.Param("id")
+
+	var rpt models.Report
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&rpt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+
+	to := c.DefaultQuery("to", strconv.Itoa(rpt.Version))
+	toVersion, ok := h.findVersion(c, rpt.ID, to)
+	if !ok {
+		return
+	}
+	from, hasFrom := c.GetQuery("from")
+	if !hasFrom {
+		if toVersion.Version <= 1 {
+			// The first version has nothing before it to compare with.
+			c.JSON(http.StatusOK, gin.H{"from": toVersion.Version, "to": toVersion.Version, "added": 0, "removed": 0, "diff": ""})
+			return
+		}
+		from = strconv.Itoa(toVersion.Version - 1)
+	}
+	fromVersion, ok := h.findVersion(c, rpt.ID, from)
+	if !ok {
+		return
+	}
+
+	diff, added, removed := report.Diff(
+		fmt.Sprintf("version %d", fromVersion.Version), fmt.Sprintf("version %d", toVersion.Version),
+		fromVersion.Content, toVersion.Content)
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":    fromVersion.Version,
+		"to":      toVersion.Version,
+		"added":   added,
+		"removed": removed,
+		"diff":    diff,
+	})
+}
+
+// RestoreVersion makes an earlier version the report's content again. The
+// restore is itself saved as a new version.
+func (h *ReportHandler) RestoreVersion(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	id := c.Param("id")
+
+	var rpt models.Report
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&rpt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+
+	v, ok := h.findVersion(c, rpt.ID, c.Param("version"))
+	if !ok {
+		return
+	}
+	if v.Version == rpt.Version {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version is already the latest"})
+		return
+	}
+
+	if err := report.Restore(h.DB, &rpt, &v); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.uploadVersion(&rpt)
+
+	c.JSON(http.StatusOK, rpt)
+}
+
+// findVersion loads version number version of a report, responding with an
+// error when it is malformed or doesn't exist.
+func (h *ReportHandler) findVersion(c *gin.Context, reportID uint, version string) (models.ReportVersion, bool) {
+	var v models.ReportVersion
+	n, err := strconv.Atoi(version)
+	if err != nil || n < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return v, false
+	}
+	if err := h.DB.Where("report_id = ? AND version = ?", reportID, n).First(&v).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+		return v, false
+	}
+	return v, true
+}
+
 // --- Template Management ---
 
 func (h *ReportHandler) ListTemplates(c *gin.Context) {
@@ -219,24 +503,38 @@ func (h *ReportHandler) CreateTemplate(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	var req struct {
-		Name      string `json:"name" binding:"required"`
-		Content   string `json:"content" binding:"required"`
-		IsDefault bool   `json:"is_default"`
+		Name       string `json:"name" binding:"required"`
+		Content    string `json:"content" binding:"required"`
+		ReportType string `json:"report_type"`
+		IsDefault  bool   `json:"is_default"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "name and content are required"})
 		return
 	}
+	if req.ReportType == "" {
+		req.ReportType = "daily"
+	}
+	if !slices.Contains(report.TemplateTypes, req.ReportType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "report_type must be daily, weekly or monthly"})
+		return
+	}
+
+	if errs := report.Lint(req.Content, req.ReportType); errs != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "template has errors", "errors": errs})
+		return
+	}
 
 	if req.IsDefault {
-		h.DB.Model(&models.ReportTemplate{}).Where("user_id = ?", userID).Update("is_default", false)
+		h.DB.Model(&models.ReportTemplate{}).Where("user_id = ? AND report_type = ?", userID, req.ReportType).Update("is_default", false)
 	}
 
 	tmpl := models.ReportTemplate{
-		UserID:    userID,
-		Name:      req.Name,
-		Content:   req.Content,
-		IsDefault: req.IsDefault,
+		UserID:     userID,
+		Name:       req.Name,
+		Content:    req.Content,
+		ReportType: req.ReportType,
+		IsDefault:  req.IsDefault,
 	}
 	h.DB.Create(&tmpl)
 
@@ -254,27 +552,43 @@ func (h *ReportHandler) UpdateTemplate(c *gin.Context) {
 	}
 
 	var req struct {
-		Name      *string `json:"name"`
-		Content   *string `json:"content"`
-		IsDefault *bool   `json:"is_default"`
+		Name       *string `json:"name"`
+		Content    *string `json:"content"`
+		ReportType *string `json:"report_type"`
+		IsDefault  *bool   `json:"is_default"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
+	if req.ReportType != nil && !slices.Contains(report.TemplateTypes, *req.ReportType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "report_type must be daily, weekly or monthly"})
+		return
+	}
+
 	if req.Name != nil {
 		tmpl.Name = *req.Name
 	}
 	if req.Content != nil {
 		tmpl.Content = *req.Content
 	}
-	if req.IsDefault != nil && *req.IsDefault {
-		h.DB.Model(&models.ReportTemplate{}).Where("user_id = ? AND id != ?", userID, tmpl.ID).Update("is_default", false)
-		tmpl.IsDefault = true
-	} else if req.IsDefault != nil {
+	if req.ReportType != nil {
+		tmpl.ReportType = *req.ReportType
+	}
+	if req.Content != nil || req.ReportType != nil {
+		if errs := report.Lint(tmpl.Content, tmpl.ReportType); errs != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "template has errors", "errors": errs})
+			return
+		}
+	}
+	if req.IsDefault != nil {
 		tmpl.IsDefault = *req.IsDefault
 	}
+	// Keep one default per report type, including when a default changes type.
+	if tmpl.IsDefault && (req.IsDefault != nil || req.ReportType != nil) {
+		h.DB.Model(&models.ReportTemplate{}).Where("user_id = ? AND report_type = ? AND id != ?", userID, tmpl.ReportType, tmpl.ID).Update("is_default", false)
+	}
 
 	h.DB.Save(&tmpl)
 	c.JSON(http.StatusOK, tmpl)
@@ -293,17 +607,28 @@ func (h *ReportHandler) DeleteTemplate(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "template deleted"})
 }
 
+// PreviewTemplate lints a template against sample data and renders it with
+// the caller's data for a day (daily), the week containing it (weekly) or its
+// month (monthly).
 func (h *ReportHandler) PreviewTemplate(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	var req struct {
-		Content string `json:"content" binding:"required"`
-		Date    string `json:"date"`
+		Content    string `json:"content" binding:"required"`
+		Date       string `json:"date"`
+		ReportType string `json:"report_type"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "content is required"})
 		return
 	}
+	if req.ReportType == "" {
+		req.ReportType = "daily"
+	}
+	if req.ReportType != "daily" && req.ReportType != "weekly" && req.ReportType != "monthly" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "report_type must be daily, weekly or monthly"})
+		return
+	}
 
 	dateStr := req.Date
 	if dateStr == "" {
@@ -316,23 +641,57 @@ func (h *ReportHandler) PreviewTemplate(c *gin.Context) {
 		return
 	}
 
-	data, err := h.Generator.BuildReportData(userID, date)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if errs := report.Lint(req.Content, req.ReportType); errs != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "template error: " + errs[0].Error(), "errors": errs})
 		return
 	}
 
-	rendered, err := h.Generator.Render(req.Content, data)
+	var rendered string
+	var stats gin.H
+	switch req.ReportType {
+	case "weekly":
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+		data, buildErr := h.Generator.BuildWeeklyReportData(userID, day)
+		if buildErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": buildErr.Error()})
+			return
+		}
+		rendered, err = h.Generator.RenderRange(req.Content, data)
+		stats = reportStats(data.Stats)
+	case "monthly":
+		data, buildErr := h.Generator.BuildMonthlyReportData(userID, int(date.Month()), date.Year())
+		if buildErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": buildErr.Error()})
+			return
+		}
+		rendered, err = h.Generator.RenderMonthly(req.Content, data)
+		stats = gin.H{"total_commits": data.TotalCommits, "total_cards": data.TotalCards}
+	default:
+		data, buildErr := h.Generator.BuildReportData(userID, date)
+		if buildErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": buildErr.Error()})
+			return
+		}
+		rendered, err = h.Generator.Render(req.Content, data)
+		stats = reportStats(data.Stats)
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "template error: " + err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "template error: " + err.Error(), "errors": report.TemplateErrors(err)})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"rendered": rendered,
-		"stats": gin.H{
-			"total_commits": data.Stats.TotalCommits,
-			"total_cards":   data.Stats.TotalCards,
-		},
+		"stats":    stats,
 	})
 }
+
+func reportStats(stats report.ReportStats) gin.H {
+	return gin.H{
+		"total_commits": stats.TotalCommits,
+		"total_cards":   stats.TotalCards,
+		"prs_opened":    stats.TotalPRsOpened,
+		"prs_reviewed":  stats.TotalPRsReviewed,
+		"prs_merged":    stats.TotalPRsMerged,
+	}
+}
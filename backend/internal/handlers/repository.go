@@ -1,28 +1,37 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
 	"net/http"
 	"net/url"
 	"strings"
 
+	"github.com/cds-id/pdt/backend/internal/crypto"
 	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services"
+	"github.com/cds-id/pdt/backend/internal/services/gitprovider"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type RepoHandler struct {
-	DB *gorm.DB
+	DB        *gorm.DB
+	Encryptor *crypto.Encryptor
 }
 
 type addRepoRequest struct {
-	URL string `json:"url" binding:"required,url"`
+	URL          string `json:"url" binding:"required,url"`
+	ConnectionID *uint  `json:"connection_id"`
 }
 
 func (h *RepoHandler) List(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	var repos []models.Repository
-	if err := h.DB.Where("user_id = ?", userID).Find(&repos).Error; err != nil {
+	if err := h.DB.Where("user_id = ?", userID).Preload("Health").Find(&repos).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch repositories"})
 		return
 	}
@@ -39,27 +48,50 @@ func (h *RepoHandler) Add(c *gin.Context) {
 		return
 	}
 
-	owner, name, provider, err := parseRepoURL(req.URL)
+	conn, err := h.resolveConnection(userID, req.URL, req.ConnectionID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var owner, name string
+	var provider models.Provider
+	var connectionID *uint
+	if conn != nil {
+		owner, name, err = parseRepoURLForConnection(req.URL, *conn)
+		provider = conn.Provider
+		connectionID = &conn.ID
+	} else {
+		var user models.User
+		h.DB.Select("gitlab_url").First(&user, userID)
+		owner, name, provider, err = parseRepoURL(req.URL, user.GitlabURL)
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Check for duplicates
+	dupQuery := h.DB.Where("user_id = ? AND owner = ? AND name = ? AND provider = ?", userID, owner, name, provider)
+	if connectionID != nil {
+		dupQuery = dupQuery.Where("connection_id = ?", *connectionID)
+	} else {
+		dupQuery = dupQuery.Where("connection_id IS NULL")
+	}
 	var existing models.Repository
-	if err := h.DB.Where("user_id = ? AND owner = ? AND name = ? AND provider = ?", userID, owner, name, provider).
-		First(&existing).Error; err == nil {
+	if err := dupQuery.First(&existing).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "repository already tracked"})
 		return
 	}
 
 	repo := models.Repository{
-		UserID:   userID,
-		Name:     name,
-		Owner:    owner,
-		Provider: provider,
-		URL:      req.URL,
-		IsValid:  true,
+		UserID:       userID,
+		Name:         name,
+		Owner:        owner,
+		Provider:     provider,
+		URL:          req.URL,
+		ConnectionID: connectionID,
+		IsValid:      true,
 	}
 
 	if err := h.DB.Create(&repo).Error; err != nil {
@@ -80,8 +112,13 @@ func (h *RepoHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	// Delete associated commits first
+	// Delete associated commits, sync cursors and pull requests first
 	h.DB.Where("repo_id = ?", repo.ID).Delete(&models.Commit{})
+	h.DB.Where("repo_id = ?", repo.ID).Delete(&models.RepoSyncCursor{})
+	h.DB.Where("repo_id = ?", repo.ID).Delete(&models.RepoSyncHealth{})
+	h.DB.Where("pull_request_id IN (?)", h.DB.Model(&models.PullRequest{}).Select("id").Where("repo_id = ?", repo.ID)).
+		Delete(&models.PullRequestReview{})
+	h.DB.Where("repo_id = ?", repo.ID).Delete(&models.PullRequest{})
 	h.DB.Delete(&repo)
 
 	c.JSON(http.StatusOK, gin.H{"message": "repository removed"})
@@ -97,39 +134,248 @@ func (h *RepoHandler) Validate(c *gin.Context) {
 		return
 	}
 
-	// Validation will be done by the sync services during SIT
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	client, token, err := gitprovider.ForRepository(h.DB, h.Encryptor, user, repo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only access failures change validity; a transient error says
+	// nothing about whether the repository is still reachable.
+	message := "repository is accessible"
+	var errorClass services.ErrorClass
+	if err := client.ValidateAccess(repo.Owner, repo.Name, token); err != nil {
+		message = err.Error()
+		errorClass = services.ClassifyError(err)
+		if errorClass.IsAccessError() {
+			repo.IsValid = false
+		}
+	} else {
+		repo.IsValid = true
+	}
+	h.DB.Model(&repo).Update("is_valid", repo.IsValid)
+
+	// Remember who the token belongs to; it is the default "my commits"
+	// author filter.
+	var account *models.ProviderAccount
+	if repo.IsValid {
+		var connectionID uint
+		if repo.ConnectionID != nil {
+			connectionID = *repo.ConnectionID
+		}
+		if account, err = gitprovider.RecordAccount(h.DB, userID, repo.Provider, connectionID, client, token); err != nil {
+			log.Printf("[repo] validate repo=%d account lookup failed: %v", repo.ID, err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"id":       repo.ID,
-		"url":      repo.URL,
-		"provider": repo.Provider,
-		"is_valid": repo.IsValid,
-		"message":  "validation will be performed during sync",
+		"id":          repo.ID,
+		"url":         repo.URL,
+		"provider":    repo.Provider,
+		"is_valid":    repo.IsValid,
+		"error_class": errorClass,
+		"message":     message,
+		"account":     account,
+	})
+}
+
+// EnableWebhook generates (or rotates) the repository's push webhook secret.
+// The plaintext secret is only returned here; it is stored encrypted.
+func (h *RepoHandler) EnableWebhook(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	repoID := c.Param("id")
+
+	var repo models.Repository
+	if err := h.DB.Where("id = ? AND user_id = ?", repoID, userID).First(&repo).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "repository not found"})
+		return
+	}
+
+	if repo.Provider != models.ProviderGitHub && repo.Provider != models.ProviderGitLab {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "push webhooks are only supported for github and gitlab"})
+		return
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret"})
+		return
+	}
+	secret := hex.EncodeToString(raw)
+
+	encrypted, err := h.Encryptor.Encrypt(secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encrypt secret"})
+		return
+	}
+
+	h.DB.Model(&repo).Updates(map[string]interface{}{
+		"webhook_secret":  encrypted,
+		"webhook_enabled": true,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":           repo.ID,
+		"provider":     repo.Provider,
+		"webhook_path": fmt.Sprintf("/api/webhooks/%s/%d", repo.Provider, repo.ID),
+		"secret":       secret,
+	})
+}
+
+func (h *RepoHandler) DisableWebhook(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	repoID := c.Param("id")
+
+	var repo models.Repository
+	if err := h.DB.Where("id = ? AND user_id = ?", repoID, userID).First(&repo).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "repository not found"})
+		return
+	}
+
+	h.DB.Model(&repo).Updates(map[string]interface{}{
+		"webhook_secret":  "",
+		"webhook_enabled": false,
 	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook disabled"})
+}
+
+// resolveConnection returns the git host connection a new repository should
+// use: the explicitly requested one, or the user's connection whose host
+// matches the repository URL. A nil result means the legacy user tokens apply.
+func (h *RepoHandler) resolveConnection(userID uint, rawURL string, connectionID *uint) (*models.GitConnection, error) {
+	if connectionID != nil {
+		var conn models.GitConnection
+		if err := h.DB.Where("id = ? AND user_id = ?", *connectionID, userID).First(&conn).Error; err != nil {
+			return nil, fmt.Errorf("git connection not found")
+		}
+		return &conn, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var conns []models.GitConnection
+	h.DB.Where("user_id = ?", userID).Find(&conns)
+	for _, conn := range conns {
+		if base, err := url.Parse(conn.BaseURL); err == nil && strings.EqualFold(base.Host, u.Host) {
+			return &conn, nil
+		}
+	}
+	return nil, nil
 }
 
-func parseRepoURL(rawURL string) (owner, name string, provider models.Provider, err error) {
+// parseRepoURL extracts owner, name and provider from a repository URL that
+// no git connection covers. gitlabURL is the user's self-hosted GitLab, if
+// any; Bitbucket Server paths are only recognised on other unknown hosts.
+func parseRepoURL(rawURL, gitlabURL string) (owner, name string, provider models.Provider, err error) {
 	u, err := url.Parse(rawURL)
 	if err != nil {
 		return "", "", "", err
 	}
 
 	host := strings.ToLower(u.Host)
-	path := strings.Trim(u.Path, "/")
-	path = strings.TrimSuffix(path, ".git")
+	path := cleanRepoPath(u.Path)
 
-	parts := strings.SplitN(path, "/", 2)
-	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-		return "", "", "", &url.Error{Op: "parse", URL: rawURL, Err: http.ErrNotSupported}
+	knownHost := strings.Contains(host, "github.com") || strings.Contains(host, "gitlab.com")
+	if gl, err := url.Parse(gitlabURL); err == nil && gl.Host != "" && strings.EqualFold(gl.Host, host) {
+		knownHost = true
+	}
+	if !knownHost {
+		if owner, name, ok := parseBitbucketServerPath(path); ok {
+			return owner, name, models.ProviderBitbucket, nil
+		}
 	}
 
-	owner = parts[0]
-	name = parts[1]
+	owner, name, err = splitOwnerName(path, rawURL)
+	if err != nil {
+		return "", "", "", err
+	}
 
 	if strings.Contains(host, "github.com") {
 		provider = models.ProviderGitHub
+	} else if strings.Contains(host, "bitbucket.org") {
+		provider = models.ProviderBitbucket
+		// Strip trailing UI paths such as /src/main
+		name = strings.SplitN(name, "/", 2)[0]
 	} else {
 		provider = models.ProviderGitLab
 	}
 
 	return owner, name, provider, nil
 }
+
+// parseRepoURLForConnection extracts owner and name from a repository URL on
+// the connection's host, ignoring any context path the instance is served under.
+func parseRepoURLForConnection(rawURL string, conn models.GitConnection) (owner, name string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	base, err := url.Parse(conn.BaseURL)
+	if err != nil {
+		return "", "", err
+	}
+	if !strings.EqualFold(u.Host, base.Host) {
+		return "", "", fmt.Errorf("repository host %s does not match connection host %s", u.Host, base.Host)
+	}
+
+	path := cleanRepoPath(u.Path)
+	if prefix := strings.Trim(base.Path, "/"); prefix != "" {
+		path = strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/")
+	}
+
+	if conn.Provider == models.ProviderBitbucket {
+		if owner, name, ok := parseBitbucketServerPath(path); ok {
+			return owner, name, nil
+		}
+	}
+
+	owner, name, err = splitOwnerName(path, rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	// GitLab keeps nested group paths in name; other providers have flat
+	// owner/name, so drop trailing UI paths such as /src/branch/main.
+	if conn.Provider != models.ProviderGitLab {
+		name = strings.SplitN(name, "/", 2)[0]
+	}
+	return owner, name, nil
+}
+
+func cleanRepoPath(path string) string {
+	return strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+}
+
+// parseBitbucketServerPath matches Bitbucket Server / Data Center paths:
+// /projects/{KEY}/repos/{slug}[/browse] or clone URLs /scm/{key}/{slug}.git
+func parseBitbucketServerPath(path string) (owner, name string, ok bool) {
+	segments := strings.Split(path, "/")
+	for i := 0; i+3 < len(segments); i++ {
+		if segments[i] == "projects" && segments[i+2] == "repos" {
+			return segments[i+1], segments[i+3], true
+		}
+	}
+	for i := 0; i+2 < len(segments); i++ {
+		if segments[i] == "scm" {
+			return segments[i+1], segments[i+2], true
+		}
+	}
+	return "", "", false
+}
+
+func splitOwnerName(path, rawURL string) (owner, name string, err error) {
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", &url.Error{Op: "parse", URL: rawURL, Err: http.ErrNotSupported}
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+func TestParseRepoURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		owner    string
+		name     string
+		provider models.Provider
+	}{
+		{"https://github.com/org/app", "org", "app", models.ProviderGitHub},
+		{"https://gitlab.example.com/group/app.git", "group", "app", models.ProviderGitLab},
+		{"https://bitbucket.org/team/app", "team", "app", models.ProviderBitbucket},
+		{"https://bitbucket.org/team/app/src/main/", "team", "app", models.ProviderBitbucket},
+		{"https://git.corp.local/projects/PROJ/repos/app/browse", "PROJ", "app", models.ProviderBitbucket},
+		{"https://git.corp.local/scm/proj/app.git", "proj", "app", models.ProviderBitbucket},
+		{"https://gitlab.com/group/scm/app", "group", "scm/app", models.ProviderGitLab},
+		{"https://github.com/scm/app", "scm", "app", models.ProviderGitHub},
+		{"https://git.example.com/group/scm/app", "group", "scm/app", models.ProviderGitLab},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			owner, name, provider, err := parseRepoURL(tt.url, "https://git.example.com")
+			if err != nil {
+				t.Fatalf("parseRepoURL: %v", err)
+			}
+			if owner != tt.owner || name != tt.name || provider != tt.provider {
+				t.Errorf("got (%q, %q, %q), want (%q, %q, %q)", owner, name, provider, tt.owner, tt.name, tt.provider)
+			}
+		})
+	}
+
+	if _, _, _, err := parseRepoURL("https://github.com/only-owner", ""); err == nil {
+		t.Error("expected error for URL without repo name")
+	}
+}
+
+func TestParseRepoURLForConnection(t *testing.T) {
+	tests := []struct {
+		name  string
+		url   string
+		conn  models.GitConnection
+		owner string
+		repo  string
+	}{
+		{"gitea web url", "https://git.corp.local/tools/bot/src/branch/main",
+			models.GitConnection{Provider: models.ProviderGitea, BaseURL: "https://git.corp.local"}, "tools", "bot"},
+		{"gitea under context path", "https://corp.local/gitea/tools/bot.git",
+			models.GitConnection{Provider: models.ProviderGitea, BaseURL: "https://corp.local/gitea"}, "tools", "bot"},
+		{"gitlab nested group", "https://gl.corp.local/group/sub/app",
+			models.GitConnection{Provider: models.ProviderGitLab, BaseURL: "https://gl.corp.local"}, "group", "sub/app"},
+		{"bitbucket server", "https://bb.corp.local/projects/OPS/repos/infra/browse",
+			models.GitConnection{Provider: models.ProviderBitbucket, BaseURL: "https://bb.corp.local"}, "OPS", "infra"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner, repo, err := parseRepoURLForConnection(tt.url, tt.conn)
+			if err != nil {
+				t.Fatalf("parseRepoURLForConnection: %v", err)
+			}
+			if owner != tt.owner || repo != tt.repo {
+				t.Errorf("got (%q, %q), want (%q, %q)", owner, repo, tt.owner, tt.repo)
+			}
+		})
+	}
+
+	_, _, err := parseRepoURLForConnection("https://other.host/a/b",
+		models.GitConnection{Provider: models.ProviderGitea, BaseURL: "https://git.corp.local"})
+	if err == nil {
+		t.Error("expected host mismatch error")
+	}
+}
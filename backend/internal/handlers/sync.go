@@ -5,6 +5,8 @@ import (
 	"strconv"
 
 	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
 	"github.com/cds-id/pdt/backend/internal/worker"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -24,6 +26,7 @@ func (h *SyncHandler) SyncCommits(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	worker.DiscoverPeople(h.DB, userID)
 
 	if results == nil {
 		c.JSON(http.StatusOK, gin.H{"message": "no repositories to sync", "results": []worker.CommitSyncResult{}})
@@ -34,8 +37,10 @@ func (h *SyncHandler) SyncCommits(c *gin.Context) {
 }
 
 // SyncJira syncs Jira data. If workspace_id query param is provided, syncs only that workspace.
+// full=true drops the incremental high-water mark and refetches every card.
 func (h *SyncHandler) SyncJira(c *gin.Context) {
 	userID := c.GetUint("user_id")
+	full := c.Query("full") == "true"
 
 	wsParam := c.Query("workspace_id")
 	if wsParam != "" {
@@ -45,19 +50,28 @@ func (h *SyncHandler) SyncJira(c *gin.Context) {
 			return
 		}
 
+		if full {
+			worker.ResetJiraSync(h.DB, userID, uint(wsID))
+		}
+
 		if err := worker.SyncUserJiraWorkspace(h.DB, h.Encryptor, userID, uint(wsID)); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		worker.DiscoverPeople(h.DB, userID)
 
 		c.JSON(http.StatusOK, gin.H{"status": "synced", "workspace_id": wsID})
 		return
 	}
 
+	if full {
+		worker.ResetJiraSync(h.DB, userID, 0)
+	}
 	if err := worker.SyncUserJira(h.DB, h.Encryptor, userID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	worker.DiscoverPeople(h.DB, userID)
 
 	c.JSON(http.StatusOK, gin.H{"status": "synced"})
 }
@@ -68,7 +82,55 @@ func (h *SyncHandler) SyncStatus(c *gin.Context) {
 	response := gin.H{
 		"commits": h.Status.GetCommitStatus(userID),
 		"jira":    h.Status.GetJiraStatus(userID),
+		"quota":   httpclient.Default.QuotasFor(h.credentialKeys(userID)...),
+		"repos":   h.repoHealth(userID),
 	}
 
 	c.JSON(http.StatusOK, response)
 }
+
+// credentialKeys returns the quota keys of every API token the user has
+// configured, so the status only exposes the user's own quotas.
+func (h *SyncHandler) credentialKeys(userID uint) []string {
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return nil
+	}
+
+	encrypted := []string{user.GithubToken, user.GitlabToken, user.BitbucketToken, user.JiraToken}
+	var conns []models.GitConnection
+	h.DB.Where("user_id = ?", userID).Find(&conns)
+	for _, conn := range conns {
+		encrypted = append(encrypted, conn.Token)
+	}
+	var workspaces []models.JiraWorkspaceConfig
+	h.DB.Select("token").Where("user_id = ? AND token <> ''", userID).Find(&workspaces)
+	for _, ws := range workspaces {
+		encrypted = append(encrypted, ws.Token)
+	}
+
+	var keys []string
+	for _, enc := range encrypted {
+		if token, err := h.Encryptor.Decrypt(enc); err == nil && token != "" {
+			keys = append(keys, httpclient.CredentialKey(token))
+		}
+	}
+	return keys
+}
+
+// repoHealth returns the commit sync health of each of the user's repositories.
+func (h *SyncHandler) repoHealth(userID uint) []gin.H {
+	var repos []models.Repository
+	h.DB.Where("user_id = ?", userID).Preload("Health").Find(&repos)
+
+	out := make([]gin.H, 0, len(repos))
+	for _, repo := range repos {
+		out = append(out, gin.H{
+			"repo_id":   repo.ID,
+			"repo_name": repo.Owner + "/" + repo.Name,
+			"is_valid":  repo.IsValid,
+			"health":    repo.Health,
+		})
+	}
+	return out
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/timesheet"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TimesheetHandler struct {
+	DB        *gorm.DB
+	Encryptor *crypto.Encryptor
+}
+
+type updateTimesheetEntryRequest struct {
+	Date      string     `json:"date" binding:"required"`
+	CardKey   string     `json:"card_key" binding:"required"`
+	Minutes   *int       `json:"minutes"` // 0 skips the card for that day
+	StartedAt *time.Time `json:"started_at"`
+	Comment   *string    `json:"comment"`
+}
+
+type approveTimesheetRequest struct {
+	Week    string `json:"week"`
+	Entries []struct {
+		Date    string `json:"date"`
+		CardKey string `json:"card_key"`
+	} `json:"entries"` // empty approves every pending entry of the week
+}
+
+// Get GET /timesheet?week=2026-W42
+func (h *TimesheetHandler) Get(c *gin.Context) {
+	week, ok := h.loadWeek(c, c.Query("week"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, week)
+}
+
+// UpdateEntry PATCH /timesheet/entries
+func (h *TimesheetHandler) UpdateEntry(c *gin.Context) {
+	var req updateTimesheetEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.CardKey = strings.ToUpper(strings.TrimSpace(req.CardKey))
+
+	week, ok := h.loadWeek(c, req.Date)
+	if !ok {
+		return
+	}
+
+	entry := timesheet.Entry{Date: req.Date, CardKey: req.CardKey}
+	for _, e := range week.Entries {
+		if e.Date == req.Date && e.CardKey == req.CardKey {
+			entry = e
+		}
+	}
+	if entry.StartedAt.IsZero() {
+		day, _ := time.ParseInLocation(timesheet.DateLayout, req.Date, time.Local)
+		entry.StartedAt = day.Add(9 * time.Hour)
+	}
+	if req.Minutes != nil {
+		entry.Seconds = *req.Minutes * 60
+	}
+	if req.StartedAt != nil {
+		entry.StartedAt = *req.StartedAt
+	}
+	if req.Comment != nil {
+		entry.Comment = *req.Comment
+	}
+
+	saved, err := timesheet.Save(h.DB, c.GetUint("user_id"), entry)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// Approve POST /timesheet/approve
+// Submits the chosen entries, or every pending entry of the week, as Jira
+// worklogs.
+func (h *TimesheetHandler) Approve(c *gin.Context) {
+	var req approveTimesheetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	weekRef := req.Week
+	if weekRef == "" && len(req.Entries) > 0 {
+		weekRef = req.Entries[0].Date
+	}
+	week, ok := h.loadWeek(c, weekRef)
+	if !ok {
+		return
+	}
+
+	var selected []timesheet.Entry
+	for _, e := range week.Entries {
+		if !e.Submittable() {
+			continue
+		}
+		if len(req.Entries) == 0 {
+			selected = append(selected, e)
+			continue
+		}
+		for _, r := range req.Entries {
+			if r.Date == e.Date && strings.EqualFold(r.CardKey, e.CardKey) {
+				selected = append(selected, e)
+			}
+		}
+	}
+	if len(selected) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no pending entries to submit"})
+		return
+	}
+
+	results := timesheet.Submit(h.DB, h.Encryptor, c.GetUint("user_id"), selected)
+	submitted, failed := 0, 0
+	for _, r := range results {
+		switch r.Status {
+		case models.TimesheetSubmitted:
+			submitted++
+		case models.TimesheetFailed:
+			failed++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"submitted": submitted,
+		"failed":    failed,
+		"entries":   results,
+	})
+}
+
+// loadWeek builds the week containing ref for the current user, writing the
+// error response itself when it fails.
+func (h *TimesheetHandler) loadWeek(c *gin.Context, ref string) (*timesheet.Week, bool) {
+	var user models.User
+	if err := h.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return nil, false
+	}
+
+	start, err := timesheet.ParseWeek(ref)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	week, err := timesheet.BuildWeek(h.DB, user, start)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build timesheet"})
+		return nil, false
+	}
+	return week, true
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/cds-id/pdt/backend/internal/crypto"
 	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services"
+	"github.com/cds-id/pdt/backend/internal/services/gitprovider"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -15,27 +17,46 @@ type UserHandler struct {
 }
 
 type profileResponse struct {
-	ID            uint   `json:"id"`
-	Email         string `json:"email"`
-	HasGithub     bool   `json:"has_github_token"`
-	HasGitlab     bool   `json:"has_gitlab_token"`
-	GitlabURL     string `json:"gitlab_url"`
-	JiraEmail     string `json:"jira_email"`
-	HasJiraToken  bool   `json:"has_jira_token"`
-	JiraWorkspace string `json:"jira_workspace"`
-	JiraUsername     string `json:"jira_username"`
-	JiraProjectKeys string `json:"jira_project_keys"`
+	ID                uint                     `json:"id"`
+	Email             string                   `json:"email"`
+	HasGithub         bool                     `json:"has_github_token"`
+	HasGitlab         bool                     `json:"has_gitlab_token"`
+	GitlabURL         string                   `json:"gitlab_url"`
+	HasBitbucket      bool                     `json:"has_bitbucket_token"`
+	BitbucketUsername string                   `json:"bitbucket_username"`
+	BitbucketURL      string                   `json:"bitbucket_url"`
+	JiraEmail         string                   `json:"jira_email"`
+	HasJiraToken      bool                     `json:"has_jira_token"`
+	JiraWorkspace     string                   `json:"jira_workspace"`
+	JiraUsername      string                   `json:"jira_username"`
+	JiraProjectKeys   string                   `json:"jira_project_keys"`
+	AuthorEmails      string                   `json:"author_emails"`
+	AuthorUsernames   string                   `json:"author_usernames"`
+	IncludeAllAuthors bool                     `json:"include_all_authors"`
+	WorklogSessionGap int                      `json:"worklog_session_gap"`
+	WorklogMinBlock   int                      `json:"worklog_min_block"`
+	WorklogLeadIn     int                      `json:"worklog_lead_in"`
+	ProviderAccounts  []models.ProviderAccount `json:"provider_accounts"`
 }
 
 type updateProfileRequest struct {
-	GithubToken   *string `json:"github_token"`
-	GitlabToken   *string `json:"gitlab_token"`
-	GitlabURL     *string `json:"gitlab_url"`
-	JiraEmail     *string `json:"jira_email"`
-	JiraToken     *string `json:"jira_token"`
-	JiraWorkspace *string `json:"jira_workspace"`
-	JiraUsername     *string `json:"jira_username"`
-	JiraProjectKeys *string `json:"jira_project_keys"`
+	GithubToken       *string `json:"github_token"`
+	GitlabToken       *string `json:"gitlab_token"`
+	GitlabURL         *string `json:"gitlab_url"`
+	BitbucketUsername *string `json:"bitbucket_username"`
+	BitbucketToken    *string `json:"bitbucket_token"`
+	BitbucketURL      *string `json:"bitbucket_url"`
+	JiraEmail         *string `json:"jira_email"`
+	JiraToken         *string `json:"jira_token"`
+	JiraWorkspace     *string `json:"jira_workspace"`
+	JiraUsername      *string `json:"jira_username"`
+	JiraProjectKeys   *string `json:"jira_project_keys"`
+	AuthorEmails      *string `json:"author_emails"`
+	AuthorUsernames   *string `json:"author_usernames"`
+	IncludeAllAuthors *bool   `json:"include_all_authors"`
+	WorklogSessionGap *int    `json:"worklog_session_gap"` // minutes
+	WorklogMinBlock   *int    `json:"worklog_min_block"`
+	WorklogLeadIn     *int    `json:"worklog_lead_in"`
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
@@ -47,17 +68,30 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
+	accounts := []models.ProviderAccount{}
+	h.DB.Where("user_id = ?", userID).Order("provider, connection_id").Find(&accounts)
+
 	c.JSON(http.StatusOK, profileResponse{
-		ID:            user.ID,
-		Email:         user.Email,
-		HasGithub:     user.GithubToken != "",
-		HasGitlab:     user.GitlabToken != "",
-		GitlabURL:     user.GitlabURL,
-		JiraEmail:     user.JiraEmail,
-		HasJiraToken:  user.JiraToken != "",
-		JiraWorkspace: user.JiraWorkspace,
-		JiraUsername:     user.JiraUsername,
-		JiraProjectKeys: user.JiraProjectKeys,
+		ID:                user.ID,
+		Email:             user.Email,
+		HasGithub:         user.GithubToken != "",
+		HasGitlab:         user.GitlabToken != "",
+		GitlabURL:         user.GitlabURL,
+		HasBitbucket:      user.BitbucketToken != "",
+		BitbucketUsername: user.BitbucketUsername,
+		BitbucketURL:      user.BitbucketURL,
+		JiraEmail:         user.JiraEmail,
+		HasJiraToken:      user.JiraToken != "",
+		JiraWorkspace:     user.JiraWorkspace,
+		JiraUsername:      user.JiraUsername,
+		JiraProjectKeys:   user.JiraProjectKeys,
+		AuthorEmails:      user.AuthorEmails,
+		AuthorUsernames:   user.AuthorUsernames,
+		IncludeAllAuthors: user.IncludeAllAuthors,
+		WorklogSessionGap: user.WorklogSessionGap,
+		WorklogMinBlock:   user.WorklogMinBlock,
+		WorklogLeadIn:     user.WorklogLeadIn,
+		ProviderAccounts:  accounts,
 	})
 }
 
@@ -97,6 +131,20 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	if req.GitlabURL != nil {
 		updates["gitlab_url"] = *req.GitlabURL
 	}
+	if req.BitbucketToken != nil {
+		encrypted, err := h.Encryptor.Encrypt(*req.BitbucketToken)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encrypt token"})
+			return
+		}
+		updates["bitbucket_token"] = encrypted
+	}
+	if req.BitbucketUsername != nil {
+		updates["bitbucket_username"] = *req.BitbucketUsername
+	}
+	if req.BitbucketURL != nil {
+		updates["bitbucket_url"] = *req.BitbucketURL
+	}
 	if req.JiraEmail != nil {
 		updates["jira_email"] = *req.JiraEmail
 	}
@@ -117,6 +165,36 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	if req.JiraProjectKeys != nil {
 		updates["jira_project_keys"] = *req.JiraProjectKeys
 	}
+	if req.AuthorEmails != nil {
+		updates["author_emails"] = *req.AuthorEmails
+	}
+	if req.AuthorUsernames != nil {
+		updates["author_usernames"] = *req.AuthorUsernames
+	}
+	if req.IncludeAllAuthors != nil {
+		updates["include_all_authors"] = *req.IncludeAllAuthors
+	}
+	if req.WorklogSessionGap != nil {
+		if *req.WorklogSessionGap < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "worklog_session_gap must be at least 1 minute"})
+			return
+		}
+		updates["worklog_session_gap"] = *req.WorklogSessionGap
+	}
+	if req.WorklogMinBlock != nil {
+		if *req.WorklogMinBlock < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "worklog_min_block cannot be negative"})
+			return
+		}
+		updates["worklog_min_block"] = *req.WorklogMinBlock
+	}
+	if req.WorklogLeadIn != nil {
+		if *req.WorklogLeadIn < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "worklog_lead_in cannot be negative"})
+			return
+		}
+		updates["worklog_lead_in"] = *req.WorklogLeadIn
+	}
 
 	if len(updates) > 0 {
 		if err := h.DB.Model(&user).Updates(updates).Error; err != nil {
@@ -138,10 +216,35 @@ func (h *UserHandler) ValidateConnections(c *gin.Context) {
 	}
 
 	results := map[string]interface{}{
-		"github": map[string]interface{}{"configured": user.GithubToken != ""},
-		"gitlab": map[string]interface{}{"configured": user.GitlabToken != ""},
-		"jira":   map[string]interface{}{"configured": user.JiraToken != "" && user.JiraWorkspace != ""},
+		"github":    h.validateGitToken(user, models.ProviderGitHub, user.GithubToken != ""),
+		"gitlab":    h.validateGitToken(user, models.ProviderGitLab, user.GitlabToken != ""),
+		"bitbucket": h.validateGitToken(user, models.ProviderBitbucket, user.BitbucketToken != ""),
+		"jira":      map[string]interface{}{"configured": user.JiraToken != "" && user.JiraWorkspace != ""},
 	}
 
 	c.JSON(http.StatusOK, results)
 }
+
+// validateGitToken checks a git token stored on the user by fetching the
+// account it belongs to, which is recorded as the default author filter.
+func (h *UserHandler) validateGitToken(user models.User, provider models.Provider, configured bool) map[string]interface{} {
+	result := map[string]interface{}{"configured": configured}
+	if !configured {
+		return result
+	}
+
+	client, token, err := gitprovider.ForUser(h.Encryptor, user, provider)
+	if err == nil {
+		var account *models.ProviderAccount
+		if account, err = gitprovider.RecordAccount(h.DB, user.ID, provider, 0, client, token); err == nil {
+			result["valid"] = true
+			result["account"] = account
+			return result
+		}
+	}
+
+	result["valid"] = false
+	result["error"] = err.Error()
+	result["error_class"] = services.ClassifyError(err)
+	return result
+}
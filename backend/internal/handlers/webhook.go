@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/scheduler/eventbus"
+	"github.com/cds-id/pdt/backend/internal/services"
+	"github.com/cds-id/pdt/backend/internal/services/cardlink"
+	wvClient "github.com/cds-id/pdt/backend/internal/services/weaviate"
+	"github.com/cds-id/pdt/backend/internal/worker"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxWebhookBody caps push payloads; GitHub itself truncates at 25 MB.
+const maxWebhookBody = 25 << 20
+
+// WebhookHandler receives push events from git hosts. The endpoints are not
+// JWT-protected; each request is authenticated with the repository's
+// webhook secret instead.
+type WebhookHandler struct {
+	DB        *gorm.DB
+	Encryptor *crypto.Encryptor
+	Weaviate  *wvClient.Client
+	EventBus  *eventbus.Bus
+}
+
+type pushCommit struct {
+	ID        string    `json:"id"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+	Author    struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	} `json:"author"`
+}
+
+// pushPayload holds the fields shared by GitHub push events and GitLab push hooks.
+type pushPayload struct {
+	Ref     string       `json:"ref"`
+	Commits []pushCommit `json:"commits"`
+}
+
+func (h *WebhookHandler) GitHub(c *gin.Context) {
+	repo, secret, body, ok := h.loadRequest(c, models.ProviderGitHub)
+	if !ok {
+		return
+	}
+
+	if !validGitHubSignature(secret, body, c.GetHeader("X-Hub-Signature-256")) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+		return
+	}
+
+	switch c.GetHeader("X-GitHub-Event") {
+	case "ping":
+		c.JSON(http.StatusOK, gin.H{"message": "pong"})
+	case "push":
+		h.handlePush(c, repo, body)
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"message": "event ignored"})
+	}
+}
+
+func (h *WebhookHandler) GitLab(c *gin.Context) {
+	repo, secret, body, ok := h.loadRequest(c, models.ProviderGitLab)
+	if !ok {
+		return
+	}
+
+	token := c.GetHeader("X-Gitlab-Token")
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	if c.GetHeader("X-Gitlab-Event") != "Push Hook" {
+		c.JSON(http.StatusAccepted, gin.H{"message": "event ignored"})
+		return
+	}
+	h.handlePush(c, repo, body)
+}
+
+// loadRequest resolves the repository from the URL, decrypts its webhook
+// secret and reads the raw body needed for signature checks.
+func (h *WebhookHandler) loadRequest(c *gin.Context, provider models.Provider) (models.Repository, string, []byte, bool) {
+	var repo models.Repository
+	if err := h.DB.Where("id = ? AND provider = ?", c.Param("repoId"), provider).First(&repo).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "repository not found"})
+		return repo, "", nil, false
+	}
+	if !repo.WebhookEnabled || repo.WebhookSecret == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not enabled"})
+		return repo, "", nil, false
+	}
+
+	secret, err := h.Encryptor.Decrypt(repo.WebhookSecret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decrypt webhook secret"})
+		return repo, "", nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return repo, "", nil, false
+	}
+
+	return repo, secret, body, true
+}
+
+func (h *WebhookHandler) handlePush(c *gin.Context, repo models.Repository, body []byte) {
+	var payload pushPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	branch, isBranch := strings.CutPrefix(payload.Ref, "refs/heads/")
+	if !isBranch {
+		c.JSON(http.StatusAccepted, gin.H{"message": "non-branch ref ignored"})
+		return
+	}
+
+	projects := cardlink.ProjectKeys(h.DB, repo.UserID)
+	var created []models.Commit
+	pushed := make([]string, 0, len(payload.Commits))
+	for _, pc := range payload.Commits {
+		pushed = append(pushed, pc.ID)
+		commit, isNew := worker.StoreCommit(h.DB, repo, services.CommitInfo{
+			SHA:         pc.ID,
+			Message:     pc.Message,
+			Author:      pc.Author.Name,
+			AuthorEmail: pc.Author.Email,
+			Branch:      branch,
+			Date:        pc.Timestamp,
+		}, projects)
+		if isNew {
+			created = append(created, commit)
+		}
+	}
+	worker.LinkBranchCommits(h.DB, repo, pushed, projects)
+
+	if h.Weaviate != nil && len(created) > 0 {
+		go func(commits []models.Commit) {
+			for _, commit := range commits {
+				worker.EmbedCommit(h.Weaviate, repo, commit)
+			}
+		}(created)
+	}
+
+	shas := make([]string, len(created))
+	for i, commit := range created {
+		shas[i] = commit.SHA
+	}
+
+	if h.EventBus != nil {
+		h.EventBus.Publish("commit_pushed", map[string]any{
+			"user_id":     repo.UserID,
+			"repo_id":     repo.ID,
+			"repo_name":   repo.Owner + "/" + repo.Name,
+			"branch":      branch,
+			"new_commits": len(created),
+			"shas":        shas,
+		})
+	}
+
+	log.Printf("[webhook] repo=%s/%s branch=%s received=%d new=%d", repo.Owner, repo.Name, branch, len(payload.Commits), len(created))
+
+	c.JSON(http.StatusOK, gin.H{
+		"received":    len(payload.Commits),
+		"new_commits": len(created),
+	})
+}
+
+// validGitHubSignature checks an X-Hub-Signature-256 header ("sha256=<hex>")
+// against the HMAC of body.
+func validGitHubSignature(secret string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
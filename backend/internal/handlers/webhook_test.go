@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/scheduler/eventbus"
+)
+
+const pushBody = `{"ref":"refs/heads/main","commits":[
+	{"id":"abc1234def","message":"PDT-12 add webhook","timestamp":"2026-10-01T10:00:00Z","author":{"name":"Ana","email":"ana@corp"}},
+	{"id":"bcd2345efa","message":"cleanup","timestamp":"2026-10-01T11:00:00Z","author":{"name":"Ana","email":"ana@corp"}}
+]}`
+
+func setupWebhookTest(t *testing.T, provider models.Provider) (*WebhookHandler, *gin.Engine, models.Repository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Repository{}, &models.Commit{}, &models.CommitCardLink{}, &models.User{}, &models.JiraWorkspaceConfig{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	enc, err := crypto.NewEncryptor(strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatalf("encryptor: %v", err)
+	}
+	secret, _ := enc.Encrypt("s3cret")
+
+	repo := models.Repository{UserID: 7, Owner: "org", Name: "app", Provider: provider, URL: "https://example.com/org/app",
+		WebhookSecret: secret, WebhookEnabled: true}
+	db.Create(&repo)
+
+	h := &WebhookHandler{DB: db, Encryptor: enc, EventBus: eventbus.New()}
+	r := gin.New()
+	r.POST("/webhooks/github/:repoId", h.GitHub)
+	r.POST("/webhooks/gitlab/:repoId", h.GitLab)
+	return h, r, repo
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhook_GitHubPush(t *testing.T) {
+	h, r, repo := setupWebhookTest(t, models.ProviderGitHub)
+
+	events := make(chan map[string]any, 1)
+	h.EventBus.Subscribe("commit_pushed", func(p map[string]any) { events <- p })
+
+	send := func(sig string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/webhooks/github/%d", repo.ID), strings.NewReader(pushBody))
+		req.Header.Set("X-GitHub-Event", "push")
+		req.Header.Set("X-Hub-Signature-256", sig)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send(sign("wrong", pushBody)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("bad signature: status %d", w.Code)
+	}
+
+	if w := send(sign("s3cret", pushBody)); w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	var commits []models.Commit
+	h.DB.Order("date").Find(&commits)
+	if len(commits) != 2 {
+		t.Fatalf("stored %d commits, want 2", len(commits))
+	}
+	if commits[0].JiraCardKey != "PDT-12" || commits[0].Branch != "main" {
+		t.Errorf("commit = %+v", commits[0])
+	}
+
+	p := <-events
+	if p["user_id"] != uint(7) || p["new_commits"] != 2 {
+		t.Errorf("event payload = %v", p)
+	}
+
+	// A redelivery stores nothing new.
+	if w := send(sign("s3cret", pushBody)); !strings.Contains(w.Body.String(), `"new_commits":0`) {
+		t.Errorf("redelivery body = %s", w.Body.String())
+	}
+}
+
+func TestWebhook_GitLabToken(t *testing.T) {
+	h, r, repo := setupWebhookTest(t, models.ProviderGitLab)
+
+	send := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/webhooks/gitlab/%d", repo.ID), strings.NewReader(pushBody))
+		req.Header.Set("X-Gitlab-Event", "Push Hook")
+		req.Header.Set("X-Gitlab-Token", token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send("nope"); code != http.StatusUnauthorized {
+		t.Fatalf("bad token: status %d", code)
+	}
+	if code := send("s3cret"); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+
+	var count int64
+	h.DB.Model(&models.Commit{}).Count(&count)
+	if count != 2 {
+		t.Errorf("stored %d commits, want 2", count)
+	}
+
+	// The GitHub endpoint must not accept a GitLab repository.
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/webhooks/github/%d", repo.ID), strings.NewReader(pushBody))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("cross-provider status %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// secretPaths are path prefixes whose next segment is a secret: the Jira
+// webhook URL carries the workspace's webhook secret.
+var secretPaths = []string{"/api/webhooks/jira/"}
+
+// Logger is gin's request logger with secrets in the path redacted.
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		if p.Latency > time.Minute {
+			p.Latency = p.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			p.StatusCode,
+			p.Latency,
+			p.ClientIP,
+			p.Method,
+			RedactPath(p.Path),
+			p.ErrorMessage,
+		)
+	})
+}
+
+// RedactPath replaces the secret segment of a path under secretPaths, along
+// with any query string, with "REDACTED".
+func RedactPath(path string) string {
+	for _, prefix := range secretPaths {
+		if strings.HasPrefix(path, prefix) {
+			return prefix + "REDACTED"
+		}
+	}
+	return path
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLogger_RedactsWebhookSecret(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var out bytes.Buffer
+	orig := gin.DefaultWriter
+	gin.DefaultWriter = &out
+	defer func() { gin.DefaultWriter = orig }()
+
+	r := gin.New()
+	r.Use(Logger())
+	r.POST("/api/webhooks/jira/:secret", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/api/reports", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/webhooks/jira/s3cr3t?x=1", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/reports?from=2026-10-01", nil))
+
+	logged := out.String()
+	if strings.Contains(logged, "s3cr3t") || !strings.Contains(logged, `"/api/webhooks/jira/REDACTED"`) {
+		t.Errorf("webhook secret not redacted:\n%s", logged)
+	}
+	if !strings.Contains(logged, `"/api/reports?from=2026-10-01"`) {
+		t.Errorf("other paths must be logged as is:\n%s", logged)
+	}
+}
@@ -9,7 +9,7 @@ type Commit struct {
 	Message     string     `gorm:"type:text" json:"message"`
 	Author      string     `gorm:"type:varchar(255)" json:"author"`
 	AuthorEmail string     `gorm:"type:varchar(255)" json:"author_email"`
-	Branch      string     `gorm:"type:varchar(255);index" json:"branch"`
+	Branch      string     `gorm:"type:varchar(255);index" json:"branch"` // first branch the commit was seen on; see CommitBranch
 	Date        time.Time  `json:"date"`
 	JiraCardKey string     `gorm:"type:varchar(50);index" json:"jira_card_key"`
 	HasLink     bool       `gorm:"default:false" json:"has_link"`
@@ -17,10 +17,33 @@ type Commit struct {
 	Repository  Repository `gorm:"foreignKey:RepoID" json:"-"`
 }
 
+// CommitBranch records one branch a commit was seen on.
+type CommitBranch struct {
+	ID       uint      `gorm:"primarykey" json:"id"`
+	CommitID uint      `gorm:"uniqueIndex:idx_commit_branch;not null" json:"commit_id"`
+	Branch   string    `gorm:"type:varchar(255);uniqueIndex:idx_commit_branch;index;not null" json:"branch"`
+	SeenAt   time.Time `json:"seen_at"`
+	Commit   Commit    `gorm:"foreignKey:CommitID" json:"-"`
+}
+
+// CardLinkSource records where a commit-to-card link came from.
+type CardLinkSource string
+
+const (
+	LinkSourceMessage     CardLinkSource = "message"      // key in the commit subject or body
+	LinkSourceBranch      CardLinkSource = "branch"       // key in the name of the branch the commit was made on
+	LinkSourcePullRequest CardLinkSource = "pull_request" // key in the title of a PR containing the commit
+	LinkSourceManual      CardLinkSource = "manual"
+	LinkSourceAgent       CardLinkSource = "agent"
+)
+
+// CommitCardLink ties a commit to one Jira card. A commit can reference
+// several cards; Commit.JiraCardKey keeps the first one for display.
 type CommitCardLink struct {
-	ID          uint      `gorm:"primarykey" json:"id"`
-	CommitID    uint      `gorm:"index;not null" json:"commit_id"`
-	JiraCardKey string    `gorm:"type:varchar(50);not null" json:"jira_card_key"`
-	LinkedAt    time.Time `json:"linked_at"`
-	Commit      Commit    `gorm:"foreignKey:CommitID" json:"-"`
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CommitID    uint           `gorm:"uniqueIndex:idx_commit_card;not null" json:"commit_id"`
+	JiraCardKey string         `gorm:"type:varchar(50);uniqueIndex:idx_commit_card;index;not null" json:"jira_card_key"`
+	Source      CardLinkSource `gorm:"type:varchar(20);default:manual" json:"source"`
+	LinkedAt    time.Time      `json:"linked_at"`
+	Commit      Commit         `gorm:"foreignKey:CommitID" json:"-"`
 }
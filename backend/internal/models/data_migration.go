@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// DataMigration records a one-off data job that has run, so it is not
+// repeated on every start.
+type DataMigration struct {
+	Name  string    `gorm:"type:varchar(100);primaryKey" json:"name"`
+	RanAt time.Time `json:"ran_at"`
+}
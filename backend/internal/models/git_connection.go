@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// GitConnection is a user's credentials for one git hosting instance. It lets
+// a user track repositories on several self-hosted servers of any provider at
+// once; repositories without a connection fall back to the tokens on User.
+type GitConnection struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	Name      string    `gorm:"type:varchar(100)" json:"name"`
+	Provider  Provider  `gorm:"type:varchar(10);not null" json:"provider"`
+	BaseURL   string    `gorm:"type:varchar(500);not null" json:"base_url"`
+	Username  string    `gorm:"type:varchar(255)" json:"username"`
+	Token     string    `gorm:"type:text" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// ProviderAccount is the account behind a git token, fetched when the token
+// is validated. Its login and emails are the default author filter for the
+// user's own commits. ConnectionID is 0 for the tokens stored on User.
+type ProviderAccount struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	UserID       uint      `gorm:"uniqueIndex:idx_provider_account;not null" json:"user_id"`
+	Provider     Provider  `gorm:"type:varchar(10);uniqueIndex:idx_provider_account;not null" json:"provider"`
+	ConnectionID uint      `gorm:"uniqueIndex:idx_provider_account;not null;default:0" json:"connection_id"`
+	Username     string    `gorm:"type:varchar(255)" json:"username"`
+	Name         string    `gorm:"type:varchar(255)" json:"name"`
+	Emails       string    `gorm:"type:varchar(1000)" json:"emails"` // comma-separated
+	FetchedAt    time.Time `json:"fetched_at"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+type IdentityKind string
+
+const (
+	IdentityEmail       IdentityKind = "email"
+	IdentityGitUsername IdentityKind = "git_username"
+	IdentityName        IdentityKind = "name"
+	IdentityWAJID       IdentityKind = "wa_jid"
+)
+
+// Person is one human seen across git providers, Jira and WhatsApp. The
+// per-source spellings (emails, git logins, sender JIDs, free-form names)
+// hang off it as PersonIdentity rows.
+type Person struct {
+	ID              uint             `gorm:"primarykey" json:"id"`
+	UserID          uint             `gorm:"index;not null" json:"user_id"`
+	DisplayName     string           `gorm:"type:varchar(255);not null" json:"display_name"`
+	JiraAccountID   string           `gorm:"type:varchar(128);index" json:"jira_account_id"`
+	JiraDisplayName string           `gorm:"type:varchar(255);index" json:"jira_display_name"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+	User            User             `gorm:"foreignKey:UserID" json:"-"`
+	Identities      []PersonIdentity `gorm:"foreignKey:PersonID" json:"identities,omitempty"`
+}
+
+// PersonIdentity is a single alias of a Person. A value belongs to at most
+// one person per user; emails and logins are stored lower-cased.
+type PersonIdentity struct {
+	ID        uint         `gorm:"primarykey" json:"id"`
+	UserID    uint         `gorm:"uniqueIndex:idx_identity_value;not null" json:"user_id"`
+	PersonID  uint         `gorm:"index;not null" json:"person_id"`
+	Kind      IdentityKind `gorm:"type:varchar(20);uniqueIndex:idx_identity_value;not null" json:"kind"`
+	Value     string       `gorm:"type:varchar(255);uniqueIndex:idx_identity_value;not null" json:"value"`
+	Source    string       `gorm:"type:varchar(20)" json:"source"` // commit, pull_request, jira, whatsapp, manual
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type MergeSuggestionStatus string
+
+const (
+	MergePending  MergeSuggestionStatus = "pending"
+	MergeAccepted MergeSuggestionStatus = "accepted"
+	MergeRejected MergeSuggestionStatus = "rejected"
+)
+
+// PersonMergeSuggestion proposes that two people are the same human.
+// PersonID is always the lower of the pair so each pair is suggested once.
+type PersonMergeSuggestion struct {
+	ID            uint                  `gorm:"primarykey" json:"id"`
+	UserID        uint                  `gorm:"uniqueIndex:idx_merge_pair;not null" json:"user_id"`
+	PersonID      uint                  `gorm:"uniqueIndex:idx_merge_pair;not null" json:"person_id"`
+	OtherPersonID uint                  `gorm:"uniqueIndex:idx_merge_pair;not null" json:"other_person_id"`
+	Reason        string                `gorm:"type:varchar(20)" json:"reason"` // email, name, seen_together
+	Detail        string                `gorm:"type:varchar(255)" json:"detail"`
+	Status        MergeSuggestionStatus `gorm:"type:varchar(20);index;default:pending" json:"status"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+	Person        *Person               `gorm:"foreignKey:PersonID" json:"person,omitempty"`
+	OtherPerson   *Person               `gorm:"foreignKey:OtherPersonID" json:"other_person,omitempty"`
+}
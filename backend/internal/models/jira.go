@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type SprintState string
 
@@ -10,48 +14,107 @@ const (
 	SprintFuture SprintState = "future"
 )
 
+// JiraDeployment is where a workspace's Jira runs.
+type JiraDeployment string
+
+const (
+	// JiraCloud is an Atlassian-hosted site, reached through REST API v3.
+	JiraCloud JiraDeployment = "cloud"
+	// JiraDataCenter is Jira Server or Data Center, reached through REST
+	// API v2 with plain-text bodies.
+	JiraDataCenter JiraDeployment = "datacenter"
+)
+
+// JiraAuthMode is how requests to a workspace authenticate.
+type JiraAuthMode string
+
+const (
+	// JiraAuthBasic sends the email (or username) with an API token or
+	// password.
+	JiraAuthBasic JiraAuthMode = "basic"
+	// JiraAuthBearer sends a personal access token, as Data Center expects.
+	JiraAuthBearer JiraAuthMode = "bearer"
+)
+
 // JiraWorkspaceConfig represents a single Jira workspace/site for a user.
-// Multiple workspaces can share the same credentials (email + token from User).
+// Each workspace may sign in with its own Atlassian account; when Email or
+// Token is empty the one stored on User is used instead.
 type JiraWorkspaceConfig struct {
-	ID          uint      `gorm:"primarykey" json:"id"`
-	UserID      uint      `gorm:"index;not null" json:"user_id"`
-	Workspace   string    `gorm:"type:varchar(255);not null" json:"workspace"`
-	Name        string    `gorm:"type:varchar(100)" json:"name"`
-	ProjectKeys string    `gorm:"type:varchar(500)" json:"project_keys"`
-	IsActive    bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	User        User      `gorm:"foreignKey:UserID" json:"-"`
+	ID     uint `gorm:"primarykey" json:"id"`
+	UserID uint `gorm:"index;not null" json:"user_id"`
+	// Workspace is a Cloud site host ("acme.atlassian.net") or a full base
+	// URL with scheme, port and context path ("https://jira.acme.lan:8443/jira").
+	Workspace  string         `gorm:"type:varchar(255);not null" json:"workspace"`
+	Deployment JiraDeployment `gorm:"type:varchar(20);default:cloud" json:"deployment"`
+	AuthMode   JiraAuthMode   `gorm:"type:varchar(10);default:basic" json:"auth_mode"`
+	Email      string         `gorm:"type:varchar(255)" json:"email"`
+	// Token is the encrypted API token or personal access token.
+	Token       string `gorm:"type:text" json:"-"`
+	HasToken    bool   `gorm:"-" json:"has_token"`
+	Name        string `gorm:"type:varchar(100)" json:"name"`
+	ProjectKeys string `gorm:"type:varchar(500)" json:"project_keys"`
+	IsActive    bool   `gorm:"default:true" json:"is_active"`
+	// IssuesSyncedAt is the high-water mark of incremental sync: the
+	// updated time of the newest issue synced so far. Nil means the next
+	// sync fetches every card.
+	IssuesSyncedAt *time.Time `json:"issues_synced_at"`
+	// WebhookSecretHash is the SHA-256 of the secret in the workspace's
+	// webhook URL; the secret itself is only shown once.
+	WebhookSecretHash string `gorm:"type:varchar(64);index" json:"-"`
+	WebhookEnabled    bool   `gorm:"default:false" json:"webhook_enabled"`
+	// StoryPointsField is the id of the custom field holding story points,
+	// discovered on the first sync that finds one.
+	StoryPointsField string `gorm:"type:varchar(50)" json:"story_points_field"`
+	// SyncBacklog and SyncFutureSprints opt in to syncing cards that are
+	// not in an active or closed sprint yet. Kanban boards are always synced.
+	SyncBacklog       bool      `gorm:"default:false" json:"sync_backlog"`
+	SyncFutureSprints bool      `gorm:"default:false" json:"sync_future_sprints"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	User              User      `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// AfterFind reports whether the workspace has a token of its own, which is
+// never serialized.
+func (ws *JiraWorkspaceConfig) AfterFind(tx *gorm.DB) error {
+	ws.HasToken = ws.Token != ""
+	return nil
 }
 
 type Sprint struct {
-	ID          uint        `gorm:"primarykey" json:"id"`
-	UserID      uint        `gorm:"index;not null" json:"user_id"`
-	WorkspaceID *uint       `gorm:"index" json:"workspace_id"`
-	JiraSprintID string     `gorm:"type:varchar(50);uniqueIndex;not null" json:"jira_sprint_id"`
-	Name         string     `gorm:"type:varchar(255)" json:"name"`
-	State        SprintState `gorm:"type:varchar(10)" json:"state"`
-	StartDate    *time.Time `json:"start_date"`
-	EndDate      *time.Time `json:"end_date"`
-	CreatedAt    time.Time  `json:"created_at"`
-	User         User       `gorm:"foreignKey:UserID" json:"-"`
+	ID          uint  `gorm:"primarykey" json:"id"`
+	UserID      uint  `gorm:"index;uniqueIndex:idx_sprint;not null" json:"user_id"`
+	WorkspaceID *uint `gorm:"index;uniqueIndex:idx_sprint" json:"workspace_id"`
+	// Sprint IDs are only unique within one Jira site.
+	JiraSprintID string               `gorm:"type:varchar(50);uniqueIndex:idx_sprint;not null" json:"jira_sprint_id"`
+	Name         string               `gorm:"type:varchar(255)" json:"name"`
+	State        SprintState          `gorm:"type:varchar(10)" json:"state"`
+	StartDate    *time.Time           `json:"start_date"`
+	EndDate      *time.Time           `json:"end_date"`
+	CreatedAt    time.Time            `json:"created_at"`
+	User         User                 `gorm:"foreignKey:UserID" json:"-"`
 	Workspace    *JiraWorkspaceConfig `gorm:"foreignKey:WorkspaceID" json:"-"`
-	Cards        []JiraCard `gorm:"foreignKey:SprintID" json:"cards,omitempty"`
+	Cards        []JiraCard           `gorm:"foreignKey:SprintID" json:"cards,omitempty"`
 }
 
 type JiraCard struct {
-	ID          uint      `gorm:"primarykey" json:"id"`
-	UserID      uint      `gorm:"index;not null" json:"user_id"`
-	WorkspaceID *uint     `gorm:"index" json:"workspace_id"`
-	Key         string    `gorm:"column:card_key;type:varchar(50);index;not null" json:"key"`
-	Summary     string    `gorm:"type:text" json:"summary"`
-	Status      string    `gorm:"type:varchar(100)" json:"status"`
-	Assignee    string    `gorm:"type:varchar(255)" json:"assignee"`
-	SprintID    *uint     `gorm:"index" json:"sprint_id"`
-	DetailsJSON string    `gorm:"type:longtext" json:"details_json,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	User        User      `gorm:"foreignKey:UserID" json:"-"`
-	Workspace   *JiraWorkspaceConfig `gorm:"foreignKey:WorkspaceID" json:"-"`
-	Sprint      *Sprint   `gorm:"foreignKey:SprintID" json:"-"`
+	ID          uint   `gorm:"primarykey" json:"id"`
+	UserID      uint   `gorm:"index;not null" json:"user_id"`
+	WorkspaceID *uint  `gorm:"index" json:"workspace_id"`
+	Key         string `gorm:"column:card_key;type:varchar(50);index;not null" json:"key"`
+	Summary     string `gorm:"type:text" json:"summary"`
+	Status      string `gorm:"type:varchar(100)" json:"status"`
+	Assignee    string `gorm:"type:varchar(255)" json:"assignee"`
+	SprintID    *uint  `gorm:"index" json:"sprint_id"` // nil for Kanban and backlog cards
+	// InBacklog is set for cards found in a board's backlog.
+	InBacklog     bool                 `gorm:"default:false" json:"backlog"`
+	DetailsJSON   string               `gorm:"type:longtext" json:"details_json,omitempty"`
+	JiraUpdatedAt *time.Time           `json:"jira_updated_at"` // issue's updated time when details and comments were last fetched
+	JiraCreatedAt *time.Time           `json:"jira_created_at"`
+	StoryPoints   *float64             `json:"story_points"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+	User          User                 `gorm:"foreignKey:UserID" json:"-"`
+	Workspace     *JiraWorkspaceConfig `gorm:"foreignKey:WorkspaceID" json:"-"`
+	Sprint        *Sprint              `gorm:"foreignKey:SprintID" json:"-"`
 }
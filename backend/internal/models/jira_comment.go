@@ -3,16 +3,19 @@ package models
 import "time"
 
 type JiraComment struct {
-	ID          uint      `gorm:"primarykey" json:"id"`
-	UserID      uint      `gorm:"index;not null" json:"user_id"`
-	WorkspaceID *uint     `gorm:"index" json:"workspace_id"`
-	CardKey     string    `gorm:"type:varchar(50);index;not null" json:"card_key"`
-	CommentID   string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"comment_id"`
-	Author      string    `gorm:"type:varchar(255)" json:"author"`
-	AuthorEmail string    `gorm:"type:varchar(255)" json:"author_email"`
-	Body        string    `gorm:"type:text" json:"body"`
-	CommentedAt time.Time `gorm:"index" json:"commented_at"`
-	CreatedAt   time.Time `json:"created_at"`
-	User        User      `gorm:"foreignKey:UserID" json:"-"`
+	ID          uint   `gorm:"primarykey" json:"id"`
+	UserID      uint   `gorm:"index;uniqueIndex:idx_jira_comment;not null" json:"user_id"`
+	WorkspaceID *uint  `gorm:"index;uniqueIndex:idx_jira_comment" json:"workspace_id"`
+	CardKey     string `gorm:"type:varchar(50);index;not null" json:"card_key"`
+	// Comment IDs are only unique within one Jira site.
+	CommentID   string `gorm:"type:varchar(50);uniqueIndex:idx_jira_comment;not null" json:"comment_id"`
+	Author      string `gorm:"type:varchar(255)" json:"author"`
+	AuthorEmail string `gorm:"type:varchar(255)" json:"author_email"`
+	Body        string `gorm:"type:text" json:"body"` // Markdown
+	// BodyADF is the body as Jira Cloud sent it, kept for round-tripping.
+	BodyADF     string               `gorm:"type:longtext" json:"body_adf,omitempty"`
+	CommentedAt time.Time            `gorm:"index" json:"commented_at"`
+	CreatedAt   time.Time            `json:"created_at"`
+	User        User                 `gorm:"foreignKey:UserID" json:"-"`
 	Workspace   *JiraWorkspaceConfig `gorm:"foreignKey:WorkspaceID" json:"-"`
 }
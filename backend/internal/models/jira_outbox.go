@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type JiraOutboxAction string
+
+const (
+	JiraActionComment    JiraOutboxAction = "comment"
+	JiraActionTransition JiraOutboxAction = "transition"
+	JiraActionWorklog    JiraOutboxAction = "worklog"
+)
+
+// JiraOutbox is a change to a Jira card waiting for the user's approval. Like
+// WaOutbox it starts as pending; only approved entries are sent to Jira.
+type JiraOutbox struct {
+	ID               uint                 `gorm:"primarykey" json:"id"`
+	UserID           uint                 `gorm:"index;not null" json:"user_id"`
+	WorkspaceID      *uint                `gorm:"index" json:"workspace_id"`
+	CardKey          string               `gorm:"type:varchar(50);index;not null" json:"card_key"`
+	Action           JiraOutboxAction     `gorm:"type:varchar(20);not null" json:"action"`
+	Body             string               `gorm:"type:text" json:"body"`                         // comment text, or the worklog comment
+	Transition       string               `gorm:"type:varchar(100)" json:"transition,omitempty"` // transition id, transition name or target status
+	TimeSpentSeconds int                  `json:"time_spent_seconds,omitempty"`
+	StartedAt        *time.Time           `json:"started_at,omitempty"`
+	Status           string               `gorm:"type:varchar(20);default:pending;index" json:"status"`
+	RequestedBy      string               `gorm:"type:varchar(20);default:agent" json:"requested_by"`
+	Context          string               `gorm:"type:text" json:"context"`
+	JiraID           string               `gorm:"type:varchar(50)" json:"jira_id,omitempty"` // id of the created comment or worklog
+	Error            string               `gorm:"type:text" json:"error,omitempty"`
+	ApprovedAt       *time.Time           `json:"approved_at,omitempty"`
+	SentAt           *time.Time           `json:"sent_at,omitempty"`
+	CreatedAt        time.Time            `json:"created_at"`
+	User             User                 `gorm:"foreignKey:UserID" json:"-"`
+	Workspace        *JiraWorkspaceConfig `gorm:"foreignKey:WorkspaceID" json:"-"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// StatusCategory is the Jira category of a status. Workflows name their
+// statuses freely; the category says whether work has not started, is under
+// way or is finished.
+type StatusCategory string
+
+const (
+	StatusCategoryNew        StatusCategory = "new"
+	StatusCategoryInProgress StatusCategory = "indeterminate"
+	StatusCategoryDone       StatusCategory = "done"
+)
+
+// JiraCardTransition is one status change of a card, taken from its
+// changelog during sync or from an issue webhook.
+type JiraCardTransition struct {
+	ID             uint                 `gorm:"primarykey" json:"id"`
+	UserID         uint                 `gorm:"index:idx_card_transition;not null" json:"user_id"`
+	WorkspaceID    *uint                `gorm:"index" json:"workspace_id"`
+	CardKey        string               `gorm:"type:varchar(50);index:idx_card_transition;not null" json:"card_key"`
+	FromStatus     string               `gorm:"type:varchar(100)" json:"from_status"`
+	ToStatus       string               `gorm:"type:varchar(100);index" json:"to_status"`
+	FromCategory   StatusCategory       `gorm:"type:varchar(20)" json:"from_category"`
+	ToCategory     StatusCategory       `gorm:"type:varchar(20)" json:"to_category"`
+	Author         string               `gorm:"type:varchar(255)" json:"author"`
+	TransitionedAt time.Time            `gorm:"index:idx_card_transition" json:"transitioned_at"`
+	CreatedAt      time.Time            `json:"created_at"`
+	User           User                 `gorm:"foreignKey:UserID" json:"-"`
+	Workspace      *JiraWorkspaceConfig `gorm:"foreignKey:WorkspaceID" json:"-"`
+}
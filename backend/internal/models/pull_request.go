@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+type PullRequestState string
+
+const (
+	PullRequestOpen   PullRequestState = "open"
+	PullRequestClosed PullRequestState = "closed"
+	PullRequestMerged PullRequestState = "merged"
+)
+
+// PullRequest is a GitHub pull request or GitLab merge request on a tracked
+// repository. Number is the PR number / MR iid.
+type PullRequest struct {
+	ID           uint                `gorm:"primarykey" json:"id"`
+	RepoID       uint                `gorm:"uniqueIndex:idx_pr_repo_number;not null" json:"repo_id"`
+	Number       int                 `gorm:"uniqueIndex:idx_pr_repo_number;not null" json:"number"`
+	Title        string              `gorm:"type:varchar(500)" json:"title"`
+	State        PullRequestState    `gorm:"type:varchar(20);index" json:"state"`
+	Author       string              `gorm:"type:varchar(255);index" json:"author"`
+	SourceBranch string              `gorm:"type:varchar(255)" json:"source_branch"`
+	TargetBranch string              `gorm:"type:varchar(255)" json:"target_branch"`
+	URL          string              `gorm:"type:varchar(1000)" json:"url"`
+	Reviewers    string              `gorm:"type:varchar(1000)" json:"reviewers"` // comma-separated logins
+	JiraCardKey  string              `gorm:"type:varchar(50);index" json:"jira_card_key"`
+	OpenedAt     time.Time           `gorm:"index" json:"opened_at"`
+	MergedAt     *time.Time          `gorm:"index" json:"merged_at"`
+	ClosedAt     *time.Time          `json:"closed_at"`
+	ActivityAt   time.Time           `json:"activity_at"` // provider's last update time
+	Repository   Repository          `gorm:"foreignKey:RepoID" json:"-"`
+	Reviews      []PullRequestReview `gorm:"foreignKey:PullRequestID" json:"reviews,omitempty"`
+}
+
+type PullRequestReview struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	PullRequestID uint      `gorm:"uniqueIndex:idx_pr_review_ext;not null" json:"pull_request_id"`
+	ExternalID    string    `gorm:"type:varchar(100);uniqueIndex:idx_pr_review_ext;not null" json:"external_id"`
+	Author        string    `gorm:"type:varchar(255);index" json:"author"`
+	State         string    `gorm:"type:varchar(30)" json:"state"`
+	Body          string    `gorm:"type:text" json:"body"`
+	SubmittedAt   time.Time `gorm:"index" json:"submitted_at"`
+}
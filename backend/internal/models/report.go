@@ -2,28 +2,59 @@ package models
 
 import "time"
 
+// ReportTemplate is a user's template for one kind of report. ReportType is
+// daily, weekly or monthly; weekly templates also render range reports. Each
+// user has at most one default per ReportType.
 type ReportTemplate struct {
-	ID        uint      `gorm:"primarykey" json:"id"`
-	UserID    uint      `gorm:"index;not null" json:"user_id"`
-	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
-	Content   string    `gorm:"type:text;not null" json:"content"`
-	IsDefault bool      `gorm:"default:false" json:"is_default"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	User      User      `gorm:"foreignKey:UserID" json:"-"`
-}
-
-type Report struct {
 	ID         uint      `gorm:"primarykey" json:"id"`
 	UserID     uint      `gorm:"index;not null" json:"user_id"`
-	TemplateID *uint     `gorm:"index" json:"template_id"`
-	Date       string    `gorm:"type:varchar(10);index;not null" json:"date"`
-	Title      string    `gorm:"type:varchar(500)" json:"title"`
-	Content    string    `gorm:"type:text" json:"content"`
-	FileURL    string    `gorm:"type:varchar(500)" json:"file_url"`
-	ReportType string    `gorm:"type:varchar(10);default:daily" json:"report_type"`
-	Month      *int      `json:"month,omitempty"`
-	Year       *int      `json:"year,omitempty"`
+	Name       string    `gorm:"type:varchar(255);not null" json:"name"`
+	Content    string    `gorm:"type:text;not null" json:"content"`
+	ReportType string    `gorm:"type:varchar(10);not null;default:daily" json:"report_type"`
+	IsDefault  bool      `gorm:"default:false" json:"is_default"`
 	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 	User       User      `gorm:"foreignKey:UserID" json:"-"`
 }
+
+// Report is a rendered report. ReportType is daily, weekly, monthly or range;
+// weekly and range reports cover Date to EndDate.
+type Report struct {
+	ID         uint   `gorm:"primarykey" json:"id"`
+	UserID     uint   `gorm:"index;uniqueIndex:idx_report_key;not null" json:"user_id"`
+	TemplateID *uint  `gorm:"index" json:"template_id"`
+	Date       string `gorm:"type:varchar(10);index;uniqueIndex:idx_report_key;not null" json:"date"`
+	EndDate    string `gorm:"type:varchar(10);uniqueIndex:idx_report_key;not null;default:''" json:"end_date,omitempty"`
+	Title      string `gorm:"type:varchar(500)" json:"title"`
+	Content    string `gorm:"type:text" json:"content"`
+	FileURL    string `gorm:"type:varchar(500)" json:"file_url"`
+	ReportType string `gorm:"type:varchar(10);uniqueIndex:idx_report_key;default:daily" json:"report_type"`
+	Month      *int   `json:"month,omitempty"`
+	Year       *int   `json:"year,omitempty"`
+	// Narrative caches the AI-written narrative as JSON; NarrativeHash
+	// identifies the report data it was written from.
+	Narrative     string `gorm:"type:text" json:"-"`
+	NarrativeHash string `gorm:"type:varchar(64)" json:"-"`
+	// Version is the number of the latest ReportVersion.
+	Version   int       `gorm:"not null;default:0" json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// ReportVersion is one generation of a report. Regenerating a report adds a
+// version and the Report row is updated to match it.
+type ReportVersion struct {
+	ID         uint   `gorm:"primarykey" json:"id"`
+	ReportID   uint   `gorm:"uniqueIndex:idx_report_version;not null" json:"report_id"`
+	Version    int    `gorm:"uniqueIndex:idx_report_version;not null" json:"version"`
+	TemplateID *uint  `json:"template_id"`
+	Title      string `gorm:"type:varchar(500)" json:"title"`
+	Content    string `gorm:"type:text" json:"content,omitempty"`
+	FileURL    string `gorm:"type:varchar(500)" json:"file_url"`
+	// Data is the report data the version was rendered from, as JSON; empty
+	// for versions recorded from reports generated before versioning.
+	Data         string    `gorm:"type:longtext" json:"-"`
+	RestoredFrom *int      `json:"restored_from,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	Report       Report    `gorm:"foreignKey:ReportID" json:"-"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// SprintSnapshot is the state of one card in a sprint's scope on one day.
+// Each Jira sync rewrites the current day's rows of the workspace's active
+// sprints, so the last sync of a day wins, and a card missing from a later
+// day was taken out of the sprint.
+type SprintSnapshot struct {
+	ID             uint           `gorm:"primarykey" json:"id"`
+	UserID         uint           `gorm:"index;not null" json:"user_id"`
+	SprintID       uint           `gorm:"uniqueIndex:idx_sprint_snapshot;not null" json:"sprint_id"`
+	Date           string         `gorm:"type:varchar(10);uniqueIndex:idx_sprint_snapshot;not null" json:"date"` // YYYY-MM-DD, server local time
+	CardKey        string         `gorm:"type:varchar(50);uniqueIndex:idx_sprint_snapshot;not null" json:"card_key"`
+	Status         string         `gorm:"type:varchar(100)" json:"status"`
+	StatusCategory StatusCategory `gorm:"type:varchar(20)" json:"status_category"`
+	StoryPoints    *float64       `json:"story_points"`
+	CreatedAt      time.Time      `json:"created_at"`
+	User           User           `gorm:"foreignKey:UserID" json:"-"`
+	Sprint         Sprint         `gorm:"foreignKey:SprintID" json:"-"`
+}
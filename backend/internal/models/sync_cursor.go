@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// RepoSyncCursor records how far commit sync has progressed on a single
+// branch of a repository, so the next run only asks the provider for newer
+// commits instead of refetching a fixed window.
+type RepoSyncCursor struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	RepoID       uint       `gorm:"not null;uniqueIndex:idx_repo_branch" json:"repo_id"`
+	Branch       string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_repo_branch" json:"branch"`
+	LastSHA      string     `gorm:"type:varchar(64)" json:"last_sha"`
+	LastCommitAt *time.Time `json:"last_commit_at"`
+	SyncedAt     time.Time  `json:"synced_at"`
+	Repository   Repository `gorm:"foreignKey:RepoID" json:"-"`
+}
+
+// RepoSyncHealth tracks recent commit sync outcomes for a repository so
+// transient failures can be told apart from revoked access.
+type RepoSyncHealth struct {
+	ID                        uint       `gorm:"primarykey" json:"-"`
+	RepoID                    uint       `gorm:"not null;uniqueIndex" json:"repo_id"`
+	LastErrorClass            string     `gorm:"type:varchar(20)" json:"last_error_class"`
+	LastError                 string     `gorm:"type:text" json:"last_error"`
+	ConsecutiveFailures       int        `json:"consecutive_failures"`
+	ConsecutiveAccessFailures int        `json:"consecutive_access_failures"` // not_found/unauthorized in a row
+	LastSuccessAt             *time.Time `json:"last_success_at"`
+	LastFailureAt             *time.Time `json:"last_failure_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type TimesheetStatus string
+
+const (
+	TimesheetSuggested  TimesheetStatus = "suggested" // estimate only, never stored
+	TimesheetEdited     TimesheetStatus = "edited"
+	TimesheetSkipped    TimesheetStatus = "skipped"
+	TimesheetSubmitting TimesheetStatus = "submitting" // claimed by an approval in progress
+	TimesheetSubmitted  TimesheetStatus = "submitted"
+	TimesheetFailed     TimesheetStatus = "failed"
+)
+
+// TimesheetEntry is the user's version of the time spent on a card on one
+// day. It is stored once the estimate is edited or submitted; until then the
+// timesheet shows the estimate computed from commits.
+type TimesheetEntry struct {
+	ID               uint            `gorm:"primarykey" json:"id"`
+	UserID           uint            `gorm:"uniqueIndex:idx_timesheet_entry;not null" json:"user_id"`
+	CardKey          string          `gorm:"type:varchar(50);uniqueIndex:idx_timesheet_entry;not null" json:"card_key"`
+	Date             string          `gorm:"type:varchar(10);uniqueIndex:idx_timesheet_entry;not null" json:"date"` // YYYY-MM-DD, local time
+	EstimatedSeconds int             `json:"estimated_seconds"`
+	Seconds          int             `json:"seconds"`
+	StartedAt        time.Time       `json:"started_at"`
+	Comment          string          `gorm:"type:text" json:"comment"`
+	Status           TimesheetStatus `gorm:"type:varchar(20);default:edited" json:"status"`
+	OutboxID         *uint           `json:"outbox_id,omitempty"` // JiraOutbox worklog it was submitted through
+	Error            string          `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	User             User            `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -3,18 +3,32 @@ package models
 import "time"
 
 type User struct {
-	ID            uint      `gorm:"primarykey" json:"id"`
-	Email         string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
-	PasswordHash  string    `gorm:"-" json:"-"`
-	Password      string    `gorm:"column:password_hash;type:varchar(255);not null" json:"-"`
-	GithubToken   string    `gorm:"type:text" json:"-"`
-	GitlabToken   string    `gorm:"type:text" json:"-"`
-	GitlabURL     string    `gorm:"type:varchar(500)" json:"gitlab_url"`
-	JiraEmail     string    `gorm:"type:varchar(255)" json:"jira_email"`
-	JiraToken     string    `gorm:"type:text" json:"-"`
-	JiraWorkspace string    `gorm:"type:varchar(255)" json:"jira_workspace"`
-	JiraUsername    string    `gorm:"type:varchar(255)" json:"jira_username"`
-	JiraProjectKeys string    `gorm:"type:varchar(500)" json:"jira_project_keys"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID                uint   `gorm:"primarykey" json:"id"`
+	Email             string `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
+	PasswordHash      string `gorm:"-" json:"-"`
+	Password          string `gorm:"column:password_hash;type:varchar(255);not null" json:"-"`
+	GithubToken       string `gorm:"type:text" json:"-"`
+	GitlabToken       string `gorm:"type:text" json:"-"`
+	GitlabURL         string `gorm:"type:varchar(500)" json:"gitlab_url"`
+	BitbucketUsername string `gorm:"type:varchar(255)" json:"bitbucket_username"`
+	BitbucketToken    string `gorm:"type:text" json:"-"`
+	BitbucketURL      string `gorm:"type:varchar(500)" json:"bitbucket_url"`
+	JiraEmail         string `gorm:"type:varchar(255)" json:"jira_email"`
+	JiraToken         string `gorm:"type:text" json:"-"`
+	JiraWorkspace     string `gorm:"type:varchar(255)" json:"jira_workspace"`
+	JiraUsername      string `gorm:"type:varchar(255)" json:"jira_username"`
+	JiraProjectKeys   string `gorm:"type:varchar(500)" json:"jira_project_keys"`
+	// Author filters for "my commits". Empty lists fall back to the
+	// ProviderAccount identities; IncludeAllAuthors disables filtering.
+	AuthorEmails      string `gorm:"type:varchar(1000)" json:"author_emails"`   // comma-separated
+	AuthorUsernames   string `gorm:"type:varchar(500)" json:"author_usernames"` // comma-separated
+	IncludeAllAuthors bool   `gorm:"default:false" json:"include_all_authors"`
+	// Timesheet heuristics, in minutes. Commits further apart than the
+	// session gap start a new session, which is credited the lead-in before
+	// its first commit; each card gets at least the minimum block per day.
+	WorklogSessionGap int       `gorm:"default:90" json:"worklog_session_gap"`
+	WorklogMinBlock   int       `gorm:"default:15" json:"worklog_min_block"`
+	WorklogLeadIn     int       `gorm:"default:30" json:"worklog_lead_in"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
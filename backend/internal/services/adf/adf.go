@@ -0,0 +1,490 @@
+// Package adf renders Atlassian Document Format, the JSON document model of
+// Jira Cloud descriptions and comments, as GitHub-flavoured Markdown.
+package adf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Node is one node of an ADF document. Block nodes hold Content; text
+// nodes hold Text and Marks.
+type Node struct {
+	Type    string         `json:"type"`
+	Text    string         `json:"text,omitempty"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+	Marks   []Mark         `json:"marks,omitempty"`
+	Content []Node         `json:"content,omitempty"`
+}
+
+// Mark is a text format such as strong, code or link.
+type Mark struct {
+	Type  string         `json:"type"`
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// IsDocument reports whether raw is an ADF document rather than a plain
+// string, as Jira REST API v2 and Data Center return.
+func IsDocument(raw json.RawMessage) bool {
+	var doc struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(raw, &doc) == nil && doc.Type == "doc"
+}
+
+// ToMarkdown renders an ADF document as Markdown.
+func ToMarkdown(raw json.RawMessage) (string, error) {
+	var doc Node
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return "", fmt.Errorf("parse adf: %w", err)
+	}
+	return doc.Markdown(), nil
+}
+
+// Markdown renders the node and its content. Unknown nodes render their
+// content, so new node types degrade to their text.
+func (n Node) Markdown() string {
+	if n.Type == "doc" {
+		return blocks(n.Content)
+	}
+	if inlineTypes[n.Type] {
+		return inline([]Node{n})
+	}
+	return block(n)
+}
+
+var inlineTypes = map[string]bool{
+	"text": true, "hardBreak": true, "mention": true, "emoji": true, "inlineCard": true,
+	"date": true, "status": true, "placeholder": true, "mediaInline": true, "inlineExtension": true,
+}
+
+func blocks(nodes []Node) string {
+	var out []string
+	for i := 0; i < len(nodes); i++ {
+		if inlineTypes[nodes[i].Type] {
+			// Inline nodes outside a paragraph form one of their own.
+			j := i
+			for j < len(nodes) && inlineTypes[nodes[j].Type] {
+				j++
+			}
+			if s := inline(nodes[i:j]); s != "" {
+				out = append(out, s)
+			}
+			i = j - 1
+			continue
+		}
+		if s := block(nodes[i]); s != "" {
+			out = append(out, s)
+		}
+	}
+	return strings.Join(out, "\n\n")
+}
+
+func block(n Node) string {
+	switch n.Type {
+	case "paragraph":
+		return inline(n.Content)
+	case "heading":
+		level := attrInt(n, "level", 1)
+		level = max(1, min(level, 6))
+		return strings.Repeat("#", level) + " " + inline(n.Content)
+	case "bulletList":
+		return list(n.Content, func(int) string { return "- " })
+	case "orderedList":
+		start := attrInt(n, "order", 1)
+		return list(n.Content, func(i int) string { return strconv.Itoa(start+i) + ". " })
+	case "taskList":
+		return taskList(n.Content)
+	case "decisionList":
+		return list(n.Content, func(int) string { return "- " })
+	case "listItem", "decisionItem", "taskItem":
+		return listItem(n, "- ")
+	case "codeBlock":
+		return codeBlock(n)
+	case "blockquote":
+		return quote(blocks(n.Content))
+	case "rule":
+		return "---"
+	case "panel":
+		return panel(n)
+	case "expand", "nestedExpand":
+		body := blocks(n.Content)
+		if title := attrString(n, "title"); title != "" {
+			return strings.TrimSpace("**" + escape(title) + "**\n\n" + body)
+		}
+		return body
+	case "table":
+		return table(n)
+	case "mediaSingle", "mediaGroup":
+		var out []string
+		for _, m := range n.Content {
+			if m.Type == "media" {
+				out = append(out, media(m))
+			}
+		}
+		return strings.Join(out, "\n")
+	case "media":
+		return media(n)
+	case "blockCard", "embedCard":
+		if url := attrString(n, "url"); url != "" {
+			return "<" + url + ">"
+		}
+		return ""
+	default:
+		return blocks(n.Content)
+	}
+}
+
+// list renders items with the marker of each; nested lists are indented
+// under their item.
+func list(items []Node, marker func(int) string) string {
+	out := make([]string, 0, len(items))
+	for i, item := range items {
+		out = append(out, listItem(item, marker(i)))
+	}
+	return strings.Join(out, "\n")
+}
+
+func listItem(item Node, marker string) string {
+	var body string
+	if item.Type == "decisionItem" || item.Type == "taskItem" {
+		body = taskBody(item)
+	} else {
+		var parts []string
+		for _, child := range item.Content {
+			var s string
+			if inlineTypes[child.Type] {
+				s = inline([]Node{child})
+			} else {
+				s = block(child)
+			}
+			if s == "" {
+				continue
+			}
+			// A nested list stays tight against the text it belongs to.
+			if len(parts) > 0 && !isList(child) {
+				parts = append(parts, "")
+			}
+			parts = append(parts, s)
+		}
+		body = strings.Join(parts, "\n")
+	}
+	return hang(marker+body, strings.Repeat(" ", len(marker)))
+}
+
+func isList(n Node) bool {
+	switch n.Type {
+	case "bulletList", "orderedList", "taskList", "decisionList":
+		return true
+	}
+	return false
+}
+
+// taskBody renders a task or decision item: its inline text, followed by
+// any list nested in it.
+func taskBody(item Node) string {
+	var text []Node
+	var nested []string
+	for _, child := range item.Content {
+		if inlineTypes[child.Type] {
+			text = append(text, child)
+		} else {
+			nested = append(nested, block(child))
+		}
+	}
+	return strings.Join(append([]string{inline(text)}, nested...), "\n")
+}
+
+func taskList(items []Node) string {
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.Type == "taskList" {
+			// Jira nests sub-tasks as a list directly inside the list.
+			out = append(out, "  "+hang(taskList(item.Content), "  "))
+			continue
+		}
+		box := "- [ ] "
+		if attrString(item, "state") == "DONE" {
+			box = "- [x] "
+		}
+		out = append(out, hang(box+taskBody(item), "  "))
+	}
+	return strings.Join(out, "\n")
+}
+
+// hang prefixes every line after the first, so continuation lines and
+// nested lists stay inside a list item.
+func hang(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = prefix + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func codeBlock(n Node) string {
+	var code strings.Builder
+	for _, t := range n.Content {
+		code.WriteString(t.Text)
+	}
+	fence := strings.Repeat("`", max(3, longestRun(code.String(), '`')+1))
+	return fence + attrString(n, "language") + "\n" + strings.TrimRight(code.String(), "\n") + "\n" + fence
+}
+
+func quote(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if l == "" {
+			lines[i] = ">"
+		} else {
+			lines[i] = "> " + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// panelAlerts maps panel types to GitHub alert kinds.
+var panelAlerts = map[string]string{
+	"info": "NOTE", "note": "NOTE", "tip": "TIP", "success": "TIP", "warning": "WARNING", "error": "CAUTION",
+}
+
+func panel(n Node) string {
+	body := blocks(n.Content)
+	if alert, ok := panelAlerts[attrString(n, "panelType")]; ok {
+		return quote("[!" + alert + "]\n" + body)
+	}
+	return quote(body)
+}
+
+func media(n Node) string {
+	name := attrString(n, "alt")
+	if name == "" {
+		name = attrString(n, "id")
+	}
+	if name == "" {
+		return "[attachment]"
+	}
+	return "[attachment: " + escape(name) + "]"
+}
+
+// table renders a GFM pipe table. The first row is the header, as GFM
+// requires one; merged cells are not expanded.
+func table(n Node) string {
+	var rows [][]string
+	cols := 0
+	for _, row := range n.Content {
+		if row.Type != "tableRow" {
+			continue
+		}
+		var cells []string
+		for _, cell := range row.Content {
+			text := blocks(cell.Content)
+			text = strings.ReplaceAll(text, "\n\n", "<br>")
+			text = strings.ReplaceAll(text, "\n", "<br>")
+			cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+		}
+		cols = max(cols, len(cells))
+		rows = append(rows, cells)
+	}
+	if len(rows) == 0 || cols == 0 {
+		return ""
+	}
+
+	line := func(cells []string) string {
+		for len(cells) < cols {
+			cells = append(cells, "")
+		}
+		return "| " + strings.Join(cells, " | ") + " |"
+	}
+	sep := make([]string, cols)
+	for i := range sep {
+		sep[i] = "---"
+	}
+	out := []string{line(rows[0]), line(sep)}
+	for _, r := range rows[1:] {
+		out = append(out, line(r))
+	}
+	return strings.Join(out, "\n")
+}
+
+func inline(nodes []Node) string {
+	var b strings.Builder
+	for _, n := range mergeText(nodes) {
+		switch n.Type {
+		case "text":
+			b.WriteString(marked(n.Text, n.Marks))
+		case "hardBreak":
+			b.WriteString("\\\n")
+		case "mention":
+			text := attrString(n, "text")
+			if text == "" {
+				text = attrString(n, "id")
+			}
+			if !strings.HasPrefix(text, "@") {
+				text = "@" + text
+			}
+			b.WriteString(escape(text))
+		case "emoji":
+			if text := attrString(n, "text"); text != "" {
+				b.WriteString(text)
+			} else {
+				b.WriteString(attrString(n, "shortName"))
+			}
+		case "inlineCard":
+			if url := attrString(n, "url"); url != "" {
+				b.WriteString("<" + url + ">")
+			}
+		case "date":
+			if ms, err := strconv.ParseInt(attrString(n, "timestamp"), 10, 64); err == nil {
+				b.WriteString(time.UnixMilli(ms).UTC().Format("2006-01-02"))
+			}
+		case "status":
+			if text := attrString(n, "text"); text != "" {
+				b.WriteString(codeSpan(text))
+			}
+		case "mediaInline":
+			b.WriteString(media(n))
+		case "placeholder":
+			// Template hints, not content.
+		default:
+			b.WriteString(inline(n.Content))
+		}
+	}
+	return b.String()
+}
+
+// mergeText joins adjacent text nodes with the same marks, so a word split
+// across nodes does not get its emphasis closed and reopened.
+func mergeText(nodes []Node) []Node {
+	var out []Node
+	for _, n := range nodes {
+		if last := len(out) - 1; last >= 0 && n.Type == "text" && out[last].Type == "text" && sameMarks(out[last].Marks, n.Marks) {
+			out[last].Text += n.Text
+			continue
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+func sameMarks(a, b []Mark) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+// marked applies text marks. Surrounding spaces stay outside the
+// delimiters, where Markdown requires them.
+func marked(text string, marks []Mark) string {
+	core := strings.TrimSpace(text)
+	if core == "" {
+		return text
+	}
+	lead := text[:strings.Index(text, core)]
+	trail := text[len(lead)+len(core):]
+
+	var code, em, strong, strike bool
+	href := ""
+	for _, m := range marks {
+		switch m.Type {
+		case "code":
+			code = true
+		case "em":
+			em = true
+		case "strong":
+			strong = true
+		case "strike":
+			strike = true
+		case "link":
+			href, _ = m.Attrs["href"].(string)
+		}
+	}
+
+	if code {
+		core = codeSpan(core)
+	} else {
+		core = escape(core)
+	}
+	if em {
+		core = "*" + core + "*"
+	}
+	if strong {
+		core = "**" + core + "**"
+	}
+	if strike {
+		core = "~~" + core + "~~"
+	}
+	if href != "" {
+		core = "[" + core + "](" + strings.ReplaceAll(href, " ", "%20") + ")"
+	}
+	return lead + core + trail
+}
+
+func codeSpan(s string) string {
+	fence := strings.Repeat("`", longestRun(s, '`')+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		return fence + " " + s + " " + fence
+	}
+	return fence + s + fence
+}
+
+// escape backslash-escapes the characters that would otherwise start
+// Markdown formatting. Underscores inside words are left alone, as GFM does
+// not treat them as emphasis.
+func escape(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		switch r {
+		case '\\', '*', '`', '[', ']', '~':
+			b.WriteRune('\\')
+		case '_':
+			inWord := i > 0 && i < len(runes)-1 && isWordRune(runes[i-1]) && isWordRune(runes[i+1])
+			if !inWord {
+				b.WriteRune('\\')
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+func attrString(n Node, key string) string {
+	switch v := n.Attrs[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+func attrInt(n Node, key string, fallback int) int {
+	if v, ok := n.Attrs[key].(float64); ok {
+		return int(v)
+	}
+	return fallback
+}
@@ -0,0 +1,53 @@
+package adf
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden .md files")
+
+// TestToMarkdown_Golden renders each testdata/*.json document and compares
+// it with the .md file of the same name.
+func TestToMarkdown_Golden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.json")
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no golden inputs: %v", err)
+	}
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".json")
+		t.Run(name, func(t *testing.T) {
+			raw, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ToMarkdown(raw)
+			if err != nil {
+				t.Fatalf("ToMarkdown: %v", err)
+			}
+			golden := strings.TrimSuffix(input, ".json") + ".md"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file, run with -update: %v", err)
+			}
+			if got+"\n" != string(want) {
+				t.Errorf("%s mismatch\n--- got ---\n%s\n--- want ---\n%s", name, got, want)
+			}
+		})
+	}
+}
+
+func TestIsDocument(t *testing.T) {
+	if IsDocument([]byte(`"plain *wiki* text"`)) || !IsDocument([]byte(`{"type":"doc","content":[]}`)) {
+		t.Error("IsDocument must tell ADF from strings")
+	}
+}
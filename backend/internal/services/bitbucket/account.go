@@ -0,0 +1,91 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cds-id/pdt/backend/internal/services"
+)
+
+// FetchAccount returns the authenticated user. Bitbucket Cloud only lists
+// emails when the token has the email scope.
+func (c *Client) FetchAccount(token string) (*services.AccountInfo, error) {
+	if c.server {
+		return c.fetchServerAccount(token)
+	}
+
+	body, err := c.doRequest(c.BaseURL+"/user", token)
+	if err != nil {
+		return nil, err
+	}
+	var user struct {
+		Username    string `json:"username"`
+		Nickname    string `json:"nickname"`
+		DisplayName string `json:"display_name"`
+	}
+	if err := json.Unmarshal(body, &user); err != nil {
+		return nil, fmt.Errorf("failed to parse user: %w", err)
+	}
+
+	account := &services.AccountInfo{Username: user.Username, Name: user.DisplayName}
+	if account.Username == "" {
+		account.Username = user.Nickname
+	}
+
+	if body, err := c.doRequest(c.BaseURL+"/user/emails?pagelen=100", token); err == nil {
+		var page struct {
+			Values []struct {
+				Email       string `json:"email"`
+				IsConfirmed bool   `json:"is_confirmed"`
+			} `json:"values"`
+		}
+		if json.Unmarshal(body, &page) == nil {
+			for _, e := range page.Values {
+				if e.IsConfirmed {
+					account.Emails = append(account.Emails, e.Email)
+				}
+			}
+		}
+	}
+
+	return account, nil
+}
+
+// fetchServerAccount resolves the token's user slug through the whoami
+// servlet (or the configured username) and loads the user's profile.
+func (c *Client) fetchServerAccount(token string) (*services.AccountInfo, error) {
+	slug := c.Username
+	if slug == "" {
+		body, err := c.doRequest(c.BaseURL+"/plugins/servlet/applinks/whoami", token)
+		if err != nil {
+			return nil, err
+		}
+		slug = strings.TrimSpace(string(body))
+		if slug == "" {
+			return nil, &services.ProviderError{Class: services.ErrUnauthorized, StatusCode: http.StatusUnauthorized, Message: "unauthorized: invalid token"}
+		}
+	}
+
+	body, err := c.doRequest(fmt.Sprintf("%s/rest/api/1.0/users/%s", c.BaseURL, url.PathEscape(slug)), token)
+	if err != nil {
+		return nil, err
+	}
+	var user struct {
+		Name         string `json:"name"`
+		Slug         string `json:"slug"`
+		DisplayName  string `json:"displayName"`
+		EmailAddress string `json:"emailAddress"`
+	}
+	if err := json.Unmarshal(body, &user); err != nil {
+		return nil, fmt.Errorf("failed to parse user: %w", err)
+	}
+
+	account := &services.AccountInfo{Username: user.Name, Name: user.DisplayName}
+	if user.EmailAddress != "" {
+		account.Emails = []string{user.EmailAddress}
+	}
+	return account, nil
+}
@@ -0,0 +1,502 @@
+package bitbucket
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/services"
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
+)
+
+const cloudBaseURL = "https://api.bitbucket.org/2.0"
+
+// Client talks to either Bitbucket Cloud (api.bitbucket.org) or a
+// self-hosted Bitbucket Server / Data Center instance. When Username is set
+// the token is sent as an app password via Basic auth, otherwise as a Bearer
+// access token.
+type Client struct {
+	BaseURL  string
+	Username string
+	server   bool
+}
+
+// New returns a client for Bitbucket Cloud when baseURL is empty or points at
+// bitbucket.org, and a Bitbucket Server client otherwise.
+func New(baseURL, username string) *Client {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if baseURL == "" || strings.Contains(baseURL, "bitbucket.org") {
+		return &Client{BaseURL: cloudBaseURL, Username: username}
+	}
+	return &Client{BaseURL: baseURL, Username: username, server: true}
+}
+
+// IsServer reports whether the client targets Bitbucket Server / Data Center.
+func (c *Client) IsServer() bool {
+	return c.server
+}
+
+// FetchCommits fetches all commits across all branches with branch info.
+func (c *Client) FetchCommits(owner, repo, token string, since time.Time) ([]services.CommitInfo, error) {
+	branches, err := c.FetchBranches(owner, repo, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch branches: %w", err)
+	}
+
+	seen := map[string]bool{}
+	var allCommits []services.CommitInfo
+
+	for _, branch := range branches {
+		commits, err := c.FetchBranchCommits(owner, repo, branch, token, since)
+		if err != nil {
+			continue
+		}
+		for _, ci := range commits {
+			if !seen[ci.SHA] {
+				seen[ci.SHA] = true
+				allCommits = append(allCommits, ci)
+			}
+		}
+	}
+
+	return allCommits, nil
+}
+
+func (c *Client) FetchBranches(owner, repo, token string) ([]string, error) {
+	if c.server {
+		return c.fetchServerBranches(owner, repo, token)
+	}
+
+	var allBranches []string
+	next := fmt.Sprintf("%s/repositories/%s/%s/refs/branches?pagelen=100", c.BaseURL, owner, repo)
+
+	for next != "" {
+		body, err := c.doRequest(next, token)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Values []struct {
+				Name string `json:"name"`
+			} `json:"values"`
+			Next string `json:"next"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse branches: %w", err)
+		}
+
+		for _, b := range page.Values {
+			allBranches = append(allBranches, b.Name)
+		}
+		next = page.Next
+	}
+
+	return allBranches, nil
+}
+
+// FetchBranchCommits lists commits reachable from branch that are newer than
+// since. Neither Bitbucket API supports a since filter, and both list commits
+// in topological order, where a merge can come before newer commits of the
+// branch it merged. Commits are therefore filtered one by one, and paging
+// stops after a full page in which every commit is older than since.
+func (c *Client) FetchBranchCommits(owner, repo, branch, token string, since time.Time) ([]services.CommitInfo, error) {
+	if c.server {
+		return c.fetchServerBranchCommits(owner, repo, branch, token, since)
+	}
+
+	var allCommits []services.CommitInfo
+	next := fmt.Sprintf("%s/repositories/%s/%s/commits?include=%s&pagelen=100",
+		c.BaseURL, owner, repo, url.QueryEscape(branch))
+
+	for next != "" {
+		body, err := c.doRequest(next, token)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Values []struct {
+				Hash    string    `json:"hash"`
+				Message string    `json:"message"`
+				Date    time.Time `json:"date"`
+				Author  struct {
+					Raw  string `json:"raw"`
+					User *struct {
+						DisplayName string `json:"display_name"`
+					} `json:"user"`
+				} `json:"author"`
+			} `json:"values"`
+			Next string `json:"next"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		recent := 0
+		for _, bc := range page.Values {
+			if bc.Date.Before(since) {
+				continue
+			}
+			recent++
+			name, email := parseRawAuthor(bc.Author.Raw)
+			if bc.Author.User != nil && bc.Author.User.DisplayName != "" {
+				name = bc.Author.User.DisplayName
+			}
+			allCommits = append(allCommits, services.CommitInfo{
+				SHA:         bc.Hash,
+				Message:     bc.Message,
+				Author:      name,
+				AuthorEmail: email,
+				Branch:      branch,
+				Date:        bc.Date,
+			})
+		}
+		if recent == 0 {
+			break
+		}
+		next = page.Next
+	}
+
+	return allCommits, nil
+}
+
+func (c *Client) FetchCommitDiff(owner, repo, sha, token string) (*services.CommitDiff, error) {
+	if c.server {
+		return c.fetchServerCommitDiff(owner, repo, sha, token)
+	}
+
+	commitURL := fmt.Sprintf("%s/repositories/%s/%s/commit/%s", c.BaseURL, owner, repo, sha)
+	commitBody, err := c.doRequest(commitURL, token)
+	if err != nil {
+		return nil, fmt.Errorf("fetch commit detail: %w", err)
+	}
+
+	var commitResp struct {
+		Hash    string `json:"hash"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(commitBody, &commitResp); err != nil {
+		return nil, fmt.Errorf("parse commit detail: %w", err)
+	}
+
+	diff := &services.CommitDiff{
+		SHA:     commitResp.Hash,
+		Message: commitResp.Message,
+	}
+
+	// Raw unified diff, split per file so each FileChange gets its own patch.
+	patches := map[string]string{}
+	if rawDiff, err := c.doRequest(fmt.Sprintf("%s/repositories/%s/%s/diff/%s", c.BaseURL, owner, repo, sha), token); err == nil {
+		patches = services.SplitUnifiedDiff(string(rawDiff))
+	}
+
+	next := fmt.Sprintf("%s/repositories/%s/%s/diffstat/%s?pagelen=100", c.BaseURL, owner, repo, sha)
+	for next != "" {
+		body, err := c.doRequest(next, token)
+		if err != nil {
+			return nil, fmt.Errorf("fetch commit diff: %w", err)
+		}
+
+		var page struct {
+			Values []struct {
+				Status       string `json:"status"`
+				LinesAdded   int    `json:"lines_added"`
+				LinesRemoved int    `json:"lines_removed"`
+				Old          *struct {
+					Path string `json:"path"`
+				} `json:"old"`
+				New *struct {
+					Path string `json:"path"`
+				} `json:"new"`
+			} `json:"values"`
+			Next string `json:"next"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("parse commit diff: %w", err)
+		}
+
+		for _, f := range page.Values {
+			filename := ""
+			if f.New != nil {
+				filename = f.New.Path
+			} else if f.Old != nil {
+				filename = f.Old.Path
+			}
+			diff.Files = append(diff.Files, services.FileChange{
+				Filename:  filename,
+				Status:    f.Status,
+				Additions: f.LinesAdded,
+				Deletions: f.LinesRemoved,
+				Patch:     truncatePatch(patches[filename]),
+			})
+			diff.Stats.Additions += f.LinesAdded
+			diff.Stats.Deletions += f.LinesRemoved
+			diff.Stats.Total += f.LinesAdded + f.LinesRemoved
+		}
+		next = page.Next
+	}
+
+	return diff, nil
+}
+
+func (c *Client) ValidateAccess(owner, repo, token string) error {
+	reqURL := fmt.Sprintf("%s/repositories/%s/%s", c.BaseURL, owner, repo)
+	if c.server {
+		reqURL = c.serverRepoURL(owner, repo)
+	}
+	_, err := c.doRequest(reqURL, token)
+	return err
+}
+
+// --- Bitbucket Server / Data Center (REST API 1.0) ---
+
+func (c *Client) serverRepoURL(project, repo string) string {
+	return fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s", c.BaseURL, url.PathEscape(project), url.PathEscape(repo))
+}
+
+type serverPage struct {
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+func (c *Client) fetchServerBranches(project, repo, token string) ([]string, error) {
+	var allBranches []string
+	start := 0
+
+	for {
+		reqURL := fmt.Sprintf("%s/branches?limit=100&start=%d", c.serverRepoURL(project, repo), start)
+		body, err := c.doRequest(reqURL, token)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			serverPage
+			Values []struct {
+				DisplayID string `json:"displayId"`
+			} `json:"values"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse branches: %w", err)
+		}
+
+		for _, b := range page.Values {
+			allBranches = append(allBranches, b.DisplayID)
+		}
+
+		if page.IsLastPage || len(page.Values) == 0 {
+			break
+		}
+		start = page.NextPageStart
+	}
+
+	return allBranches, nil
+}
+
+func (c *Client) fetchServerBranchCommits(project, repo, branch, token string, since time.Time) ([]services.CommitInfo, error) {
+	var allCommits []services.CommitInfo
+	start := 0
+
+	for {
+		reqURL := fmt.Sprintf("%s/commits?until=%s&limit=100&start=%d",
+			c.serverRepoURL(project, repo), url.QueryEscape(branch), start)
+		body, err := c.doRequest(reqURL, token)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			serverPage
+			Values []struct {
+				ID      string `json:"id"`
+				Message string `json:"message"`
+				Author  struct {
+					Name         string `json:"name"`
+					DisplayName  string `json:"displayName"`
+					EmailAddress string `json:"emailAddress"`
+				} `json:"author"`
+				AuthorTimestamp int64 `json:"authorTimestamp"`
+			} `json:"values"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		recent := 0
+		for _, sc := range page.Values {
+			date := time.UnixMilli(sc.AuthorTimestamp)
+			if date.Before(since) {
+				continue
+			}
+			recent++
+			name := sc.Author.DisplayName
+			if name == "" {
+				name = sc.Author.Name
+			}
+			allCommits = append(allCommits, services.CommitInfo{
+				SHA:         sc.ID,
+				Message:     sc.Message,
+				Author:      name,
+				AuthorEmail: sc.Author.EmailAddress,
+				Branch:      branch,
+				Date:        date,
+			})
+		}
+
+		if page.IsLastPage || recent == 0 {
+			break
+		}
+		start = page.NextPageStart
+	}
+
+	return allCommits, nil
+}
+
+func (c *Client) fetchServerCommitDiff(project, repo, sha, token string) (*services.CommitDiff, error) {
+	commitBody, err := c.doRequest(fmt.Sprintf("%s/commits/%s", c.serverRepoURL(project, repo), sha), token)
+	if err != nil {
+		return nil, fmt.Errorf("fetch commit detail: %w", err)
+	}
+
+	var commitResp struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(commitBody, &commitResp); err != nil {
+		return nil, fmt.Errorf("parse commit detail: %w", err)
+	}
+
+	diffBody, err := c.doRequest(fmt.Sprintf("%s/commits/%s/diff?contextLines=3", c.serverRepoURL(project, repo), sha), token)
+	if err != nil {
+		return nil, fmt.Errorf("fetch commit diff: %w", err)
+	}
+
+	var diffResp struct {
+		Diffs []struct {
+			Source *struct {
+				ToString string `json:"toString"`
+			} `json:"source"`
+			Destination *struct {
+				ToString string `json:"toString"`
+			} `json:"destination"`
+			Hunks []struct {
+				Segments []struct {
+					Type  string `json:"type"`
+					Lines []struct {
+						Line string `json:"line"`
+					} `json:"lines"`
+				} `json:"segments"`
+			} `json:"hunks"`
+		} `json:"diffs"`
+	}
+	if err := json.Unmarshal(diffBody, &diffResp); err != nil {
+		return nil, fmt.Errorf("parse commit diff: %w", err)
+	}
+
+	diff := &services.CommitDiff{
+		SHA:     commitResp.ID,
+		Message: commitResp.Message,
+	}
+
+	for _, d := range diffResp.Diffs {
+		status := "modified"
+		filename := ""
+		switch {
+		case d.Source == nil && d.Destination != nil:
+			status = "added"
+			filename = d.Destination.ToString
+		case d.Source != nil && d.Destination == nil:
+			status = "removed"
+			filename = d.Source.ToString
+		case d.Source != nil && d.Destination != nil:
+			filename = d.Destination.ToString
+			if d.Source.ToString != d.Destination.ToString {
+				status = "renamed"
+			}
+		}
+
+		additions, deletions := 0, 0
+		var patch strings.Builder
+		for _, h := range d.Hunks {
+			for _, seg := range h.Segments {
+				prefix := " "
+				switch seg.Type {
+				case "ADDED":
+					prefix = "+"
+					additions += len(seg.Lines)
+				case "REMOVED":
+					prefix = "-"
+					deletions += len(seg.Lines)
+				}
+				for _, l := range seg.Lines {
+					patch.WriteString(prefix + l.Line + "\n")
+				}
+			}
+		}
+
+		diff.Files = append(diff.Files, services.FileChange{
+			Filename:  filename,
+			Status:    status,
+			Additions: additions,
+			Deletions: deletions,
+			Patch:     truncatePatch(patch.String()),
+		})
+		diff.Stats.Additions += additions
+		diff.Stats.Deletions += deletions
+		diff.Stats.Total += additions + deletions
+	}
+
+	return diff, nil
+}
+
+// --- helpers ---
+
+// parseRawAuthor splits a git author string like "Jane Doe <jane@example.com>".
+func parseRawAuthor(raw string) (name, email string) {
+	lt := strings.LastIndex(raw, "<")
+	gt := strings.LastIndex(raw, ">")
+	if lt < 0 || gt < lt {
+		return strings.TrimSpace(raw), ""
+	}
+	return strings.TrimSpace(raw[:lt]), raw[lt+1 : gt]
+}
+
+func truncatePatch(patch string) string {
+	if len(patch) > 500 {
+		return patch[:500] + "\n... (truncated)"
+	}
+	return patch
+}
+
+func (c *Client) doRequest(reqURL, token string) ([]byte, error) {
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Username != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + token))
+		req.Header.Set("Authorization", "Basic "+auth)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpclient.Client.Do(req)
+	if err != nil {
+		return nil, services.NewNetworkError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, services.NewResponseError(resp, "repository")
+	}
+
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,177 @@
+package bitbucket
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCloudStandIn(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	var srvURL string
+
+	mux.HandleFunc("/repositories/team/app", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"full_name":"team/app"}`)
+	})
+	mux.HandleFunc("/repositories/team/app/refs/branches", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `{"values":[{"name":"feature/CORE-1"}]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"values":[{"name":"main"}],"next":"%s/repositories/team/app/refs/branches?page=2"}`, srvURL)
+	})
+	mux.HandleFunc("/repositories/team/app/commits", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("include") != "main" {
+			fmt.Fprint(w, `{"values":[]}`)
+			return
+		}
+		// Topological order: the merged c1 is older than c3 after it.
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprintf(w, `{"values":[
+				{"hash":"c2","message":"CORE-2 new","date":"2026-03-02T10:00:00+00:00","author":{"raw":"Jane Doe <jane@example.com>","user":{"display_name":"Jane D"}}},
+				{"hash":"c1","message":"old","date":"2026-01-01T10:00:00+00:00","author":{"raw":"Jane Doe <jane@example.com>"}},
+				{"hash":"c3","message":"merged in","date":"2026-02-15T10:00:00+00:00","author":{"raw":"Bob <bob@example.com>"}}
+			],"next":"%s/repositories/team/app/commits?include=main&page=2"}`, srvURL)
+		case "2":
+			fmt.Fprintf(w, `{"values":[
+				{"hash":"c0","message":"older","date":"2025-12-01T10:00:00+00:00","author":{"raw":"Jane Doe <jane@example.com>"}}
+			],"next":"%s/repositories/team/app/commits?include=main&page=3"}`, srvURL)
+		default:
+			t.Errorf("fetched page %s after a page of old commits", r.URL.Query().Get("page"))
+			fmt.Fprint(w, `{"values":[]}`)
+		}
+	})
+	mux.HandleFunc("/repositories/team/app/commit/c2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"hash":"c2","message":"CORE-2 new"}`)
+	})
+	mux.HandleFunc("/repositories/team/app/diff/c2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "diff --git a/main.go b/main.go\nindex 1..2 100644\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-old\n+new\n")
+	})
+	mux.HandleFunc("/repositories/team/app/diffstat/c2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[{"status":"modified","lines_added":1,"lines_removed":1,"old":{"path":"main.go"},"new":{"path":"main.go"}}]}`)
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	srvURL = srv.URL
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCloud_BranchesCommitsAndDiff(t *testing.T) {
+	srv := newCloudStandIn(t)
+	c := &Client{BaseURL: srv.URL, Username: "jane"}
+
+	if err := c.ValidateAccess("team", "app", "app-pass"); err != nil {
+		t.Fatalf("ValidateAccess: %v", err)
+	}
+
+	branches, err := c.FetchBranches("team", "app", "app-pass")
+	if err != nil {
+		t.Fatalf("FetchBranches: %v", err)
+	}
+	if strings.Join(branches, ",") != "main,feature/CORE-1" {
+		t.Errorf("branches = %v", branches)
+	}
+
+	since := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	commits, err := c.FetchBranchCommits("team", "app", "main", "app-pass", since)
+	if err != nil {
+		t.Fatalf("FetchBranchCommits: %v", err)
+	}
+	if len(commits) != 2 || commits[1].SHA != "c3" {
+		t.Fatalf("got %+v, want c2 and c3 (an older commit between them must not stop paging)", commits)
+	}
+	if commits[0].Author != "Jane D" || commits[0].AuthorEmail != "jane@example.com" || commits[0].Branch != "main" {
+		t.Errorf("commit = %+v", commits[0])
+	}
+
+	diff, err := c.FetchCommitDiff("team", "app", "c2", "app-pass")
+	if err != nil {
+		t.Fatalf("FetchCommitDiff: %v", err)
+	}
+	if diff.Stats.Total != 2 || len(diff.Files) != 1 || !strings.Contains(diff.Files[0].Patch, "+new") {
+		t.Errorf("diff = %+v", diff)
+	}
+}
+
+func TestServer_BranchesCommitsAndDiff(t *testing.T) {
+	mux := http.NewServeMux()
+	base := "/rest/api/1.0/projects/PROJ/repos/app"
+	mux.HandleFunc(base, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"slug":"app"}`)
+	})
+	mux.HandleFunc(base+"/branches", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start") == "1" {
+			fmt.Fprint(w, `{"isLastPage":true,"values":[{"displayId":"develop"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"isLastPage":false,"nextPageStart":1,"values":[{"displayId":"master"}]}`)
+	})
+	mux.HandleFunc(base+"/commits", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"isLastPage":true,"values":[{"id":"s1","message":"CORE-9 fix","author":{"name":"bob","displayName":"Bob","emailAddress":"bob@corp.local"},"authorTimestamp":1772445600000}]}`)
+	})
+	mux.HandleFunc(base+"/commits/s1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"s1","message":"CORE-9 fix"}`)
+	})
+	mux.HandleFunc(base+"/commits/s1/diff", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"diffs":[{"source":null,"destination":{"toString":"new.go"},"hunks":[{"segments":[{"type":"ADDED","lines":[{"line":"package x"},{"line":"func A() {}"}]}]}]}]}`)
+	})
+
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "")
+	if !c.IsServer() {
+		t.Fatal("expected server client for custom base URL")
+	}
+
+	if err := c.ValidateAccess("PROJ", "app", "pat"); err != nil {
+		t.Fatalf("ValidateAccess: %v", err)
+	}
+	if gotAuth != "Bearer pat" {
+		t.Errorf("Authorization = %q, want bearer token", gotAuth)
+	}
+
+	branches, err := c.FetchBranches("PROJ", "app", "pat")
+	if err != nil || strings.Join(branches, ",") != "master,develop" {
+		t.Fatalf("FetchBranches = %v, %v", branches, err)
+	}
+
+	commits, err := c.FetchBranchCommits("PROJ", "app", "master", "pat", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(commits) != 1 || commits[0].Author != "Bob" {
+		t.Fatalf("FetchBranchCommits = %+v, %v", commits, err)
+	}
+
+	diff, err := c.FetchCommitDiff("PROJ", "app", "s1", "pat")
+	if err != nil {
+		t.Fatalf("FetchCommitDiff: %v", err)
+	}
+	if len(diff.Files) != 1 || diff.Files[0].Status != "added" || diff.Stats.Additions != 2 {
+		t.Errorf("diff = %+v", diff)
+	}
+}
+
+func TestValidateAccess_NotFound(t *testing.T) {
+	srv := newCloudStandIn(t)
+	c := &Client{BaseURL: srv.URL}
+
+	err := c.ValidateAccess("team", "missing", "tok")
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("ValidateAccess err = %v, want not found", err)
+	}
+}
@@ -0,0 +1,115 @@
+// Package cardlink records which Jira cards a commit belongs to. Keys come
+// from the commit message, the branch it was made on, the title of a pull
+// request containing it, or a manual link; each is stored as a
+// CommitCardLink so a commit can count towards several cards.
+package cardlink
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+// OnCard is a condition for queries joined on the commits table that keeps
+// commits linked to the card key bound to its placeholder.
+const OnCard = "commits.id IN (SELECT commit_id FROM commit_card_links WHERE jira_card_key = ?)"
+
+// ProjectKeys returns the upper-cased project keys of the user's active Jira
+// workspaces, falling back to the legacy keys on the user. An empty result
+// means no filter is configured.
+func ProjectKeys(db *gorm.DB, userID uint) []string {
+	var lists []string
+	db.Model(&models.JiraWorkspaceConfig{}).
+		Where("user_id = ? AND is_active = ? AND project_keys <> ''", userID, true).
+		Pluck("project_keys", &lists)
+	if len(lists) == 0 {
+		var user models.User
+		if db.Select("jira_project_keys").First(&user, userID).Error == nil {
+			lists = append(lists, user.JiraProjectKeys)
+		}
+	}
+
+	var keys []string
+	for _, list := range lists {
+		for _, k := range strings.Split(list, ",") {
+			if k = strings.ToUpper(strings.TrimSpace(k)); k != "" && !slices.Contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+// Link records commit as belonging to each of keys. Existing links are left
+// as they are, whatever their source. The commit's JiraCardKey is set to the
+// first key when it has none yet, and HasLink is raised. It returns the
+// number of new links.
+func Link(db *gorm.DB, commit *models.Commit, keys []string, source models.CardLinkSource) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	created := 0
+	now := time.Now()
+	for _, key := range keys {
+		tx := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.CommitCardLink{
+			CommitID:    commit.ID,
+			JiraCardKey: key,
+			Source:      source,
+			LinkedAt:    now,
+		})
+		if tx.Error != nil {
+			return created, tx.Error
+		}
+		created += int(tx.RowsAffected)
+	}
+
+	updates := map[string]any{}
+	if !commit.HasLink {
+		updates["has_link"] = true
+	}
+	if commit.JiraCardKey == "" {
+		updates["jira_card_key"] = keys[0]
+	}
+	if len(updates) > 0 {
+		if err := db.Model(commit).Updates(updates).Error; err != nil {
+			return created, err
+		}
+		commit.HasLink = true
+		if commit.JiraCardKey == "" {
+			commit.JiraCardKey = keys[0]
+		}
+	}
+	return created, nil
+}
+
+// Keys returns the card keys linked to each of the given commits, ordered by
+// link time.
+func Keys(db *gorm.DB, commitIDs []uint) map[uint][]string {
+	out := make(map[uint][]string)
+	if len(commitIDs) == 0 {
+		return out
+	}
+	var links []models.CommitCardLink
+	db.Where("commit_id IN ?", commitIDs).Order("linked_at, id").Find(&links)
+	for _, l := range links {
+		out[l.CommitID] = append(out[l.CommitID], l.JiraCardKey)
+	}
+	return out
+}
+
+// Backfill copies the key extracted into commits.jira_card_key before links
+// were recorded per source into commit_card_links, so readers only need the
+// link table.
+func Backfill(db *gorm.DB) {
+	db.Exec(`INSERT INTO commit_card_links (commit_id, jira_card_key, source, linked_at)
+		SELECT commits.id, commits.jira_card_key, ?, commits.created_at FROM commits
+		WHERE commits.jira_card_key <> '' AND NOT EXISTS (
+			SELECT 1 FROM commit_card_links l WHERE l.commit_id = commits.id AND l.jira_card_key = commits.jira_card_key)`,
+		models.LinkSourceMessage)
+}
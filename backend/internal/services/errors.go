@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
+)
+
+// ErrorClass groups provider failures by how sync should react to them.
+type ErrorClass string
+
+const (
+	ErrNotFound     ErrorClass = "not_found"
+	ErrUnauthorized ErrorClass = "unauthorized"
+	ErrRateLimited  ErrorClass = "rate_limited"
+	ErrNetwork      ErrorClass = "network"
+	ErrServer       ErrorClass = "server"
+	ErrUnknown      ErrorClass = "unknown"
+)
+
+// IsAccessError reports whether the class means the credentials or the
+// resource itself are gone, as opposed to a transient failure.
+func (c ErrorClass) IsAccessError() bool {
+	return c == ErrNotFound || c == ErrUnauthorized
+}
+
+// ProviderError is returned by the provider clients for failed API calls.
+type ProviderError struct {
+	Class      ErrorClass
+	StatusCode int
+	Message    string
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Message
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// NewNetworkError wraps a transport failure. Requests refused by the shared
+// transport because the quota is exhausted are classed as rate limited.
+func NewNetworkError(err error) *ProviderError {
+	var rl *httpclient.RateLimitError
+	if errors.As(err, &rl) {
+		return &ProviderError{Class: ErrRateLimited, Message: err.Error(), Err: err}
+	}
+	return &ProviderError{Class: ErrNetwork, Message: fmt.Sprintf("request failed: %v", err), Err: err}
+}
+
+// NewResponseError classifies a non-200 response. resource names what was
+// requested ("repository", "project") for the not-found message.
+func NewResponseError(resp *http.Response, resource string) *ProviderError {
+	e := &ProviderError{StatusCode: resp.StatusCode}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && (resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != ""):
+		e.Class = ErrRateLimited
+		e.Message = fmt.Sprintf("rate limited: status %d", resp.StatusCode)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		e.Class = ErrUnauthorized
+		e.Message = "unauthorized: invalid token"
+	case resp.StatusCode == http.StatusNotFound:
+		e.Class = ErrNotFound
+		e.Message = resource + " not found"
+		if resp.Request != nil {
+			e.Message += ": " + resp.Request.URL.String()
+		}
+	case resp.StatusCode >= 500:
+		e.Class = ErrServer
+		e.Message = fmt.Sprintf("server error: status %d", resp.StatusCode)
+	default:
+		e.Class = ErrUnknown
+		e.Message = fmt.Sprintf("unexpected status: %d", resp.StatusCode)
+	}
+
+	return e
+}
+
+// ClassifyError returns the class of a (possibly wrapped) provider error.
+func ClassifyError(err error) ErrorClass {
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return pe.Class
+	}
+	return ErrUnknown
+}
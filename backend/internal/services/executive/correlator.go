@@ -12,8 +12,10 @@ import (
 )
 
 type Correlator struct {
-	Client WeaviateClient
-	Now    func() time.Time
+	Client  WeaviateClient
+	People  PeopleDirectory // optional; nil leaves Person fields empty
+	Sprints SprintDirectory // optional; nil leaves the dataset without sprints
+	Now     func() time.Time
 }
 
 func NewCorrelator(client WeaviateClient) *Correlator {
@@ -76,9 +78,55 @@ func (c *Correlator) Build(ctx context.Context, userID uint, workspaceID *uint,
 	}
 	ds.Metrics = computeMetrics(topics, orphanCommits, orphanWA)
 	ds.Metrics.Truncated = truncated
+	c.resolvePeople(ctx, ds)
+	if c.Sprints != nil {
+		report, err := c.Sprints.Sprints(ctx, userID, workspaceID, r)
+		if err != nil {
+			slog.WarnContext(ctx, "sprint analytics failed", "error", err)
+		} else {
+			ds.Sprints = report
+		}
+	}
 	return ds, nil
 }
 
+// resolvePeople fills the Person field of every card, commit and message in
+// ds from the identity directory, so one human reads the same everywhere.
+func (c *Correlator) resolvePeople(ctx context.Context, ds *CorrelatedDataset) {
+	if c.People == nil {
+		return
+	}
+	dir, err := c.People.Directory(ctx, ds.UserID)
+	if err != nil {
+		slog.WarnContext(ctx, "identity directory lookup failed", "error", err)
+		return
+	}
+	lookup := func(raw string) string {
+		return dir[strings.ToLower(strings.TrimSpace(raw))]
+	}
+	resolveCommits := func(list []Commit) {
+		for i := range list {
+			list[i].Person = lookup(list[i].Author)
+		}
+	}
+	resolveWA := func(list []WAMessage) {
+		for i := range list {
+			list[i].Person = lookup(list[i].SenderName)
+		}
+	}
+
+	for i := range ds.Topics {
+		t := &ds.Topics[i]
+		t.Anchor.Person = lookup(t.Anchor.Assignee)
+		resolveCommits(t.Commits)
+		resolveWA(t.Messages)
+	}
+	resolveCommits(ds.OrphanCommits)
+	for i := range ds.OrphanWA {
+		resolveWA(ds.OrphanWA[i].Messages)
+	}
+}
+
 func (c *Correlator) buildTopics(ctx context.Context, userID uint, workspaceID *uint, anchors []JiraCard, rawCommits []Commit, r DateRange, staleDays int) []Topic {
 	sem := make(chan struct{}, PerAnchorWorkers)
 	var mu sync.Mutex
@@ -163,3 +163,40 @@ func TestCorrelator_WorkspaceScoping(t *testing.T) {
 		t.Fatalf("expected only A-1 (ws=3), got %+v", ds.Topics)
 	}
 }
+
+type fakePeople map[string]string
+
+func (f fakePeople) Directory(_ context.Context, _ uint) (map[string]string, error) {
+	return f, nil
+}
+
+func TestCorrelator_ResolvesPeople(t *testing.T) {
+	r := mkRange()
+	fake := &fakeWeaviate{
+		jira: []JiraCard{
+			{CardKey: "PROJ-1", Title: "Login", Content: "login", Status: "Done", Assignee: "Alice Smith", UpdatedAt: r.Start},
+		},
+		commits: []Commit{
+			{SHA: "aaa", Message: "PROJ-1: login form", Author: "asmith", CommittedAt: r.Start.Add(time.Hour)},
+			{SHA: "bbb", Message: "cleanup", Author: "someone", CommittedAt: r.Start.Add(time.Hour)},
+		},
+		semanticCommits: map[string][]CommitHit{},
+		semanticWA:      map[string][]WAHit{},
+	}
+
+	c := NewCorrelator(fake)
+	c.People = fakePeople{"alice smith": "Alice", "asmith": "Alice"}
+	c.Now = func() time.Time { return r.End }
+
+	ds, err := c.Build(context.Background(), 42, nil, r, 7)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	topic := ds.Topics[0]
+	if topic.Anchor.Person != "Alice" || len(topic.Commits) != 1 || topic.Commits[0].Person != "Alice" {
+		t.Fatalf("expected assignee and commit author resolved to Alice: %+v", topic)
+	}
+	if len(ds.OrphanCommits) != 1 || ds.OrphanCommits[0].Person != "" {
+		t.Fatalf("unknown author must stay unresolved: %+v", ds.OrphanCommits)
+	}
+}
@@ -0,0 +1,53 @@
+package executive
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/sprintstats"
+)
+
+// VelocitySprints is how many closed sprints the velocity in a report covers.
+const VelocitySprints = 5
+
+type sprintAdapter struct {
+	db *gorm.DB
+}
+
+// NewSprintAdapter returns a SprintDirectory backed by sprintstats.
+func NewSprintAdapter(db *gorm.DB) SprintDirectory {
+	return &sprintAdapter{db: db}
+}
+
+// Sprints summarizes the user's started sprints that overlap r, and the
+// velocity as of r's end.
+func (a *sprintAdapter) Sprints(ctx context.Context, userID uint, workspaceID *uint, r DateRange) (*SprintReport, error) {
+	db := a.db.WithContext(ctx)
+	query := db.Where("user_id = ? AND state IN ?", userID, []models.SprintState{models.SprintActive, models.SprintClosed}).
+		Where("start_date <= ? AND (end_date IS NULL OR end_date >= ?)", r.End, r.Start)
+	if workspaceID != nil {
+		query = query.Where("workspace_id = ?", *workspaceID)
+	}
+	var sprints []models.Sprint
+	if err := query.Order("start_date asc").Find(&sprints).Error; err != nil {
+		return nil, err
+	}
+
+	report := &SprintReport{Sprints: []sprintstats.Summary{}}
+	for _, sp := range sprints {
+		sum, err := sprintstats.Summarize(db, userID, sp.ID, r.End)
+		if err != nil {
+			return nil, err
+		}
+		report.Sprints = append(report.Sprints, *sum)
+	}
+
+	velocity, err := sprintstats.Velocity(db, userID, workspaceID, VelocitySprints, r.End)
+	if err != nil {
+		return nil, err
+	}
+	report.Velocity = velocity
+	return report, nil
+}
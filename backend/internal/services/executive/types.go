@@ -1,6 +1,10 @@
 package executive
 
-import "time"
+import (
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/services/sprintstats"
+)
 
 type DateRange struct {
 	Start time.Time
@@ -12,6 +16,7 @@ type JiraCard struct {
 	Title       string    `json:"title"`
 	Status      string    `json:"status"`
 	Assignee    string    `json:"assignee"`
+	Person      string    `json:"person,omitempty"` // assignee resolved through the identity map
 	Content     string    `json:"content"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	WorkspaceID *uint     `json:"workspace_id,omitempty"`
@@ -22,12 +27,14 @@ type Commit struct {
 	Message     string    `json:"message"`
 	RepoName    string    `json:"repo_name"`
 	Author      string    `json:"author"`
+	Person      string    `json:"person,omitempty"`
 	CommittedAt time.Time `json:"committed_at"`
 }
 
 type WAMessage struct {
 	MessageID  string    `json:"message_id"`
 	SenderName string    `json:"sender_name"`
+	Person     string    `json:"person,omitempty"`
 	Content    string    `json:"content"`
 	Timestamp  time.Time `json:"timestamp"`
 }
@@ -75,6 +82,14 @@ type CorrelatedDataset struct {
 	OrphanCommits []Commit      `json:"orphan_commits"`
 	Metrics       Metrics       `json:"metrics"`
 	DailyBuckets  []DailyBucket `json:"daily_buckets"`
+	Sprints       *SprintReport `json:"sprints,omitempty"`
+}
+
+// SprintReport is the sprint analytics of a range: every sprint that
+// overlapped it, and the velocity of the last closed sprints.
+type SprintReport struct {
+	Sprints  []sprintstats.Summary       `json:"sprints"`
+	Velocity *sprintstats.VelocityReport `json:"velocity"`
 }
 
 type Suggestion struct {
@@ -35,3 +35,15 @@ type WAHit struct {
 	Message  WAMessage
 	Distance float64
 }
+
+// SprintDirectory reports the sprints that ran during a range. The
+// production implementation is NewSprintAdapter.
+type SprintDirectory interface {
+	Sprints(ctx context.Context, userID uint, workspaceID *uint, r DateRange) (*SprintReport, error)
+}
+
+// PeopleDirectory maps lower-cased author, assignee and sender aliases to a
+// person's display name. The production implementation is identity.Resolver.
+type PeopleDirectory interface {
+	Directory(ctx context.Context, userID uint) (map[string]string, error)
+}
@@ -0,0 +1,140 @@
+// Package flow keeps each Jira card's status history as rows of
+// jira_card_transitions and derives flow metrics from it: cycle time, lead
+// time, time in status and throughput.
+package flow
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/jira"
+)
+
+// changelogTimeLayout is how Jira formats changelog timestamps.
+const changelogTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// Category returns the category of a status, looked up in the workspace's
+// categories (keyed by lower-cased name) and otherwise guessed from common
+// status names.
+func Category(status string, known map[string]string) models.StatusCategory {
+	name := strings.ToLower(strings.TrimSpace(status))
+	if c, ok := known[name]; ok && c != "" {
+		return models.StatusCategory(c)
+	}
+	switch name {
+	case "":
+		return ""
+	case "to do", "todo", "open", "backlog", "new", "selected for development", "reopened":
+		return models.StatusCategoryNew
+	case "done", "closed", "resolved", "released", "cancelled", "canceled", "won't do", "complete", "completed":
+		return models.StatusCategoryDone
+	}
+	return models.StatusCategoryInProgress
+}
+
+// FromChangelog turns the status items of a card's changelog into
+// transitions, oldest first.
+func FromChangelog(card models.JiraCard, changelog []jira.ChangeHistory, categories map[string]string) []models.JiraCardTransition {
+	var out []models.JiraCardTransition
+	for _, h := range changelog {
+		at, err := time.Parse(changelogTimeLayout, h.Created)
+		if err != nil {
+			continue
+		}
+		for _, item := range h.Items {
+			if item.Field != "status" {
+				continue
+			}
+			out = append(out, models.JiraCardTransition{
+				UserID:         card.UserID,
+				WorkspaceID:    card.WorkspaceID,
+				CardKey:        card.Key,
+				FromStatus:     item.FromString,
+				ToStatus:       item.ToString,
+				FromCategory:   Category(item.FromString, categories),
+				ToCategory:     Category(item.ToString, categories),
+				Author:         h.Author,
+				TransitionedAt: at,
+			})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].TransitionedAt.Before(out[j].TransitionedAt) })
+	return out
+}
+
+// FromDetailsJSON reads the transitions of a card from the changelog stored
+// in its DetailsJSON.
+func FromDetailsJSON(card models.JiraCard, categories map[string]string) []models.JiraCardTransition {
+	if card.DetailsJSON == "" {
+		return nil
+	}
+	var detail jira.IssueDetail
+	if json.Unmarshal([]byte(card.DetailsJSON), &detail) != nil {
+		return nil
+	}
+	return FromChangelog(card, detail.Changelog, categories)
+}
+
+// Replace swaps the stored transitions of a card for rows, which should be
+// its full history.
+func Replace(db *gorm.DB, userID uint, cardKey string, rows []models.JiraCardTransition) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND card_key = ?", userID, cardKey).
+			Delete(&models.JiraCardTransition{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// History returns a card's transitions, oldest first.
+func History(db *gorm.DB, userID uint, cardKey string) []models.JiraCardTransition {
+	var rows []models.JiraCardTransition
+	db.Where("user_id = ? AND card_key = ?", userID, cardKey).
+		Order("transitioned_at asc, id asc").
+		Find(&rows)
+	return rows
+}
+
+// Histories returns the transitions of several cards by card key, oldest
+// first.
+func Histories(db *gorm.DB, userID uint, cardKeys []string) (map[string][]models.JiraCardTransition, error) {
+	history := map[string][]models.JiraCardTransition{}
+	if len(cardKeys) == 0 {
+		return history, nil
+	}
+	var rows []models.JiraCardTransition
+	if err := db.Where("user_id = ? AND card_key IN ?", userID, cardKeys).
+		Order("transitioned_at asc, id asc").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		history[r.CardKey] = append(history[r.CardKey], r)
+	}
+	return history, nil
+}
+
+// Backfill fills the transitions of cards synced before they were stored,
+// from the changelog in their DetailsJSON. Categories are guessed from the
+// status names; the next sync of a card replaces them with Jira's.
+func Backfill(db *gorm.DB) {
+	var cards []models.JiraCard
+	db.Where("details_json <> '' AND NOT EXISTS (SELECT 1 FROM jira_card_transitions t WHERE t.user_id = jira_cards.user_id AND t.card_key = jira_cards.card_key)").
+		FindInBatches(&cards, 200, func(tx *gorm.DB, batch int) error {
+			for _, card := range cards {
+				if rows := FromDetailsJSON(card, nil); len(rows) > 0 {
+					db.Create(&rows)
+				}
+			}
+			return nil
+		})
+}
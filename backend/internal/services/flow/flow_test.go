@@ -0,0 +1,90 @@
+package flow
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/jira"
+)
+
+func TestCategory(t *testing.T) {
+	known := map[string]string{"qa": "done"}
+	cases := map[string]models.StatusCategory{
+		"QA":          models.StatusCategoryDone,
+		"To Do":       models.StatusCategoryNew,
+		"In Progress": models.StatusCategoryInProgress,
+		"Closed":      models.StatusCategoryDone,
+		"":            "",
+	}
+	for status, want := range cases {
+		if got := Category(status, known); got != want {
+			t.Errorf("Category(%q) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.JiraCard{}, &models.JiraCardTransition{}, &models.Sprint{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	day := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	created := day.Add(-24 * time.Hour)
+	card := models.JiraCard{UserID: 1, Key: "CORE-1", Status: "Done", Assignee: "Ann", JiraCreatedAt: &created}
+	db.Create(&card)
+	db.Create(&models.JiraCard{UserID: 1, Key: "CORE-2", Status: "In Progress", Assignee: "Bob", JiraCreatedAt: &created})
+
+	changelog := []jira.ChangeHistory{
+		{Created: day.Format(changelogTimeLayout), Items: []jira.ChangeItem{{Field: "status", FromString: "To Do", ToString: "In Progress"}}},
+		{Created: day.Add(10 * time.Hour).Format(changelogTimeLayout), Items: []jira.ChangeItem{{Field: "status", FromString: "In Progress", ToString: "Done"}}},
+	}
+	if err := Replace(db, 1, "CORE-1", FromChangelog(card, changelog, nil)); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	db.Create(&models.JiraCardTransition{UserID: 1, CardKey: "CORE-2", FromStatus: "To Do", ToStatus: "In Progress",
+		FromCategory: models.StatusCategoryNew, ToCategory: models.StatusCategoryInProgress, TransitionedAt: day})
+
+	if got := len(History(db, 1, "CORE-1")); got != 2 {
+		t.Fatalf("history = %d rows, want 2", got)
+	}
+
+	flows, err := Flows(db, Filter{UserID: 1})
+	if err != nil {
+		t.Fatalf("Flows: %v", err)
+	}
+	if len(flows) != 1 || flows[0].Key != "CORE-1" {
+		t.Fatalf("flows = %+v, want only CORE-1", flows)
+	}
+	if *flows[0].CycleHours != 10 || *flows[0].LeadHours != 34 {
+		t.Errorf("cycle = %v, lead = %v, want 10 and 34", *flows[0].CycleHours, *flows[0].LeadHours)
+	}
+
+	statuses, err := TimeInStatus(db, Filter{UserID: 1}, day.Add(20*time.Hour))
+	if err != nil {
+		t.Fatalf("TimeInStatus: %v", err)
+	}
+	got := map[string]StatusTime{}
+	for _, s := range statuses {
+		got[s.Status] = s
+	}
+	// To Do: 24h for each card; In Progress: 10h for CORE-1 and 20h for CORE-2.
+	if got["To Do"].TotalHours != 48 || got["In Progress"].TotalHours != 30 || got["In Progress"].Cards != 2 {
+		t.Errorf("time in status = %+v", statuses)
+	}
+
+	buckets, err := Throughput(db, Filter{UserID: 1}, "assignee")
+	if err != nil {
+		t.Fatalf("Throughput: %v", err)
+	}
+	if len(buckets) != 1 || buckets[0].Key != "Ann" || buckets[0].Count != 1 {
+		t.Errorf("throughput = %+v", buckets)
+	}
+}
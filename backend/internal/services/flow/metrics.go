@@ -0,0 +1,317 @@
+package flow
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+// Filter selects the cards metrics are computed over. From and To bound
+// the time a card was finished for cycle time, lead time and throughput,
+// and clip the intervals counted for time in status.
+type Filter struct {
+	UserID      uint
+	WorkspaceID *uint
+	SprintID    *uint
+	Assignee    string
+	From        *time.Time
+	To          *time.Time
+}
+
+// CardFlow is the flow of one card through its workflow.
+type CardFlow struct {
+	Key        string     `json:"key"`
+	Summary    string     `json:"summary"`
+	Assignee   string     `json:"assignee"`
+	SprintID   *uint      `json:"sprint_id"`
+	CreatedAt  *time.Time `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at"` // first move out of a to-do status
+	DoneAt     *time.Time `json:"done_at"`    // last move into a done status, while still done
+	CycleHours *float64   `json:"cycle_hours"`
+	LeadHours  *float64   `json:"lead_hours"`
+}
+
+// Stats summarises a set of durations in hours.
+type Stats struct {
+	Count  int     `json:"count"`
+	Avg    float64 `json:"avg_hours"`
+	Median float64 `json:"median_hours"`
+	P85    float64 `json:"p85_hours"`
+	Min    float64 `json:"min_hours"`
+	Max    float64 `json:"max_hours"`
+}
+
+// Summarize computes Stats over hours.
+func Summarize(hours []float64) Stats {
+	if len(hours) == 0 {
+		return Stats{}
+	}
+	sorted := append([]float64(nil), hours...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, h := range sorted {
+		sum += h
+	}
+	return Stats{
+		Count:  len(sorted),
+		Avg:    round(sum / float64(len(sorted))),
+		Median: round(percentile(sorted, 0.5)),
+		P85:    round(percentile(sorted, 0.85)),
+		Min:    round(sorted[0]),
+		Max:    round(sorted[len(sorted)-1]),
+	}
+}
+
+// Flows returns the cards matching f that were finished within its range,
+// with their cycle and lead times.
+func Flows(db *gorm.DB, f Filter) ([]CardFlow, error) {
+	cards, history, err := load(db, f)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []CardFlow{}
+	for _, card := range cards {
+		cf := cardFlow(card, history[card.Key])
+		if cf.DoneAt == nil || !inRange(*cf.DoneAt, f) {
+			continue
+		}
+		out = append(out, cf)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DoneAt.Before(*out[j].DoneAt) })
+	return out, nil
+}
+
+func cardFlow(card models.JiraCard, rows []models.JiraCardTransition) CardFlow {
+	cf := CardFlow{
+		Key:       card.Key,
+		Summary:   card.Summary,
+		Assignee:  card.Assignee,
+		SprintID:  card.SprintID,
+		CreatedAt: card.JiraCreatedAt,
+	}
+	for i := range rows {
+		t := rows[i]
+		if cf.StartedAt == nil && t.ToCategory != models.StatusCategoryNew {
+			at := t.TransitionedAt
+			cf.StartedAt = &at
+		}
+		if t.ToCategory == models.StatusCategoryDone {
+			at := t.TransitionedAt
+			cf.DoneAt = &at
+		} else {
+			cf.DoneAt = nil
+		}
+	}
+	if cf.DoneAt == nil {
+		return cf
+	}
+	if cf.StartedAt != nil {
+		h := hours(cf.DoneAt.Sub(*cf.StartedAt))
+		cf.CycleHours = &h
+	}
+	if cf.CreatedAt != nil {
+		h := hours(cf.DoneAt.Sub(*cf.CreatedAt))
+		cf.LeadHours = &h
+	}
+	return cf
+}
+
+// StatusTime is the time cards spent in one status.
+type StatusTime struct {
+	Status     string                `json:"status"`
+	Category   models.StatusCategory `json:"category"`
+	Cards      int                   `json:"cards"`
+	TotalHours float64               `json:"total_hours"`
+	AvgHours   float64               `json:"avg_hours"` // per card that was in the status
+}
+
+// TimeInStatus adds up how long the cards matching f stayed in each status.
+// A card's first status runs from its creation; its current status runs
+// until now. Intervals are clipped to f's range.
+func TimeInStatus(db *gorm.DB, f Filter, now time.Time) ([]StatusTime, error) {
+	cards, history, err := load(db, f)
+	if err != nil {
+		return nil, err
+	}
+
+	type acc struct {
+		category models.StatusCategory
+		total    time.Duration
+		cards    map[string]bool
+	}
+	byStatus := map[string]*acc{}
+	add := func(card, status string, category models.StatusCategory, from, to time.Time) {
+		if f.From != nil && from.Before(*f.From) {
+			from = *f.From
+		}
+		if f.To != nil && to.After(*f.To) {
+			to = *f.To
+		}
+		if status == "" || !to.After(from) {
+			return
+		}
+		a, ok := byStatus[status]
+		if !ok {
+			a = &acc{category: category, cards: map[string]bool{}}
+			byStatus[status] = a
+		}
+		a.total += to.Sub(from)
+		a.cards[card] = true
+	}
+
+	for _, card := range cards {
+		rows := history[card.Key]
+		if len(rows) == 0 {
+			continue
+		}
+		if card.JiraCreatedAt != nil {
+			add(card.Key, rows[0].FromStatus, rows[0].FromCategory, *card.JiraCreatedAt, rows[0].TransitionedAt)
+		}
+		for i, t := range rows {
+			end := now
+			if i+1 < len(rows) {
+				end = rows[i+1].TransitionedAt
+			}
+			add(card.Key, t.ToStatus, t.ToCategory, t.TransitionedAt, end)
+		}
+	}
+
+	out := make([]StatusTime, 0, len(byStatus))
+	for status, a := range byStatus {
+		total := hours(a.total)
+		out = append(out, StatusTime{
+			Status:     status,
+			Category:   a.category,
+			Cards:      len(a.cards),
+			TotalHours: total,
+			AvgHours:   round(total / float64(len(a.cards))),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TotalHours > out[j].TotalHours })
+	return out, nil
+}
+
+// Bucket is the number of cards finished in one group.
+type Bucket struct {
+	Key   string   `json:"key"` // week start, sprint name or assignee
+	Count int      `json:"count"`
+	Cards []string `json:"cards"`
+}
+
+// Throughput counts the cards finished within f's range, grouped by "week"
+// (the Monday starting it), "sprint" or "assignee".
+func Throughput(db *gorm.DB, f Filter, groupBy string) ([]Bucket, error) {
+	flows, err := Flows(db, f)
+	if err != nil {
+		return nil, err
+	}
+
+	sprintNames := map[uint]string{}
+	if groupBy == "sprint" {
+		var sprints []models.Sprint
+		db.Select("id, name").Where("user_id = ?", f.UserID).Find(&sprints)
+		for _, s := range sprints {
+			sprintNames[s.ID] = s.Name
+		}
+	}
+
+	buckets := map[string]*Bucket{}
+	var keys []string
+	for _, cf := range flows {
+		var key string
+		switch groupBy {
+		case "sprint":
+			if cf.SprintID != nil {
+				key = sprintNames[*cf.SprintID]
+			}
+		case "assignee":
+			key = cf.Assignee
+		default:
+			d := cf.DoneAt.In(time.Local)
+			day := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local)
+			key = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)).Format("2006-01-02")
+		}
+		b, ok := buckets[key]
+		if !ok {
+			b = &Bucket{Key: key}
+			buckets[key] = b
+			keys = append(keys, key)
+		}
+		b.Count++
+		b.Cards = append(b.Cards, cf.Key)
+	}
+
+	out := make([]Bucket, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, *buckets[k])
+	}
+	if groupBy == "week" || groupBy == "" {
+		sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	} else {
+		sort.SliceStable(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	}
+	return out, nil
+}
+
+// load fetches the cards matching f and their transitions by card key.
+func load(db *gorm.DB, f Filter) ([]models.JiraCard, map[string][]models.JiraCardTransition, error) {
+	query := db.Select("id, user_id, workspace_id, card_key, summary, status, assignee, sprint_id, jira_created_at").
+		Where("user_id = ?", f.UserID)
+	if f.WorkspaceID != nil {
+		query = query.Where("workspace_id = ?", *f.WorkspaceID)
+	}
+	if f.SprintID != nil {
+		query = query.Where("sprint_id = ?", *f.SprintID)
+	}
+	if f.Assignee != "" {
+		query = query.Where("assignee LIKE ?", "%"+f.Assignee+"%")
+	}
+	var cards []models.JiraCard
+	if err := query.Find(&cards).Error; err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([]string, len(cards))
+	for i, c := range cards {
+		keys[i] = c.Key
+	}
+	history, err := Histories(db, f.UserID, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cards, history, nil
+}
+
+func inRange(t time.Time, f Filter) bool {
+	if f.From != nil && t.Before(*f.From) {
+		return false
+	}
+	if f.To != nil && !t.Before(*f.To) {
+		return false
+	}
+	return true
+}
+
+// percentile interpolates linearly between the closest ranks of sorted.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	pos := p * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+func hours(d time.Duration) float64 {
+	return round(d.Hours())
+}
+
+func round(h float64) float64 {
+	return math.Round(h*100) / 100
+}
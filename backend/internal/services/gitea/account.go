@@ -0,0 +1,30 @@
+package gitea
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cds-id/pdt/backend/internal/services"
+)
+
+// FetchAccount returns the authenticated user.
+func (c *Client) FetchAccount(token string) (*services.AccountInfo, error) {
+	body, err := c.doRequest(c.BaseURL+"/api/v1/user", token)
+	if err != nil {
+		return nil, err
+	}
+	var user struct {
+		Login    string `json:"login"`
+		FullName string `json:"full_name"`
+		Email    string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &user); err != nil {
+		return nil, fmt.Errorf("failed to parse user: %w", err)
+	}
+
+	account := &services.AccountInfo{Username: user.Login, Name: user.FullName}
+	if user.Email != "" {
+		account.Emails = []string{user.Email}
+	}
+	return account, nil
+}
@@ -0,0 +1,234 @@
+package gitea
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/services"
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
+)
+
+const pageSize = 50
+
+// Client talks to the Gitea REST API. Forgejo exposes the same API, so the
+// client works for both.
+type Client struct {
+	BaseURL string
+}
+
+// New returns a client for the Gitea/Forgejo instance served at baseURL
+// (the web URL, e.g. https://git.example.com).
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (c *Client) repoURL(owner, repo string) string {
+	return fmt.Sprintf("%s/api/v1/repos/%s/%s", c.BaseURL, url.PathEscape(owner), url.PathEscape(repo))
+}
+
+type giteaCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name  string    `json:"name"`
+			Email string    `json:"email"`
+			Date  time.Time `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+// FetchCommits fetches all commits across all branches with branch info.
+func (c *Client) FetchCommits(owner, repo, token string, since time.Time) ([]services.CommitInfo, error) {
+	branches, err := c.FetchBranches(owner, repo, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch branches: %w", err)
+	}
+
+	seen := map[string]bool{}
+	var allCommits []services.CommitInfo
+
+	for _, branch := range branches {
+		commits, err := c.FetchBranchCommits(owner, repo, branch, token, since)
+		if err != nil {
+			continue
+		}
+		for _, ci := range commits {
+			if !seen[ci.SHA] {
+				seen[ci.SHA] = true
+				allCommits = append(allCommits, ci)
+			}
+		}
+	}
+
+	return allCommits, nil
+}
+
+func (c *Client) FetchBranches(owner, repo, token string) ([]string, error) {
+	var allBranches []string
+	page := 1
+
+	for {
+		reqURL := fmt.Sprintf("%s/branches?page=%d&limit=%d", c.repoURL(owner, repo), page, pageSize)
+		body, err := c.doRequest(reqURL, token)
+		if err != nil {
+			return nil, err
+		}
+
+		var branches []struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(body, &branches); err != nil {
+			return nil, fmt.Errorf("failed to parse branches: %w", err)
+		}
+
+		for _, b := range branches {
+			allBranches = append(allBranches, b.Name)
+		}
+
+		if len(branches) < pageSize {
+			break
+		}
+		page++
+	}
+
+	return allBranches, nil
+}
+
+// FetchBranchCommits lists commits on branch newer than since. Older Gitea
+// releases ignore the since parameter, so paging also stops at the first
+// commit older than since.
+func (c *Client) FetchBranchCommits(owner, repo, branch, token string, since time.Time) ([]services.CommitInfo, error) {
+	var allCommits []services.CommitInfo
+	page := 1
+
+	for {
+		reqURL := fmt.Sprintf("%s/commits?sha=%s&since=%s&stat=false&verification=false&files=false&page=%d&limit=%d",
+			c.repoURL(owner, repo), url.QueryEscape(branch), url.QueryEscape(since.Format(time.RFC3339)), page, pageSize)
+		body, err := c.doRequest(reqURL, token)
+		if err != nil {
+			return nil, err
+		}
+
+		var commits []giteaCommit
+		if err := json.Unmarshal(body, &commits); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		for _, gc := range commits {
+			if gc.Commit.Author.Date.Before(since) {
+				return allCommits, nil
+			}
+			allCommits = append(allCommits, services.CommitInfo{
+				SHA:         gc.SHA,
+				Message:     gc.Commit.Message,
+				Author:      gc.Commit.Author.Name,
+				AuthorEmail: gc.Commit.Author.Email,
+				Branch:      branch,
+				Date:        gc.Commit.Author.Date,
+			})
+		}
+
+		if len(commits) < pageSize {
+			break
+		}
+		page++
+	}
+
+	return allCommits, nil
+}
+
+func (c *Client) FetchCommitDiff(owner, repo, sha, token string) (*services.CommitDiff, error) {
+	commitBody, err := c.doRequest(fmt.Sprintf("%s/git/commits/%s?stat=false", c.repoURL(owner, repo), sha), token)
+	if err != nil {
+		return nil, fmt.Errorf("fetch commit detail: %w", err)
+	}
+
+	var commitResp struct {
+		SHA    string `json:"sha"`
+		Commit struct {
+			Message string `json:"message"`
+		} `json:"commit"`
+		Files []struct {
+			Filename string `json:"filename"`
+			Status   string `json:"status"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(commitBody, &commitResp); err != nil {
+		return nil, fmt.Errorf("parse commit detail: %w", err)
+	}
+
+	rawDiff, err := c.doRequest(fmt.Sprintf("%s/git/commits/%s.diff", c.repoURL(owner, repo), sha), token)
+	if err != nil {
+		return nil, fmt.Errorf("fetch commit diff: %w", err)
+	}
+	patches := services.SplitUnifiedDiff(string(rawDiff))
+
+	diff := &services.CommitDiff{
+		SHA:     commitResp.SHA,
+		Message: commitResp.Commit.Message,
+	}
+
+	for _, f := range commitResp.Files {
+		patch := patches[f.Filename]
+
+		// Count additions/deletions from diff lines
+		additions, deletions := 0, 0
+		for _, line := range strings.Split(patch, "\n") {
+			if strings.HasPrefix(line, "+") {
+				additions++
+			} else if strings.HasPrefix(line, "-") {
+				deletions++
+			}
+		}
+
+		if len(patch) > 500 {
+			patch = patch[:500] + "\n... (truncated)"
+		}
+
+		diff.Files = append(diff.Files, services.FileChange{
+			Filename:  f.Filename,
+			Status:    f.Status,
+			Additions: additions,
+			Deletions: deletions,
+			Patch:     patch,
+		})
+		diff.Stats.Additions += additions
+		diff.Stats.Deletions += deletions
+		diff.Stats.Total += additions + deletions
+	}
+
+	return diff, nil
+}
+
+func (c *Client) ValidateAccess(owner, repo, token string) error {
+	_, err := c.doRequest(c.repoURL(owner, repo), token)
+	return err
+}
+
+func (c *Client) doRequest(reqURL, token string) ([]byte, error) {
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "token "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpclient.Client.Do(req)
+	if err != nil {
+		return nil, services.NewNetworkError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, services.NewResponseError(resp, "repository")
+	}
+
+	return io.ReadAll(resp.Body)
+}
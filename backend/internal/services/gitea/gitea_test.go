@@ -0,0 +1,75 @@
+package gitea
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_AgainstStandIn(t *testing.T) {
+	mux := http.NewServeMux()
+	base := "/gitea/api/v1/repos/tools/bot"
+	mux.HandleFunc(base, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"full_name":"tools/bot"}`)
+	})
+	mux.HandleFunc(base+"/branches", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"name":"main"},{"name":"feature/OPS-4"}]`)
+	})
+	mux.HandleFunc(base+"/commits", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sha") != "main" {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `[
+			{"sha":"g2","commit":{"message":"OPS-4 add","author":{"name":"Ana","email":"ana@corp","date":"2026-05-02T09:00:00Z"}}},
+			{"sha":"g1","commit":{"message":"init","author":{"name":"Ana","email":"ana@corp","date":"2026-01-02T09:00:00Z"}}}
+		]`)
+	})
+	mux.HandleFunc(base+"/git/commits/g2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"sha":"g2","commit":{"message":"OPS-4 add"},"files":[{"filename":"bot.go","status":"modified"}]}`)
+	})
+	mux.HandleFunc(base+"/git/commits/g2.diff", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "diff --git a/bot.go b/bot.go\n--- a/bot.go\n+++ b/bot.go\n@@ -1,2 +1,2 @@\n-a\n+b\n+c\n")
+	})
+
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL + "/gitea/")
+
+	if err := c.ValidateAccess("tools", "bot", "tok"); err != nil {
+		t.Fatalf("ValidateAccess: %v", err)
+	}
+	if gotAuth != "token tok" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+
+	branches, err := c.FetchBranches("tools", "bot", "tok")
+	if err != nil || strings.Join(branches, ",") != "main,feature/OPS-4" {
+		t.Fatalf("FetchBranches = %v, %v", branches, err)
+	}
+
+	commits, err := c.FetchBranchCommits("tools", "bot", "main", "tok", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(commits) != 1 || commits[0].SHA != "g2" {
+		t.Fatalf("FetchBranchCommits = %+v, %v", commits, err)
+	}
+
+	diff, err := c.FetchCommitDiff("tools", "bot", "g2", "tok")
+	if err != nil {
+		t.Fatalf("FetchCommitDiff: %v", err)
+	}
+	if len(diff.Files) != 1 || diff.Files[0].Additions != 2 || diff.Files[0].Deletions != 1 {
+		t.Errorf("diff = %+v", diff)
+	}
+
+	if err := c.ValidateAccess("tools", "missing", "tok"); err == nil {
+		t.Error("expected error for missing repository")
+	}
+}
@@ -0,0 +1,46 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cds-id/pdt/backend/internal/services"
+)
+
+// FetchAccount returns the authenticated user. Private emails need the
+// user:email scope; without it only the public profile email is returned.
+func (c *Client) FetchAccount(token string) (*services.AccountInfo, error) {
+	body, err := c.doRequest(c.BaseURL+"/user", token)
+	if err != nil {
+		return nil, err
+	}
+	var user struct {
+		Login string `json:"login"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &user); err != nil {
+		return nil, fmt.Errorf("failed to parse user: %w", err)
+	}
+
+	account := &services.AccountInfo{Username: user.Login, Name: user.Name}
+	if user.Email != "" {
+		account.Emails = append(account.Emails, user.Email)
+	}
+
+	if body, err := c.doRequest(c.BaseURL+"/user/emails", token); err == nil {
+		var emails []struct {
+			Email    string `json:"email"`
+			Verified bool   `json:"verified"`
+		}
+		if json.Unmarshal(body, &emails) == nil {
+			for _, e := range emails {
+				if e.Verified {
+					account.Emails = appendUnique(account.Emails, e.Email)
+				}
+			}
+		}
+	}
+
+	return account, nil
+}
@@ -0,0 +1,33 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchAccount(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"login":"ana","name":"Ana Lima","email":null}`)
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"email":"ana@corp.com","verified":true},{"email":"old@corp.com","verified":false},
+			{"email":"1+ana@users.noreply.github.com","verified":true}]`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL}
+	account, err := c.FetchAccount("tok")
+	if err != nil {
+		t.Fatalf("FetchAccount: %v", err)
+	}
+	if account.Username != "ana" || account.Name != "Ana Lima" {
+		t.Fatalf("unexpected account: %+v", account)
+	}
+	if len(account.Emails) != 2 || account.Emails[0] != "ana@corp.com" {
+		t.Fatalf("expected verified emails only, got %v", account.Emails)
+	}
+}
@@ -4,17 +4,31 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/cds-id/pdt/backend/internal/services"
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
 )
 
-const baseURL = "https://api.github.com"
+const defaultBaseURL = "https://api.github.com"
 
-type Client struct{}
+type Client struct {
+	BaseURL string
+}
 
 func New() *Client {
-	return &Client{}
+	return &Client{BaseURL: defaultBaseURL}
+}
+
+// NewWithBaseURL returns a client for a GitHub Enterprise Server instance
+// served at webURL. github.com maps to the public API.
+func NewWithBaseURL(webURL string) *Client {
+	webURL = strings.TrimRight(webURL, "/")
+	if webURL == "" || strings.Contains(webURL, "github.com") {
+		return New()
+	}
+	return &Client{BaseURL: webURL + "/api/v3"}
 }
 
 type githubCommit struct {
@@ -65,7 +79,7 @@ func (c *Client) FetchBranches(owner, repo, token string) ([]string, error) {
 
 	for {
 		url := fmt.Sprintf("%s/repos/%s/%s/branches?per_page=100&page=%d",
-			baseURL, owner, repo, page)
+			c.BaseURL, owner, repo, page)
 
 		body, err := c.doRequest(url, token)
 		if err != nil {
@@ -100,7 +114,7 @@ func (c *Client) FetchBranchCommits(owner, repo, branch, token string, since tim
 
 	for {
 		url := fmt.Sprintf("%s/repos/%s/%s/commits?sha=%s&since=%s&per_page=100&page=%d",
-			baseURL, owner, repo, branch, since.Format(time.RFC3339), page)
+			c.BaseURL, owner, repo, branch, since.Format(time.RFC3339), page)
 
 		body, err := c.doRequest(url, token)
 		if err != nil {
@@ -137,7 +151,7 @@ func (c *Client) FetchBranchCommits(owner, repo, branch, token string, since tim
 }
 
 func (c *Client) FetchCommitDiff(owner, repo, sha, token string) (*services.CommitDiff, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/commits/%s", baseURL, owner, repo, sha)
+	url := fmt.Sprintf("%s/repos/%s/%s/commits/%s", c.BaseURL, owner, repo, sha)
 	body, err := c.doRequest(url, token)
 	if err != nil {
 		return nil, fmt.Errorf("fetch commit diff: %w", err)
@@ -194,7 +208,7 @@ func (c *Client) FetchCommitDiff(owner, repo, sha, token string) (*services.Comm
 }
 
 func (c *Client) ValidateAccess(owner, repo, token string) error {
-	url := fmt.Sprintf("%s/repos/%s/%s", baseURL, owner, repo)
+	url := fmt.Sprintf("%s/repos/%s/%s", c.BaseURL, owner, repo)
 	_, err := c.doRequest(url, token)
 	return err
 }
@@ -208,20 +222,14 @@ func (c *Client) doRequest(url, token string) ([]byte, error) {
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Accept", "application/vnd.github+json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, services.NewNetworkError(err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("unauthorized: invalid token")
-	}
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("repository not found: %s", url)
-	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+		return nil, services.NewResponseError(resp, "repository")
 	}
 
 	var body []byte
@@ -0,0 +1,208 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/services"
+)
+
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+type githubPull struct {
+	Number             int          `json:"number"`
+	Title              string       `json:"title"`
+	State              string       `json:"state"`
+	HTMLURL            string       `json:"html_url"`
+	User               githubUser   `json:"user"`
+	RequestedReviewers []githubUser `json:"requested_reviewers"`
+	Head               struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	MergedAt  *time.Time `json:"merged_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+}
+
+// FetchPullRequests lists pull requests updated since the given time, newest
+// first, with their reviews and inline review comments.
+func (c *Client) FetchPullRequests(owner, repo, token string, since time.Time) ([]services.PullRequestInfo, error) {
+	var prs []services.PullRequestInfo
+	page := 1
+
+	for {
+		url := fmt.Sprintf("%s/repos/%s/%s/pulls?state=all&sort=updated&direction=desc&per_page=100&page=%d",
+			c.BaseURL, owner, repo, page)
+
+		body, err := c.doRequest(url, token)
+		if err != nil {
+			return nil, err
+		}
+
+		var pulls []githubPull
+		if err := json.Unmarshal(body, &pulls); err != nil {
+			return nil, fmt.Errorf("failed to parse pull requests: %w", err)
+		}
+
+		for _, p := range pulls {
+			if p.UpdatedAt.Before(since) {
+				return prs, nil
+			}
+
+			pr := services.PullRequestInfo{
+				Number:       p.Number,
+				Title:        p.Title,
+				State:        p.State,
+				Author:       p.User.Login,
+				SourceBranch: p.Head.Ref,
+				TargetBranch: p.Base.Ref,
+				URL:          p.HTMLURL,
+				CreatedAt:    p.CreatedAt,
+				UpdatedAt:    p.UpdatedAt,
+				MergedAt:     p.MergedAt,
+				ClosedAt:     p.ClosedAt,
+			}
+			if p.MergedAt != nil {
+				pr.State = "merged"
+			}
+			for _, r := range p.RequestedReviewers {
+				pr.Reviewers = appendUnique(pr.Reviewers, r.Login)
+			}
+
+			reviews, err := c.fetchPullReviews(owner, repo, p.Number, token)
+			if err != nil {
+				return nil, fmt.Errorf("pull #%d reviews: %w", p.Number, err)
+			}
+			for _, r := range reviews {
+				pr.Reviewers = appendUnique(pr.Reviewers, r.Author)
+			}
+			pr.Reviews = reviews
+
+			if pr.CommitSHAs, err = c.fetchPullCommits(owner, repo, p.Number, token); err != nil {
+				return nil, fmt.Errorf("pull #%d commits: %w", p.Number, err)
+			}
+
+			prs = append(prs, pr)
+		}
+
+		if len(pulls) < 100 {
+			break
+		}
+		page++
+	}
+
+	return prs, nil
+}
+
+// fetchPullReviews returns a pull request's reviews and inline review
+// comments, following pagination on both lists.
+func (c *Client) fetchPullReviews(owner, repo string, number int, token string) ([]services.ReviewInfo, error) {
+	var reviews []services.ReviewInfo
+
+	for page := 1; ; page++ {
+		body, err := c.doRequest(fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?per_page=100&page=%d", c.BaseURL, owner, repo, number, page), token)
+		if err != nil {
+			return nil, err
+		}
+		var verdicts []struct {
+			ID          int64      `json:"id"`
+			User        githubUser `json:"user"`
+			State       string     `json:"state"`
+			Body        string     `json:"body"`
+			SubmittedAt time.Time  `json:"submitted_at"`
+		}
+		if err := json.Unmarshal(body, &verdicts); err != nil {
+			return nil, fmt.Errorf("failed to parse reviews: %w", err)
+		}
+		for _, v := range verdicts {
+			if v.State == "PENDING" {
+				continue
+			}
+			reviews = append(reviews, services.ReviewInfo{
+				ExternalID:  fmt.Sprintf("review-%d", v.ID),
+				Author:      v.User.Login,
+				State:       strings.ToLower(v.State),
+				Body:        v.Body,
+				SubmittedAt: v.SubmittedAt,
+			})
+		}
+		if len(verdicts) < 100 {
+			break
+		}
+	}
+
+	for page := 1; ; page++ {
+		body, err := c.doRequest(fmt.Sprintf("%s/repos/%s/%s/pulls/%d/comments?per_page=100&page=%d", c.BaseURL, owner, repo, number, page), token)
+		if err != nil {
+			return nil, err
+		}
+		var comments []struct {
+			ID        int64      `json:"id"`
+			User      githubUser `json:"user"`
+			Body      string     `json:"body"`
+			CreatedAt time.Time  `json:"created_at"`
+		}
+		if err := json.Unmarshal(body, &comments); err != nil {
+			return nil, fmt.Errorf("failed to parse review comments: %w", err)
+		}
+		for _, cm := range comments {
+			reviews = append(reviews, services.ReviewInfo{
+				ExternalID:  fmt.Sprintf("comment-%d", cm.ID),
+				Author:      cm.User.Login,
+				State:       "commented",
+				Body:        cm.Body,
+				SubmittedAt: cm.CreatedAt,
+			})
+		}
+		if len(comments) < 100 {
+			break
+		}
+	}
+
+	return reviews, nil
+}
+
+// fetchPullCommits returns the SHAs of the commits on a pull request. GitHub
+// caps the list at 250 commits.
+func (c *Client) fetchPullCommits(owner, repo string, number int, token string) ([]string, error) {
+	var shas []string
+	for page := 1; page <= 3; page++ {
+		body, err := c.doRequest(fmt.Sprintf("%s/repos/%s/%s/pulls/%d/commits?per_page=100&page=%d", c.BaseURL, owner, repo, number, page), token)
+		if err != nil {
+			return nil, err
+		}
+		var commits []struct {
+			SHA string `json:"sha"`
+		}
+		if err := json.Unmarshal(body, &commits); err != nil {
+			return nil, fmt.Errorf("failed to parse pull commits: %w", err)
+		}
+		for _, cm := range commits {
+			shas = append(shas, cm.SHA)
+		}
+		if len(commits) < 100 {
+			break
+		}
+	}
+	return shas, nil
+}
+
+func appendUnique(list []string, v string) []string {
+	if v == "" {
+		return list
+	}
+	for _, s := range list {
+		if s == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
@@ -0,0 +1,87 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchPullRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/app/pulls", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"number":42,"title":"CORE-123 add webhooks","state":"closed","html_url":"https://github.com/org/app/pull/42",
+			 "user":{"login":"ana"},"requested_reviewers":[{"login":"cy"}],"head":{"ref":"feature/webhooks"},"base":{"ref":"main"},
+			 "created_at":"2026-10-10T09:00:00Z","updated_at":"2026-10-12T09:00:00Z","merged_at":"2026-10-12T09:00:00Z","closed_at":"2026-10-12T09:00:00Z"},
+			{"number":7,"title":"old","state":"closed","user":{"login":"ana"},"head":{"ref":"x"},"base":{"ref":"main"},
+			 "created_at":"2026-01-01T09:00:00Z","updated_at":"2026-01-02T09:00:00Z"}
+		]`)
+	})
+	mux.HandleFunc("/repos/org/app/pulls/42/reviews", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":1,"user":{"login":"bob"},"state":"APPROVED","body":"lgtm","submitted_at":"2026-10-11T15:00:00Z"},
+			{"id":2,"user":{"login":"bob"},"state":"PENDING"}]`)
+	})
+	mux.HandleFunc("/repos/org/app/pulls/42/comments", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":9,"user":{"login":"dee"},"body":"nit","created_at":"2026-10-11T14:00:00Z"}]`)
+	})
+	mux.HandleFunc("/repos/org/app/pulls/42/commits", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"sha":"abc123"},{"sha":"def456"}]`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL}
+	prs, err := c.FetchPullRequests("org", "app", "tok", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("FetchPullRequests: %v", err)
+	}
+	if len(prs) != 1 {
+		t.Fatalf("got %d pull requests, want 1 (older PR must stop paging)", len(prs))
+	}
+
+	pr := prs[0]
+	if pr.State != "merged" || pr.SourceBranch != "feature/webhooks" || pr.MergedAt == nil {
+		t.Errorf("pr = %+v", pr)
+	}
+	if strings.Join(pr.Reviewers, ",") != "cy,bob,dee" {
+		t.Errorf("reviewers = %v", pr.Reviewers)
+	}
+	if strings.Join(pr.CommitSHAs, ",") != "abc123,def456" {
+		t.Errorf("commit shas = %v", pr.CommitSHAs)
+	}
+	if len(pr.Reviews) != 2 || pr.Reviews[0].State != "approved" || pr.Reviews[1].State != "commented" {
+		t.Errorf("reviews = %+v", pr.Reviews)
+	}
+}
+
+func TestFetchPullReviews_Paginates(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/app/pulls/1/reviews", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+	mux.HandleFunc("/repos/org/app/pulls/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		n := 100
+		if r.URL.Query().Get("page") == "2" {
+			n = 3
+		}
+		var items []string
+		for i := 0; i < n; i++ {
+			items = append(items, fmt.Sprintf(`{"id":%d,"user":{"login":"bob"},"body":"c"}`, i))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(items, ","))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL}
+	reviews, err := c.fetchPullReviews("org", "app", 1, "tok")
+	if err != nil {
+		t.Fatalf("fetchPullReviews: %v", err)
+	}
+	if len(reviews) != 103 {
+		t.Errorf("got %d review comments, want 103 across two pages", len(reviews))
+	}
+}
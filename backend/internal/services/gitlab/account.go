@@ -0,0 +1,33 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cds-id/pdt/backend/internal/services"
+)
+
+// FetchAccount returns the authenticated user with its primary, public and
+// commit emails.
+func (c *Client) FetchAccount(token string) (*services.AccountInfo, error) {
+	body, err := c.doRequest(c.BaseURL+"/api/v4/user", token)
+	if err != nil {
+		return nil, err
+	}
+	var user struct {
+		Username    string `json:"username"`
+		Name        string `json:"name"`
+		Email       string `json:"email"`
+		PublicEmail string `json:"public_email"`
+		CommitEmail string `json:"commit_email"`
+	}
+	if err := json.Unmarshal(body, &user); err != nil {
+		return nil, fmt.Errorf("failed to parse user: %w", err)
+	}
+
+	account := &services.AccountInfo{Username: user.Username, Name: user.Name}
+	for _, e := range []string{user.CommitEmail, user.Email, user.PublicEmail} {
+		account.Emails = appendUnique(account.Emails, e)
+	}
+	return account, nil
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/cds-id/pdt/backend/internal/services"
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
 )
 
 const defaultBaseURL = "https://gitlab.com"
@@ -243,20 +244,14 @@ func (c *Client) doRequest(reqURL, token string) ([]byte, error) {
 
 	req.Header.Set("PRIVATE-TOKEN", token)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, services.NewNetworkError(err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("unauthorized: invalid token")
-	}
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("project not found")
-	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+		return nil, services.NewResponseError(resp, "project")
 	}
 
 	var body []byte
@@ -0,0 +1,197 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/services"
+)
+
+type gitlabUser struct {
+	Username string `json:"username"`
+}
+
+type gitlabMergeRequest struct {
+	IID          int          `json:"iid"`
+	Title        string       `json:"title"`
+	State        string       `json:"state"`
+	WebURL       string       `json:"web_url"`
+	Author       gitlabUser   `json:"author"`
+	Reviewers    []gitlabUser `json:"reviewers"`
+	SourceBranch string       `json:"source_branch"`
+	TargetBranch string       `json:"target_branch"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+	MergedAt     *time.Time   `json:"merged_at"`
+	ClosedAt     *time.Time   `json:"closed_at"`
+}
+
+// FetchPullRequests lists merge requests updated since the given time with
+// their review notes. Approvals are read from the MR's system notes.
+func (c *Client) FetchPullRequests(owner, repo, token string, since time.Time) ([]services.PullRequestInfo, error) {
+	projectPath := url.PathEscape(owner + "/" + repo)
+	var prs []services.PullRequestInfo
+	page := 1
+
+	for {
+		reqURL := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests?state=all&scope=all&order_by=updated_at&sort=desc&updated_after=%s&per_page=100&page=%d",
+			c.BaseURL, projectPath, url.QueryEscape(since.Format(time.RFC3339)), page)
+
+		body, err := c.doRequest(reqURL, token)
+		if err != nil {
+			return nil, err
+		}
+
+		var mrs []gitlabMergeRequest
+		if err := json.Unmarshal(body, &mrs); err != nil {
+			return nil, fmt.Errorf("failed to parse merge requests: %w", err)
+		}
+
+		for _, mr := range mrs {
+			pr := services.PullRequestInfo{
+				Number:       mr.IID,
+				Title:        mr.Title,
+				State:        mr.State,
+				Author:       mr.Author.Username,
+				SourceBranch: mr.SourceBranch,
+				TargetBranch: mr.TargetBranch,
+				URL:          mr.WebURL,
+				CreatedAt:    mr.CreatedAt,
+				UpdatedAt:    mr.UpdatedAt,
+				MergedAt:     mr.MergedAt,
+				ClosedAt:     mr.ClosedAt,
+			}
+			if mr.State == "opened" || mr.State == "locked" {
+				pr.State = "open"
+			}
+			for _, r := range mr.Reviewers {
+				pr.Reviewers = appendUnique(pr.Reviewers, r.Username)
+			}
+
+			reviews, err := c.fetchMergeRequestNotes(projectPath, mr.IID, mr.Author.Username, token)
+			if err != nil {
+				return nil, fmt.Errorf("merge request !%d notes: %w", mr.IID, err)
+			}
+			for _, r := range reviews {
+				pr.Reviewers = appendUnique(pr.Reviewers, r.Author)
+			}
+			pr.Reviews = reviews
+
+			if pr.CommitSHAs, err = c.fetchMergeRequestCommits(projectPath, mr.IID, token); err != nil {
+				return nil, fmt.Errorf("merge request !%d commits: %w", mr.IID, err)
+			}
+
+			prs = append(prs, pr)
+		}
+
+		if len(mrs) < 100 {
+			break
+		}
+		page++
+	}
+
+	return prs, nil
+}
+
+// fetchMergeRequestNotes returns the discussion notes left by anyone but the
+// MR author, plus approvals recorded as system notes.
+func (c *Client) fetchMergeRequestNotes(projectPath string, iid int, mrAuthor, token string) ([]services.ReviewInfo, error) {
+	var reviews []services.ReviewInfo
+	page := 1
+
+	for {
+		reqURL := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d/notes?sort=asc&per_page=100&page=%d",
+			c.BaseURL, projectPath, iid, page)
+
+		body, err := c.doRequest(reqURL, token)
+		if err != nil {
+			return nil, err
+		}
+
+		var notes []struct {
+			ID        int64      `json:"id"`
+			Body      string     `json:"body"`
+			System    bool       `json:"system"`
+			Author    gitlabUser `json:"author"`
+			CreatedAt time.Time  `json:"created_at"`
+		}
+		if err := json.Unmarshal(body, &notes); err != nil {
+			return nil, fmt.Errorf("failed to parse notes: %w", err)
+		}
+
+		for _, n := range notes {
+			var state string
+			switch {
+			case n.System && n.Body == "approved this merge request":
+				state = "approved"
+			case n.System && n.Body == "requested changes":
+				state = "changes_requested"
+			case !n.System && n.Author.Username != mrAuthor:
+				state = "commented"
+			default:
+				continue
+			}
+			reviews = append(reviews, services.ReviewInfo{
+				ExternalID:  fmt.Sprintf("note-%d", n.ID),
+				Author:      n.Author.Username,
+				State:       state,
+				Body:        n.Body,
+				SubmittedAt: n.CreatedAt,
+			})
+		}
+
+		if len(notes) < 100 {
+			break
+		}
+		page++
+	}
+
+	return reviews, nil
+}
+
+// fetchMergeRequestCommits returns the SHAs of the commits on a merge request.
+func (c *Client) fetchMergeRequestCommits(projectPath string, iid int, token string) ([]string, error) {
+	var shas []string
+	page := 1
+
+	for {
+		reqURL := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d/commits?per_page=100&page=%d",
+			c.BaseURL, projectPath, iid, page)
+
+		body, err := c.doRequest(reqURL, token)
+		if err != nil {
+			return nil, err
+		}
+
+		var commits []struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(body, &commits); err != nil {
+			return nil, fmt.Errorf("failed to parse merge request commits: %w", err)
+		}
+		for _, cm := range commits {
+			shas = append(shas, cm.ID)
+		}
+
+		if len(commits) < 100 {
+			break
+		}
+		page++
+	}
+
+	return shas, nil
+}
+
+func appendUnique(list []string, v string) []string {
+	if v == "" {
+		return list
+	}
+	for _, s := range list {
+		if s == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
@@ -0,0 +1,68 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchPullRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v4/projects/org/app/merge_requests":
+			if r.URL.Query().Get("updated_after") != "2026-10-01T00:00:00Z" {
+				t.Errorf("updated_after = %q", r.URL.Query().Get("updated_after"))
+			}
+			fmt.Fprint(w, `[
+				{"iid":5,"title":"CORE-9 fix login","state":"merged","web_url":"https://gitlab.com/org/app/-/merge_requests/5",
+				 "author":{"username":"ana"},"reviewers":[{"username":"cy"}],"source_branch":"fix/login","target_branch":"main",
+				 "created_at":"2026-10-10T09:00:00Z","updated_at":"2026-10-12T09:00:00Z","merged_at":"2026-10-12T09:00:00Z"},
+				{"iid":6,"title":"wip","state":"opened","author":{"username":"bob"},"source_branch":"wip","target_branch":"main",
+				 "created_at":"2026-10-11T09:00:00Z","updated_at":"2026-10-11T09:00:00Z"}
+			]`)
+		case "/api/v4/projects/org/app/merge_requests/5/notes":
+			fmt.Fprint(w, `[
+				{"id":1,"body":"approved this merge request","system":true,"author":{"username":"bob"},"created_at":"2026-10-11T15:00:00Z"},
+				{"id":2,"body":"nit","author":{"username":"dee"},"created_at":"2026-10-11T14:00:00Z"},
+				{"id":3,"body":"thanks","author":{"username":"ana"},"created_at":"2026-10-11T16:00:00Z"},
+				{"id":4,"body":"added 1 commit","system":true,"author":{"username":"ana"},"created_at":"2026-10-11T17:00:00Z"}
+			]`)
+		case "/api/v4/projects/org/app/merge_requests/5/commits":
+			fmt.Fprint(w, `[{"id":"abc123"},{"id":"def456"}]`)
+		case "/api/v4/projects/org/app/merge_requests/6/notes", "/api/v4/projects/org/app/merge_requests/6/commits":
+			fmt.Fprint(w, `[]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	prs, err := c.FetchPullRequests("org", "app", "tok", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("FetchPullRequests: %v", err)
+	}
+	if len(prs) != 2 {
+		t.Fatalf("got %d merge requests, want 2", len(prs))
+	}
+
+	mr := prs[0]
+	if mr.Number != 5 || mr.State != "merged" || mr.SourceBranch != "fix/login" || mr.MergedAt == nil {
+		t.Errorf("mr = %+v", mr)
+	}
+	if strings.Join(mr.Reviewers, ",") != "cy,bob,dee" {
+		t.Errorf("reviewers = %v", mr.Reviewers)
+	}
+	if strings.Join(mr.CommitSHAs, ",") != "abc123,def456" {
+		t.Errorf("commit shas = %v", mr.CommitSHAs)
+	}
+	if len(mr.Reviews) != 2 || mr.Reviews[0].State != "approved" || mr.Reviews[1].State != "commented" || mr.Reviews[1].ExternalID != "note-2" {
+		t.Errorf("reviews = %+v", mr.Reviews)
+	}
+	if prs[1].State != "open" {
+		t.Errorf("opened MR state = %q, want open", prs[1].State)
+	}
+}
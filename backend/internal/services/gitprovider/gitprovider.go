@@ -0,0 +1,131 @@
+package gitprovider
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services"
+	"github.com/cds-id/pdt/backend/internal/services/bitbucket"
+	"github.com/cds-id/pdt/backend/internal/services/gitea"
+	"github.com/cds-id/pdt/backend/internal/services/github"
+	"github.com/cds-id/pdt/backend/internal/services/gitlab"
+	"gorm.io/gorm"
+)
+
+// Client is implemented by every supported git hosting provider.
+type Client interface {
+	services.CommitProvider
+	services.DiffProvider
+	services.AccountProvider
+}
+
+// New builds the client for provider at baseURL (the instance's web URL;
+// empty means the public SaaS host where one exists).
+func New(provider models.Provider, baseURL, username string) (Client, error) {
+	switch provider {
+	case models.ProviderGitHub:
+		return github.NewWithBaseURL(baseURL), nil
+	case models.ProviderGitLab:
+		return gitlab.New(baseURL), nil
+	case models.ProviderBitbucket:
+		return bitbucket.New(baseURL, username), nil
+	case models.ProviderGitea:
+		if baseURL == "" {
+			return nil, fmt.Errorf("gitea requires a base URL")
+		}
+		return gitea.New(baseURL), nil
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+}
+
+// ForConnection builds the client for a git host connection and decrypts its token.
+func ForConnection(enc *crypto.Encryptor, conn models.GitConnection) (Client, string, error) {
+	client, err := New(conn.Provider, conn.BaseURL, conn.Username)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := enc.Decrypt(conn.Token)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decrypt %s connection token", conn.Provider)
+	}
+	if token == "" {
+		return nil, "", fmt.Errorf("no token configured for connection %q", conn.Name)
+	}
+
+	return client, token, nil
+}
+
+// ForRepository builds the provider client for repo and decrypts its token.
+// Repositories bound to a git host connection use that connection; others
+// fall back to the credentials stored on the user.
+func ForRepository(db *gorm.DB, enc *crypto.Encryptor, user models.User, repo models.Repository) (Client, string, error) {
+	if repo.ConnectionID != nil {
+		var conn models.GitConnection
+		if err := db.Where("id = ? AND user_id = ?", *repo.ConnectionID, user.ID).First(&conn).Error; err != nil {
+			return nil, "", fmt.Errorf("git connection %d not found", *repo.ConnectionID)
+		}
+		return ForConnection(enc, conn)
+	}
+
+	return ForUser(enc, user, repo.Provider)
+}
+
+// ForUser builds the client for the provider credentials stored on the user
+// and decrypts the token.
+func ForUser(enc *crypto.Encryptor, user models.User, provider models.Provider) (Client, string, error) {
+	var baseURL, username, encrypted string
+	switch provider {
+	case models.ProviderGitHub:
+		encrypted = user.GithubToken
+	case models.ProviderGitLab:
+		baseURL = user.GitlabURL
+		encrypted = user.GitlabToken
+	case models.ProviderBitbucket:
+		baseURL = user.BitbucketURL
+		username = user.BitbucketUsername
+		encrypted = user.BitbucketToken
+	case models.ProviderGitea:
+		return nil, "", fmt.Errorf("gitea repositories require a git host connection")
+	}
+
+	client, err := New(provider, baseURL, username)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := enc.Decrypt(encrypted)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decrypt %s token", provider)
+	}
+	if token == "" {
+		return nil, "", fmt.Errorf("no %s token configured", provider)
+	}
+
+	return client, token, nil
+}
+
+// RecordAccount fetches the account behind token and stores it as the
+// user's default author identity for that provider and connection
+// (connectionID 0 for the tokens on User).
+func RecordAccount(db *gorm.DB, userID uint, provider models.Provider, connectionID uint, client Client, token string) (*models.ProviderAccount, error) {
+	info, err := client.FetchAccount(token)
+	if err != nil {
+		return nil, err
+	}
+
+	account := models.ProviderAccount{UserID: userID, Provider: provider, ConnectionID: connectionID}
+	db.Where("user_id = ? AND provider = ? AND connection_id = ?", userID, provider, connectionID).Limit(1).Find(&account)
+	account.Username = info.Username
+	account.Name = info.Name
+	account.Emails = strings.Join(info.Emails, ",")
+	account.FetchedAt = time.Now()
+	if err := db.Save(&account).Error; err != nil {
+		return nil, fmt.Errorf("failed to store %s account: %w", provider, err)
+	}
+	return &account, nil
+}
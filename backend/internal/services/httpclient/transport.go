@@ -0,0 +1,530 @@
+// Package httpclient provides the rate-limit-aware HTTP transport shared by
+// the git provider and Jira clients.
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRate       = 10.0 // requests per second per credential
+	defaultBurst      = 20
+	minRate           = 0.05
+	defaultMaxRetries = 4
+	maxCachedBody     = 1 << 20
+	maxCacheBytes     = 64 << 20
+)
+
+// Client is the shared HTTP client used by the provider clients.
+var Client = &http.Client{Transport: Default, Timeout: 60 * time.Second}
+
+// Default is the transport behind Client.
+var Default = NewTransport(http.DefaultTransport)
+
+// Transport wraps a base RoundTripper with per-credential token buckets,
+// retries with exponential backoff and jitter on 429/5xx, ETag caching for
+// GET requests, and quota tracking from rate-limit response headers.
+type Transport struct {
+	Base       http.RoundTripper
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// MaxWait is the longest a request waits for an exhausted quota to
+	// reset before failing with a RateLimitError.
+	MaxWait time.Duration
+
+	mu         sync.Mutex
+	buckets    map[string]*bucket
+	cache      map[string]*cacheEntry
+	cacheBytes int
+	quotas     map[string]*Quota
+}
+
+// Quota is the last known rate-limit state of one credential on one host.
+type Quota struct {
+	Host       string    `json:"host"`
+	Credential string    `json:"credential"`
+	Limit      int       `json:"limit"`
+	Remaining  int       `json:"remaining"`
+	ResetAt    time.Time `json:"reset_at"`
+	Requests   int64     `json:"requests"`
+	Retries    int64     `json:"retries"`
+	CacheHits  int64     `json:"cache_hits"`
+	Throttled  int64     `json:"throttled"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type bucket struct {
+	tokens float64
+	rate   float64
+	last   time.Time
+	// blockedUntil holds requests back after the provider reported an
+	// exhausted quota or asked us to retry later.
+	blockedUntil time.Time
+}
+
+type cacheEntry struct {
+	etag   string
+	header http.Header
+	body   []byte
+	stored time.Time
+}
+
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{
+		Base:       base,
+		MaxRetries: defaultMaxRetries,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+		MaxWait:    2 * time.Minute,
+		buckets:    map[string]*bucket{},
+		cache:      map[string]*cacheEntry{},
+		quotas:     map[string]*Quota{},
+	}
+}
+
+// RateLimitError is returned when a credential's quota is exhausted for
+// longer than the transport is willing to wait.
+type RateLimitError struct {
+	Host    string
+	ResetAt time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by %s until %s", e.Host, e.ResetAt.Format(time.RFC3339))
+}
+
+// CredentialKey returns the non-reversible identifier the transport uses for
+// a token in quota snapshots.
+func CredentialKey(token string) string {
+	if token == "" {
+		return "anonymous"
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:6])
+}
+
+// credentialKey extracts the token from the auth headers the provider
+// clients set (Bearer/token/Basic Authorization or GitLab's PRIVATE-TOKEN).
+func credentialKey(req *http.Request) string {
+	if t := req.Header.Get("PRIVATE-TOKEN"); t != "" {
+		return CredentialKey(t)
+	}
+	auth := req.Header.Get("Authorization")
+	scheme, value, ok := strings.Cut(auth, " ")
+	if !ok {
+		return CredentialKey(auth)
+	}
+	if strings.EqualFold(scheme, "basic") {
+		if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+			if _, secret, ok := strings.Cut(string(decoded), ":"); ok {
+				return CredentialKey(secret)
+			}
+		}
+	}
+	return CredentialKey(value)
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cred := credentialKey(req)
+	key := req.URL.Host + "|" + cred
+	cacheKey := cred + "|" + req.URL.String()
+	cacheable := req.Method == http.MethodGet
+
+	var cached *cacheEntry
+	if cacheable {
+		t.mu.Lock()
+		cached = t.cache[cacheKey]
+		t.mu.Unlock()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(req.Context(), req.URL.Host, key); err != nil {
+			return nil, err
+		}
+
+		out := req
+		if attempt > 0 || cached != nil {
+			out = req.Clone(req.Context())
+			if req.Body != nil && req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				out.Body = body
+			}
+		}
+		if cached != nil {
+			out.Header.Set("If-None-Match", cached.etag)
+		}
+
+		resp, err := t.Base.RoundTrip(out)
+		t.record(req.URL.Host, cred, key, resp, attempt > 0)
+		if err != nil {
+			if attempt >= t.MaxRetries || !retryableMethod(req) || req.Context().Err() != nil {
+				return nil, err
+			}
+			if !t.sleep(req.Context(), t.backoff(attempt, nil)) {
+				return nil, req.Context().Err()
+			}
+			continue
+		}
+
+		if cached != nil && resp.StatusCode == http.StatusNotModified {
+			resp.Body.Close()
+			t.countCacheHit(key)
+			return cachedResponse(req, resp, cached), nil
+		}
+
+		if shouldRetry(req, resp) && attempt < t.MaxRetries {
+			delay := t.backoff(attempt, resp)
+			if delay > t.MaxWait {
+				return resp, nil
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if !t.sleep(req.Context(), delay) {
+				return nil, req.Context().Err()
+			}
+			continue
+		}
+
+		if cacheable && resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "" {
+			return t.store(cacheKey, resp)
+		}
+		return resp, nil
+	}
+}
+
+// Snapshot returns the current quota state for every credential seen.
+func (t *Transport) Snapshot() []Quota {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]Quota, 0, len(t.quotas))
+	for _, q := range t.quotas {
+		out = append(out, *q)
+	}
+	return out
+}
+
+// QuotasFor returns the quota state of the given credentials.
+func (t *Transport) QuotasFor(credentials ...string) []Quota {
+	want := make(map[string]bool, len(credentials))
+	for _, c := range credentials {
+		want[c] = true
+	}
+
+	out := []Quota{}
+	for _, q := range t.Snapshot() {
+		if want[q.Credential] {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+// wait blocks until the credential's bucket has a token.
+func (t *Transport) wait(ctx context.Context, host, key string) error {
+	for {
+		t.mu.Lock()
+		b := t.buckets[key]
+		if b == nil {
+			b = &bucket{tokens: defaultBurst, rate: defaultRate, last: time.Now()}
+			t.buckets[key] = b
+		}
+
+		now := time.Now()
+		var delay time.Duration
+		if now.Before(b.blockedUntil) {
+			delay = b.blockedUntil.Sub(now)
+			if delay > t.MaxWait {
+				resetAt := b.blockedUntil
+				t.mu.Unlock()
+				return &RateLimitError{Host: host, ResetAt: resetAt}
+			}
+		} else {
+			b.tokens += now.Sub(b.last).Seconds() * b.rate
+			if b.tokens > defaultBurst {
+				b.tokens = defaultBurst
+			}
+			b.last = now
+			if b.tokens >= 1 {
+				b.tokens--
+				t.mu.Unlock()
+				return nil
+			}
+			delay = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		}
+		t.mu.Unlock()
+
+		if !t.sleep(ctx, delay) {
+			return ctx.Err()
+		}
+	}
+}
+
+// record updates request counters and, from rate-limit headers, the quota
+// and the bucket's refill rate so the remaining budget lasts until reset.
+func (t *Transport) record(host, cred, key string, resp *http.Response, retry bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	q := t.quotas[key]
+	if q == nil {
+		q = &Quota{Host: host, Credential: cred, Limit: -1, Remaining: -1}
+		t.quotas[key] = q
+	}
+	q.Requests++
+	if retry {
+		q.Retries++
+	}
+	if resp == nil {
+		return
+	}
+
+	limit, hasLimit := headerInt(resp.Header, "X-RateLimit-Limit", "RateLimit-Limit")
+	remaining, hasRemaining := headerInt(resp.Header, "X-RateLimit-Remaining", "RateLimit-Remaining")
+	reset, hasReset := resetTime(resp.Header)
+
+	if hasLimit {
+		q.Limit = limit
+	}
+	if hasRemaining {
+		q.Remaining = remaining
+	}
+	if hasReset {
+		q.ResetAt = reset
+	}
+	q.UpdatedAt = time.Now()
+
+	b := t.buckets[key]
+	if b == nil {
+		return
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		q.Throttled++
+		if d, ok := retryAfter(resp.Header); ok {
+			b.blockedUntil = time.Now().Add(d)
+		}
+	}
+	if !hasRemaining {
+		return
+	}
+
+	if remaining <= 0 && hasReset && reset.After(time.Now()) {
+		b.blockedUntil = reset
+		log.Printf("[httpclient] quota exhausted for %s credential %s until %s", host, cred, reset.Format(time.RFC3339))
+		return
+	}
+
+	if hasReset {
+		if until := time.Until(reset).Seconds(); until > 0 {
+			rate := float64(remaining) / until
+			if rate < minRate {
+				rate = minRate
+			}
+			if rate > defaultRate {
+				rate = defaultRate
+			}
+			b.rate = rate
+		}
+	}
+	if hasLimit && limit > 0 && remaining*10 < limit && remaining%100 == 0 {
+		log.Printf("[httpclient] %s credential %s low on quota: %d/%d remaining", host, cred, remaining, limit)
+	}
+}
+
+func (t *Transport) countCacheHit(key string) {
+	t.mu.Lock()
+	if q := t.quotas[key]; q != nil {
+		q.CacheHits++
+	}
+	t.mu.Unlock()
+}
+
+// backoff returns how long to wait before the next attempt: Retry-After when
+// the server sent one, otherwise exponential backoff with full jitter.
+func (t *Transport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header); ok {
+			return d
+		}
+	}
+
+	ceiling := t.BaseDelay << attempt
+	if ceiling <= 0 || ceiling > t.MaxDelay {
+		ceiling = t.MaxDelay
+	}
+	return time.Duration(rand.Int64N(int64(ceiling)) + 1)
+}
+
+func (t *Transport) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// store buffers a 200 response carrying an ETag so later identical requests
+// can be revalidated with If-None-Match. Bodies over maxCachedBody are not
+// cached; the caller still gets the whole body, streamed from the network
+// after the part already read.
+func (t *Transport) store(cacheKey string, resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBody+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxCachedBody {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if old := t.cache[cacheKey]; old != nil {
+		t.cacheBytes -= len(old.body)
+		delete(t.cache, cacheKey)
+	}
+	for t.cacheBytes+len(body) > maxCacheBytes && len(t.cache) > 0 {
+		t.evictOldest()
+	}
+	t.cache[cacheKey] = &cacheEntry{
+		etag:   resp.Header.Get("ETag"),
+		header: resp.Header.Clone(),
+		body:   body,
+		stored: time.Now(),
+	}
+	t.cacheBytes += len(body)
+	return resp, nil
+}
+
+func (t *Transport) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for k, e := range t.cache {
+		if oldestKey == "" || e.stored.Before(oldest) {
+			oldestKey, oldest = k, e.stored
+		}
+	}
+	t.cacheBytes -= len(t.cache[oldestKey].body)
+	delete(t.cache, oldestKey)
+}
+
+func cachedResponse(req *http.Request, notModified *http.Response, e *cacheEntry) *http.Response {
+	header := e.header.Clone()
+	// Keep fresh rate-limit headers from the 304.
+	for k, v := range notModified.Header {
+		if strings.Contains(strings.ToLower(k), "ratelimit") {
+			header[k] = v
+		}
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+func retryableMethod(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// shouldRetry reports whether a response is transient. 429s and GitHub's
+// quota 403s are always retried (the request was not processed); 5xx only
+// for idempotent methods.
+func shouldRetry(req *http.Request, resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+	if resp.StatusCode >= 500 && retryableMethod(req) {
+		switch resp.StatusCode {
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+func retryAfter(h http.Header) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return time.Until(at), true
+	}
+	return 0, false
+}
+
+func headerInt(h http.Header, names ...string) (int, bool) {
+	for _, n := range names {
+		if v := h.Get(n); v != "" {
+			if i, err := strconv.Atoi(v); err == nil {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// resetTime reads the quota reset from GitHub/GitLab (unix seconds) or Jira
+// (ISO 8601 timestamp) headers.
+func resetTime(h http.Header) (time.Time, bool) {
+	for _, n := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		v := h.Get(n)
+		if v == "" {
+			continue
+		}
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(secs, 0), true
+		}
+		if at, err := time.Parse(time.RFC3339, v); err == nil {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,156 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient() (*http.Client, *Transport) {
+	tr := NewTransport(http.DefaultTransport)
+	tr.BaseDelay = time.Millisecond
+	tr.MaxDelay = 5 * time.Millisecond
+	return &http.Client{Transport: tr}, tr
+}
+
+func get(t *testing.T, c *http.Client, url, token string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestTransport_RetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			io.WriteString(w, "ok")
+		}
+	}))
+	defer srv.Close()
+
+	c, tr := newTestClient()
+	resp, body := get(t, c, srv.URL, "tok")
+	if resp.StatusCode != http.StatusOK || body != "ok" || calls.Load() != 3 {
+		t.Fatalf("status=%d body=%q calls=%d", resp.StatusCode, body, calls.Load())
+	}
+
+	q := tr.QuotasFor(CredentialKey("tok"))
+	if len(q) != 1 || q[0].Retries != 2 || q[0].Throttled != 1 {
+		t.Errorf("quota = %+v", q)
+	}
+}
+
+func TestTransport_DoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c, _ := newTestClient()
+	if resp, _ := get(t, c, srv.URL, "tok"); resp.StatusCode != http.StatusNotFound || calls.Load() != 1 {
+		t.Errorf("status=%d calls=%d", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestTransport_ETagRevalidation(t *testing.T) {
+	var full atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, `{"name":"main"}`)
+	}))
+	defer srv.Close()
+
+	c, tr := newTestClient()
+	get(t, c, srv.URL+"/branches", "tok")
+	resp, body := get(t, c, srv.URL+"/branches", "tok")
+	if resp.StatusCode != http.StatusOK || body != `{"name":"main"}` {
+		t.Fatalf("revalidated response = %d %q", resp.StatusCode, body)
+	}
+	if full.Load() != 1 {
+		t.Errorf("server sent %d full responses, want 1", full.Load())
+	}
+
+	// A different credential must not see the first one's cache.
+	get(t, c, srv.URL+"/branches", "other")
+	if full.Load() != 2 {
+		t.Errorf("cache leaked across credentials")
+	}
+
+	q := tr.QuotasFor(CredentialKey("tok"))
+	if len(q) != 1 || q[0].CacheHits != 1 || q[0].Remaining != 4999 || q[0].Limit != 5000 {
+		t.Errorf("quota = %+v", q)
+	}
+}
+
+func TestTransport_LargeBodyIsNotTruncated(t *testing.T) {
+	payload := strings.Repeat("x", maxCachedBody+4096)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"big"`)
+		io.WriteString(w, payload)
+	}))
+	defer srv.Close()
+
+	c, tr := newTestClient()
+	if _, body := get(t, c, srv.URL+"/diff", "tok"); body != payload {
+		t.Fatalf("got %d bytes, want %d", len(body), len(payload))
+	}
+	if len(tr.cache) != 0 || tr.cacheBytes != 0 {
+		t.Errorf("oversized body was cached: %d entries, %d bytes", len(tr.cache), tr.cacheBytes)
+	}
+}
+
+func TestTransport_ExhaustedQuotaFailsFast(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Unix()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		io.WriteString(w, "last one")
+	}))
+	defer srv.Close()
+
+	c, _ := newTestClient()
+	get(t, c, srv.URL, "tok")
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Authorization", "Bearer tok")
+	_, err := c.Do(req)
+	if err == nil {
+		t.Fatal("expected rate limit error while quota is exhausted")
+	}
+}
+
+func TestCredentialKey_BasicAuthUsesSecret(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.atlassian.net", nil)
+	req.SetBasicAuth("me@example.com", "api-token")
+	if got := credentialKey(req); got != CredentialKey("api-token") {
+		t.Errorf("credentialKey = %s, want key of the API token", got)
+	}
+}
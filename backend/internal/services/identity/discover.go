@@ -0,0 +1,356 @@
+package identity
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+// Observation is one set of identities seen together in synced data, such
+// as a commit's author name and email or a WhatsApp sender's JID and name.
+type Observation struct {
+	Source      string
+	Email       string
+	GitUsername string
+	Name        string
+	JiraName    string
+	WAJID       string
+}
+
+// DiscoverResult counts what a discovery run added.
+type DiscoverResult struct {
+	People      int `json:"people"`
+	Identities  int `json:"identities"`
+	Suggestions int `json:"suggestions"`
+}
+
+// Discover attaches every author, reviewer, assignee and sender the user has
+// synced to a person, creating people for unseen ones, then suggests merges.
+// Only exact emails, logins, JIDs and Jira display names attach
+// automatically; similar names and email local parts become suggestions.
+func Discover(db *gorm.DB, userID uint) (DiscoverResult, error) {
+	var res DiscoverResult
+
+	observations, err := collect(db, userID)
+	if err != nil {
+		return res, err
+	}
+	for _, o := range observations {
+		if err := attach(db, userID, o, &res); err != nil {
+			return res, err
+		}
+	}
+
+	n, err := Suggest(db, userID)
+	res.Suggestions += n
+	return res, err
+}
+
+func collect(db *gorm.DB, userID uint) ([]Observation, error) {
+	var out []Observation
+
+	var commitAuthors []struct{ Author, AuthorEmail string }
+	if err := db.Model(&models.Commit{}).
+		Joins("JOIN repositories ON repositories.id = commits.repo_id").
+		Where("repositories.user_id = ?", userID).
+		Distinct("commits.author", "commits.author_email").
+		Scan(&commitAuthors).Error; err != nil {
+		return nil, fmt.Errorf("commit authors: %w", err)
+	}
+	for _, a := range commitAuthors {
+		out = append(out, Observation{Source: "commit", Name: a.Author, Email: a.AuthorEmail})
+	}
+
+	var logins []string
+	if err := db.Model(&models.PullRequest{}).
+		Joins("JOIN repositories ON repositories.id = pull_requests.repo_id").
+		Where("repositories.user_id = ?", userID).
+		Distinct().Pluck("pull_requests.author", &logins).Error; err != nil {
+		return nil, fmt.Errorf("pull request authors: %w", err)
+	}
+	var reviewers []string
+	if err := db.Model(&models.PullRequestReview{}).
+		Joins("JOIN pull_requests ON pull_requests.id = pull_request_reviews.pull_request_id").
+		Joins("JOIN repositories ON repositories.id = pull_requests.repo_id").
+		Where("repositories.user_id = ?", userID).
+		Distinct().Pluck("pull_request_reviews.author", &reviewers).Error; err != nil {
+		return nil, fmt.Errorf("pull request reviewers: %w", err)
+	}
+	for _, l := range append(logins, reviewers...) {
+		out = append(out, Observation{Source: "pull_request", GitUsername: l})
+	}
+
+	var commenters []struct{ Author, AuthorEmail string }
+	if err := db.Model(&models.JiraComment{}).
+		Where("user_id = ?", userID).
+		Distinct("author", "author_email").
+		Scan(&commenters).Error; err != nil {
+		return nil, fmt.Errorf("jira comment authors: %w", err)
+	}
+	for _, a := range commenters {
+		out = append(out, Observation{Source: "jira", JiraName: a.Author, Email: a.AuthorEmail})
+	}
+
+	var assignees []string
+	if err := db.Model(&models.JiraCard{}).
+		Where("user_id = ?", userID).
+		Distinct().Pluck("assignee", &assignees).Error; err != nil {
+		return nil, fmt.Errorf("jira assignees: %w", err)
+	}
+	for _, a := range assignees {
+		out = append(out, Observation{Source: "jira", JiraName: a})
+	}
+
+	var senders []struct{ SenderJID, SenderName string }
+	if err := db.Model(&models.WaMessage{}).
+		Joins("JOIN wa_listeners ON wa_listeners.id = wa_messages.wa_listener_id").
+		Joins("JOIN wa_numbers ON wa_numbers.id = wa_listeners.wa_number_id").
+		Where("wa_numbers.user_id = ?", userID).
+		Distinct("wa_messages.sender_jid", "wa_messages.sender_name").
+		Scan(&senders).Error; err != nil {
+		return nil, fmt.Errorf("whatsapp senders: %w", err)
+	}
+	for _, s := range senders {
+		out = append(out, Observation{Source: "whatsapp", WAJID: s.SenderJID, Name: s.SenderName})
+	}
+
+	return out, nil
+}
+
+// identities returns the aliases the observation would add to its person.
+func (o Observation) identities() []models.PersonIdentity {
+	var ids []models.PersonIdentity
+	add := func(kind models.IdentityKind, v string) {
+		if v = Normalize(kind, v); v != "" {
+			ids = append(ids, models.PersonIdentity{Kind: kind, Value: v, Source: o.Source})
+		}
+	}
+	add(models.IdentityEmail, o.Email)
+	add(models.IdentityGitUsername, o.GitUsername)
+	add(models.IdentityGitUsername, noreplyLogin(o.Email))
+	add(models.IdentityWAJID, o.WAJID)
+	add(models.IdentityName, o.Name)
+	return ids
+}
+
+func (o Observation) displayName() string {
+	for _, v := range []string{o.Name, o.JiraName, o.GitUsername} {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	if local, _, ok := strings.Cut(o.Email, "@"); ok && local != "" {
+		return local
+	}
+	return o.WAJID
+}
+
+// noreplyLogin extracts the GitHub login from a noreply commit email such as
+// 1234+octocat@users.noreply.github.com.
+func noreplyLogin(email string) string {
+	local, domain, ok := strings.Cut(strings.ToLower(email), "@")
+	if !ok || domain != "users.noreply.github.com" {
+		return ""
+	}
+	if _, login, ok := strings.Cut(local, "+"); ok {
+		return login
+	}
+	return local
+}
+
+func attach(db *gorm.DB, userID uint, o Observation, res *DiscoverResult) error {
+	ids := o.identities()
+	if len(ids) == 0 && strings.TrimSpace(o.JiraName) == "" {
+		return nil
+	}
+
+	var matched []uint
+	for _, id := range ids {
+		if id.Kind == models.IdentityName {
+			continue // names only suggest, see Suggest
+		}
+		var owner models.PersonIdentity
+		if db.Where("user_id = ? AND kind = ? AND value = ?", userID, id.Kind, id.Value).Limit(1).Find(&owner).Error == nil && owner.ID != 0 {
+			matched = appendUniqueID(matched, owner.PersonID)
+		}
+	}
+	if jiraName := strings.TrimSpace(o.JiraName); jiraName != "" {
+		var p models.Person
+		if db.Where("user_id = ? AND jira_display_name = ?", userID, jiraName).Limit(1).Find(&p).Error == nil && p.ID != 0 {
+			matched = appendUniqueID(matched, p.ID)
+		}
+	}
+
+	var person models.Person
+	if len(matched) == 0 {
+		person = models.Person{UserID: userID, DisplayName: o.displayName(), JiraDisplayName: strings.TrimSpace(o.JiraName)}
+		if err := db.Create(&person).Error; err != nil {
+			return fmt.Errorf("create person: %w", err)
+		}
+		res.People++
+	} else {
+		if err := db.First(&person, matched[0]).Error; err != nil {
+			return err
+		}
+		for _, other := range matched[1:] {
+			n, err := suggest(db, userID, person.ID, other, "seen_together", fmt.Sprintf("seen together in %s data", o.Source))
+			if err != nil {
+				return err
+			}
+			res.Suggestions += n
+		}
+		if person.JiraDisplayName == "" && strings.TrimSpace(o.JiraName) != "" {
+			db.Model(&person).Update("jira_display_name", strings.TrimSpace(o.JiraName))
+		}
+	}
+
+	for _, id := range ids {
+		id.UserID = userID
+		id.PersonID = person.ID
+		// Names (and any alias a concurrent run claimed) already owned by
+		// another person are skipped here and surface as suggestions.
+		tx := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&id)
+		if tx.Error != nil {
+			return fmt.Errorf("add identity: %w", tx.Error)
+		}
+		res.Identities += int(tx.RowsAffected)
+	}
+	return nil
+}
+
+// ignoredKeys are email local parts too generic to suggest a merge on.
+var ignoredKeys = map[string]bool{
+	"noreply": true, "admin": true, "root": true, "info": true, "dev": true, "git": true, "support": true,
+}
+
+type aliasKey struct {
+	personID uint
+	value    string
+	email    bool
+}
+
+// Suggest records a pending merge suggestion for each pair of people whose
+// names, logins or email local parts normalize to the same key, e.g.
+// "John Doe", "john.doe@corp.com" and "johndoe". Pairs already suggested,
+// accepted or rejected are left alone. It returns the number of new ones.
+func Suggest(db *gorm.DB, userID uint) (int, error) {
+	var people []models.Person
+	if err := db.Preload("Identities").Where("user_id = ?", userID).Find(&people).Error; err != nil {
+		return 0, err
+	}
+
+	buckets := map[string][]aliasKey{}
+	add := func(p models.Person, value string, email bool) {
+		source := value
+		if email {
+			source, _, _ = strings.Cut(value, "@")
+			source, _, _ = strings.Cut(source, "+")
+		}
+		k := matchKey(source)
+		if len(k) < 3 || ignoredKeys[k] {
+			return
+		}
+		for _, e := range buckets[k] {
+			if e.personID == p.ID {
+				return
+			}
+		}
+		buckets[k] = append(buckets[k], aliasKey{personID: p.ID, value: value, email: email})
+	}
+	for _, p := range people {
+		add(p, p.DisplayName, false)
+		add(p, p.JiraDisplayName, false)
+		for _, id := range p.Identities {
+			switch id.Kind {
+			case models.IdentityEmail:
+				if noreplyLogin(id.Value) == "" {
+					add(p, id.Value, true)
+				}
+			case models.IdentityGitUsername, models.IdentityName:
+				add(p, id.Value, false)
+			}
+		}
+	}
+
+	// Pairs suggested before, in any status, are skipped without a query.
+	var existing []models.PersonMergeSuggestion
+	if err := db.Select("person_id, other_person_id").Where("user_id = ?", userID).Find(&existing).Error; err != nil {
+		return 0, err
+	}
+	known := make(map[[2]uint]bool, len(existing))
+	for _, s := range existing {
+		known[[2]uint{s.PersonID, s.OtherPersonID}] = true
+	}
+
+	created := 0
+	for _, entries := range buckets {
+		for i := 0; i < len(entries); i++ {
+			for j := i + 1; j < len(entries); j++ {
+				a, b := entries[i], entries[j]
+				lo, hi := orderPair(a.personID, b.personID)
+				if known[[2]uint{lo, hi}] {
+					continue
+				}
+				known[[2]uint{lo, hi}] = true
+				reason := "name"
+				if a.email || b.email {
+					reason = "email"
+				}
+				n, err := suggest(db, userID, a.personID, b.personID, reason, fmt.Sprintf("%q matches %q", a.value, b.value))
+				if err != nil {
+					return created, err
+				}
+				created += n
+			}
+		}
+	}
+	return created, nil
+}
+
+// maxDetailLen is the size of PersonMergeSuggestion.Detail.
+const maxDetailLen = 255
+
+func suggest(db *gorm.DB, userID, a, b uint, reason, detail string) (int, error) {
+	lo, hi := orderPair(a, b)
+	if runes := []rune(detail); len(runes) > maxDetailLen {
+		detail = string(runes[:maxDetailLen-1]) + "…"
+	}
+	s := models.PersonMergeSuggestion{
+		UserID:        userID,
+		PersonID:      lo,
+		OtherPersonID: hi,
+		Reason:        reason,
+		Detail:        detail,
+		Status:        models.MergePending,
+	}
+	tx := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&s)
+	if tx.Error != nil {
+		return 0, fmt.Errorf("create merge suggestion: %w", tx.Error)
+	}
+	return int(tx.RowsAffected), nil
+}
+
+// matchKey lower-cases s and drops everything but letters and digits.
+func matchKey(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func appendUniqueID(list []uint, id uint) []uint {
+	for _, v := range list {
+		if v == id {
+			return list
+		}
+	}
+	return append(list, id)
+}
@@ -0,0 +1,252 @@
+// Package identity maps the author, assignee and sender strings found in
+// commits, pull requests, Jira and WhatsApp onto people.
+package identity
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+// ErrPersonNotFound is returned when a person id does not belong to the user.
+var ErrPersonNotFound = errors.New("person not found")
+
+// Normalize returns the stored form of an identity value. Emails, logins and
+// JIDs compare case-insensitively and are lower-cased; names keep their case.
+func Normalize(kind models.IdentityKind, value string) string {
+	value = strings.TrimSpace(value)
+	if kind == models.IdentityName {
+		return value
+	}
+	return strings.ToLower(value)
+}
+
+// ValidKind reports whether kind is one of the identity kinds.
+func ValidKind(kind models.IdentityKind) bool {
+	switch kind {
+	case models.IdentityEmail, models.IdentityGitUsername, models.IdentityName, models.IdentityWAJID:
+		return true
+	}
+	return false
+}
+
+// Find returns the person term belongs to: any identity value, the Jira
+// account id, or the display or Jira display name. It returns nil, nil when
+// nobody matches.
+func Find(db *gorm.DB, userID uint, term string) (*models.Person, error) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return nil, nil
+	}
+
+	var ident models.PersonIdentity
+	err := db.Where("user_id = ? AND LOWER(value) = ?", userID, strings.ToLower(term)).First(&ident).Error
+	if err == nil {
+		return Get(db, userID, ident.PersonID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var person models.Person
+	err = db.Preload("Identities").
+		Where("user_id = ? AND (jira_account_id = ? OR LOWER(jira_display_name) = ? OR LOWER(display_name) = ?)",
+			userID, term, strings.ToLower(term), strings.ToLower(term)).
+		First(&person).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+// Get loads a person of the user with their identities.
+func Get(db *gorm.DB, userID, personID uint) (*models.Person, error) {
+	var person models.Person
+	err := db.Preload("Identities").Where("id = ? AND user_id = ?", personID, userID).First(&person).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPersonNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+// Aliases lists every spelling of one person, grouped by where it is used.
+type Aliases struct {
+	Emails       []string
+	GitUsernames []string
+	WAJIDs       []string
+	Names        []string // display name, Jira display name and name identities
+}
+
+// AliasesOf collects the aliases of a person loaded with its identities.
+func AliasesOf(p *models.Person) Aliases {
+	var a Aliases
+	a.Names = appendUnique(a.Names, p.DisplayName)
+	a.Names = appendUnique(a.Names, p.JiraDisplayName)
+	for _, id := range p.Identities {
+		switch id.Kind {
+		case models.IdentityEmail:
+			a.Emails = appendUnique(a.Emails, id.Value)
+		case models.IdentityGitUsername:
+			a.GitUsernames = appendUnique(a.GitUsernames, id.Value)
+		case models.IdentityWAJID:
+			a.WAJIDs = appendUnique(a.WAJIDs, id.Value)
+		case models.IdentityName:
+			a.Names = appendUnique(a.Names, id.Value)
+		}
+	}
+	return a
+}
+
+// HasName reports whether s equals one of the person's names, ignoring case.
+func (a Aliases) HasName(s string) bool {
+	for _, n := range a.Names {
+		if strings.EqualFold(n, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeCommits narrows a query joined on the commits table to commits
+// authored under any of the person's emails, logins or names.
+func (a Aliases) ScopeCommits(query *gorm.DB) *gorm.DB {
+	authors := append(append([]string{}, a.Names...), a.GitUsernames...)
+	switch {
+	case len(a.Emails) > 0 && len(authors) > 0:
+		return query.Where("(LOWER(commits.author_email) IN ? OR commits.author IN ?)", a.Emails, authors)
+	case len(a.Emails) > 0:
+		return query.Where("LOWER(commits.author_email) IN ?", a.Emails)
+	case len(authors) > 0:
+		return query.Where("commits.author IN ?", authors)
+	}
+	return query.Where("1 = 0")
+}
+
+// ScopeCommitAuthor narrows a query joined on the commits table to author:
+// every alias of the person it resolves to, or else a substring match on the
+// raw author name and email.
+func ScopeCommitAuthor(db *gorm.DB, userID uint, author string, query *gorm.DB) *gorm.DB {
+	if person, _ := Find(db, userID, author); person != nil {
+		return AliasesOf(person).ScopeCommits(query)
+	}
+	return query.Where("(commits.author LIKE ? OR commits.author_email LIKE ?)", "%"+author+"%", "%"+author+"%")
+}
+
+// Directory maps every known alias of the user's people, lower-cased, to
+// the person's display name.
+func Directory(db *gorm.DB, userID uint) (map[string]string, error) {
+	var people []models.Person
+	if err := db.Preload("Identities").Where("user_id = ?", userID).Find(&people).Error; err != nil {
+		return nil, err
+	}
+
+	dir := make(map[string]string)
+	for _, p := range people {
+		add := func(alias string) {
+			if alias = strings.ToLower(strings.TrimSpace(alias)); alias != "" {
+				dir[alias] = p.DisplayName
+			}
+		}
+		add(p.DisplayName)
+		add(p.JiraDisplayName)
+		add(p.JiraAccountID)
+		for _, id := range p.Identities {
+			add(id.Value)
+		}
+	}
+	return dir, nil
+}
+
+// Resolver serves Directory to callers that only hold an interface, such as
+// the executive correlator.
+type Resolver struct {
+	DB *gorm.DB
+}
+
+func (r *Resolver) Directory(ctx context.Context, userID uint) (map[string]string, error) {
+	return Directory(r.DB.WithContext(ctx), userID)
+}
+
+// Merge folds drop into keep: identities move over, empty Jira fields are
+// filled from drop, and drop is deleted. Pending suggestions involving drop
+// are discarded; the next discovery run re-suggests against keep.
+func Merge(db *gorm.DB, userID, keepID, dropID uint) (*models.Person, error) {
+	if keepID == dropID {
+		return nil, errors.New("cannot merge a person into itself")
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		keep, err := Get(tx, userID, keepID)
+		if err != nil {
+			return err
+		}
+		drop, err := Get(tx, userID, dropID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.PersonIdentity{}).Where("person_id = ?", drop.ID).
+			Update("person_id", keep.ID).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]any{}
+		if keep.JiraAccountID == "" && drop.JiraAccountID != "" {
+			updates["jira_account_id"] = drop.JiraAccountID
+		}
+		if keep.JiraDisplayName == "" && drop.JiraDisplayName != "" {
+			updates["jira_display_name"] = drop.JiraDisplayName
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(keep).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		lo, hi := orderPair(keep.ID, drop.ID)
+		if err := tx.Model(&models.PersonMergeSuggestion{}).
+			Where("user_id = ? AND person_id = ? AND other_person_id = ?", userID, lo, hi).
+			Update("status", models.MergeAccepted).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND (person_id = ? OR other_person_id = ?) AND NOT (person_id = ? AND other_person_id = ?)",
+			userID, drop.ID, drop.ID, lo, hi).Delete(&models.PersonMergeSuggestion{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(drop).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Get(db, userID, keepID)
+}
+
+func orderPair(a, b uint) (uint, uint) {
+	if a < b {
+		return a, b
+	}
+	return b, a
+}
+
+func appendUnique(list []string, v string) []string {
+	if v == "" {
+		return list
+	}
+	for _, s := range list {
+		if s == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
@@ -0,0 +1,173 @@
+package identity
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+func setupIdentityDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Repository{}, &models.Commit{}, &models.PullRequest{}, &models.PullRequestReview{},
+		&models.JiraCard{}, &models.JiraComment{}, &models.WaNumber{}, &models.WaListener{}, &models.WaMessage{},
+		&models.Person{}, &models.PersonIdentity{}, &models.PersonMergeSuggestion{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestDiscover_AttachesExactAndSuggestsSimilar(t *testing.T) {
+	db := setupIdentityDB(t)
+	repo := models.Repository{UserID: 1, Owner: "o", Name: "r", Provider: models.ProviderGitHub, URL: "https://github.com/o/r"}
+	db.Create(&repo)
+
+	now := time.Now()
+	db.Create(&models.Commit{RepoID: repo.ID, SHA: "a1", Author: "Jane Doe", AuthorEmail: "Jane@Corp.com", Date: now})
+	db.Create(&models.Commit{RepoID: repo.ID, SHA: "a2", Author: "Jane Doe", AuthorEmail: "jane@corp.com", Date: now})
+	db.Create(&models.Commit{RepoID: repo.ID, SHA: "a3", Author: "Jane Doe", AuthorEmail: "1234+janed@users.noreply.github.com", Date: now})
+	db.Create(&models.PullRequest{RepoID: repo.ID, Number: 1, Author: "janed", OpenedAt: now})
+	db.Create(&models.JiraComment{UserID: 1, CardKey: "PROJ-1", CommentID: "c1", Author: "Jane Doe", AuthorEmail: "jane@corp.com"})
+	db.Create(&models.JiraCard{UserID: 1, Key: "PROJ-1", Assignee: "Jane Doe"})
+
+	res, err := Discover(db, 1)
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if res.People != 2 {
+		t.Fatalf("expected 2 people (corp email, noreply login), got %+v", res)
+	}
+
+	jane, err := Find(db, 1, "JANE@corp.com")
+	if err != nil || jane == nil {
+		t.Fatalf("Find by email: %v %v", jane, err)
+	}
+	if jane.JiraDisplayName != "Jane Doe" {
+		t.Fatalf("Jira comment email should attach the Jira name: %+v", jane)
+	}
+	login, _ := Find(db, 1, "janed")
+	if login == nil || login.ID == jane.ID {
+		t.Fatalf("noreply commit and PR author should share a separate person: %+v", login)
+	}
+
+	var suggestions []models.PersonMergeSuggestion
+	db.Where("user_id = ? AND status = ?", 1, models.MergePending).Find(&suggestions)
+	if len(suggestions) != 1 || suggestions[0].Reason != "name" {
+		t.Fatalf("expected one name suggestion for the two Jane Does, got %+v", suggestions)
+	}
+
+	again, err := Discover(db, 1)
+	if err != nil || again.People != 0 || again.Identities != 0 || again.Suggestions != 0 {
+		t.Fatalf("second run must be a no-op: %+v %v", again, err)
+	}
+
+	merged, err := Merge(db, 1, jane.ID, login.ID)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	a := AliasesOf(merged)
+	if len(a.Emails) != 2 || len(a.GitUsernames) != 1 || !a.HasName("jane doe") {
+		t.Fatalf("merged aliases incomplete: %+v", a)
+	}
+	db.First(&suggestions[0], suggestions[0].ID)
+	if suggestions[0].Status != models.MergeAccepted {
+		t.Fatalf("merged pair suggestion should be accepted, got %s", suggestions[0].Status)
+	}
+
+	var count int64
+	AliasesOf(merged).ScopeCommits(db.Model(&models.Commit{})).Count(&count)
+	if count != 3 {
+		t.Fatalf("expected all 3 commits attributed to the merged person, got %d", count)
+	}
+}
+
+func TestSuggest_EmailLocalPartMatchesLogin(t *testing.T) {
+	db := setupIdentityDB(t)
+	a := models.Person{UserID: 1, DisplayName: "J. Smith", Identities: []models.PersonIdentity{
+		{UserID: 1, Kind: models.IdentityEmail, Value: "john.smith+ci@corp.com"},
+	}}
+	b := models.Person{UserID: 1, DisplayName: "johnsmith", Identities: []models.PersonIdentity{
+		{UserID: 1, Kind: models.IdentityGitUsername, Value: "johnsmith"},
+	}}
+	c := models.Person{UserID: 1, DisplayName: "Admin", Identities: []models.PersonIdentity{
+		{UserID: 1, Kind: models.IdentityEmail, Value: "admin@corp.com"},
+	}}
+	db.Create(&a)
+	db.Create(&b)
+	db.Create(&c)
+
+	n, err := Suggest(db, 1)
+	if err != nil || n != 1 {
+		t.Fatalf("expected exactly one suggestion, got %d (%v)", n, err)
+	}
+	var s models.PersonMergeSuggestion
+	db.First(&s)
+	if s.PersonID != a.ID || s.OtherPersonID != b.ID || s.Reason != "email" {
+		t.Fatalf("unexpected suggestion: %+v", s)
+	}
+}
+
+func TestSuggest_LongNamesFitDetail(t *testing.T) {
+	db := setupIdentityDB(t)
+	long := strings.Repeat("Very Long Name ", 20)
+	db.Create(&models.Person{UserID: 1, DisplayName: long})
+	db.Create(&models.Person{UserID: 1, DisplayName: strings.ToLower(long)})
+
+	if n, err := Suggest(db, 1); err != nil || n != 1 {
+		t.Fatalf("expected one suggestion, got %d (%v)", n, err)
+	}
+	var s models.PersonMergeSuggestion
+	db.First(&s)
+	if n := utf8.RuneCountInString(s.Detail); n > maxDetailLen {
+		t.Errorf("detail is %d characters, want at most %d", n, maxDetailLen)
+	}
+	if n, _ := Suggest(db, 1); n != 0 {
+		t.Errorf("second run suggested %d pairs again", n)
+	}
+}
+
+func TestAuthorFilter_EmailsOnlyScopesPullRequestsByLogin(t *testing.T) {
+	db := setupIdentityDB(t)
+	if err := db.AutoMigrate(&models.ProviderAccount{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	db.Create(&models.PullRequest{RepoID: 1, Number: 1, Author: "ana-gh"})
+	db.Create(&models.PullRequest{RepoID: 1, Number: 2, Author: "bruno"})
+	user := models.User{ID: 1, AuthorEmails: "ana@corp.com"}
+	authors := func() []string {
+		a, ok := AuthorFilter(db, user)
+		if !ok {
+			t.Fatal("expected a filter for a user with emails")
+		}
+		var got []string
+		a.ScopePullRequests(db.Model(&models.PullRequest{})).Order("number").Pluck("author", &got)
+		return got
+	}
+
+	if got := authors(); len(got) != 2 {
+		t.Errorf("no login known: authors = %v, want every PR", got)
+	}
+
+	db.Create(&models.ProviderAccount{UserID: 1, Provider: models.ProviderGitHub, Username: "Ana-GH"})
+	if got := authors(); len(got) != 1 || got[0] != "ana-gh" {
+		t.Errorf("provider account: authors = %v, want [ana-gh]", got)
+	}
+
+	db.Where("1 = 1").Delete(&models.ProviderAccount{})
+	db.Create(&models.Person{UserID: 1, DisplayName: "Ana", Identities: []models.PersonIdentity{
+		{UserID: 1, Kind: models.IdentityEmail, Value: "ana@corp.com"},
+		{UserID: 1, Kind: models.IdentityGitUsername, Value: "ana-gh"},
+	}})
+	if got := authors(); len(got) != 1 || got[0] != "ana-gh" {
+		t.Errorf("identity map: authors = %v, want [ana-gh]", got)
+	}
+}
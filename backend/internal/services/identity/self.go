@@ -0,0 +1,111 @@
+package identity
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+// AuthorFilter returns the aliases whose commits, pull requests and reviews
+// count as the user's own work. The base is the emails and usernames on the
+// user's profile or, when both are empty, the provider accounts recorded at
+// validation time; each entry is widened through the identity map so commit
+// author names match too. A profile listing only emails takes its logins from
+// the identity map, then from the provider accounts. ok is false when the
+// user includes all authors or no identity is known yet, in which case
+// callers should not filter.
+func AuthorFilter(db *gorm.DB, user models.User) (Aliases, bool) {
+	var a Aliases
+	if user.IncludeAllAuthors {
+		return a, false
+	}
+
+	for _, e := range splitList(user.AuthorEmails) {
+		a.Emails = appendUnique(a.Emails, Normalize(models.IdentityEmail, e))
+	}
+	for _, u := range splitList(user.AuthorUsernames) {
+		a.GitUsernames = appendUnique(a.GitUsernames, Normalize(models.IdentityGitUsername, u))
+	}
+
+	if len(a.Emails) == 0 && len(a.GitUsernames) == 0 {
+		var accounts []models.ProviderAccount
+		db.Where("user_id = ?", user.ID).Find(&accounts)
+		for _, acc := range accounts {
+			for _, e := range splitList(acc.Emails) {
+				a.Emails = appendUnique(a.Emails, Normalize(models.IdentityEmail, e))
+			}
+			a.GitUsernames = appendUnique(a.GitUsernames, Normalize(models.IdentityGitUsername, acc.Username))
+			a.Names = appendUnique(a.Names, strings.TrimSpace(acc.Name))
+		}
+	}
+
+	if len(a.Emails) == 0 && len(a.GitUsernames) == 0 {
+		return a, false
+	}
+
+	seen := map[uint]bool{}
+	for _, term := range append(append([]string{}, a.Emails...), a.GitUsernames...) {
+		person, _ := Find(db, user.ID, term)
+		if person == nil || seen[person.ID] {
+			continue
+		}
+		seen[person.ID] = true
+		a = a.merge(AliasesOf(person))
+	}
+
+	if len(a.GitUsernames) == 0 {
+		var logins []string
+		db.Model(&models.ProviderAccount{}).Where("user_id = ? AND username <> ''", user.ID).Pluck("username", &logins)
+		for _, u := range logins {
+			a.GitUsernames = appendUnique(a.GitUsernames, Normalize(models.IdentityGitUsername, u))
+		}
+	}
+	return a, true
+}
+
+func (a Aliases) merge(o Aliases) Aliases {
+	for _, v := range o.Emails {
+		a.Emails = appendUnique(a.Emails, v)
+	}
+	for _, v := range o.GitUsernames {
+		a.GitUsernames = appendUnique(a.GitUsernames, v)
+	}
+	for _, v := range o.WAJIDs {
+		a.WAJIDs = appendUnique(a.WAJIDs, v)
+	}
+	for _, v := range o.Names {
+		a.Names = appendUnique(a.Names, v)
+	}
+	return a
+}
+
+// ScopePullRequests narrows a query on pull_requests to PRs opened by one of
+// the person's logins. PRs only carry a login, so the query is left as is
+// when none is known.
+func (a Aliases) ScopePullRequests(query *gorm.DB) *gorm.DB {
+	if len(a.GitUsernames) == 0 {
+		return query
+	}
+	return query.Where("LOWER(pull_requests.author) IN ?", a.GitUsernames)
+}
+
+// ScopeReviews narrows a query on pull_request_reviews to reviews left by
+// one of the person's logins, or leaves it as is when none is known.
+func (a Aliases) ScopeReviews(query *gorm.DB) *gorm.DB {
+	if len(a.GitUsernames) == 0 {
+		return query
+	}
+	return query.Where("LOWER(pull_request_reviews.author) IN ?", a.GitUsernames)
+}
+
+func splitList(csv string) []string {
+	var out []string
+	for _, v := range strings.Split(csv, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -1,18 +1,37 @@
 package jira
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/cds-id/pdt/backend/internal/services"
+	"github.com/cds-id/pdt/backend/internal/services/adf"
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
 )
 
 type Client struct {
+	// Workspace is a Cloud site host ("acme.atlassian.net") or the full base
+	// URL of a Server / Data Center install ("https://jira.acme.lan:8443/jira").
 	Workspace string
 	Email     string
 	Token     string
+	// Bearer sends Token as a personal access token instead of using Basic
+	// auth with Email.
+	Bearer bool
+	// DataCenter targets Jira Server / Data Center: REST API v2, and plain
+	// text instead of ADF for comment bodies.
+	DataCenter bool
+	// StoryPointsField is the custom field FetchIssue reads story points
+	// from; see FetchFieldIDs. Empty skips them.
+	StoryPointsField string
 }
 
 func New(workspace, email, token string) *Client {
@@ -39,15 +58,19 @@ type CardInfo struct {
 }
 
 type IssueDetail struct {
-	Key         string          `json:"key"`
-	Summary     string          `json:"summary"`
-	Description string          `json:"description,omitempty"`
-	Status      string          `json:"status"`
-	Assignee    string          `json:"assignee"`
-	IssueType   string          `json:"issue_type"`
-	Parent      *IssueRef       `json:"parent,omitempty"`
-	Subtasks    []IssueRef      `json:"subtasks,omitempty"`
-	Changelog   []ChangeHistory `json:"changelog,omitempty"`
+	Key         string `json:"key"`
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"` // Markdown
+	// DescriptionADF is the description as Jira Cloud sent it, kept for
+	// round-tripping. Empty for plain-text descriptions.
+	DescriptionADF json.RawMessage `json:"description_adf,omitempty"`
+	Status         string          `json:"status"`
+	Assignee       string          `json:"assignee"`
+	IssueType      string          `json:"issue_type"`
+	StoryPoints    *float64        `json:"story_points,omitempty"`
+	Parent         *IssueRef       `json:"parent,omitempty"`
+	Subtasks       []IssueRef      `json:"subtasks,omitempty"`
+	Changelog      []ChangeHistory `json:"changelog,omitempty"`
 }
 
 type IssueRef struct {
@@ -69,15 +92,27 @@ type ChangeItem struct {
 	ToString   string `json:"to_string"`
 }
 
+// Board types as the agile API reports them. Team-managed projects have
+// "simple" boards, which may or may not use sprints.
+const (
+	BoardScrum  = "scrum"
+	BoardKanban = "kanban"
+	BoardSimple = "simple"
+)
+
+// Board is a Jira Software board.
+type Board struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
 type boardResponse struct {
-	Values []struct {
-		ID   int    `json:"id"`
-		Name string `json:"name"`
-	} `json:"values"`
-	StartAt    int  `json:"startAt"`
-	MaxResults int  `json:"maxResults"`
-	Total      int  `json:"total"`
-	IsLast     bool `json:"isLast"`
+	Values     []Board `json:"values"`
+	StartAt    int     `json:"startAt"`
+	MaxResults int     `json:"maxResults"`
+	Total      int     `json:"total"`
+	IsLast     bool    `json:"isLast"`
 }
 
 type sprintResponse struct {
@@ -89,11 +124,23 @@ type sprintResponse struct {
 }
 
 func (c *Client) baseURL() string {
+	if strings.Contains(c.Workspace, "://") {
+		return strings.TrimRight(c.Workspace, "/") + "/rest"
+	}
 	return fmt.Sprintf("https://%s/rest", c.Workspace)
 }
 
-func (c *Client) FetchBoards() ([]int, error) {
-	var ids []int
+// apiURL returns the base of the platform REST API: v3 on Cloud, v2 on
+// Server / Data Center, which has no v3.
+func (c *Client) apiURL() string {
+	if c.DataCenter {
+		return c.baseURL() + "/api/2"
+	}
+	return c.baseURL() + "/api/3"
+}
+
+func (c *Client) FetchBoards() ([]Board, error) {
+	var boards []Board
 	startAt := 0
 	maxResults := 50
 
@@ -110,9 +157,7 @@ func (c *Client) FetchBoards() ([]int, error) {
 			return nil, fmt.Errorf("failed to parse boards: %w", err)
 		}
 
-		for _, b := range resp.Values {
-			ids = append(ids, b.ID)
-		}
+		boards = append(boards, resp.Values...)
 
 		if len(resp.Values) == 0 || resp.IsLast || startAt+len(resp.Values) >= resp.Total {
 			break
@@ -120,7 +165,7 @@ func (c *Client) FetchBoards() ([]int, error) {
 		startAt += len(resp.Values)
 	}
 
-	return ids, nil
+	return boards, nil
 }
 
 func (c *Client) FetchSprints(boardID int) ([]SprintInfo, error) {
@@ -170,8 +215,8 @@ func (c *Client) FetchSprintIssues(sprintID int) ([]CardInfo, error) {
 			Issues []struct {
 				Key    string `json:"key"`
 				Fields struct {
-					Summary  string `json:"summary"`
-					Status   struct{ Name string } `json:"status"`
+					Summary  string                        `json:"summary"`
+					Status   struct{ Name string }         `json:"status"`
 					Assignee *struct{ DisplayName string } `json:"assignee"`
 				} `json:"fields"`
 			} `json:"issues"`
@@ -210,7 +255,11 @@ func (c *Client) FetchSprintIssues(sprintID int) ([]CardInfo, error) {
 }
 
 func (c *Client) FetchIssue(key string) (*IssueDetail, error) {
-	reqURL := fmt.Sprintf("%s/api/2/issue/%s?fields=summary,description,status,assignee,parent,subtasks,issuetype&expand=changelog", c.baseURL(), key)
+	fields := "summary,description,status,assignee,parent,subtasks,issuetype"
+	if c.StoryPointsField != "" {
+		fields += "," + c.StoryPointsField
+	}
+	reqURL := fmt.Sprintf("%s/issue/%s?fields=%s&expand=changelog", c.apiURL(), key, fields)
 	body, err := c.doRequest(reqURL)
 	if err != nil {
 		return nil, err
@@ -281,30 +330,17 @@ func (c *Client) FetchIssue(key string) (*IssueDetail, error) {
 		IssueType: raw.Fields.IssueType.Name,
 	}
 
-	// Parse description — can be a plain string or ADF (Atlassian Document Format) object
-	if len(raw.Fields.Description) > 0 {
-		var descStr string
-		if err := json.Unmarshal(raw.Fields.Description, &descStr); err == nil {
-			detail.Description = descStr
-		} else {
-			// ADF format — extract text content
-			var adf struct {
-				Content []struct {
-					Content []struct {
-						Text string `json:"text"`
-					} `json:"content"`
-				} `json:"content"`
-			}
-			if err := json.Unmarshal(raw.Fields.Description, &adf); err == nil {
-				var texts []string
-				for _, block := range adf.Content {
-					for _, inline := range block.Content {
-						if inline.Text != "" {
-							texts = append(texts, inline.Text)
-						}
-					}
-				}
-				detail.Description = strings.Join(texts, "\n")
+	detail.Description = DescriptionText(raw.Fields.Description)
+	detail.DescriptionADF = RawADF(raw.Fields.Description)
+
+	if c.StoryPointsField != "" {
+		var custom struct {
+			Fields map[string]json.RawMessage `json:"fields"`
+		}
+		if json.Unmarshal(body, &custom) == nil {
+			var points *float64
+			if json.Unmarshal(custom.Fields[c.StoryPointsField], &points) == nil {
+				detail.StoryPoints = points
 			}
 		}
 	}
@@ -349,16 +385,43 @@ func (c *Client) FetchIssue(key string) (*IssueDetail, error) {
 	return detail, nil
 }
 
+// DescriptionText returns a description or comment body as Markdown. The
+// body is a plain string on REST API v2 and Data Center, and an ADF
+// (Atlassian Document Format) document on Cloud's v3.
+func DescriptionText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var descStr string
+	if err := json.Unmarshal(raw, &descStr); err == nil {
+		return descStr
+	}
+	text, err := adf.ToMarkdown(raw)
+	if err != nil {
+		return ""
+	}
+	return text
+}
+
+// RawADF returns raw when it is an ADF document, and nil for plain text.
+func RawADF(raw json.RawMessage) json.RawMessage {
+	if adf.IsDocument(raw) {
+		return raw
+	}
+	return nil
+}
+
 type CommentInfo struct {
-	ID          string    `json:"id"`
-	Author      string    `json:"author"`
-	AuthorEmail string    `json:"author_email"`
-	Body        string    `json:"body"`
-	Created     time.Time `json:"created"`
+	ID          string          `json:"id"`
+	Author      string          `json:"author"`
+	AuthorEmail string          `json:"author_email"`
+	Body        string          `json:"body"` // Markdown
+	BodyADF     json.RawMessage `json:"body_adf,omitempty"`
+	Created     time.Time       `json:"created"`
 }
 
 func (c *Client) FetchIssueComments(key string) ([]CommentInfo, error) {
-	url := fmt.Sprintf("%s/api/2/issue/%s?fields=comment", c.baseURL(), key)
+	url := fmt.Sprintf("%s/issue/%s?fields=comment", c.apiURL(), key)
 	body, err := c.doRequest(url)
 	if err != nil {
 		return nil, fmt.Errorf("fetch comments for %s: %w", key, err)
@@ -373,8 +436,8 @@ func (c *Client) FetchIssueComments(key string) ([]CommentInfo, error) {
 						DisplayName  string `json:"displayName"`
 						EmailAddress string `json:"emailAddress"`
 					} `json:"author"`
-					Body    string `json:"body"`
-					Created string `json:"created"`
+					Body    json.RawMessage `json:"body"`
+					Created string          `json:"created"`
 				} `json:"comments"`
 			} `json:"comment"`
 		} `json:"fields"`
@@ -391,7 +454,8 @@ func (c *Client) FetchIssueComments(key string) ([]CommentInfo, error) {
 			ID:          c.ID,
 			Author:      c.Author.DisplayName,
 			AuthorEmail: c.Author.EmailAddress,
-			Body:        c.Body,
+			Body:        DescriptionText(c.Body),
+			BodyADF:     RawADF(c.Body),
 			Created:     created,
 		})
 	}
@@ -405,42 +469,71 @@ func (c *Client) Validate() error {
 }
 
 func (c *Client) doRequest(reqURL string) ([]byte, error) {
-	req, err := http.NewRequest("GET", reqURL, nil)
+	return c.do("GET", reqURL, nil)
+}
+
+// do sends a request with an optional JSON payload and returns the body of
+// any 2xx response.
+func (c *Client) do(method, reqURL string, payload any) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, reqURL, reqBody)
 	if err != nil {
 		return nil, err
 	}
 
-	auth := base64.StdEncoding.EncodeToString([]byte(c.Email + ":" + c.Token))
-	req.Header.Set("Authorization", "Basic "+auth)
+	if c.Bearer {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	} else {
+		auth := base64.StdEncoding.EncodeToString([]byte(c.Email + ":" + c.Token))
+		req.Header.Set("Authorization", "Basic "+auth)
+	}
 	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, services.NewNetworkError(err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
-		return nil, fmt.Errorf("unauthorized: check jira credentials")
-	}
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("not found: %s", reqURL)
-	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
-	}
+	body, _ := io.ReadAll(resp.Body)
 
-	var body []byte
-	buf := make([]byte, 4096)
-	for {
-		n, err := resp.Body.Read(buf)
-		if n > 0 {
-			body = append(body, buf[:n]...)
-		}
-		if err != nil {
-			break
+	if resp.StatusCode == http.StatusBadRequest {
+		return nil, &services.ProviderError{Class: services.ErrUnknown, StatusCode: resp.StatusCode, Message: "bad request: " + errorMessage(body)}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		perr := services.NewResponseError(resp, "jira resource")
+		if perr.Class == services.ErrUnauthorized {
+			perr.Message = "unauthorized: check jira credentials"
 		}
+		return nil, perr
 	}
 
 	return body, nil
 }
+
+// errorMessage joins the messages of a Jira error response.
+func errorMessage(body []byte) string {
+	var resp struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	if json.Unmarshal(body, &resp) != nil {
+		return strings.TrimSpace(string(body))
+	}
+	msgs := resp.ErrorMessages
+	for _, field := range slices.Sorted(maps.Keys(resp.Errors)) {
+		msgs = append(msgs, field+": "+resp.Errors[field])
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,309 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jiraTimeLayout is how the REST API formats issue and comment timestamps.
+const jiraTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// sprintFieldSchema identifies the Jira Software sprint custom field.
+const sprintFieldSchema = "com.pyxis.greenhopper.jira:gh-sprint"
+
+// IssueUpdate is an issue returned by a JQL search: its card fields, when it
+// was created and last updated, and every sprint it has been in.
+type IssueUpdate struct {
+	CardInfo
+	Created time.Time
+	Updated time.Time
+	Sprints []SprintInfo
+}
+
+// storyPointsFieldSchema identifies the Jira Software story points field of
+// team-managed projects; company-managed ones use a number field named
+// "Story Points".
+const storyPointsFieldSchema = "com.pyxis.greenhopper.jira:jsw-story-points"
+
+type fieldInfo struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Schema struct {
+		Custom string `json:"custom"`
+	} `json:"schema"`
+}
+
+func (c *Client) fetchFields() ([]fieldInfo, error) {
+	body, err := c.doRequest(fmt.Sprintf("%s/field", c.apiURL()))
+	if err != nil {
+		return nil, fmt.Errorf("fetch fields: %w", err)
+	}
+
+	var fields []fieldInfo
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("parse fields: %w", err)
+	}
+	return fields, nil
+}
+
+// FieldIDs holds the ids of the custom fields sync reads, which differ per
+// site. An empty id means the site has no such field.
+type FieldIDs struct {
+	// Sprint holds an issue's sprints, e.g. "customfield_10020".
+	Sprint string
+	// StoryPoints holds an issue's story points.
+	StoryPoints string
+}
+
+// FetchFieldIDs resolves the sprint and story points fields from a single
+// listing of the site's fields.
+func (c *Client) FetchFieldIDs() (FieldIDs, error) {
+	fields, err := c.fetchFields()
+	if err != nil {
+		return FieldIDs{}, err
+	}
+	var ids FieldIDs
+	var pointsByName string
+	for _, f := range fields {
+		switch {
+		case f.Schema.Custom == sprintFieldSchema:
+			if ids.Sprint == "" {
+				ids.Sprint = f.ID
+			}
+		case f.Schema.Custom == storyPointsFieldSchema:
+			if ids.StoryPoints == "" {
+				ids.StoryPoints = f.ID
+			}
+		}
+		switch strings.ToLower(f.Name) {
+		case "story points", "story point estimate":
+			if pointsByName == "" {
+				pointsByName = f.ID
+			}
+		}
+	}
+	if ids.StoryPoints == "" {
+		ids.StoryPoints = pointsByName
+	}
+	return ids, nil
+}
+
+// FetchStatusCategories maps each status name, lower-cased, to the key of
+// its category: "new", "indeterminate" or "done".
+func (c *Client) FetchStatusCategories() (map[string]string, error) {
+	body, err := c.doRequest(fmt.Sprintf("%s/status", c.apiURL()))
+	if err != nil {
+		return nil, fmt.Errorf("fetch statuses: %w", err)
+	}
+
+	var statuses []struct {
+		Name           string `json:"name"`
+		StatusCategory struct {
+			Key string `json:"key"`
+		} `json:"statusCategory"`
+	}
+	if err := json.Unmarshal(body, &statuses); err != nil {
+		return nil, fmt.Errorf("parse statuses: %w", err)
+	}
+	categories := make(map[string]string, len(statuses))
+	for _, s := range statuses {
+		categories[strings.ToLower(s.Name)] = s.StatusCategory.Key
+	}
+	return categories, nil
+}
+
+// SearchIssues runs a JQL query through the search API, following
+// pagination. Sprints are read from sprintField when it is set.
+func (c *Client) SearchIssues(jql, sprintField string) ([]IssueUpdate, error) {
+	return c.searchPages(c.apiURL()+"/search", jql, sprintField)
+}
+
+// FetchBoardIssues lists the issues on a board that match jql, which may be
+// empty. Kanban boards have no sprints, so this is how their cards are
+// found.
+func (c *Client) FetchBoardIssues(boardID int, jql, sprintField string) ([]IssueUpdate, error) {
+	return c.searchPages(fmt.Sprintf("%s/agile/1.0/board/%d/issue", c.baseURL(), boardID), jql, sprintField)
+}
+
+// FetchBacklogIssues lists the issues in a board's backlog that match jql:
+// those in no active or future sprint.
+func (c *Client) FetchBacklogIssues(boardID int, jql, sprintField string) ([]IssueUpdate, error) {
+	return c.searchPages(fmt.Sprintf("%s/agile/1.0/board/%d/backlog", c.baseURL(), boardID), jql, sprintField)
+}
+
+// searchPages reads every page of an endpoint that answers a JQL query
+// with {"issues": [...], "total": n}.
+func (c *Client) searchPages(endpoint, jql, sprintField string) ([]IssueUpdate, error) {
+	fields := "summary,status,assignee,created,updated"
+	if sprintField != "" {
+		fields += "," + sprintField
+	}
+
+	var issues []IssueUpdate
+	startAt := 0
+	maxResults := 100
+
+	for {
+		reqURL := fmt.Sprintf("%s?jql=%s&fields=%s&startAt=%d&maxResults=%d",
+			endpoint, url.QueryEscape(jql), fields, startAt, maxResults)
+
+		body, err := c.doRequest(reqURL)
+		if err != nil {
+			return nil, fmt.Errorf("search issues: %w", err)
+		}
+
+		var resp struct {
+			Issues []json.RawMessage `json:"issues"`
+			Total  int               `json:"total"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("parse search results: %w", err)
+		}
+
+		for _, raw := range resp.Issues {
+			issue, err := parseSearchIssue(raw, sprintField)
+			if err != nil {
+				return nil, err
+			}
+			issues = append(issues, issue)
+		}
+
+		if len(resp.Issues) == 0 {
+			break
+		}
+		startAt += len(resp.Issues)
+		if startAt >= resp.Total {
+			break
+		}
+	}
+
+	return issues, nil
+}
+
+func parseSearchIssue(raw json.RawMessage, sprintField string) (IssueUpdate, error) {
+	var issue struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary  string                        `json:"summary"`
+			Status   struct{ Name string }         `json:"status"`
+			Assignee *struct{ DisplayName string } `json:"assignee"`
+			Created  string                        `json:"created"`
+			Updated  string                        `json:"updated"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal(raw, &issue); err != nil {
+		return IssueUpdate{}, fmt.Errorf("parse issue: %w", err)
+	}
+
+	out := IssueUpdate{CardInfo: CardInfo{
+		Key:     issue.Key,
+		Summary: issue.Fields.Summary,
+		Status:  issue.Fields.Status.Name,
+	}}
+	if issue.Fields.Assignee != nil {
+		out.Assignee = issue.Fields.Assignee.DisplayName
+	}
+	out.Created, _ = time.Parse(jiraTimeLayout, issue.Fields.Created)
+	out.Updated, _ = time.Parse(jiraTimeLayout, issue.Fields.Updated)
+
+	if sprintField == "" {
+		return out, nil
+	}
+	// The sprint field's id is only known at runtime, so read it separately.
+	var custom struct {
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	if err := json.Unmarshal(raw, &custom); err != nil {
+		return out, fmt.Errorf("parse issue %s: %w", issue.Key, err)
+	}
+	if sprints := custom.Fields[sprintField]; len(sprints) > 0 && string(sprints) != "null" {
+		if err := json.Unmarshal(sprints, &out.Sprints); err != nil {
+			// Server / Data Center returns each sprint as its Java toString.
+			var legacy []string
+			if json.Unmarshal(sprints, &legacy) != nil {
+				return out, fmt.Errorf("parse sprints of %s: %w", issue.Key, err)
+			}
+			out.Sprints = nil
+			for _, l := range legacy {
+				if sprint, ok := parseLegacySprint(l); ok {
+					out.Sprints = append(out.Sprints, sprint)
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+var legacySprintKey = regexp.MustCompile(`(?:\[|,)(id|rapidViewId|state|name|goal|startDate|endDate|completeDate|activatedDate|sequence|synced|autoStartStop|incompleteIssuesDestinationId)=`)
+
+// parseLegacySprint reads a sprint written as
+// "com.atlassian.greenhopper.service.sprint.Sprint@1a2b[id=5,state=ACTIVE,name=S5,startDate=...,...]".
+// Values may contain commas, so they are split on the known keys.
+func parseLegacySprint(s string) (SprintInfo, bool) {
+	open, end := strings.Index(s, "["), strings.LastIndex(s, "]")
+	if open < 0 || end < open {
+		return SprintInfo{}, false
+	}
+	s = s[open : end+1]
+
+	values := map[string]string{}
+	matches := legacySprintKey.FindAllStringSubmatchIndex(s, -1)
+	for i, m := range matches {
+		stop := len(s) - 1
+		if i+1 < len(matches) {
+			stop = matches[i+1][0]
+		}
+		values[s[m[2]:m[3]]] = s[m[1]:stop]
+	}
+
+	id, err := strconv.Atoi(values["id"])
+	if err != nil {
+		return SprintInfo{}, false
+	}
+	sprint := SprintInfo{ID: id, Name: values["name"], State: strings.ToLower(values["state"])}
+	for key, dst := range map[string]**time.Time{"startDate": &sprint.StartDate, "endDate": &sprint.EndDate} {
+		if t, err := time.Parse(time.RFC3339, values[key]); err == nil {
+			*dst = &t
+		}
+	}
+	return sprint, true
+}
+
+// UpdatedSinceJQL builds the JQL for issues in a sprint of the given
+// projects that changed since a time. The bound is relative ("-90m") so it
+// does not depend on the timezone of the Jira user; it is rounded up to
+// whole minutes, so callers see a little overlap. A nil since matches every
+// issue.
+func UpdatedSinceJQL(projectKeys []string, since *time.Time) string {
+	clauses := []string{"sprint is not EMPTY"}
+	if len(projectKeys) > 0 {
+		quoted := make([]string, len(projectKeys))
+		for i, k := range projectKeys {
+			quoted[i] = fmt.Sprintf("%q", k)
+		}
+		clauses = append([]string{"project in (" + strings.Join(quoted, ", ") + ")"}, clauses...)
+	}
+	if since != nil {
+		clauses = append(clauses, updatedSince(*since))
+	}
+	return strings.Join(clauses, " AND ") + " ORDER BY updated ASC"
+}
+
+// BoardUpdatedSinceJQL narrows a board's issues to those changed since a
+// time, like UpdatedSinceJQL. A nil since matches every issue on the board.
+func BoardUpdatedSinceJQL(since *time.Time) string {
+	if since == nil {
+		return ""
+	}
+	return updatedSince(*since)
+}
+
+func updatedSince(since time.Time) string {
+	minutes := int(time.Since(since).Minutes()) + 1
+	return fmt.Sprintf("updated >= -%dm", minutes)
+}
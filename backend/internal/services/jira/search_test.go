@@ -0,0 +1,91 @@
+package jira
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
+)
+
+func TestSearchIssues_PaginatesAndReadsSprints(t *testing.T) {
+	var jqls []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/3/search", func(w http.ResponseWriter, r *http.Request) {
+		jqls = append(jqls, r.URL.Query().Get("jql"))
+		if !strings.Contains(r.URL.Query().Get("fields"), "customfield_10020") {
+			t.Errorf("sprint field not requested: %s", r.URL.RawQuery)
+		}
+		if r.URL.Query().Get("startAt") == "0" {
+			fmt.Fprint(w, `{"total":2,"issues":[{"key":"CORE-1","fields":{"summary":"one","status":{"name":"Done"},
+				"assignee":{"displayName":"Ana"},"updated":"2026-10-12T09:30:00.000+0000",
+				"customfield_10020":[{"id":7,"name":"S7","state":"closed"},{"id":8,"name":"S8","state":"active"}]}}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"total":2,"issues":[{"key":"CORE-2","fields":{"summary":"two","status":{"name":"To Do"},
+			"assignee":null,"updated":"2026-10-12T10:00:00.000+0000","customfield_10020":null}}]}`)
+	})
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+	orig := httpclient.Client
+	httpclient.Client = srv.Client()
+	defer func() { httpclient.Client = orig }()
+
+	c := New(srv.Listener.Addr().String(), "a@b.c", "tok")
+	issues, err := c.SearchIssues("project = CORE", "customfield_10020")
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if len(issues) != 2 || len(jqls) != 2 {
+		t.Fatalf("got %d issues in %d requests, want 2 in 2", len(issues), len(jqls))
+	}
+	first := issues[0]
+	if first.Assignee != "Ana" || len(first.Sprints) != 2 || first.Sprints[1].State != "active" {
+		t.Errorf("first = %+v", first)
+	}
+	if want := time.Date(2026, 10, 12, 9, 30, 0, 0, time.UTC); !first.Updated.Equal(want) {
+		t.Errorf("updated = %v, want %v", first.Updated, want)
+	}
+	if issues[1].Assignee != "" || issues[1].Sprints != nil {
+		t.Errorf("second = %+v", issues[1])
+	}
+}
+
+func TestFetchFieldIDs(t *testing.T) {
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/3/field", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `[{"id":"summary","name":"Summary","schema":{}},
+			{"id":"customfield_10016","name":"Story point estimate","schema":{}},
+			{"id":"customfield_10020","name":"Sprint","schema":{"custom":"com.pyxis.greenhopper.jira:gh-sprint"}}]`)
+	})
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+	orig := httpclient.Client
+	httpclient.Client = srv.Client()
+	defer func() { httpclient.Client = orig }()
+
+	c := New(srv.Listener.Addr().String(), "a@b.c", "tok")
+	ids, err := c.FetchFieldIDs()
+	if err != nil {
+		t.Fatalf("FetchFieldIDs: %v", err)
+	}
+	if ids.Sprint != "customfield_10020" || ids.StoryPoints != "customfield_10016" || requests != 1 {
+		t.Errorf("ids = %+v after %d requests", ids, requests)
+	}
+}
+
+func TestUpdatedSinceJQL(t *testing.T) {
+	if got := UpdatedSinceJQL(nil, nil); got != "sprint is not EMPTY ORDER BY updated ASC" {
+		t.Errorf("no bound: %q", got)
+	}
+	since := time.Now().Add(-90 * time.Minute)
+	got := UpdatedSinceJQL([]string{"CORE", "OPS"}, &since)
+	if want := `project in ("CORE", "OPS") AND sprint is not EMPTY AND updated >= -91m ORDER BY updated ASC`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+package jira
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+// ErrNoCredentials is returned when a workspace lacks what its auth mode
+// needs.
+var ErrNoCredentials = errors.New("jira credentials not configured")
+
+// ForWorkspace returns a client for a configured workspace, reached the way
+// its deployment and auth mode say. Basic auth needs an email; a bearer
+// personal access token does not.
+func ForWorkspace(ws models.JiraWorkspaceConfig, email, token string) (*Client, error) {
+	bearer := ws.AuthMode == models.JiraAuthBearer
+	if token == "" || (!bearer && email == "") {
+		return nil, ErrNoCredentials
+	}
+	client := New(ws.Workspace, email, token)
+	client.Bearer = bearer
+	client.DataCenter = ws.Deployment == models.JiraDataCenter
+	client.StoryPointsField = ws.StoryPointsField
+	return client, nil
+}
+
+// Credentials returns the email and decrypted token a workspace signs in
+// with. Each falls back to the user's own when the workspace has none.
+func Credentials(ws models.JiraWorkspaceConfig, user models.User, enc *crypto.Encryptor) (email, token string, err error) {
+	email, encrypted := ws.Email, ws.Token
+	if email == "" {
+		email = user.JiraEmail
+	}
+	if encrypted == "" {
+		encrypted = user.JiraToken
+	}
+	if encrypted == "" {
+		return email, "", nil
+	}
+	token, err = enc.Decrypt(encrypted)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decrypt jira token: %w", err)
+	}
+	return email, token, nil
+}
+
+// ClientFor returns a client for one of the user's workspaces, signed in
+// with the workspace's credentials or, failing those, the user's.
+func ClientFor(ws models.JiraWorkspaceConfig, user models.User, enc *crypto.Encryptor) (*Client, error) {
+	email, token, err := Credentials(ws, user, enc)
+	if err != nil {
+		return nil, err
+	}
+	return ForWorkspace(ws, email, token)
+}
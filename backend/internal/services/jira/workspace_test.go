@@ -0,0 +1,98 @@
+package jira
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
+)
+
+func TestForWorkspace_DataCenter(t *testing.T) {
+	var auth, comment string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jira/rest/api/2/search", func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"total":1,"issues":[{"key":"OPS-1","fields":{"summary":"s","status":{"name":"Open"},
+			"updated":"2026-10-12T09:00:00.000+0700","customfield_10100":[
+			"com.atlassian.greenhopper.service.sprint.Sprint@1a2b[id=7,rapidViewId=2,state=ACTIVE,name=Ops, week 42,startDate=2026-10-12T09:00:00.000+07:00,endDate=<null>,completeDate=<null>,sequence=7]"]}}]}`)
+	})
+	mux.HandleFunc("/jira/rest/api/2/issue/OPS-1/comment", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		comment = string(body)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"1"}`)
+	})
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+	orig := httpclient.Client
+	httpclient.Client = srv.Client()
+	defer func() { httpclient.Client = orig }()
+
+	ws := models.JiraWorkspaceConfig{Workspace: srv.URL + "/jira/", Deployment: models.JiraDataCenter, AuthMode: models.JiraAuthBearer}
+	if _, err := ForWorkspace(models.JiraWorkspaceConfig{AuthMode: models.JiraAuthBasic}, "", "tok"); err != ErrNoCredentials {
+		t.Errorf("basic auth without email: err = %v", err)
+	}
+	c, err := ForWorkspace(ws, "", "pat")
+	if err != nil {
+		t.Fatalf("ForWorkspace: %v", err)
+	}
+
+	issues, err := c.SearchIssues("project = OPS", "customfield_10100")
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if auth != "Bearer pat" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if len(issues) != 1 || len(issues[0].Sprints) != 1 {
+		t.Fatalf("issues = %+v", issues)
+	}
+	sprint := issues[0].Sprints[0]
+	if sprint.ID != 7 || sprint.Name != "Ops, week 42" || sprint.State != "active" || sprint.StartDate == nil || sprint.EndDate != nil {
+		t.Errorf("sprint = %+v", sprint)
+	}
+
+	if _, err := c.AddComment("OPS-1", "done"); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	if comment != `{"body":"done"}` {
+		t.Errorf("comment body = %s, want plain text", comment)
+	}
+}
+
+func TestClientFor_FallsBackToUserCredentials(t *testing.T) {
+	enc, err := crypto.NewEncryptor(strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatalf("encryptor: %v", err)
+	}
+	userToken, _ := enc.Encrypt("user-tok")
+	clientToken, _ := enc.Encrypt("client-tok")
+	user := models.User{JiraEmail: "ana@corp.com", JiraToken: userToken}
+
+	shared, err := ClientFor(models.JiraWorkspaceConfig{Workspace: "corp.atlassian.net"}, user, enc)
+	if err != nil {
+		t.Fatalf("ClientFor shared: %v", err)
+	}
+	if shared.Email != "ana@corp.com" || shared.Token != "user-tok" {
+		t.Errorf("shared workspace signs in as %q/%q, want the user's credentials", shared.Email, shared.Token)
+	}
+
+	own := models.JiraWorkspaceConfig{Workspace: "client.atlassian.net", Email: "ana@client.com", Token: clientToken}
+	client, err := ClientFor(own, user, enc)
+	if err != nil {
+		t.Fatalf("ClientFor own: %v", err)
+	}
+	if client.Email != "ana@client.com" || client.Token != "client-tok" {
+		t.Errorf("workspace signs in as %q/%q, want its own credentials", client.Email, client.Token)
+	}
+
+	if _, err := ClientFor(models.JiraWorkspaceConfig{Workspace: "x.atlassian.net"}, models.User{}, enc); err != ErrNoCredentials {
+		t.Errorf("no credentials anywhere: err = %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Transition is a workflow transition available on an issue in its current
+// status.
+type Transition struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ToStatus string `json:"to_status"`
+}
+
+// FetchTransitions lists the transitions the credentials may perform on an
+// issue. They depend on the issue's workflow and current status, so they are
+// discovered per issue rather than configured.
+func (c *Client) FetchTransitions(key string) ([]Transition, error) {
+	body, err := c.doRequest(fmt.Sprintf("%s/issue/%s/transitions", c.apiURL(), key))
+	if err != nil {
+		return nil, fmt.Errorf("fetch transitions for %s: %w", key, err)
+	}
+
+	var resp struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("parse transitions for %s: %w", key, err)
+	}
+
+	transitions := make([]Transition, 0, len(resp.Transitions))
+	for _, t := range resp.Transitions {
+		transitions = append(transitions, Transition{ID: t.ID, Name: t.Name, ToStatus: t.To.Name})
+	}
+	return transitions, nil
+}
+
+// FindTransition picks the transition matching ref by id, by transition name
+// or by target status name, ignoring case.
+func FindTransition(transitions []Transition, ref string) (Transition, bool) {
+	ref = strings.TrimSpace(ref)
+	for _, t := range transitions {
+		if t.ID == ref {
+			return t, true
+		}
+	}
+	for _, t := range transitions {
+		if strings.EqualFold(t.Name, ref) || strings.EqualFold(t.ToStatus, ref) {
+			return t, true
+		}
+	}
+	return Transition{}, false
+}
+
+// TransitionIssue moves an issue through the transition with the given id.
+func (c *Client) TransitionIssue(key, transitionID string) error {
+	payload := map[string]any{"transition": map[string]string{"id": transitionID}}
+	if _, err := c.do("POST", fmt.Sprintf("%s/issue/%s/transitions", c.apiURL(), key), payload); err != nil {
+		return fmt.Errorf("transition %s: %w", key, err)
+	}
+	return nil
+}
+
+// AddComment posts a plain-text comment on an issue and returns it as stored
+// by Jira.
+func (c *Client) AddComment(key, text string) (*CommentInfo, error) {
+	payload := map[string]any{"body": c.richText(text)}
+	body, err := c.do("POST", fmt.Sprintf("%s/issue/%s/comment", c.apiURL(), key), payload)
+	if err != nil {
+		return nil, fmt.Errorf("add comment to %s: %w", key, err)
+	}
+
+	var resp struct {
+		ID     string `json:"id"`
+		Author struct {
+			DisplayName  string `json:"displayName"`
+			EmailAddress string `json:"emailAddress"`
+		} `json:"author"`
+		Created string `json:"created"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("parse comment on %s: %w", key, err)
+	}
+
+	created, _ := time.Parse(jiraTimeLayout, resp.Created)
+	return &CommentInfo{
+		ID:          resp.ID,
+		Author:      resp.Author.DisplayName,
+		AuthorEmail: resp.Author.EmailAddress,
+		Body:        text,
+		Created:     created,
+	}, nil
+}
+
+// AddWorklog logs time spent on an issue, starting at started, and returns
+// the id of the new worklog. The comment is optional.
+func (c *Client) AddWorklog(key string, started time.Time, seconds int, comment string) (string, error) {
+	if seconds < 60 {
+		return "", fmt.Errorf("add worklog to %s: time spent must be at least one minute", key)
+	}
+	payload := map[string]any{
+		"started":          started.Format(jiraTimeLayout),
+		"timeSpentSeconds": seconds,
+	}
+	if comment != "" {
+		payload["comment"] = c.richText(comment)
+	}
+
+	body, err := c.do("POST", fmt.Sprintf("%s/issue/%s/worklog", c.apiURL(), key), payload)
+	if err != nil {
+		return "", fmt.Errorf("add worklog to %s: %w", key, err)
+	}
+
+	var resp struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("parse worklog on %s: %w", key, err)
+	}
+	return resp.ID, nil
+}
+
+// richText returns text as a comment body: ADF on Cloud, plain text on
+// Server / Data Center.
+func (c *Client) richText(text string) any {
+	if c.DataCenter {
+		return text
+	}
+	return textToADF(text)
+}
+
+// textToADF wraps plain text in an Atlassian Document, one paragraph per
+// blank-line separated block with line breaks kept.
+func textToADF(text string) map[string]any {
+	var paragraphs []any
+	for _, block := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		block = strings.Trim(block, "\n")
+		if block == "" {
+			continue
+		}
+		var content []any
+		for i, line := range strings.Split(block, "\n") {
+			if i > 0 {
+				content = append(content, map[string]any{"type": "hardBreak"})
+			}
+			if line != "" {
+				content = append(content, map[string]any{"type": "text", "text": line})
+			}
+		}
+		paragraphs = append(paragraphs, map[string]any{"type": "paragraph", "content": content})
+	}
+	if paragraphs == nil {
+		paragraphs = []any{}
+	}
+	return map[string]any{"type": "doc", "version": 1, "content": paragraphs}
+}
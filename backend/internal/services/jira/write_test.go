@@ -0,0 +1,124 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/services"
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
+)
+
+func TestWriteCalls(t *testing.T) {
+	var transitioned, worklog map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/3/issue/CORE-1/transitions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &transitioned)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprint(w, `{"transitions":[{"id":"11","name":"Start","to":{"name":"In Progress"}},
+			{"id":"31","name":"Resolve","to":{"name":"Done"}}]}`)
+	})
+	mux.HandleFunc("/rest/api/3/issue/CORE-1/comment", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"type":"hardBreak"`) {
+			t.Errorf("comment body is not ADF with line breaks: %s", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"10100","author":{"displayName":"Ana"},"created":"2026-10-12T09:30:00.000+0000"}`)
+	})
+	mux.HandleFunc("/rest/api/3/issue/CORE-1/worklog", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &worklog)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"20200"}`)
+	})
+	mux.HandleFunc("/rest/api/3/issue/CORE-2/transitions", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errorMessages":[],"errors":{"resolution":"Resolution is required."}}`)
+	})
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+	orig := httpclient.Client
+	httpclient.Client = srv.Client()
+	defer func() { httpclient.Client = orig }()
+
+	c := New(srv.Listener.Addr().String(), "a@b.c", "tok")
+
+	transitions, err := c.FetchTransitions("CORE-1")
+	if err != nil {
+		t.Fatalf("FetchTransitions: %v", err)
+	}
+	tr, ok := FindTransition(transitions, "done")
+	if !ok || tr.ID != "31" {
+		t.Fatalf("FindTransition(done) = %+v, %v", tr, ok)
+	}
+	if err := c.TransitionIssue("CORE-1", tr.ID); err != nil {
+		t.Fatalf("TransitionIssue: %v", err)
+	}
+	if got := transitioned["transition"].(map[string]any)["id"]; got != "31" {
+		t.Errorf("transition id sent = %v", got)
+	}
+
+	comment, err := c.AddComment("CORE-1", "Shipped.\nSee PR.")
+	if err != nil || comment.ID != "10100" || comment.Author != "Ana" {
+		t.Fatalf("AddComment = %+v, %v", comment, err)
+	}
+
+	started := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	id, err := c.AddWorklog("CORE-1", started, 5400, "")
+	if err != nil || id != "20200" {
+		t.Fatalf("AddWorklog = %q, %v", id, err)
+	}
+	if worklog["started"] != "2026-10-12T09:00:00.000+0000" || worklog["timeSpentSeconds"] != float64(5400) {
+		t.Errorf("worklog sent = %v", worklog)
+	}
+	if _, has := worklog["comment"]; has {
+		t.Errorf("empty comment was sent: %v", worklog)
+	}
+
+	err = c.TransitionIssue("CORE-2", "31")
+	if err == nil || !strings.Contains(err.Error(), "Resolution is required.") {
+		t.Errorf("TransitionIssue error = %v, want Jira's message", err)
+	}
+}
+
+func TestDo_ClassifiesErrors(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/3/myself":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/rest/api/3/issue/GONE-1":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	orig := httpclient.Client
+	httpclient.Client = srv.Client()
+	defer func() { httpclient.Client = orig }()
+
+	c := New(srv.Listener.Addr().String(), "a@b.c", "tok")
+	for _, tc := range []struct {
+		path string
+		want services.ErrorClass
+	}{
+		{"/rest/api/3/myself", services.ErrUnauthorized},
+		{"/rest/api/3/issue/GONE-1", services.ErrNotFound},
+		{"/rest/api/3/field", services.ErrServer},
+	} {
+		_, err := c.doRequest("https://" + c.Workspace + tc.path)
+		if got := services.ClassifyError(err); got != tc.want {
+			t.Errorf("%s: class %q, want %q (err %v)", tc.path, got, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Package jiraoutbox queues changes to Jira cards (comments, transitions and
+// worklogs) for approval and sends approved ones. Nothing is written to Jira
+// while an entry is pending, so agents can propose changes but only the user
+// can make them happen.
+package jiraoutbox
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/jira"
+)
+
+// Outbox statuses, the same values WaOutbox uses.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusSent     = "sent"
+	StatusFailed   = "failed"
+)
+
+// ErrNotApproved is returned by Send for entries that are not approved.
+var ErrNotApproved = errors.New("outbox item is not approved")
+
+// Validate checks that an entry carries what its action needs.
+func Validate(item *models.JiraOutbox) error {
+	if strings.TrimSpace(item.CardKey) == "" {
+		return fmt.Errorf("card_key is required")
+	}
+	switch item.Action {
+	case models.JiraActionComment:
+		if strings.TrimSpace(item.Body) == "" {
+			return fmt.Errorf("body is required for a comment")
+		}
+	case models.JiraActionTransition:
+		if strings.TrimSpace(item.Transition) == "" {
+			return fmt.Errorf("transition is required for a transition")
+		}
+	case models.JiraActionWorklog:
+		if item.TimeSpentSeconds < 60 {
+			return fmt.Errorf("time_spent_seconds must be at least 60 for a worklog")
+		}
+	default:
+		return fmt.Errorf("action must be one of comment, transition or worklog")
+	}
+	return nil
+}
+
+// Queue validates an entry and stores it as pending. The card key is
+// upper-cased and, when the card is synced, its workspace is recorded.
+func Queue(db *gorm.DB, item *models.JiraOutbox) error {
+	item.CardKey = strings.ToUpper(strings.TrimSpace(item.CardKey))
+	if err := Validate(item); err != nil {
+		return err
+	}
+	if item.WorkspaceID == nil {
+		var card models.JiraCard
+		if db.Where("user_id = ? AND card_key = ?", item.UserID, item.CardKey).First(&card).Error == nil {
+			item.WorkspaceID = card.WorkspaceID
+		}
+	}
+	item.Status = StatusPending
+	item.ApprovedAt = nil
+	return db.Create(item).Error
+}
+
+// Client returns a Jira client for one of the user's workspaces, or for their
+// first active workspace when workspaceID is nil.
+func Client(db *gorm.DB, enc *crypto.Encryptor, userID uint, workspaceID *uint) (*jira.Client, *models.JiraWorkspaceConfig, error) {
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return nil, nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	var ws models.JiraWorkspaceConfig
+	query := db.Where("user_id = ?", userID)
+	if workspaceID != nil {
+		query = query.Where("id = ?", *workspaceID)
+	} else {
+		query = query.Where("is_active = ?", true)
+	}
+	if err := query.First(&ws).Error; err != nil {
+		return nil, nil, fmt.Errorf("workspace not found")
+	}
+
+	client, err := jira.ClientFor(ws, user, enc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, &ws, nil
+}
+
+// Approve marks a pending entry as approved and sends it. The entry ends up
+// sent, with the id Jira assigned, or failed with the error.
+func Approve(db *gorm.DB, enc *crypto.Encryptor, item *models.JiraOutbox) error {
+	if item.Status != StatusPending {
+		return fmt.Errorf("only pending outbox items can be approved")
+	}
+	// Only one approval of an entry may send it.
+	now := time.Now()
+	tx := db.Model(&models.JiraOutbox{}).Where("id = ? AND status = ?", item.ID, StatusPending).
+		Updates(map[string]interface{}{"status": StatusApproved, "approved_at": &now})
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return fmt.Errorf("only pending outbox items can be approved")
+	}
+	item.Status = StatusApproved
+	item.ApprovedAt = &now
+	return Send(db, enc, item)
+}
+
+// Send performs an approved entry against Jira and records the outcome on it.
+// Successful changes are mirrored locally so they show up before the next
+// sync.
+func Send(db *gorm.DB, enc *crypto.Encryptor, item *models.JiraOutbox) error {
+	if item.Status != StatusApproved {
+		return ErrNotApproved
+	}
+
+	jiraID, err := send(db, enc, item)
+	if err != nil {
+		log.Printf("[jira-outbox] send %d (%s %s) failed: %v", item.ID, item.Action, item.CardKey, err)
+		item.Status, item.Error = StatusFailed, err.Error()
+	} else {
+		now := time.Now()
+		item.Status, item.Error, item.JiraID, item.SentAt = StatusSent, "", jiraID, &now
+	}
+	if dbErr := db.Model(item).Updates(map[string]interface{}{
+		"status":  item.Status,
+		"error":   item.Error,
+		"jira_id": item.JiraID,
+		"sent_at": item.SentAt,
+	}).Error; dbErr != nil {
+		return dbErr
+	}
+	return err
+}
+
+func send(db *gorm.DB, enc *crypto.Encryptor, item *models.JiraOutbox) (string, error) {
+	client, ws, err := Client(db, enc, item.UserID, item.WorkspaceID)
+	if err != nil {
+		return "", err
+	}
+
+	switch item.Action {
+	case models.JiraActionComment:
+		comment, err := client.AddComment(item.CardKey, item.Body)
+		if err != nil {
+			return "", err
+		}
+		wsID := ws.ID
+		db.Create(&models.JiraComment{
+			UserID:      item.UserID,
+			WorkspaceID: &wsID,
+			CardKey:     item.CardKey,
+			CommentID:   comment.ID,
+			Author:      comment.Author,
+			AuthorEmail: comment.AuthorEmail,
+			Body:        comment.Body,
+			CommentedAt: comment.Created,
+		})
+		return comment.ID, nil
+
+	case models.JiraActionTransition:
+		// Transitions depend on the card's status, so the one to use is
+		// looked up at send time rather than when the entry was queued.
+		transitions, err := client.FetchTransitions(item.CardKey)
+		if err != nil {
+			return "", err
+		}
+		t, ok := jira.FindTransition(transitions, item.Transition)
+		if !ok {
+			return "", fmt.Errorf("transition %q is not available for %s", item.Transition, item.CardKey)
+		}
+		if err := client.TransitionIssue(item.CardKey, t.ID); err != nil {
+			return "", err
+		}
+		db.Model(&models.JiraCard{}).Where("user_id = ? AND card_key = ?", item.UserID, item.CardKey).
+			Update("status", t.ToStatus)
+		return t.ID, nil
+
+	case models.JiraActionWorklog:
+		started := time.Now().Add(-time.Duration(item.TimeSpentSeconds) * time.Second)
+		if item.StartedAt != nil {
+			started = *item.StartedAt
+		}
+		return client.AddWorklog(item.CardKey, started, item.TimeSpentSeconds, item.Body)
+	}
+	return "", fmt.Errorf("unknown action: %s", item.Action)
+}
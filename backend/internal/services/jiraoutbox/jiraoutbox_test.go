@@ -0,0 +1,84 @@
+package jiraoutbox
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
+)
+
+func TestQueueAndApprove(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.JiraWorkspaceConfig{}, &models.JiraCard{}, &models.JiraComment{}, &models.JiraOutbox{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	var posts int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/3/issue/CORE-1/transitions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			posts++
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprint(w, `{"transitions":[{"id":"31","name":"Resolve","to":{"name":"Done"}}]}`)
+	})
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+	orig := httpclient.Client
+	httpclient.Client = srv.Client()
+	defer func() { httpclient.Client = orig }()
+
+	enc, err := crypto.NewEncryptor(strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatalf("encryptor: %v", err)
+	}
+	token, _ := enc.Encrypt("tok")
+	user := models.User{Email: "me@x.io", JiraEmail: "me@x.io", JiraToken: token}
+	db.Create(&user)
+	ws := models.JiraWorkspaceConfig{UserID: user.ID, Workspace: srv.Listener.Addr().String(), Name: "core", IsActive: true}
+	db.Create(&ws)
+	db.Create(&models.JiraCard{UserID: user.ID, WorkspaceID: &ws.ID, Key: "CORE-1", Status: "In Progress"})
+
+	if err := Queue(db, &models.JiraOutbox{UserID: user.ID, CardKey: "CORE-1", Action: models.JiraActionWorklog, TimeSpentSeconds: 30}); err == nil {
+		t.Error("worklog under a minute was queued")
+	}
+
+	item := models.JiraOutbox{UserID: user.ID, CardKey: "core-1", Action: models.JiraActionTransition, Transition: "done"}
+	if err := Queue(db, &item); err != nil {
+		t.Fatalf("Queue: %v", err)
+	}
+	if item.Status != StatusPending || item.CardKey != "CORE-1" || item.WorkspaceID == nil || *item.WorkspaceID != ws.ID {
+		t.Fatalf("queued = %+v", item)
+	}
+	if posts != 0 {
+		t.Fatal("queueing wrote to Jira")
+	}
+
+	if err := Approve(db, enc, &item); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if item.Status != StatusSent || item.JiraID != "31" || posts != 1 {
+		t.Errorf("after approve: %+v, %d posts", item, posts)
+	}
+	var card models.JiraCard
+	db.Where("card_key = ?", "CORE-1").First(&card)
+	if card.Status != "Done" {
+		t.Errorf("card status = %q, want Done", card.Status)
+	}
+
+	if err := Approve(db, enc, &item); err == nil || posts != 1 {
+		t.Errorf("second approve: err=%v, %d posts", err, posts)
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 )
@@ -36,6 +37,32 @@ type DiffStats struct {
 	Total     int `json:"total"`
 }
 
+type PullRequestInfo struct {
+	Number       int
+	Title        string
+	State        string // "open", "closed", "merged"
+	Author       string
+	SourceBranch string
+	TargetBranch string
+	URL          string
+	Reviewers    []string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	MergedAt     *time.Time
+	ClosedAt     *time.Time
+	Reviews      []ReviewInfo
+	CommitSHAs   []string // commits on the PR, for linking them to the title's Jira keys
+}
+
+// ReviewInfo is a review verdict or review comment left on a pull request.
+type ReviewInfo struct {
+	ExternalID  string
+	Author      string
+	State       string // "approved", "changes_requested", "commented"
+	Body        string
+	SubmittedAt time.Time
+}
+
 type CommitProvider interface {
 	FetchCommits(owner, repo, token string, since time.Time) ([]CommitInfo, error)
 	FetchBranches(owner, repo, token string) ([]string, error)
@@ -47,6 +74,25 @@ type DiffProvider interface {
 	FetchCommitDiff(owner, repo, sha, token string) (*CommitDiff, error)
 }
 
+// PullRequestProvider lists pull/merge requests updated since a time,
+// including their reviews.
+type PullRequestProvider interface {
+	FetchPullRequests(owner, repo, token string, since time.Time) ([]PullRequestInfo, error)
+}
+
+// AccountInfo is the provider account a token authenticates as.
+type AccountInfo struct {
+	Username string
+	Name     string
+	Emails   []string
+}
+
+// AccountProvider reports which account a token belongs to. Providers that
+// hide email addresses behind an extra scope return what they can see.
+type AccountProvider interface {
+	FetchAccount(token string) (*AccountInfo, error)
+}
+
 var jiraKeyRegex = regexp.MustCompile(`([A-Z][A-Z0-9]+-\d+)`)
 
 func ExtractJiraKey(message string) string {
@@ -55,3 +101,47 @@ func ExtractJiraKey(message string) string {
 	match := jiraKeyRegex.FindString(firstLine)
 	return match
 }
+
+// ExtractJiraKeys returns every distinct Jira key in text, subject and body
+// alike, in order of appearance. When projects is non-empty, keys of other
+// projects are dropped, which also weeds out look-alikes such as "UTF-8".
+func ExtractJiraKeys(text string, projects []string) []string {
+	var keys []string
+	for _, key := range jiraKeyRegex.FindAllString(text, -1) {
+		if len(projects) > 0 && !slices.Contains(projects, key[:strings.LastIndex(key, "-")]) {
+			continue
+		}
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ExtractBranchJiraKeys returns the Jira keys in a branch name. Branches are
+// often lower-cased ("feature/core-12-login"), so with a project filter the
+// name is matched case-insensitively; without one only upper-case keys count.
+func ExtractBranchJiraKeys(branch string, projects []string) []string {
+	if len(projects) > 0 {
+		branch = strings.ToUpper(branch)
+	}
+	return ExtractJiraKeys(branch, projects)
+}
+
+// SplitUnifiedDiff maps each file path in a git unified diff to its hunks,
+// for providers that only serve a commit's diff as one raw patch.
+func SplitUnifiedDiff(raw string) map[string]string {
+	patches := map[string]string{}
+	for _, chunk := range strings.Split(raw, "diff --git ")[1:] {
+		header := strings.SplitN(chunk, "\n", 2)[0]
+		// header: a/path b/path
+		idx := strings.LastIndex(header, " b/")
+		if idx < 0 {
+			continue
+		}
+		if at := strings.Index(chunk, "\n@@"); at >= 0 {
+			patches[header[idx+3:]] = chunk[at+1:]
+		}
+	}
+	return patches
+}
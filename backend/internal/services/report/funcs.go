@@ -0,0 +1,361 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// Funcs are the functions report templates may call on top of text/template's
+// built-ins. They only transform the values passed to them: none reach the
+// database, the network or the clock. List arguments come last so the
+// functions can be piped into, e.g. {{.Stats.Repos | join ", "}}.
+//
+//	date "02 Jan 2006" .Date          format a time or a YYYY-MM-DD string
+//	duration 5400                     "1h 30m" from seconds or a time.Duration
+//	groupBy "Repo" .Commits           [{Key, Items}] in order of first appearance
+//	sortBy "-Commits" .RepoBreakdown  sort by a field, descending with "-"
+//	sum "Commits" .WeeklyBreakdown    add up a numeric field, or numbers
+//	join ", " .Stats.Repos            join a list
+//	plural "commit" 3                 "3 commits"
+//	truncate 40 .Summary              shorten to 40 characters with "…"
+//	md .Message                       escape Markdown punctuation
+//	default "n/a" .Status             the fallback when the value is empty
+//	upper, lower, trim                change or trim a string
+var Funcs = template.FuncMap{
+	"date":     formatDate,
+	"duration": formatDuration,
+	"groupBy":  groupBy,
+	"sortBy":   sortBy,
+	"sum":      sum,
+	"join":     join,
+	"plural":   plural,
+	"truncate": truncate,
+	"md":       escapeMarkdown,
+	"default":  defaultValue,
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"trim":     strings.TrimSpace,
+
+	// call would let templates invoke arbitrary function values.
+	"call": func(...any) (any, error) { return nil, errors.New("call is not allowed in report templates") },
+}
+
+// Group is one group returned by groupBy.
+type Group struct {
+	Key   string
+	Items []any
+}
+
+func formatDate(layout string, value any) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		return v.Format(layout), nil
+	case string:
+		if v == "" {
+			return "", nil
+		}
+		for _, in := range []string{"2006-01-02", time.RFC3339, "2006-01-02 15:04", "2006-01"} {
+			if t, err := time.Parse(in, v); err == nil {
+				return t.Format(layout), nil
+			}
+		}
+		return "", fmt.Errorf("date: can't parse %q", v)
+	}
+	return "", fmt.Errorf("date: expected a time or a string, got %T", value)
+}
+
+func formatDuration(value any) (string, error) {
+	var d time.Duration
+	switch v := value.(type) {
+	case time.Duration:
+		d = v
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return "", fmt.Errorf("duration: %w", err)
+		}
+		d = parsed
+	default:
+		secs, ok := toFloat(value)
+		if !ok {
+			return "", fmt.Errorf("duration: expected seconds or a duration, got %T", value)
+		}
+		d = time.Duration(secs * float64(time.Second))
+	}
+
+	if d < 0 {
+		d = -d
+	}
+	d = d.Round(time.Minute)
+	days, hours, mins := int(d/(24*time.Hour)), int(d%(24*time.Hour)/time.Hour), int(d%time.Hour/time.Minute)
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if mins > 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%dm", mins))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func groupBy(field string, list any) ([]Group, error) {
+	items, err := listValues("groupBy", list)
+	if err != nil {
+		return nil, err
+	}
+	var groups []Group
+	index := map[string]int{}
+	for _, item := range items {
+		v, err := fieldValue(item, field)
+		if err != nil {
+			return nil, fmt.Errorf("groupBy: %w", err)
+		}
+		key := fmt.Sprint(v.Interface())
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, Group{Key: key})
+		}
+		groups[i].Items = append(groups[i].Items, item.Interface())
+	}
+	return groups, nil
+}
+
+func sortBy(field string, list any) ([]any, error) {
+	items, err := listValues("sortBy", list)
+	if err != nil {
+		return nil, err
+	}
+	desc := strings.HasPrefix(field, "-")
+	field = strings.TrimPrefix(field, "-")
+
+	keys := make([]reflect.Value, len(items))
+	for i, item := range items {
+		if keys[i], err = fieldValue(item, field); err != nil {
+			return nil, fmt.Errorf("sortBy: %w", err)
+		}
+	}
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		less := lessValue(keys[order[a]], keys[order[b]])
+		if desc {
+			return lessValue(keys[order[b]], keys[order[a]])
+		}
+		return less
+	})
+
+	out := make([]any, len(items))
+	for i, idx := range order {
+		out[i] = items[idx].Interface()
+	}
+	return out, nil
+}
+
+// sum adds up list, which holds numbers or, with a field, items with a
+// numeric field: sum .Hours or sum "Commits" .WeeklyBreakdown.
+func sum(args ...any) (float64, error) {
+	var field string
+	switch len(args) {
+	case 1:
+	case 2:
+		f, ok := args[0].(string)
+		if !ok {
+			return 0, fmt.Errorf("sum: the field name must be a string")
+		}
+		field = f
+	default:
+		return 0, fmt.Errorf("sum: expected a list, optionally after a field name")
+	}
+	items, err := listValues("sum", args[len(args)-1])
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, item := range items {
+		v := item
+		if field != "" {
+			if v, err = fieldValue(item, field); err != nil {
+				return 0, fmt.Errorf("sum: %w", err)
+			}
+		}
+		n, ok := toFloat(v.Interface())
+		if !ok {
+			return 0, fmt.Errorf("sum: %v is not a number", v.Interface())
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func join(sep string, list any) (string, error) {
+	items, err := listValues("join", list)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = fmt.Sprint(item.Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// plural returns the count with the word, pluralized unless the count is 1.
+func plural(word string, count any) (string, error) {
+	n, ok := toFloat(count)
+	if !ok {
+		return "", fmt.Errorf("plural: %v is not a number", count)
+	}
+	num := strconv.FormatFloat(n, 'f', -1, 64)
+	if n == 1 {
+		return num + " " + word, nil
+	}
+	lower := strings.ToLower(word)
+	switch {
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		word = word[:len(word)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		word += "es"
+	default:
+		word += "s"
+	}
+	return num + " " + word, nil
+}
+
+func truncate(n int, s string) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimRight(string(runes[:n-1]), " ") + "…"
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`, "~", `\~`,
+)
+
+// escapeMarkdown escapes characters that would otherwise format s, such as a
+// commit message containing "*" or "_", and flattens newlines.
+func escapeMarkdown(s string) string {
+	s = markdownEscaper.Replace(s)
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, "\n", " ")), " ")
+}
+
+func defaultValue(fallback, value any) any {
+	if value == nil {
+		return fallback
+	}
+	v := reflect.ValueOf(value)
+	if v.IsZero() || ((v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0) {
+		return fallback
+	}
+	return value
+}
+
+func listValues(fn string, list any) ([]reflect.Value, error) {
+	v := indirect(reflect.ValueOf(list))
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%s: expected a list, got %T", fn, list)
+	}
+	out := make([]reflect.Value, v.Len())
+	for i := range out {
+		out[i] = v.Index(i)
+	}
+	return out, nil
+}
+
+// fieldValue returns a struct field or map key of item; dots walk nested
+// fields.
+func fieldValue(item reflect.Value, path string) (reflect.Value, error) {
+	v := item
+	for _, name := range strings.Split(path, ".") {
+		v = indirect(v)
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := v.Type().FieldByName(name)
+			if !ok || !f.IsExported() {
+				return reflect.Value{}, fmt.Errorf("no field %s in %s", name, v.Type())
+			}
+			v = v.FieldByIndex(f.Index)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, fmt.Errorf("can't look up %s in %s", name, v.Type())
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return reflect.ValueOf(""), nil
+			}
+		default:
+			return reflect.Value{}, fmt.Errorf("can't look up %s in %s", name, v.Type())
+		}
+	}
+	return v, nil
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func lessValue(a, b reflect.Value) bool {
+	a, b = indirect(a), indirect(b)
+	if !a.IsValid() || !b.IsValid() {
+		return !a.IsValid() && b.IsValid()
+	}
+	if x, ok := toFloat(a.Interface()); ok {
+		if y, ok := toFloat(b.Interface()); ok {
+			return x < y
+		}
+	}
+	if x, ok := a.Interface().(time.Time); ok {
+		if y, ok := b.Interface().(time.Time); ok {
+			return x.Before(y)
+		}
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+func toFloat(value any) (float64, bool) {
+	v := indirect(reflect.ValueOf(value))
+	if !v.IsValid() {
+		return 0, false
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		return f, !math.IsNaN(f) && !math.IsInf(f, 0)
+	}
+	return 0, false
+}
@@ -0,0 +1,273 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"text/template"
+	"text/template/parse"
+)
+
+const (
+	// MaxOutputBytes caps what a single template execution may write.
+	MaxOutputBytes = 1 << 20
+	// MaxRangeIterations caps the items all range actions of a single
+	// execution may visit, which bounds templates that loop without output.
+	MaxRangeIterations = 1_000_000
+)
+
+// rangeGuardFunc is the function the sandbox appends to every range
+// pipeline; see rangeGuard.
+const rangeGuardFunc = "rangeable"
+
+var (
+	errOutputTooLarge   = fmt.Errorf("output exceeds %d bytes", MaxOutputBytes)
+	errTooManyRanges    = fmt.Errorf("range actions visit more than %d items", MaxRangeIterations)
+	errRangeOverNumber  = errors.New("range over a number is not allowed in report templates")
+	errRangeOverFunc    = errors.New("range over a function or channel is not allowed in report templates")
+	sandboxRuntimeError = []error{errTooManyRanges, errRangeOverNumber, errRangeOverFunc}
+)
+
+// TemplateError is a problem found in a report template. Line and Column are
+// 1-based; Column is 0 when the template parser only reports the line.
+type TemplateError struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *TemplateError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// text/template reports "template: NAME:LINE[:COL]: [executing "NAME" ]MSG".
+var templateErrorPattern = regexp.MustCompile(`(?s)template: [^:]*:(\d+)(?::(\d+))?: (?:executing "[^"]*" )?(.*)`)
+
+// parse.Tree.ErrorContext reports "NAME:LINE:COL".
+var locationPattern = regexp.MustCompile(`:(\d+):(\d+)$`)
+
+// TemplateErrors extracts the position of a parse or execution error returned
+// by Render, RenderMonthly, RenderRange or Lint. Errors without a position are
+// returned on line 0.
+func TemplateErrors(err error) []TemplateError {
+	if err == nil {
+		return nil
+	}
+	var te *TemplateError
+	if errors.As(err, &te) {
+		return []TemplateError{*te}
+	}
+	if errors.Is(err, errOutputTooLarge) {
+		return []TemplateError{{Message: errOutputTooLarge.Error()}}
+	}
+	m := templateErrorPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return []TemplateError{{Message: err.Error()}}
+	}
+	line, _ := strconv.Atoi(m[1])
+	col, _ := strconv.Atoi(m[2])
+	if m[2] != "" {
+		col++
+	}
+	msg := m[3]
+	for _, sandboxErr := range sandboxRuntimeError {
+		if errors.Is(err, sandboxErr) {
+			msg = sandboxErr.Error()
+		}
+	}
+	return []TemplateError{{Line: line, Column: col, Message: msg}}
+}
+
+// TemplateTypes lists the report types a stored template can be written for.
+// Weekly templates also render range reports.
+var TemplateTypes = []string{"daily", "weekly", "monthly"}
+
+// Lint parses content and executes it against sample data for reportType
+// (daily, weekly, range or monthly), so that unknown fields and functions and
+// misused arguments surface before the template is saved. Branches the sample
+// data doesn't reach are only parsed.
+func Lint(content, reportType string) []TemplateError {
+	_, err := execute("report", content, SampleData(reportType))
+	return TemplateErrors(err)
+}
+
+// SampleData returns representative data for reportType, with every list
+// populated so that range bodies execute.
+func SampleData(reportType string) any {
+	commit := CommitReport{SHA: "a1b2c3d4", Message: "Fix login redirect", Branch: "feature/CORE-1-login", Repo: "org/app", Date: "2026-10-12", Time: "10:30"}
+	unlinked := CommitReport{SHA: "e5f6a7b8", Message: "Bump dependencies", Branch: "main", Repo: "org/api", Date: "2026-10-12", Time: "16:05"}
+	card := CardReport{Key: "CORE-1", Summary: "Login redirect loop", Status: "In Progress", Commits: []CommitReport{commit}}
+	pr := PullRequestReport{Number: 42, Title: "Fix login redirect", State: "open", Author: "ana", Repo: "org/app", Branch: "feature/CORE-1-login", JiraKey: "CORE-1", URL: "https://github.com/org/app/pull/42", Reviewers: []string{"bruno"}, Time: "11:00"}
+	stats := ReportStats{TotalCommits: 2, TotalCards: 1, Repos: []string{"org/api", "org/app"}, TotalPRsOpened: 1, TotalPRsReviewed: 1, TotalPRsMerged: 1}
+	prs := []PullRequestReport{pr}
+	narrative := Narrative{Summary: "Fixed the login redirect loop.", Highlights: []string{"CORE-1 fix is in review"}, Blockers: []string{"Waiting on QA for CORE-1"}}
+
+	switch reportType {
+	case "monthly":
+		return &MonthlyReportData{
+			Month: 10, Year: 2026, MonthName: "October", Author: "ana@example.com",
+			TotalCommits: 2, TotalCards: 1, CardsCompleted: 1, CardsInProgress: 1,
+			WeeklyBreakdown: []WeekStats{{WeekNumber: 1, StartDate: "2026-10-01", EndDate: "2026-10-04", Commits: 2, Cards: 1}},
+			RepoBreakdown:   []RepoStats{{Repo: "org/app", Commits: 2}},
+			TopCards:        []CardReport{card},
+			DailyReports:    []DailyReportSummary{{Date: "2026-10-12", Title: "Daily Report — 12 Oct 2026", Commits: 2, Cards: 1}},
+			Narrative:       narrative,
+		}
+	case "weekly", "range":
+		return &RangeReportData{
+			Title: "Weekly Report — 12 Oct – 18 Oct 2026", StartDate: "2026-10-12", EndDate: "2026-10-18",
+			Period: "12 Oct – 18 Oct 2026", Sprint: "Sprint 42", Author: "ana@example.com",
+			Days: []DayReport{{
+				Date: "2026-10-12", DateFormatted: "Monday, 12 Oct 2026", Commits: 2,
+				Cards: []CardReport{card}, UnlinkedCommits: []CommitReport{unlinked},
+			}},
+			Cards: []CardReport{card}, UnlinkedCommits: []CommitReport{unlinked},
+			PRsOpened: prs, PRsReviewed: prs, PRsMerged: prs,
+			ActiveDays: 1, Stats: stats,
+		}
+	}
+	return &ReportData{
+		Date: "2026-10-12", DateFormatted: "Monday, 12 Oct 2026", Author: "ana@example.com",
+		Cards: []CardReport{card}, UnlinkedCommits: []CommitReport{unlinked},
+		PRsOpened: prs, PRsReviewed: prs, PRsMerged: prs,
+		Stats: stats, Narrative: narrative,
+	}
+}
+
+// execute parses content with Funcs, checks it against the sandbox rules and
+// renders it with data, writing at most MaxOutputBytes and visiting at most
+// MaxRangeIterations items in range actions.
+func execute(name, content string, data any) (string, error) {
+	guard := &rangeGuard{}
+	tmpl, err := template.New(name).Funcs(Funcs).Funcs(template.FuncMap{rangeGuardFunc: guard.check}).Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+	if err := sandbox(tmpl); err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	out := &limitedBuffer{}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", fmt.Errorf("template execution failed: %w", err)
+	}
+	return out.String(), nil
+}
+
+// sandbox rejects the constructs that can make a template run for an
+// unbounded time without output: recursive {{template}} calls (and the
+// {{define}}/{{block}} that they need) and ranging over a number literal.
+// Every other range pipeline gets rangeGuardFunc appended, which checks the
+// value ranged over when the template runs.
+func sandbox(tmpl *template.Template) error {
+	for _, t := range tmpl.Templates() {
+		if t.Name() != tmpl.Name() && t.Tree != nil {
+			return nodeError(t.Tree, t.Tree.Root, "define and block are not allowed in report templates")
+		}
+	}
+	if tmpl.Tree == nil {
+		return nil
+	}
+	return walk(tmpl.Tree, tmpl.Tree.Root)
+}
+
+func walk(tree *parse.Tree, node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := walk(tree, child); err != nil {
+				return err
+			}
+		}
+	case *parse.TemplateNode:
+		return nodeError(tree, n, "template is not allowed in report templates")
+	case *parse.IfNode:
+		return walkBranch(tree, &n.BranchNode)
+	case *parse.WithNode:
+		return walkBranch(tree, &n.BranchNode)
+	case *parse.RangeNode:
+		if cmds := n.Pipe.Cmds; len(cmds) == 1 && len(cmds[0].Args) == 1 {
+			if _, ok := cmds[0].Args[0].(*parse.NumberNode); ok {
+				return nodeError(tree, n, errRangeOverNumber.Error())
+			}
+		}
+		// The guard receives the pipeline's value as its argument and
+		// reports errors at the pipeline's position.
+		guard := parse.NewIdentifier(rangeGuardFunc).SetTree(tree).SetPos(n.Pipe.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pipe.Pos,
+			Args:     []parse.Node{guard},
+		})
+		return walkBranch(tree, &n.BranchNode)
+	}
+	return nil
+}
+
+// rangeGuard checks each value a range action is about to visit: numbers
+// (which text/template counts up to), functions and channels are rejected,
+// and lists count towards MaxRangeIterations.
+type rangeGuard struct {
+	visited int
+}
+
+func (g *rangeGuard) check(value any) (any, error) {
+	v := indirect(reflect.ValueOf(value))
+	if !v.IsValid() {
+		return value, nil
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return nil, errRangeOverNumber
+	case reflect.Func, reflect.Chan:
+		return nil, errRangeOverFunc
+	case reflect.Slice, reflect.Array, reflect.Map:
+		g.visited += v.Len()
+		if g.visited > MaxRangeIterations {
+			return nil, errTooManyRanges
+		}
+	}
+	return value, nil
+}
+
+func walkBranch(tree *parse.Tree, n *parse.BranchNode) error {
+	if err := walk(tree, n.List); err != nil {
+		return err
+	}
+	return walk(tree, n.ElseList)
+}
+
+func nodeError(tree *parse.Tree, node parse.Node, msg string) error {
+	location, _ := tree.ErrorContext(node)
+	te := &TemplateError{Message: msg}
+	if m := locationPattern.FindStringSubmatch(location); m != nil {
+		te.Line, _ = strconv.Atoi(m[1])
+		te.Column, _ = strconv.Atoi(m[2])
+		te.Column++
+	}
+	return te
+}
+
+// limitedBuffer is a bytes.Buffer that fails writes past MaxOutputBytes,
+// which stops the template executing.
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > MaxOutputBytes {
+		return 0, errOutputTooLarge
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,90 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint_DefaultTemplates(t *testing.T) {
+	for kind, content := range map[string]string{"daily": DefaultTemplate, "weekly": DefaultWeeklyTemplate, "monthly": DefaultMonthlyTemplate} {
+		if errs := Lint(content, kind); errs != nil {
+			t.Errorf("%s default: %v", kind, errs)
+		}
+	}
+}
+
+func TestLint_Positions(t *testing.T) {
+	cases := []struct {
+		content, kind string
+		line, column  int
+		message       string
+	}{
+		{"# {{.DateFormatted}}\n\n{{range .Cards}}- {{.Title}}{{end}}", "daily", 3, 21, "can't evaluate field Title"},
+		{"# {{.MonthName}}\n{{.Date}}", "monthly", 2, 3, "can't evaluate field Date"},
+		{"line one\n{{nope .Date}}", "daily", 2, 0, `function "nope" not defined`},
+		{"{{if .Cards}}\n{{range 1000000000}}{{end}}{{end}}", "daily", 2, 9, "range over a number"},
+		{"{{$n := 1000000000}}{{range $n}}{{range $n}}{{end}}{{end}}", "daily", 1, 29, "range over a number"},
+		{"{{range (1000000000)}}{{end}}", "daily", 1, 9, "range over a number"},
+		{`{{define "x"}}{{template "x"}}{{end}}`, "daily", 1, 15, "define and block are not allowed"},
+		{`{{call .Author}}`, "daily", 1, 3, "call is not allowed"},
+		{`{{date "2006" .Author}}`, "daily", 1, 3, "can't parse"},
+	}
+	for _, tc := range cases {
+		errs := Lint(tc.content, tc.kind)
+		if len(errs) != 1 {
+			t.Errorf("%q: got %v, want one error", tc.content, errs)
+			continue
+		}
+		e := errs[0]
+		if e.Line != tc.line || e.Column != tc.column || !strings.Contains(e.Message, tc.message) {
+			t.Errorf("%q: got %d:%d %q, want %d:%d containing %q", tc.content, e.Line, e.Column, e.Message, tc.line, tc.column, tc.message)
+		}
+	}
+}
+
+func TestLint_OutputLimit(t *testing.T) {
+	content := "{{range .Cards}}" + strings.Repeat("x", MaxOutputBytes+1) + "{{end}}"
+	if errs := Lint(content, "daily"); len(errs) != 1 || !strings.Contains(errs[0].Message, "output exceeds") {
+		t.Errorf("got %v", errs)
+	}
+}
+
+func TestRender_RangeBudget(t *testing.T) {
+	data := &ReportData{UnlinkedCommits: make([]CommitReport, 1000)}
+	content := "{{range .UnlinkedCommits}}{{range $.UnlinkedCommits}}{{range $.UnlinkedCommits}}{{end}}{{end}}{{end}}"
+	_, err := (&Generator{}).Render(content, data)
+	if errs := TemplateErrors(err); len(errs) != 1 || !strings.Contains(errs[0].Message, "visit more than") {
+		t.Errorf("got %v", errs)
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	g := &Generator{}
+	data := &ReportData{
+		Date: "2026-10-12",
+		UnlinkedCommits: []CommitReport{
+			{SHA: "a1", Message: "Fix *bold* _typo_", Repo: "org/app"},
+			{SHA: "b2", Message: "Add [link]", Repo: "org/api"},
+			{SHA: "c3", Message: "Tidy", Repo: "org/app"},
+		},
+		Stats: ReportStats{TotalCommits: 3, Repos: []string{"org/api", "org/app"}},
+	}
+	content := `{{date "Mon 02 Jan" .Date}} · {{plural "commit" .Stats.TotalCommits}} · {{plural "entry" 1}} · {{duration 5400}} · {{.Stats.Repos | join ", "}}
+{{range groupBy "Repo" .UnlinkedCommits}}{{.Key}}: {{len .Items}}
+{{end}}{{range sortBy "-SHA" .UnlinkedCommits}}{{.SHA}} {{md .Message}} {{truncate 3 .Message}}
+{{end}}{{default "none" .Author}}`
+	got, err := g.Render(content, data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	want := `Mon 12 Oct · 3 commits · 1 entry · 1h 30m · org/api, org/app
+org/app: 2
+org/api: 1
+c3 Tidy Ti…
+b2 Add \[link\] Ad…
+a1 Fix \*bold\* \_typo\_ Fi…
+none`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
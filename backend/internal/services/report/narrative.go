@@ -0,0 +1,278 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/ai/minimax"
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+// NarrativeFeature is the AIUsage feature narratives are recorded under.
+const NarrativeFeature = "report_narrative"
+
+// narrativeVersion is part of the cache key; bump it when the prompt changes
+// so cached narratives are rewritten.
+const narrativeVersion = "1"
+
+const (
+	maxNarrativeComments    = 60
+	maxNarrativeCommentLen  = 500
+	maxNarrativeDescription = 1000
+)
+
+// NarrativeLLM writes report narratives. *minimax.Client satisfies it.
+type NarrativeLLM interface {
+	Chat(req minimax.ChatRequest) (*minimax.ChatResponse, error)
+}
+
+// Narrative is the AI-written part of a report. In a template, {{.Narrative}}
+// prints all three sections as Markdown, and nothing when no narrative was
+// requested; the fields can also be used on their own.
+type Narrative struct {
+	Summary    string   `json:"summary"`
+	Highlights []string `json:"highlights"`
+	Blockers   []string `json:"blockers"`
+}
+
+func (n Narrative) String() string {
+	if n.Summary == "" && len(n.Highlights) == 0 && len(n.Blockers) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Summary\n\n" + n.Summary + "\n")
+	for _, section := range []struct {
+		title string
+		items []string
+	}{{"Highlights", n.Highlights}, {"Blockers", n.Blockers}} {
+		b.WriteString("\n## " + section.title + "\n\n")
+		if len(section.items) == 0 {
+			b.WriteString("None.\n")
+		}
+		for _, item := range section.items {
+			b.WriteString("- " + item + "\n")
+		}
+	}
+	return b.String()
+}
+
+const narrativeSystemPrompt = `You write the narrative part of a developer's %s work report for their team lead.
+You receive JSON with the report period, the Jira cards worked on (with status, description and commit messages), commits not linked to a card, pull requests and the Jira comments posted in the period.
+Reply with a single JSON object and nothing else:
+{"summary": "2-4 sentences on what was achieved and why it matters", "highlights": ["..."], "blockers": ["..."]}
+Highlights are the most notable outcomes, at most 5. Blockers are impediments, risks or open questions evident from the data, especially from comments; use an empty list when there are none.
+Refer to cards by key, e.g. CORE-12. Only state what the data supports. Plain sentences, no Markdown headings.`
+
+type narrativeCard struct {
+	Key         string   `json:"key"`
+	Summary     string   `json:"summary,omitempty"`
+	Status      string   `json:"status,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Commits     []string `json:"commits,omitempty"`
+}
+
+type narrativeComment struct {
+	Card   string `json:"card"`
+	Author string `json:"author"`
+	At     string `json:"at"`
+	Body   string `json:"body"`
+}
+
+type narrativeInput struct {
+	Period          string             `json:"period"`
+	Author          string             `json:"author"`
+	Cards           []narrativeCard    `json:"cards"`
+	UnlinkedCommits []string           `json:"unlinked_commits,omitempty"`
+	PullRequests    []string           `json:"pull_requests,omitempty"`
+	Breakdown       any                `json:"breakdown,omitempty"`
+	Comments        []narrativeComment `json:"comments,omitempty"`
+}
+
+// Narrate writes data.Narrative for a daily report. The narrative cached on
+// rpt is reused while the report's inputs are unchanged; otherwise the LLM is
+// called, its token usage recorded and rpt's cache updated for the caller to
+// save.
+func (g *Generator) Narrate(rpt *models.Report, data *ReportData) error {
+	day, err := time.Parse("2006-01-02", data.Date)
+	if err != nil {
+		return fmt.Errorf("narrative: %w", err)
+	}
+	in := narrativeInput{Period: data.DateFormatted, Author: data.Author}
+	in.Cards = g.narrativeCards(rpt.UserID, data.Cards)
+	for _, c := range data.UnlinkedCommits {
+		in.UnlinkedCommits = append(in.UnlinkedCommits, c.Repo+": "+c.Message)
+	}
+	for _, group := range []struct {
+		verb string
+		prs  []PullRequestReport
+	}{{"opened", data.PRsOpened}, {"reviewed", data.PRsReviewed}, {"merged", data.PRsMerged}} {
+		for _, pr := range group.prs {
+			in.PullRequests = append(in.PullRequests, fmt.Sprintf("%s %s#%d %s", group.verb, pr.Repo, pr.Number, pr.Title))
+		}
+	}
+	in.Comments = g.narrativeComments(rpt.UserID, cardKeys(data.Cards), day, day.AddDate(0, 0, 1))
+
+	n, err := g.narrate(rpt, "daily", in)
+	if err != nil {
+		return err
+	}
+	data.Narrative = n
+	return nil
+}
+
+// NarrateMonthly is Narrate for a monthly report, written from the month's
+// top cards, breakdowns and comments.
+func (g *Generator) NarrateMonthly(rpt *models.Report, data *MonthlyReportData) error {
+	start := time.Date(data.Year, time.Month(data.Month), 1, 0, 0, 0, 0, time.Local)
+	in := narrativeInput{
+		Period: fmt.Sprintf("%s %d", data.MonthName, data.Year),
+		Author: data.Author,
+		Cards:  g.narrativeCards(rpt.UserID, data.TopCards),
+		Breakdown: map[string]any{
+			"total_commits":     data.TotalCommits,
+			"total_cards":       data.TotalCards,
+			"cards_completed":   data.CardsCompleted,
+			"cards_in_progress": data.CardsInProgress,
+			"weeks":             data.WeeklyBreakdown,
+			"repos":             data.RepoBreakdown,
+		},
+	}
+	in.Comments = g.narrativeComments(rpt.UserID, cardKeys(data.TopCards), start, start.AddDate(0, 1, 0))
+
+	n, err := g.narrate(rpt, "monthly", in)
+	if err != nil {
+		return err
+	}
+	data.Narrative = n
+	return nil
+}
+
+func (g *Generator) narrate(rpt *models.Report, kind string, in narrativeInput) (Narrative, error) {
+	var n Narrative
+	if g.LLM == nil {
+		return n, fmt.Errorf("narrative: no LLM is configured")
+	}
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return n, fmt.Errorf("narrative: %w", err)
+	}
+	sum := sha256.Sum256(append([]byte(narrativeVersion+g.LLMModel), payload...))
+	hash := hex.EncodeToString(sum[:])
+	if rpt.NarrativeHash == hash && json.Unmarshal([]byte(rpt.Narrative), &n) == nil {
+		return n, nil
+	}
+
+	resp, err := g.LLM.Chat(minimax.ChatRequest{
+		Model: g.LLMModel,
+		Messages: []minimax.Message{
+			{Role: "system", Content: fmt.Sprintf(narrativeSystemPrompt, kind)},
+			{Role: "user", Content: string(payload)},
+		},
+		Temperature: 0.3,
+	})
+	if err != nil {
+		return n, fmt.Errorf("narrative: %w", err)
+	}
+	g.DB.Create(&models.AIUsage{
+		UserID:           rpt.UserID,
+		Provider:         resp.Provider,
+		Model:            resp.Model,
+		Feature:          NarrativeFeature,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	})
+
+	content := resp.Content
+	if len(resp.Choices) > 0 {
+		content = resp.Choices[0].Delta.Content
+	}
+	if n, err = parseNarrative(content); err != nil {
+		return n, err
+	}
+	cached, _ := json.Marshal(n)
+	rpt.Narrative = string(cached)
+	rpt.NarrativeHash = hash
+	return n, nil
+}
+
+// parseNarrative reads the model's JSON reply, ignoring any reasoning or
+// code fence around the object.
+func parseNarrative(content string) (Narrative, error) {
+	var n Narrative
+	if i := strings.LastIndex(content, "</think>"); i >= 0 {
+		content = content[i+len("</think>"):]
+	}
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return n, fmt.Errorf("narrative: the reply has no JSON object")
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &n); err != nil {
+		return n, fmt.Errorf("narrative: %w", err)
+	}
+	n.Summary = strings.TrimSpace(n.Summary)
+	if n.Summary == "" {
+		return n, fmt.Errorf("narrative: the reply has no summary")
+	}
+	return n, nil
+}
+
+// narrativeCards adds each card's synced description to its report entry.
+func (g *Generator) narrativeCards(userID uint, cards []CardReport) []narrativeCard {
+	var synced []models.JiraCard
+	if keys := cardKeys(cards); len(keys) > 0 {
+		g.DB.Select("card_key, details_json").Where("user_id = ? AND card_key IN ?", userID, keys).Find(&synced)
+	}
+	descriptions := make(map[string]string, len(synced))
+	for _, jc := range synced {
+		var details map[string]any
+		if json.Unmarshal([]byte(jc.DetailsJSON), &details) == nil {
+			if desc, ok := details["description"].(string); ok {
+				descriptions[jc.Key] = truncate(maxNarrativeDescription, desc)
+			}
+		}
+	}
+
+	out := make([]narrativeCard, 0, len(cards))
+	for _, c := range cards {
+		nc := narrativeCard{Key: c.Key, Summary: c.Summary, Status: c.Status, Description: descriptions[c.Key]}
+		for _, commit := range c.Commits {
+			nc.Commits = append(nc.Commits, commit.Message)
+		}
+		out = append(out, nc)
+	}
+	return out
+}
+
+// narrativeComments returns the comments posted on the cards in [start, end),
+// oldest first.
+func (g *Generator) narrativeComments(userID uint, keys []string, start, end time.Time) []narrativeComment {
+	if len(keys) == 0 {
+		return nil
+	}
+	var comments []models.JiraComment
+	g.DB.Where("user_id = ? AND card_key IN ? AND commented_at >= ? AND commented_at < ?", userID, keys, start, end).
+		Order("commented_at DESC").Limit(maxNarrativeComments).Find(&comments)
+
+	out := make([]narrativeComment, len(comments))
+	for i, c := range comments {
+		out[len(comments)-1-i] = narrativeComment{
+			Card:   c.CardKey,
+			Author: c.Author,
+			At:     c.CommentedAt.Format("2006-01-02 15:04"),
+			Body:   truncate(maxNarrativeCommentLen, c.Body),
+		}
+	}
+	return out
+}
+
+func cardKeys(cards []CardReport) []string {
+	keys := make([]string, len(cards))
+	for i, c := range cards {
+		keys[i] = c.Key
+	}
+	return keys
+}
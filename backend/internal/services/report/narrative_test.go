@@ -0,0 +1,84 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/ai/minimax"
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+type stubLLM struct {
+	requests []minimax.ChatRequest
+	reply    string
+}
+
+func (s *stubLLM) Chat(req minimax.ChatRequest) (*minimax.ChatResponse, error) {
+	s.requests = append(s.requests, req)
+	return &minimax.ChatResponse{
+		Provider: "stub",
+		Model:    req.Model,
+		Choices:  []minimax.Choice{{Delta: minimax.Delta{Content: s.reply}}},
+		Usage:    minimax.Usage{PromptTokens: 120, CompletionTokens: 40},
+	}, nil
+}
+
+func TestNarrate_CachedOnReport(t *testing.T) {
+	db := setupReportDB(t)
+	if err := db.AutoMigrate(&models.JiraComment{}, &models.AIUsage{}, &models.Report{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	user := models.User{Email: "ana@corp.com", Password: "x"}
+	db.Create(&user)
+	db.Create(&models.JiraCard{UserID: user.ID, Key: "CORE-1", Summary: "Login loop", DetailsJSON: `{"description":"Users bounce between /login and /home"}`})
+	day := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	db.Create(&models.JiraComment{UserID: user.ID, CardKey: "CORE-1", CommentID: "1", Author: "QA", Body: "Still failing on Safari", CommentedAt: day.Add(14 * time.Hour)})
+	db.Create(&models.JiraComment{UserID: user.ID, CardKey: "CORE-1", CommentID: "2", Author: "QA", Body: "yesterday's note", CommentedAt: day.Add(-2 * time.Hour)})
+
+	llm := &stubLLM{reply: "<think>plan</think>\n```json\n{\"summary\": \"Fixed the login loop.\", \"highlights\": [\"CORE-1 fixed\"], \"blockers\": [\"Safari still fails\"]}\n```"}
+	g := NewGenerator(db, nil)
+	g.LLM, g.LLMModel = llm, "test-model"
+
+	data := &ReportData{Date: "2026-10-12", DateFormatted: "Monday, 12 October 2026", Author: "ana@corp.com",
+		Cards: []CardReport{{Key: "CORE-1", Summary: "Login loop", Commits: []CommitReport{{Message: "Fix redirect"}}}}}
+	rpt := models.Report{UserID: user.ID}
+	if err := g.Narrate(&rpt, data); err != nil {
+		t.Fatalf("Narrate: %v", err)
+	}
+	if data.Narrative.Summary != "Fixed the login loop." || len(data.Narrative.Blockers) != 1 {
+		t.Fatalf("unexpected narrative %+v", data.Narrative)
+	}
+	prompt := llm.requests[0].Messages[1].Content
+	for _, want := range []string{"Users bounce between", "Still failing on Safari", "Fix redirect"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt lacks %q", want)
+		}
+	}
+	if strings.Contains(prompt, "yesterday's note") {
+		t.Error("prompt includes a comment from another day")
+	}
+	var usage models.AIUsage
+	if db.Where("feature = ?", NarrativeFeature).First(&usage).Error != nil || usage.PromptTokens != 120 || usage.Provider != "stub" || usage.Model != "test-model" {
+		t.Errorf("usage not recorded: %+v", usage)
+	}
+
+	// Unchanged data reuses the narrative cached on the report.
+	data.Narrative = Narrative{}
+	if err := g.Narrate(&rpt, data); err != nil || len(llm.requests) != 1 || data.Narrative.Summary == "" {
+		t.Fatalf("expected the cached narrative, got %+v after %d calls (%v)", data.Narrative, len(llm.requests), err)
+	}
+	data.Cards[0].Status = "Done"
+	g.Narrate(&rpt, data)
+	if len(llm.requests) != 2 {
+		t.Errorf("changed data should call the model again, got %d calls", len(llm.requests))
+	}
+
+	rendered, err := g.Render("{{.Narrative}}", data)
+	if err != nil || !strings.Contains(rendered, "## Blockers\n\n- Safari still fails") {
+		t.Errorf("rendered %q, %v", rendered, err)
+	}
+	if rendered, _ := g.Render("[{{.Narrative}}]", &ReportData{}); rendered != "[]" {
+		t.Errorf("empty narrative rendered %q", rendered)
+	}
+}
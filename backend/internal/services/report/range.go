@@ -0,0 +1,265 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/cardlink"
+	"github.com/cds-id/pdt/backend/internal/services/identity"
+	"github.com/cds-id/pdt/backend/internal/services/timesheet"
+)
+
+// MaxRangeDays is the longest range a range report may cover.
+const MaxRangeDays = 92
+
+const DefaultWeeklyTemplate = `# {{.Title}}
+
+**Author:** {{.Author}}
+
+**Period:** {{.StartDate}} — {{.EndDate}}{{if .Sprint}} ({{.Sprint}}){{end}}
+
+## Summary
+- **Commits:** {{.Stats.TotalCommits}}
+- **Active Days:** {{.ActiveDays}}
+- **Jira Cards:** {{.Stats.TotalCards}}
+- **Pull Requests:** {{.Stats.TotalPRsOpened}} opened, {{.Stats.TotalPRsReviewed}} reviewed, {{.Stats.TotalPRsMerged}} merged
+- **Repositories:** {{range $i, $r := .Stats.Repos}}{{if $i}}, {{end}}{{$r}}{{end}}
+
+## Cards
+{{range .Cards}}
+### {{.Key}} — {{.Summary}}
+**Status:** {{.Status}} · {{len .Commits}} commits
+{{range .Commits}}
+- ` + "`{{.SHA}}`" + ` {{.Message}} ({{.Date}} {{.Time}})
+{{end}}
+{{end}}
+{{if .UnlinkedCommits}}
+## Other Commits
+{{range .UnlinkedCommits}}
+- ` + "`{{.SHA}}`" + ` {{.Message}} ({{.Repo}}/{{.Branch}}, {{.Date}})
+{{end}}
+{{end}}
+## Day by Day
+{{range .Days}}
+### {{.DateFormatted}}
+{{range .Cards}}
+- **{{.Key}}**{{if .Summary}} {{.Summary}}{{end}}: {{len .Commits}} commits
+{{end}}{{if .UnlinkedCommits}}
+- Other: {{len .UnlinkedCommits}} commits
+{{end}}
+{{end}}
+{{if or .PRsOpened .PRsReviewed .PRsMerged}}
+## Pull Requests
+{{range .PRsOpened}}
+- Opened #{{.Number}} {{.Title}}{{if .JiraKey}} for {{.JiraKey}}{{end}} ({{.Repo}})
+{{end}}
+{{range .PRsReviewed}}
+- Reviewed #{{.Number}} {{.Title}}{{if .JiraKey}} for {{.JiraKey}}{{end}} ({{.Repo}})
+{{end}}
+{{range .PRsMerged}}
+- Merged #{{.Number}} {{.Title}}{{if .JiraKey}} for {{.JiraKey}}{{end}} ({{.Repo}})
+{{end}}
+{{end}}`
+
+// RangeReportData covers every day from StartDate to EndDate inclusive, such
+// as a week or a sprint. Cards groups the commits by card across the whole
+// range and Days groups them by day.
+type RangeReportData struct {
+	Title           string
+	StartDate       string
+	EndDate         string
+	Period          string
+	Sprint          string
+	Author          string
+	Days            []DayReport
+	Cards           []CardReport
+	UnlinkedCommits []CommitReport
+	PRsOpened       []PullRequestReport
+	PRsReviewed     []PullRequestReport
+	PRsMerged       []PullRequestReport
+	ActiveDays      int
+	Stats           ReportStats
+}
+
+// DayReport is one day of a range report that had commits.
+type DayReport struct {
+	Date            string
+	DateFormatted   string
+	Commits         int
+	Cards           []CardReport
+	UnlinkedCommits []CommitReport
+}
+
+// HasActivity reports whether the range had any commits or PR activity.
+func (d *RangeReportData) HasActivity() bool {
+	return d.Stats.TotalCommits > 0 || len(d.PRsOpened) > 0 || len(d.PRsReviewed) > 0 || len(d.PRsMerged) > 0
+}
+
+// BuildRangeReportData aggregates commits, Jira cards and pull requests for a
+// user from start to end, both days inclusive. The author filter is the same
+// as BuildReportData's.
+func (g *Generator) BuildRangeReportData(userID uint, start, end time.Time, opts ...BuildOptions) (*RangeReportData, error) {
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, start.Location())
+	if endDay.Before(startDay) {
+		return nil, fmt.Errorf("end date is before start date")
+	}
+	rangeEnd := endDay.AddDate(0, 0, 1)
+	if startDay.AddDate(0, 0, MaxRangeDays).Before(rangeEnd) {
+		return nil, fmt.Errorf("range is longer than %d days", MaxRangeDays)
+	}
+
+	var user models.User
+	if err := g.DB.First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	var filter *identity.Aliases
+	if mine, filtered := g.authorFilter(user, opts); filtered {
+		filter = &mine
+	}
+	commits := g.findCommits(userID, startDay, rangeEnd, filter)
+	linked := cardlink.Keys(g.DB, commitIDs(commits))
+
+	type dayGroup struct {
+		date     time.Time
+		commits  int
+		cards    map[string][]CommitReport
+		unlinked []CommitReport
+	}
+	var days []*dayGroup
+	byDate := map[string]*dayGroup{}
+	cardCommits := map[string][]CommitReport{}
+	var unlinked []CommitReport
+	repoSet := map[string]bool{}
+
+	for _, c := range commits {
+		local := c.Date.In(startDay.Location())
+		cr := commitReport(c)
+		cr.Date = local.Format("2006-01-02")
+		cr.Time = local.Format("15:04")
+		repoSet[cr.Repo] = true
+
+		day, ok := byDate[cr.Date]
+		if !ok {
+			day = &dayGroup{date: local, cards: map[string][]CommitReport{}}
+			byDate[cr.Date] = day
+			days = append(days, day)
+		}
+		day.commits++
+
+		// As in daily reports, a commit is listed under each card it links.
+		for _, key := range linked[c.ID] {
+			cardCommits[key] = append(cardCommits[key], cr)
+			day.cards[key] = append(day.cards[key], cr)
+		}
+		if len(linked[c.ID]) == 0 {
+			unlinked = append(unlinked, cr)
+			day.unlinked = append(day.unlinked, cr)
+		}
+	}
+
+	details := g.cardDetails(user, mapKeys(cardCommits))
+	cards := cardReports(details, cardCommits)
+
+	dayReports := make([]DayReport, 0, len(days))
+	for _, day := range days {
+		dayReports = append(dayReports, DayReport{
+			Date:            day.date.Format("2006-01-02"),
+			DateFormatted:   day.date.Format("Monday, 02 January 2006"),
+			Commits:         day.commits,
+			Cards:           cardReports(details, day.cards),
+			UnlinkedCommits: day.unlinked,
+		})
+	}
+
+	repos := mapKeys(repoSet)
+	opened, reviewed, merged := g.buildPullRequestActivity(userID, startDay, rangeEnd, filter)
+
+	period := formatPeriod(startDay, endDay)
+	return &RangeReportData{
+		Title:           "Report — " + period,
+		StartDate:       startDay.Format("2006-01-02"),
+		EndDate:         endDay.Format("2006-01-02"),
+		Period:          period,
+		Author:          user.Email,
+		Days:            dayReports,
+		Cards:           cards,
+		UnlinkedCommits: unlinked,
+		PRsOpened:       opened,
+		PRsReviewed:     reviewed,
+		PRsMerged:       merged,
+		ActiveDays:      len(dayReports),
+		Stats: ReportStats{
+			TotalCommits:     len(commits),
+			TotalCards:       len(cards),
+			Repos:            repos,
+			TotalPRsOpened:   len(opened),
+			TotalPRsReviewed: len(reviewed),
+			TotalPRsMerged:   len(merged),
+		},
+	}, nil
+}
+
+// BuildWeeklyReportData builds the range report for the Monday-to-Sunday week
+// containing day.
+func (g *Generator) BuildWeeklyReportData(userID uint, day time.Time, opts ...BuildOptions) (*RangeReportData, error) {
+	start := timesheet.WeekStart(day)
+	data, err := g.BuildRangeReportData(userID, start, start.AddDate(0, 0, 6), opts...)
+	if err != nil {
+		return nil, err
+	}
+	data.Title = "Weekly Report — " + data.Period
+	return data, nil
+}
+
+// BuildSprintReportData builds the range report from one of the user's
+// sprints' start date to its end date.
+func (g *Generator) BuildSprintReportData(userID, sprintID uint, opts ...BuildOptions) (*RangeReportData, error) {
+	var sprint models.Sprint
+	if err := g.DB.Where("id = ? AND user_id = ?", sprintID, userID).First(&sprint).Error; err != nil {
+		return nil, fmt.Errorf("sprint not found")
+	}
+	if sprint.StartDate == nil || sprint.EndDate == nil {
+		return nil, fmt.Errorf("sprint %s has no start or end date", sprint.Name)
+	}
+
+	start := sprint.StartDate.In(time.Local)
+	data, err := g.BuildRangeReportData(userID, start, sprint.EndDate.In(time.Local), opts...)
+	if err != nil {
+		return nil, err
+	}
+	data.Sprint = sprint.Name
+	data.Title = "Sprint Report — " + sprint.Name
+	return data, nil
+}
+
+// GetWeeklyTemplateContent returns the template for weekly and range reports.
+// Priority: specific template_id > user's default weekly template > built-in default.
+func (g *Generator) GetWeeklyTemplateContent(userID uint, templateID *uint) (string, *uint) {
+	var tmpl models.ReportTemplate
+	if templateID != nil {
+		if err := g.DB.Where("id = ? AND user_id = ?", *templateID, userID).First(&tmpl).Error; err == nil {
+			return tmpl.Content, &tmpl.ID
+		}
+	}
+	if g.DB.Where("user_id = ? AND report_type = ? AND is_default = ?", userID, "weekly", true).First(&tmpl).Error == nil {
+		return tmpl.Content, &tmpl.ID
+	}
+	return DefaultWeeklyTemplate, nil
+}
+
+// RenderRange renders a template string with RangeReportData.
+func (g *Generator) RenderRange(templateContent string, data *RangeReportData) (string, error) {
+	return execute("range_report", templateContent, data)
+}
+
+// formatPeriod formats a range as "12 Oct – 18 Oct 2026", repeating the
+// year only when the range spans two.
+func formatPeriod(start, end time.Time) string {
+	if start.Year() == end.Year() {
+		return start.Format("02 Jan") + " – " + end.Format("02 Jan 2006")
+	}
+	return start.Format("02 Jan 2006") + " – " + end.Format("02 Jan 2006")
+}
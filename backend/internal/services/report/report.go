@@ -1,15 +1,17 @@
 package report
 
 import (
-	"bytes"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
-	"text/template"
 	"time"
 
 	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/helpers"
 	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/cardlink"
+	"github.com/cds-id/pdt/backend/internal/services/identity"
 	"github.com/cds-id/pdt/backend/internal/services/jira"
 	"gorm.io/gorm"
 )
@@ -19,10 +21,18 @@ const DefaultTemplate = `# Daily Report — {{.DateFormatted}}
 **Author:** {{.Author}}
 
 ## Summary
-- **Commits:** {{.Stats.TotalCommits}}
+{{with .Narrative.Summary}}{{.}}
+
+{{end}}- **Commits:** {{.Stats.TotalCommits}}
 - **Jira Cards:** {{.Stats.TotalCards}}
 - **Repositories:** {{range $i, $r := .Stats.Repos}}{{if $i}}, {{end}}{{$r}}{{end}}
-
+{{with .Narrative.Highlights}}
+## Highlights
+{{range .}}- {{.}}
+{{end}}{{end}}{{with .Narrative.Blockers}}
+## Blockers
+{{range .}}- {{.}}
+{{end}}{{end}}
 ## Work Details
 {{range .Cards}}
 ### {{.Key}} — {{.Summary}}
@@ -36,6 +46,18 @@ const DefaultTemplate = `# Daily Report — {{.DateFormatted}}
 {{range .UnlinkedCommits}}
 - ` + "`{{.SHA}}`" + ` {{.Message}} ({{.Repo}}/{{.Branch}}, {{.Time}})
 {{end}}
+{{end}}
+{{if or .PRsOpened .PRsReviewed .PRsMerged}}
+## Pull Requests
+{{range .PRsOpened}}
+- Opened #{{.Number}} {{.Title}}{{if .JiraKey}} for {{.JiraKey}}{{end}} ({{.Repo}})
+{{end}}
+{{range .PRsReviewed}}
+- Reviewed #{{.Number}} {{.Title}}{{if .JiraKey}} for {{.JiraKey}}{{end}} ({{.Repo}})
+{{end}}
+{{range .PRsMerged}}
+- Merged #{{.Number}} {{.Title}}{{if .JiraKey}} for {{.JiraKey}}{{end}} ({{.Repo}})
+{{end}}
 {{end}}`
 
 type ReportData struct {
@@ -44,7 +66,11 @@ type ReportData struct {
 	Author          string
 	Cards           []CardReport
 	UnlinkedCommits []CommitReport
+	PRsOpened       []PullRequestReport
+	PRsReviewed     []PullRequestReport
+	PRsMerged       []PullRequestReport
 	Stats           ReportStats
+	Narrative       Narrative // empty unless Narrate was called
 }
 
 type CardReport struct {
@@ -59,26 +85,70 @@ type CommitReport struct {
 	Message string
 	Branch  string
 	Repo    string
+	Date    string
 	Time    string
 }
 
+// HasActivity reports whether the day had any commits or PR activity.
+func (d *ReportData) HasActivity() bool {
+	return d.Stats.TotalCommits > 0 || len(d.PRsOpened) > 0 || len(d.PRsReviewed) > 0 || len(d.PRsMerged) > 0
+}
+
+// PullRequestReport describes a PR/MR in a report. Reviewers lists who
+// reviewed it that day for PRsReviewed, and all reviewers otherwise.
+type PullRequestReport struct {
+	Number    int
+	Title     string
+	State     string
+	Author    string
+	Repo      string
+	Branch    string
+	JiraKey   string
+	URL       string
+	Reviewers []string
+	Time      string
+}
+
 type ReportStats struct {
-	TotalCommits int
-	TotalCards   int
-	Repos        []string
+	TotalCommits     int
+	TotalCards       int
+	Repos            []string
+	TotalPRsOpened   int
+	TotalPRsReviewed int
+	TotalPRsMerged   int
 }
 
 type Generator struct {
 	DB        *gorm.DB
 	Encryptor *crypto.Encryptor // nil = skip Jira API fallback
+	LLM       NarrativeLLM      // nil = narratives unavailable
+	LLMModel  string            // model for narratives; empty = the client's default
 }
 
 func NewGenerator(db *gorm.DB, enc *crypto.Encryptor) *Generator {
 	return &Generator{DB: db, Encryptor: enc}
 }
 
+// BuildOptions tunes what a report collects.
+type BuildOptions struct {
+	// AllAuthors includes every author's commits and pull requests instead
+	// of only the user's own.
+	AllAuthors bool
+}
+
+// authorFilter returns the user's "my work" filter; ok is false when the
+// report should include all authors.
+func (g *Generator) authorFilter(user models.User, opts []BuildOptions) (identity.Aliases, bool) {
+	if len(opts) > 0 && opts[0].AllAuthors {
+		return identity.Aliases{}, false
+	}
+	return identity.AuthorFilter(g.DB, user)
+}
+
 // BuildReportData aggregates commits and Jira cards for a user on a given date.
-func (g *Generator) BuildReportData(userID uint, date time.Time) (*ReportData, error) {
+// Only the user's own commits and pull requests are included unless the user
+// or opts asks for all authors.
+func (g *Generator) BuildReportData(userID uint, date time.Time, opts ...BuildOptions) (*ReportData, error) {
 	var user models.User
 	if err := g.DB.First(&user, userID).Error; err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
@@ -87,92 +157,274 @@ func (g *Generator) BuildReportData(userID uint, date time.Time) (*ReportData, e
 	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 	dayEnd := dayStart.Add(24 * time.Hour)
 
-	var commits []models.Commit
-	g.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
-		Where("repositories.user_id = ? AND commits.date >= ? AND commits.date < ?", userID, dayStart, dayEnd).
-		Preload("Repository").
-		Order("commits.date asc").
-		Find(&commits)
+	mine, filtered := g.authorFilter(user, opts)
+
+	var filter *identity.Aliases
+	if filtered {
+		filter = &mine
+	}
+	commits := g.findCommits(userID, dayStart, dayEnd, filter)
 
 	cardCommits := map[string][]CommitReport{}
 	var unlinked []CommitReport
 	repoSet := map[string]bool{}
+	linked := cardlink.Keys(g.DB, commitIDs(commits))
 
 	for _, c := range commits {
-		repoName := fmt.Sprintf("%s/%s", c.Repository.Owner, c.Repository.Name)
-		repoSet[repoName] = true
-
-		cr := CommitReport{
-			SHA:     shortSHA(c.SHA),
-			Message: firstLine(c.Message),
-			Branch:  c.Branch,
-			Repo:    repoName,
-			Time:    c.Date.Format("15:04"),
-		}
+		cr := commitReport(c)
+		repoSet[cr.Repo] = true
 
-		if c.JiraCardKey != "" {
-			cardCommits[c.JiraCardKey] = append(cardCommits[c.JiraCardKey], cr)
-		} else {
-			unlinked = append(unlinked, cr)
+		// A commit referencing several cards is listed under each of them.
+		for _, key := range linked[c.ID] {
+			cardCommits[key] = append(cardCommits[key], cr)
 		}
-	}
-
-	// Build Jira client for API fallback (if user has Jira configured)
-	var jiraClient *jira.Client
-	if g.Encryptor != nil && user.JiraToken != "" && user.JiraWorkspace != "" && user.JiraEmail != "" {
-		token, err := g.Encryptor.Decrypt(user.JiraToken)
-		if err == nil {
-			jiraClient = jira.New(user.JiraWorkspace, user.JiraEmail, token)
+		if len(linked[c.ID]) == 0 {
+			unlinked = append(unlinked, cr)
 		}
 	}
 
-	var cards []CardReport
-	for key, crs := range cardCommits {
-		card := CardReport{
-			Key:     key,
-			Commits: crs,
-		}
-
-		// Try DB first
-		var jiraCard models.JiraCard
-		if err := g.DB.Where("user_id = ? AND card_key = ?", userID, key).First(&jiraCard).Error; err == nil {
-			card.Summary = jiraCard.Summary
-			card.Status = jiraCard.Status
-		} else if jiraClient != nil {
-			// Fallback: fetch from Jira API
-			if issue, err := jiraClient.FetchIssue(key); err == nil {
-				card.Summary = issue.Summary
-				card.Status = issue.Status
-				log.Printf("[report] fetched Jira card %s from API: %s (%s)", key, issue.Summary, issue.Status)
-			} else {
-				log.Printf("[report] failed to fetch Jira card %s: %v", key, err)
-			}
-		}
-
-		cards = append(cards, card)
-	}
+	cards := cardReports(g.cardDetails(user, mapKeys(cardCommits)), cardCommits)
 
 	var repos []string
 	for r := range repoSet {
 		repos = append(repos, r)
 	}
 
+	opened, reviewed, merged := g.buildPullRequestActivity(userID, dayStart, dayEnd, filter)
+
 	data := &ReportData{
 		Date:            date.Format("2006-01-02"),
 		DateFormatted:   date.Format("Monday, 02 January 2006"),
 		Author:          user.Email,
 		Cards:           cards,
 		UnlinkedCommits: unlinked,
+		PRsOpened:       opened,
+		PRsReviewed:     reviewed,
+		PRsMerged:       merged,
 		Stats: ReportStats{
-			TotalCommits: len(commits),
-			TotalCards:   len(cards),
-			Repos:        repos,
+			TotalCommits:     len(commits),
+			TotalCards:       len(cards),
+			Repos:            repos,
+			TotalPRsOpened:   len(opened),
+			TotalPRsReviewed: len(reviewed),
+			TotalPRsMerged:   len(merged),
 		},
 	}
 
 	return data, nil
 }
 
+// findCommits returns the commits on the user's repositories within
+// [start, end), oldest first. A non-nil mine limits them to its authors.
+func (g *Generator) findCommits(userID uint, start, end time.Time, mine *identity.Aliases) []models.Commit {
+	var commits []models.Commit
+	commitQuery := g.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
+		Where("repositories.user_id = ? AND commits.date >= ? AND commits.date < ?", userID, start, end)
+	if mine != nil {
+		commitQuery = mine.ScopeCommits(commitQuery)
+	}
+	commitQuery.Preload("Repository").
+		Order("commits.date asc").
+		Find(&commits)
+	return commits
+}
+
+func commitReport(c models.Commit) CommitReport {
+	return CommitReport{
+		SHA:     shortSHA(c.SHA),
+		Message: firstLine(c.Message),
+		Branch:  c.Branch,
+		Repo:    fmt.Sprintf("%s/%s", c.Repository.Owner, c.Repository.Name),
+		Date:    c.Date.Format("2006-01-02"),
+		Time:    c.Date.Format("15:04"),
+	}
+}
+
+// cardReports returns a CardReport, sorted by key, for each card in
+// cardCommits, with the summary and status from details.
+func cardReports(details map[string]CardReport, cardCommits map[string][]CommitReport) []CardReport {
+	var cards []CardReport
+	for _, key := range mapKeys(cardCommits) {
+		card := details[key]
+		card.Key = key
+		card.Commits = cardCommits[key]
+		cards = append(cards, card)
+	}
+	return cards
+}
+
+// cardDetails looks up the summary and status of each card key in the synced
+// cards, falling back to the Jira API of the workspace the card belongs to.
+func (g *Generator) cardDetails(user models.User, keys []string) map[string]CardReport {
+	details := make(map[string]CardReport, len(keys))
+	if len(keys) == 0 {
+		return details
+	}
+
+	var synced []models.JiraCard
+	g.DB.Where("user_id = ? AND card_key IN ?", user.ID, keys).Find(&synced)
+	for _, jc := range synced {
+		details[jc.Key] = CardReport{Key: jc.Key, Summary: jc.Summary, Status: jc.Status}
+	}
+
+	var missing []string
+	for _, key := range keys {
+		if _, ok := details[key]; !ok {
+			details[key] = CardReport{Key: key}
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 || g.Encryptor == nil {
+		return details
+	}
+
+	// Fallback: fetch from the Jira API, one client per workspace
+	var workspaces []models.JiraWorkspaceConfig
+	g.DB.Where("user_id = ? AND is_active = ?", user.ID, true).Order("id").Find(&workspaces)
+	byWorkspace := map[int][]string{}
+	for _, key := range missing {
+		if i := workspaceFor(workspaces, key); i >= 0 {
+			byWorkspace[i] = append(byWorkspace[i], key)
+		}
+	}
+	for i, ws := range workspaces {
+		if len(byWorkspace[i]) == 0 {
+			continue
+		}
+		client, err := jira.ClientFor(ws, user, g.Encryptor)
+		if err != nil {
+			log.Printf("[report] no Jira client for workspace %s: %v", ws.Workspace, err)
+			continue
+		}
+		for _, key := range byWorkspace[i] {
+			issue, err := client.FetchIssue(key)
+			if err != nil {
+				log.Printf("[report] failed to fetch Jira card %s: %v", key, err)
+				continue
+			}
+			details[key] = CardReport{Key: key, Summary: issue.Summary, Status: issue.Status}
+			log.Printf("[report] fetched Jira card %s from API: %s (%s)", key, issue.Summary, issue.Status)
+		}
+	}
+	return details
+}
+
+// workspaceFor returns the index of the workspace a card key belongs to: the
+// first whose project keys list the key's project, else the first without
+// project keys. It returns -1 when none fits.
+func workspaceFor(workspaces []models.JiraWorkspaceConfig, key string) int {
+	fallback := -1
+	for i, ws := range workspaces {
+		if ws.ProjectKeys == "" {
+			if fallback < 0 {
+				fallback = i
+			}
+		} else if helpers.FilterByProjectKeys(key, ws.ProjectKeys) {
+			return i
+		}
+	}
+	return fallback
+}
+
+// buildPullRequestActivity returns the PRs on the user's repositories that
+// were opened, reviewed or merged within [start, end). A non-nil mine limits
+// opened and merged PRs to the ones authored by it, and reviews to its own.
+func (g *Generator) buildPullRequestActivity(userID uint, start, end time.Time, mine *identity.Aliases) (opened, reviewed, merged []PullRequestReport) {
+	base := func() *gorm.DB {
+		q := g.DB.Model(&models.PullRequest{}).
+			Joins("JOIN repositories ON repositories.id = pull_requests.repo_id").
+			Where("repositories.user_id = ?", userID).
+			Preload("Repository")
+		if mine != nil {
+			q = mine.ScopePullRequests(q)
+		}
+		return q
+	}
+
+	var openedPRs []models.PullRequest
+	base().Where("pull_requests.opened_at >= ? AND pull_requests.opened_at < ?", start, end).
+		Order("pull_requests.opened_at asc").Find(&openedPRs)
+	for _, pr := range openedPRs {
+		opened = append(opened, pullRequestReport(pr, pr.OpenedAt))
+	}
+
+	var mergedPRs []models.PullRequest
+	base().Where("pull_requests.merged_at >= ? AND pull_requests.merged_at < ?", start, end).
+		Order("pull_requests.merged_at asc").Find(&mergedPRs)
+	for _, pr := range mergedPRs {
+		merged = append(merged, pullRequestReport(pr, *pr.MergedAt))
+	}
+
+	var reviews []models.PullRequestReview
+	reviewQuery := g.DB.Joins("JOIN pull_requests ON pull_requests.id = pull_request_reviews.pull_request_id").
+		Joins("JOIN repositories ON repositories.id = pull_requests.repo_id").
+		Where("repositories.user_id = ? AND pull_request_reviews.submitted_at >= ? AND pull_request_reviews.submitted_at < ?", userID, start, end)
+	if mine != nil {
+		reviewQuery = mine.ScopeReviews(reviewQuery)
+	}
+	reviewQuery.Order("pull_request_reviews.submitted_at asc").
+		Find(&reviews)
+
+	reviewersByPR := map[uint][]string{}
+	firstReview := map[uint]time.Time{}
+	var prOrder []uint
+	for _, r := range reviews {
+		if _, ok := firstReview[r.PullRequestID]; !ok {
+			prOrder = append(prOrder, r.PullRequestID)
+			firstReview[r.PullRequestID] = r.SubmittedAt
+		}
+		reviewersByPR[r.PullRequestID] = appendUnique(reviewersByPR[r.PullRequestID], r.Author)
+	}
+
+	if len(prOrder) > 0 {
+		var reviewedPRs []models.PullRequest
+		g.DB.Where("id IN ?", prOrder).Preload("Repository").Find(&reviewedPRs)
+		byID := make(map[uint]models.PullRequest, len(reviewedPRs))
+		for _, pr := range reviewedPRs {
+			byID[pr.ID] = pr
+		}
+		for _, id := range prOrder {
+			pr, ok := byID[id]
+			if !ok {
+				continue
+			}
+			pRep := pullRequestReport(pr, firstReview[id])
+			pRep.Reviewers = reviewersByPR[id]
+			reviewed = append(reviewed, pRep)
+		}
+	}
+
+	return opened, reviewed, merged
+}
+
+func pullRequestReport(pr models.PullRequest, at time.Time) PullRequestReport {
+	var reviewers []string
+	if pr.Reviewers != "" {
+		reviewers = strings.Split(pr.Reviewers, ",")
+	}
+	return PullRequestReport{
+		Number:    pr.Number,
+		Title:     firstLine(pr.Title),
+		State:     string(pr.State),
+		Author:    pr.Author,
+		Repo:      fmt.Sprintf("%s/%s", pr.Repository.Owner, pr.Repository.Name),
+		Branch:    pr.SourceBranch,
+		JiraKey:   pr.JiraCardKey,
+		URL:       pr.URL,
+		Reviewers: reviewers,
+		Time:      at.Format("15:04"),
+	}
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, s := range list {
+		if s == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
+
 type MonthlyReportData struct {
 	Month           int
 	Year            int
@@ -186,6 +438,7 @@ type MonthlyReportData struct {
 	RepoBreakdown   []RepoStats
 	TopCards        []CardReport
 	DailyReports    []DailyReportSummary
+	Narrative       Narrative // empty unless NarrateMonthly was called
 }
 
 type WeekStats struct {
@@ -208,7 +461,7 @@ type DailyReportSummary struct {
 	Cards   int    `json:"cards"`
 }
 
-func (g *Generator) BuildMonthlyReportData(userID uint, month, year int) (*MonthlyReportData, error) {
+func (g *Generator) BuildMonthlyReportData(userID uint, month, year int, opts ...BuildOptions) (*MonthlyReportData, error) {
 	var user models.User
 	if err := g.DB.First(&user, userID).Error; err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
@@ -219,17 +472,21 @@ func (g *Generator) BuildMonthlyReportData(userID uint, month, year int) (*Month
 
 	// Fetch all commits for the month
 	var commits []models.Commit
-	g.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
-		Where("repositories.user_id = ? AND commits.date >= ? AND commits.date < ?", userID, monthStart, monthEnd).
-		Preload("Repository").
+	commitQuery := g.DB.Joins("JOIN repositories ON repositories.id = commits.repo_id").
+		Where("repositories.user_id = ? AND commits.date >= ? AND commits.date < ?", userID, monthStart, monthEnd)
+	if mine, filtered := g.authorFilter(user, opts); filtered {
+		commitQuery = mine.ScopeCommits(commitQuery)
+	}
+	commitQuery.Preload("Repository").
 		Order("commits.date asc").
 		Find(&commits)
 
 	// Fetch all cards worked on (cards with commits this month)
+	linked := cardlink.Keys(g.DB, commitIDs(commits))
 	cardKeys := make(map[string]bool)
-	for _, c := range commits {
-		if c.JiraCardKey != "" {
-			cardKeys[c.JiraCardKey] = true
+	for _, keys := range linked {
+		for _, k := range keys {
+			cardKeys[k] = true
 		}
 	}
 
@@ -256,8 +513,8 @@ func (g *Generator) BuildMonthlyReportData(userID uint, month, year int) (*Month
 		for _, c := range commits {
 			if !c.Date.Before(current) && c.Date.Before(weekEnd) {
 				weekCommits++
-				if c.JiraCardKey != "" {
-					weekCards[c.JiraCardKey] = true
+				for _, k := range linked[c.ID] {
+					weekCards[k] = true
 				}
 			}
 		}
@@ -339,11 +596,19 @@ const DefaultMonthlyTemplate = `# Monthly Report — {{.MonthName}} {{.Year}}
 **Author:** {{.Author}}
 
 ## Summary
-- **Total Commits:** {{.TotalCommits}}
+{{with .Narrative.Summary}}{{.}}
+
+{{end}}- **Total Commits:** {{.TotalCommits}}
 - **Total Jira Cards Worked On:** {{.TotalCards}}
 - **Cards Completed:** {{.CardsCompleted}}
 - **Cards In Progress:** {{.CardsInProgress}}
-
+{{with .Narrative.Highlights}}
+## Highlights
+{{range .}}- {{.}}
+{{end}}{{end}}{{with .Narrative.Blockers}}
+## Blockers
+{{range .}}- {{.}}
+{{end}}{{end}}
 ## Weekly Breakdown
 {{range .WeeklyBreakdown}}
 ### Week {{.WeekNumber}} ({{.StartDate}} — {{.EndDate}})
@@ -364,7 +629,7 @@ const DefaultMonthlyTemplate = `# Monthly Report — {{.MonthName}} {{.Year}}
 
 func (g *Generator) GetMonthlyTemplateContent(userID uint) string {
 	var tmpl models.ReportTemplate
-	if g.DB.Where("user_id = ? AND name = ? AND is_default = ?", userID, "Monthly Default", true).First(&tmpl).Error == nil {
+	if g.DB.Where("user_id = ? AND report_type = ? AND is_default = ?", userID, "monthly", true).First(&tmpl).Error == nil {
 		return tmpl.Content
 	}
 	return DefaultMonthlyTemplate
@@ -372,36 +637,16 @@ func (g *Generator) GetMonthlyTemplateContent(userID uint) string {
 
 // RenderMonthly renders a template string with MonthlyReportData.
 func (g *Generator) RenderMonthly(templateContent string, data *MonthlyReportData) (string, error) {
-	tmpl, err := template.New("monthly_report").Parse(templateContent)
-	if err != nil {
-		return "", fmt.Errorf("invalid template: %w", err)
-	}
-
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("template execution failed: %w", err)
-	}
-
-	return buf.String(), nil
+	return execute("monthly_report", templateContent, data)
 }
 
 // Render renders a template string with the given data.
 func (g *Generator) Render(templateContent string, data *ReportData) (string, error) {
-	tmpl, err := template.New("report").Parse(templateContent)
-	if err != nil {
-		return "", fmt.Errorf("invalid template: %w", err)
-	}
-
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("template execution failed: %w", err)
-	}
-
-	return buf.String(), nil
+	return execute("report", templateContent, data)
 }
 
 // GetTemplateContent returns the template content for a user.
-// Priority: specific template_id > user's default > built-in default.
+// Priority: specific template_id > user's default daily template > built-in default.
 func (g *Generator) GetTemplateContent(userID uint, templateID *uint) (string, *uint) {
 	if templateID != nil {
 		var tmpl models.ReportTemplate
@@ -411,13 +656,21 @@ func (g *Generator) GetTemplateContent(userID uint, templateID *uint) (string, *
 	}
 
 	var tmpl models.ReportTemplate
-	if err := g.DB.Where("user_id = ? AND is_default = ?", userID, true).First(&tmpl).Error; err == nil {
+	if err := g.DB.Where("user_id = ? AND report_type = ? AND is_default = ?", userID, "daily", true).First(&tmpl).Error; err == nil {
 		return tmpl.Content, &tmpl.ID
 	}
 
 	return DefaultTemplate, nil
 }
 
+func commitIDs(commits []models.Commit) []uint {
+	ids := make([]uint, len(commits))
+	for i, c := range commits {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
 func shortSHA(sha string) string {
 	if len(sha) > 8 {
 		return sha[:8]
@@ -425,6 +678,15 @@ func shortSHA(sha string) string {
 	return sha
 }
 
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func firstLine(msg string) string {
 	lines := strings.SplitN(msg, "\n", 2)
 	line := lines[0]
@@ -0,0 +1,205 @@
+package report
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
+)
+
+func setupReportDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Repository{}, &models.Commit{}, &models.PullRequest{}, &models.PullRequestReview{},
+		&models.JiraCard{}, &models.ProviderAccount{}, &models.Person{}, &models.PersonIdentity{}, &models.CommitCardLink{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestBuildReportData_OnlyOwnWork(t *testing.T) {
+	db := setupReportDB(t)
+	user := models.User{Email: "ana@corp.com", Password: "x"}
+	db.Create(&user)
+	repo := models.Repository{UserID: user.ID, Owner: "org", Name: "app", Provider: models.ProviderGitHub, URL: "https://github.com/org/app"}
+	db.Create(&repo)
+
+	day := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+	db.Create(&models.Commit{RepoID: repo.ID, SHA: "a1", Message: "mine", Author: "Ana Lima", AuthorEmail: "Ana@corp.com", Date: day.Add(9 * time.Hour)})
+	db.Create(&models.Commit{RepoID: repo.ID, SHA: "a2", Message: "laptop", Author: "Ana Lima", AuthorEmail: "ana@home.dev", Date: day.Add(10 * time.Hour)})
+	db.Create(&models.Commit{RepoID: repo.ID, SHA: "b1", Message: "teammate", Author: "Bob", AuthorEmail: "bob@corp.com", Date: day.Add(11 * time.Hour)})
+	db.Create(&models.PullRequest{RepoID: repo.ID, Number: 1, Author: "Ana", State: models.PullRequestOpen, OpenedAt: day.Add(9 * time.Hour)})
+	db.Create(&models.PullRequest{RepoID: repo.ID, Number: 2, Author: "bob", State: models.PullRequestOpen, OpenedAt: day.Add(9 * time.Hour)})
+
+	g := NewGenerator(db, nil)
+
+	data, err := g.BuildReportData(user.ID, day)
+	if err != nil {
+		t.Fatalf("BuildReportData: %v", err)
+	}
+	if data.Stats.TotalCommits != 3 {
+		t.Fatalf("no identity known yet: expected all 3 commits, got %d", data.Stats.TotalCommits)
+	}
+
+	db.Create(&models.ProviderAccount{UserID: user.ID, Provider: models.ProviderGitHub, Username: "ana", Name: "Ana Lima", Emails: "ana@corp.com"})
+	data, _ = g.BuildReportData(user.ID, day)
+	if data.Stats.TotalCommits != 2 || data.Stats.TotalPRsOpened != 1 {
+		t.Fatalf("expected own commits (by email or name) and PR only, got %+v", data.Stats)
+	}
+
+	data, _ = g.BuildReportData(user.ID, day, BuildOptions{AllAuthors: true})
+	if data.Stats.TotalCommits != 3 || data.Stats.TotalPRsOpened != 2 {
+		t.Fatalf("AllAuthors must include teammates, got %+v", data.Stats)
+	}
+
+	db.Model(&user).Updates(map[string]any{"author_emails": "ana@home.dev"})
+	data, _ = g.BuildReportData(user.ID, day)
+	if data.Stats.TotalCommits != 1 || data.Stats.TotalPRsOpened != 1 {
+		t.Fatalf("profile emails override the provider account's, which still supplies the login for PRs, got %+v", data.Stats)
+	}
+}
+
+func TestBuildReportData_CommitUnderEveryLinkedCard(t *testing.T) {
+	db := setupReportDB(t)
+	user := models.User{Email: "ana@corp.com", Password: "x", IncludeAllAuthors: true}
+	db.Create(&user)
+	repo := models.Repository{UserID: user.ID, Owner: "org", Name: "app", Provider: models.ProviderGitHub, URL: "https://github.com/org/app"}
+	db.Create(&repo)
+
+	day := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+	both := models.Commit{RepoID: repo.ID, SHA: "a1", Message: "CORE-1 CORE-2 shared fix", Date: day.Add(9 * time.Hour)}
+	db.Create(&both)
+	db.Create(&models.Commit{RepoID: repo.ID, SHA: "a2", Message: "chore", Date: day.Add(10 * time.Hour)})
+	db.Create(&models.CommitCardLink{CommitID: both.ID, JiraCardKey: "CORE-1", Source: models.LinkSourceMessage})
+	db.Create(&models.CommitCardLink{CommitID: both.ID, JiraCardKey: "CORE-2", Source: models.LinkSourceMessage})
+
+	data, err := NewGenerator(db, nil).BuildReportData(user.ID, day)
+	if err != nil {
+		t.Fatalf("BuildReportData: %v", err)
+	}
+	if data.Stats.TotalCards != 2 || len(data.UnlinkedCommits) != 1 {
+		t.Fatalf("expected the commit under both cards and one unlinked, got %+v", data.Stats)
+	}
+	for _, card := range data.Cards {
+		if len(card.Commits) != 1 || card.Commits[0].SHA != "a1" {
+			t.Errorf("card %s commits = %+v", card.Key, card.Commits)
+		}
+	}
+}
+
+func TestBuildRangeReportData_GroupsByDayAndCard(t *testing.T) {
+	db := setupReportDB(t)
+	user := models.User{Email: "ana@corp.com", Password: "x", IncludeAllAuthors: true}
+	db.Create(&user)
+	repo := models.Repository{UserID: user.ID, Owner: "org", Name: "app", Provider: models.ProviderGitHub, URL: "https://github.com/org/app"}
+	db.Create(&repo)
+	db.Create(&models.JiraCard{UserID: user.ID, Key: "CORE-1", Summary: "Login", Status: "Done"})
+
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+	c1 := models.Commit{RepoID: repo.ID, SHA: "a1", Message: "CORE-1 start", Date: monday.Add(9 * time.Hour)}
+	c2 := models.Commit{RepoID: repo.ID, SHA: "a2", Message: "CORE-1 finish", Date: monday.AddDate(0, 0, 2).Add(9 * time.Hour)}
+	db.Create(&c1)
+	db.Create(&c2)
+	db.Create(&models.Commit{RepoID: repo.ID, SHA: "a3", Message: "chore", Date: monday.AddDate(0, 0, 2).Add(10 * time.Hour)})
+	db.Create(&models.Commit{RepoID: repo.ID, SHA: "a4", Message: "next week", Date: monday.AddDate(0, 0, 7).Add(9 * time.Hour)})
+	db.Create(&models.CommitCardLink{CommitID: c1.ID, JiraCardKey: "CORE-1", Source: models.LinkSourceMessage})
+	db.Create(&models.CommitCardLink{CommitID: c2.ID, JiraCardKey: "CORE-1", Source: models.LinkSourceMessage})
+
+	g := NewGenerator(db, nil)
+	data, err := g.BuildWeeklyReportData(user.ID, monday.AddDate(0, 0, 4))
+	if err != nil {
+		t.Fatalf("BuildWeeklyReportData: %v", err)
+	}
+	if data.StartDate != "2026-10-12" || data.EndDate != "2026-10-18" {
+		t.Fatalf("week = %s..%s, want 2026-10-12..2026-10-18", data.StartDate, data.EndDate)
+	}
+	if data.Stats.TotalCommits != 3 || data.ActiveDays != 2 || len(data.UnlinkedCommits) != 1 {
+		t.Fatalf("unexpected totals: commits=%d days=%d unlinked=%d", data.Stats.TotalCommits, data.ActiveDays, len(data.UnlinkedCommits))
+	}
+	if len(data.Cards) != 1 || data.Cards[0].Summary != "Login" || len(data.Cards[0].Commits) != 2 {
+		t.Fatalf("expected CORE-1 with both commits across the week, got %+v", data.Cards)
+	}
+	wed := data.Days[1]
+	if wed.Date != "2026-10-14" || wed.Commits != 2 || len(wed.Cards) != 1 || len(wed.UnlinkedCommits) != 1 {
+		t.Fatalf("unexpected Wednesday group: %+v", wed)
+	}
+
+	if _, err := g.RenderRange(DefaultWeeklyTemplate, data); err != nil {
+		t.Fatalf("RenderRange: %v", err)
+	}
+	if _, err := g.BuildRangeReportData(user.ID, monday, monday.AddDate(0, 0, -1)); err == nil {
+		t.Fatal("expected an error for an end date before the start date")
+	}
+}
+
+func TestTemplateContent_DefaultPerReportType(t *testing.T) {
+	db := setupReportDB(t)
+	if err := db.AutoMigrate(&models.ReportTemplate{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	daily := models.ReportTemplate{UserID: 1, Name: "Mine", Content: "daily", ReportType: "daily", IsDefault: true}
+	weekly := models.ReportTemplate{UserID: 1, Name: "Mine", Content: "weekly", ReportType: "weekly", IsDefault: true}
+	db.Create(&daily)
+	db.Create(&weekly)
+
+	g := NewGenerator(db, nil)
+	if content, id := g.GetTemplateContent(1, nil); content != "daily" || id == nil || *id != daily.ID {
+		t.Errorf("daily = %q, want the default daily template", content)
+	}
+	if content, id := g.GetWeeklyTemplateContent(1, nil); content != "weekly" || id == nil || *id != weekly.ID {
+		t.Errorf("weekly = %q, want the default weekly template", content)
+	}
+	if content := g.GetMonthlyTemplateContent(1); content != DefaultMonthlyTemplate {
+		t.Errorf("monthly = %q, want the built-in template", content)
+	}
+}
+
+func TestCardDetails_FetchesEachCardFromItsWorkspace(t *testing.T) {
+	db := setupReportDB(t)
+	if err := db.AutoMigrate(&models.JiraWorkspaceConfig{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// The stub answers with the token the request signed in with, so each
+	// summary names the workspace that fetched it.
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, token, _ := r.BasicAuth()
+		key := strings.TrimPrefix(r.URL.Path, "/rest/api/3/issue/")
+		fmt.Fprintf(w, `{"key":%q,"fields":{"summary":%q,"status":{"name":"Done"}}}`, key, token)
+	}))
+	defer srv.Close()
+	orig := httpclient.Client
+	httpclient.Client = srv.Client()
+	defer func() { httpclient.Client = orig }()
+
+	enc, err := crypto.NewEncryptor(strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatalf("encryptor: %v", err)
+	}
+	userToken, _ := enc.Encrypt("user-tok")
+	opsToken, _ := enc.Encrypt("ops-tok")
+	user := models.User{Email: "ana@corp.com", Password: "x", JiraEmail: "ana@corp.com", JiraToken: userToken}
+	db.Create(&user)
+	db.Create(&models.JiraWorkspaceConfig{UserID: user.ID, Workspace: srv.Listener.Addr().String(), ProjectKeys: "CORE", IsActive: true})
+	db.Create(&models.JiraWorkspaceConfig{UserID: user.ID, Workspace: srv.Listener.Addr().String(), Email: "ops@corp.com", Token: opsToken, ProjectKeys: "OPS", IsActive: true})
+
+	g := NewGenerator(db, enc)
+	details := g.cardDetails(user, []string{"CORE-1", "OPS-1", "HR-1"})
+	if details["CORE-1"].Summary != "user-tok" || details["OPS-1"].Summary != "ops-tok" {
+		t.Errorf("details = %+v, want CORE-1 fetched with the user's token and OPS-1 with the OPS workspace's", details)
+	}
+	if hr := details["HR-1"]; hr.Key != "HR-1" || hr.Summary != "" {
+		t.Errorf("HR-1 = %+v, want a bare card outside every workspace", hr)
+	}
+}
@@ -0,0 +1,155 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Save stores rpt as the next version of the report with the same user, type
+// and date (and EndDate, for weekly and range reports), creating the report
+// at version 1 the first time. data is the value rpt was rendered from and is
+// snapshotted with the version. FileURL is the new version's own upload, if
+// any; see SetFileURL. An empty NarrativeHash keeps the report's current
+// narrative. On return rpt is the stored report and created reports whether
+// it is new.
+func Save(db *gorm.DB, rpt *models.Report, data any) (created bool, err error) {
+	var snapshot string
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return false, fmt.Errorf("snapshot report data: %w", err)
+		}
+		snapshot = string(b)
+	}
+	return save(db, rpt, snapshot, nil)
+}
+
+// Restore makes v the latest version of rpt again by saving a copy of it as
+// a new version.
+func Restore(db *gorm.DB, rpt *models.Report, v *models.ReportVersion) error {
+	restored := models.Report{
+		UserID:     rpt.UserID,
+		ReportType: rpt.ReportType,
+		Date:       rpt.Date,
+		EndDate:    rpt.EndDate,
+		TemplateID: v.TemplateID,
+		Title:      v.Title,
+		Content:    v.Content,
+	}
+	version := v.Version
+	if _, err := save(db, &restored, v.Data, &version); err != nil {
+		return err
+	}
+	*rpt = restored
+	return nil
+}
+
+func save(db *gorm.DB, rpt *models.Report, snapshot string, restoredFrom *int) (created bool, err error) {
+	if rpt.ReportType == "" {
+		rpt.ReportType = "daily"
+	}
+	created, err = saveVersion(db, rpt, snapshot, restoredFrom)
+	if err != nil && created && findReport(db, rpt, &models.Report{}) == nil {
+		// A concurrent save created the report first and the unique index
+		// on its key rejected this one; add a version to that report
+		// instead.
+		return saveVersion(db, rpt, snapshot, restoredFrom)
+	}
+	return created, err
+}
+
+// findReport loads the stored report with rpt's user, type, date and end
+// date into out, locking it until tx ends so concurrent saves number their
+// versions one after the other.
+func findReport(tx *gorm.DB, rpt *models.Report, out *models.Report) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ? AND report_type = ? AND date = ? AND end_date = ?", rpt.UserID, rpt.ReportType, rpt.Date, rpt.EndDate).
+		First(out).Error
+}
+
+func saveVersion(db *gorm.DB, rpt *models.Report, snapshot string, restoredFrom *int) (created bool, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var existing models.Report
+		if findReport(tx, rpt, &existing) == nil {
+			// Reports generated before versioning keep their content as
+			// version 1.
+			if existing.Version == 0 {
+				existing.Version = 1
+				if err := tx.Create(&models.ReportVersion{
+					ReportID:   existing.ID,
+					Version:    1,
+					TemplateID: existing.TemplateID,
+					Title:      existing.Title,
+					Content:    existing.Content,
+					FileURL:    existing.FileURL,
+					CreatedAt:  existing.CreatedAt,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			existing.TemplateID = rpt.TemplateID
+			existing.Title = rpt.Title
+			existing.Content = rpt.Content
+			existing.FileURL = rpt.FileURL
+			if rpt.NarrativeHash != "" {
+				existing.Narrative, existing.NarrativeHash = rpt.Narrative, rpt.NarrativeHash
+			}
+			existing.Version++
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+			*rpt = existing
+		} else {
+			created = true
+			rpt.ID, rpt.Version = 0, 1
+			if err := tx.Create(rpt).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.ReportVersion{
+			ReportID:     rpt.ID,
+			Version:      rpt.Version,
+			TemplateID:   rpt.TemplateID,
+			Title:        rpt.Title,
+			Content:      rpt.Content,
+			FileURL:      rpt.FileURL,
+			Data:         snapshot,
+			RestoredFrom: restoredFrom,
+		}).Error
+	})
+	return created, err
+}
+
+// FileName is the name the exports of rpt's latest version are stored under
+// in R2. It includes the version number so every version keeps its files.
+func FileName(rpt models.Report) string {
+	name := rpt.Date
+	switch rpt.ReportType {
+	case "monthly", "weekly":
+		name = rpt.ReportType + "-" + rpt.Date
+	case "range":
+		name = "range-" + rpt.Date + "_" + rpt.EndDate
+	}
+	return fmt.Sprintf("%s.v%d", name, rpt.Version)
+}
+
+// SetFileURL records url as where rpt's latest version was uploaded, on both
+// the report and the version.
+func SetFileURL(db *gorm.DB, rpt *models.Report, url string) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Report{}).Where("id = ?", rpt.ID).Update("file_url", url).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ReportVersion{}).Where("report_id = ? AND version = ?", rpt.ID, rpt.Version).
+			Update("file_url", url).Error
+	})
+	if err != nil {
+		return err
+	}
+	rpt.FileURL = url
+	return nil
+}
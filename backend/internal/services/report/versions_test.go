@@ -0,0 +1,136 @@
+package report
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+func TestSave_Versions(t *testing.T) {
+	db := setupReportDB(t)
+	if err := db.AutoMigrate(&models.Report{}, &models.ReportVersion{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// A report generated before versioning.
+	legacy := models.Report{UserID: 1, Date: "2026-10-12", ReportType: "daily", Title: "Daily", Content: "old\n", FileURL: "https://r2/old.md"}
+	db.Create(&legacy)
+
+	tmplID := uint(7)
+	rpt := models.Report{UserID: 1, Date: "2026-10-12", TemplateID: &tmplID, Title: "Daily", Content: "new\n"}
+	created, err := Save(db, &rpt, &ReportData{Date: "2026-10-12", Author: "ana"})
+	if err != nil || created {
+		t.Fatalf("Save: created=%v err=%v", created, err)
+	}
+	if rpt.ID != legacy.ID || rpt.Version != 2 || rpt.FileURL != "" {
+		t.Fatalf("expected version 2 of the legacy report, not uploaded yet, got %+v", rpt)
+	}
+	if name := FileName(rpt); name != "2026-10-12.v2" {
+		t.Errorf("FileName = %q", name)
+	}
+	if err := SetFileURL(db, &rpt, "https://r2/2026-10-12.v2.md"); err != nil {
+		t.Fatalf("SetFileURL: %v", err)
+	}
+
+	var versions []models.ReportVersion
+	db.Where("report_id = ?", rpt.ID).Order("version").Find(&versions)
+	if len(versions) != 2 || versions[0].Content != "old\n" || versions[0].Data != "" || versions[0].FileURL != "https://r2/old.md" {
+		t.Fatalf("expected the legacy content and file as version 1, got %+v", versions)
+	}
+	if versions[1].FileURL != "https://r2/2026-10-12.v2.md" {
+		t.Errorf("version 2 file = %q, want its own upload", versions[1].FileURL)
+	}
+	if versions[1].TemplateID == nil || *versions[1].TemplateID != 7 || !strings.Contains(versions[1].Data, `"Author":"ana"`) {
+		t.Errorf("version 2 lacks its template or data snapshot: %+v", versions[1])
+	}
+
+	// Other types and ranges are separate reports.
+	weekly := models.Report{UserID: 1, Date: "2026-10-12", EndDate: "2026-10-18", ReportType: "weekly", Content: "week"}
+	if created, _ := Save(db, &weekly, nil); !created || weekly.Version != 1 {
+		t.Errorf("expected a new weekly report at version 1, got created=%v %+v", created, weekly)
+	}
+
+	if err := Restore(db, &rpt, &versions[0]); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if rpt.Version != 3 || rpt.Content != "old\n" || rpt.TemplateID != nil {
+		t.Errorf("expected version 3 with version 1's content, got %+v", rpt)
+	}
+	var restored models.ReportVersion
+	db.Where("report_id = ? AND version = ?", rpt.ID, 3).First(&restored)
+	if restored.RestoredFrom == nil || *restored.RestoredFrom != 1 {
+		t.Errorf("restored version should point at version 1: %+v", restored)
+	}
+
+	var count int64
+	db.Model(&models.Report{}).Where("user_id = ? AND report_type = ?", 1, "daily").Count(&count)
+	if count != 1 {
+		t.Errorf("regenerating should keep one daily report, got %d", count)
+	}
+}
+
+func TestSave_ConcurrentCreate(t *testing.T) {
+	// A file database in WAL mode, so a second connection can store the
+	// report while the save's transaction is open.
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "reports.db")+"?_journal_mode=WAL&_busy_timeout=1000"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Report{}, &models.ReportVersion{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// Another request stores the report between this save's lookup and its
+	// insert.
+	raced := false
+	db.Callback().Query().After("gorm:query").Register("test:race", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*models.Report); ok && tx.RowsAffected == 0 && !raced {
+			raced = true
+			if err := db.Exec("INSERT INTO reports (user_id, date, end_date, report_type, content, version) VALUES (1, '2026-10-12', '', 'daily', 'first', 1)").Error; err != nil {
+				t.Errorf("racing insert: %v", err)
+			}
+		}
+	})
+
+	rpt := models.Report{UserID: 1, Date: "2026-10-12", Content: "second"}
+	created, err := Save(db, &rpt, nil)
+	if err != nil || created || rpt.Version != 2 || rpt.Content != "second" {
+		t.Fatalf("expected version 2 of the racing report, got created=%v err=%v", created, err)
+	}
+	var count int64
+	db.Model(&models.Report{}).Count(&count)
+	if count != 1 {
+		t.Errorf("reports = %d, want 1", count)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := "# Report\n\na\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	to := "# Report v2\n\na\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	diff, added, removed := Diff("version 1", "version 2", from, to)
+	want := `--- version 1
++++ version 2
+@@ -1,4 +1,4 @@
+-# Report
++# Report v2
+ 
+ a
+ b
+@@ -10,3 +10,4 @@
+ h
+ i
+ j
++k
+`
+	if diff != want || added != 2 || removed != 1 {
+		t.Errorf("got +%d -%d\n%s\nwant\n%s", added, removed, diff, want)
+	}
+	if diff, _, _ := Diff("a", "b", from, from); diff != "" {
+		t.Errorf("identical contents should have no diff, got %q", diff)
+	}
+}
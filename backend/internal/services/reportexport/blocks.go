@@ -0,0 +1,244 @@
+package reportexport
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// The PDF and DOCX renderers work from a flat list of blocks rather than the
+// Markdown AST, so both handle the same subset of Markdown the same way.
+
+type blockKind int
+
+const (
+	blockParagraph blockKind = iota
+	blockHeading
+	blockListItem
+	blockCode
+	blockRule
+	blockTable
+)
+
+type block struct {
+	Kind blockKind
+	// Level is the heading level for headings and the nesting depth, from
+	// 0, for list items and paragraphs inside lists.
+	Level int
+	// Quote is how many block quotes the block is nested in.
+	Quote  int
+	Marker string
+	Spans  []span
+	Code   string
+	// Rows holds table cells; the first row is the header.
+	Rows [][][]span
+}
+
+type span struct {
+	Text   string
+	Bold   bool
+	Italic bool
+	Strike bool
+	Code   bool
+	Link   string
+}
+
+func newMarkdown() goldmark.Markdown {
+	return goldmark.New(goldmark.WithExtensions(extension.GFM))
+}
+
+// parseBlocks parses Markdown into blocks. Raw HTML is dropped.
+func parseBlocks(markdown string) []block {
+	src := []byte(markdown)
+	doc := newMarkdown().Parser().Parse(text.NewReader(src))
+	p := &blockParser{src: src}
+	p.walk(doc, 0, 0, false)
+	return p.blocks
+}
+
+type blockParser struct {
+	src    []byte
+	blocks []block
+}
+
+// walk appends the blocks of n's children. depth is the list nesting and
+// indented marks paragraphs that continue a list item.
+func (p *blockParser) walk(n ast.Node, depth, quote int, indented bool) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		level := 0
+		if indented {
+			level = depth
+		}
+		switch c := c.(type) {
+		case *ast.Heading:
+			p.blocks = append(p.blocks, block{Kind: blockHeading, Level: c.Level, Quote: quote, Spans: p.spans(c, span{})})
+		case *ast.Paragraph, *ast.TextBlock:
+			p.blocks = append(p.blocks, block{Kind: blockParagraph, Level: level, Quote: quote, Spans: p.spans(c, span{})})
+		case *ast.List:
+			number := c.Start
+			for item := c.FirstChild(); item != nil; item = item.NextSibling() {
+				marker := "•"
+				if c.IsOrdered() {
+					marker = strconv.Itoa(number) + "."
+					number++
+				}
+				p.listItem(item, marker, depth, quote)
+			}
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			p.blocks = append(p.blocks, block{Kind: blockCode, Level: level, Quote: quote, Code: p.lines(c)})
+		case *ast.Blockquote:
+			p.walk(c, depth, quote+1, indented)
+		case *ast.ThematicBreak:
+			p.blocks = append(p.blocks, block{Kind: blockRule, Quote: quote})
+		case *extast.Table:
+			b := block{Kind: blockTable, Level: level, Quote: quote}
+			for row := c.FirstChild(); row != nil; row = row.NextSibling() {
+				var cells [][]span
+				for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+					cells = append(cells, p.spans(cell, span{Bold: row.Kind() == extast.KindTableHeader}))
+				}
+				b.Rows = append(b.Rows, cells)
+			}
+			p.blocks = append(p.blocks, b)
+		}
+	}
+}
+
+// listItem appends the item's first paragraph as a list item block and the
+// rest of its content nested below it.
+func (p *blockParser) listItem(item ast.Node, marker string, depth, quote int) {
+	first := item.FirstChild()
+	b := block{Kind: blockListItem, Level: depth, Quote: quote, Marker: marker}
+	if first != nil && (first.Kind() == ast.KindParagraph || first.Kind() == ast.KindTextBlock) {
+		b.Spans = p.spans(first, span{})
+		first = first.NextSibling()
+	}
+	p.blocks = append(p.blocks, b)
+
+	rest := ast.NewDocument()
+	for first != nil {
+		next := first.NextSibling()
+		rest.AppendChild(rest, first)
+		first = next
+	}
+	p.walk(rest, depth+1, quote, true)
+}
+
+func (p *blockParser) lines(n ast.Node) string {
+	var sb strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		sb.Write(seg.Value(p.src))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// spans flattens n's inline content, with style carried down from parents.
+func (p *blockParser) spans(n ast.Node, style span) []span {
+	var out []span
+	add := func(s span, txt string) {
+		if txt == "" {
+			return
+		}
+		if last := len(out) - 1; last >= 0 && sameStyle(out[last], s) {
+			out[last].Text += txt
+			return
+		}
+		s.Text = txt
+		out = append(out, s)
+	}
+
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			add(style, string(c.Value(p.src)))
+			if c.HardLineBreak() {
+				add(style, "\n")
+			} else if c.SoftLineBreak() {
+				add(style, " ")
+			}
+		case *ast.String:
+			add(style, string(c.Value))
+		case *ast.CodeSpan:
+			s := style
+			s.Code = true
+			for _, cs := range p.spans(c, s) {
+				add(s, cs.Text)
+			}
+		case *ast.Emphasis:
+			s := style
+			if c.Level >= 2 {
+				s.Bold = true
+			} else {
+				s.Italic = true
+			}
+			for _, cs := range p.spans(c, s) {
+				add(cs, cs.Text)
+			}
+		case *extast.Strikethrough:
+			s := style
+			s.Strike = true
+			for _, cs := range p.spans(c, s) {
+				add(cs, cs.Text)
+			}
+		case *ast.Link:
+			s := style
+			s.Link = safeLink(string(c.Destination))
+			for _, cs := range p.spans(c, s) {
+				add(cs, cs.Text)
+			}
+		case *ast.AutoLink:
+			s := style
+			s.Link = string(c.URL(p.src))
+			if c.AutoLinkType == ast.AutoLinkEmail {
+				s.Link = "mailto:" + s.Link
+			}
+			add(s, string(c.Label(p.src)))
+		case *ast.Image:
+			s := style
+			s.Italic = true
+			for _, cs := range p.spans(c, s) {
+				add(cs, cs.Text)
+			}
+		case *extast.TaskCheckBox:
+			if c.IsChecked {
+				add(style, "[x] ")
+			} else {
+				add(style, "[ ] ")
+			}
+		case *ast.RawHTML:
+		default:
+			for _, cs := range p.spans(c, style) {
+				add(cs, cs.Text)
+			}
+		}
+	}
+	return out
+}
+
+// safeLink returns dest if it is an http(s) or mailto link, and "" otherwise.
+func safeLink(dest string) string {
+	lower := strings.ToLower(strings.TrimSpace(dest))
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "mailto:") {
+		return strings.TrimSpace(dest)
+	}
+	return ""
+}
+
+func sameStyle(a, b span) bool {
+	return a.Bold == b.Bold && a.Italic == b.Italic && a.Strike == b.Strike && a.Code == b.Code && a.Link == b.Link
+}
+
+func plainText(spans []span) string {
+	var sb strings.Builder
+	for _, s := range spans {
+		sb.WriteString(s.Text)
+	}
+	return sb.String()
+}
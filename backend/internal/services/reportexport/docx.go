@@ -0,0 +1,284 @@
+package reportexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DOCX renders a Markdown report as a Word document. Headings, lists, code,
+// quotes and tables map to paragraph styles defined from the theme, so the
+// document can be restyled in Word.
+func DOCX(title, markdown string, theme Theme) ([]byte, error) {
+	w := &docxWriter{theme: theme}
+	if theme.BrandName != "" {
+		w.paragraph("Brand", "", []span{{Text: theme.BrandName}})
+	}
+	for _, b := range parseBlocks(markdown) {
+		w.block(b)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRootRels},
+		{"docProps/core.xml", docxCore(title)},
+		{"word/_rels/document.xml.rels", w.rels()},
+		{"word/styles.xml", docxStyles(theme)},
+		{"word/document.xml", w.document()},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return nil, fmt.Errorf("docx: %w", err)
+		}
+		if _, err := fw.Write([]byte(f.content)); err != nil {
+			return nil, fmt.Errorf("docx: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("docx: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+type docxWriter struct {
+	theme Theme
+	body  strings.Builder
+	links []string
+}
+
+const docxIndent = 360 // twentieths of a point, a quarter inch
+
+func (w *docxWriter) block(b block) {
+	indent := (b.Quote + b.Level) * docxIndent
+	switch b.Kind {
+	case blockHeading:
+		w.paragraph(fmt.Sprintf("Heading%d", min(b.Level, 4)), indentXML(b.Quote*docxIndent, 0), b.Spans)
+	case blockParagraph:
+		style := "Normal"
+		if b.Quote > 0 {
+			style = "Quote"
+		}
+		w.paragraph(style, indentXML(indent, 0), b.Spans)
+	case blockListItem:
+		spans := append([]span{{Text: b.Marker + "\t"}}, b.Spans...)
+		w.paragraph("ListParagraph", indentXML(indent+docxIndent, docxIndent), spans)
+	case blockCode:
+		w.paragraph("Code", indentXML(indent, 0), []span{{Text: b.Code}})
+	case blockRule:
+		w.body.WriteString(`<w:p><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="` + docxColor(w.theme.Muted) + `"/></w:pBdr></w:pPr></w:p>`)
+	case blockTable:
+		w.table(b, indent)
+	}
+}
+
+func indentXML(left, hanging int) string {
+	if left == 0 {
+		return ""
+	}
+	if hanging > 0 {
+		return fmt.Sprintf(`<w:ind w:left="%d" w:hanging="%d"/>`, left, hanging)
+	}
+	return fmt.Sprintf(`<w:ind w:left="%d"/>`, left)
+}
+
+func (w *docxWriter) paragraph(style, pPr string, spans []span) {
+	w.body.WriteString(`<w:p><w:pPr><w:pStyle w:val="` + style + `"/>` + pPr + `</w:pPr>`)
+	w.runs(spans, "")
+	w.body.WriteString(`</w:p>`)
+}
+
+// runs writes spans as runs, wrapping links in hyperlinks. extra is added to
+// every run's properties.
+func (w *docxWriter) runs(spans []span, extra string) {
+	for _, s := range spans {
+		var rPr strings.Builder
+		if s.Link != "" {
+			rPr.WriteString(`<w:rStyle w:val="Hyperlink"/>`)
+		}
+		if s.Code {
+			rPr.WriteString(`<w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:cs="Consolas"/>`)
+		}
+		if s.Bold {
+			rPr.WriteString(`<w:b/>`)
+		}
+		if s.Italic {
+			rPr.WriteString(`<w:i/>`)
+		}
+		if s.Strike {
+			rPr.WriteString(`<w:strike/>`)
+		}
+		rPr.WriteString(extra)
+
+		run := `<w:r><w:rPr>` + rPr.String() + `</w:rPr>` + docxText(s.Text) + `</w:r>`
+		if s.Link != "" {
+			w.links = append(w.links, s.Link)
+			run = fmt.Sprintf(`<w:hyperlink r:id="rIdLink%d">%s</w:hyperlink>`, len(w.links), run)
+		}
+		w.body.WriteString(run)
+	}
+}
+
+// docxText returns text as w:t elements, turning newlines into breaks and tabs
+// into tab stops.
+func docxText(text string) string {
+	var sb strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			sb.WriteString(`<w:br/>`)
+		}
+		for j, part := range strings.Split(line, "\t") {
+			if j > 0 {
+				sb.WriteString(`<w:tab/>`)
+			}
+			if part != "" {
+				sb.WriteString(`<w:t xml:space="preserve">` + escapeXML(part) + `</w:t>`)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func (w *docxWriter) table(b block, indent int) {
+	cols := 0
+	for _, row := range b.Rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return
+	}
+	border := docxColor(w.theme.CodeBg)
+	w.body.WriteString(`<w:tbl><w:tblPr><w:tblW w:w="5000" w:type="pct"/>`)
+	if indent > 0 {
+		fmt.Fprintf(&w.body, `<w:tblInd w:w="%d" w:type="dxa"/>`, indent)
+	}
+	w.body.WriteString(`<w:tblBorders>`)
+	for _, side := range []string{"top", "left", "bottom", "right", "insideH", "insideV"} {
+		fmt.Fprintf(&w.body, `<w:%s w:val="single" w:sz="4" w:space="0" w:color="%s"/>`, side, border)
+	}
+	w.body.WriteString(`</w:tblBorders><w:tblCellMar><w:left w:w="100" w:type="dxa"/><w:right w:w="100" w:type="dxa"/></w:tblCellMar></w:tblPr><w:tblGrid>`)
+	// A4 width less the page margins, split evenly.
+	colWidth := (11906 - 2*1134 - indent) / cols
+	for i := 0; i < cols; i++ {
+		fmt.Fprintf(&w.body, `<w:gridCol w:w="%d"/>`, colWidth)
+	}
+	w.body.WriteString(`</w:tblGrid>`)
+
+	for r, row := range b.Rows {
+		header := r == 0
+		w.body.WriteString(`<w:tr>`)
+		if header {
+			w.body.WriteString(`<w:trPr><w:tblHeader/></w:trPr>`)
+		}
+		for i := 0; i < cols; i++ {
+			w.body.WriteString(`<w:tc><w:tcPr>`)
+			extra := ""
+			if header {
+				w.body.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="` + docxColor(w.theme.Accent) + `"/>`)
+				extra = `<w:color w:val="FFFFFF"/>`
+			}
+			w.body.WriteString(`</w:tcPr><w:p><w:pPr><w:spacing w:before="40" w:after="40"/></w:pPr>`)
+			if i < len(row) {
+				w.runs(row[i], extra)
+			}
+			w.body.WriteString(`</w:p></w:tc>`)
+		}
+		w.body.WriteString(`</w:tr>`)
+	}
+	w.body.WriteString(`</w:tbl><w:p/>`)
+}
+
+func (w *docxWriter) document() string {
+	return xml.Header + `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><w:body>` +
+		w.body.String() +
+		`<w:p/><w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1134" w:right="1134" w:bottom="1134" w:left="1134" w:header="567" w:footer="567" w:gutter="0"/></w:sectPr></w:body></w:document>`
+}
+
+func (w *docxWriter) rels() string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	sb.WriteString(`<Relationship Id="rIdStyles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`)
+	for i, link := range w.links {
+		fmt.Fprintf(&sb, `<Relationship Id="rIdLink%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="%s" TargetMode="External"/>`, i+1, escapeXML(link))
+	}
+	sb.WriteString(`</Relationships>`)
+	return sb.String()
+}
+
+const docxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+	`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+	`</Types>`
+
+const docxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+	`</Relationships>`
+
+func docxCore(title string) string {
+	now := time.Now().UTC().Format(time.RFC3339)
+	return xml.Header + `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<dc:title>` + escapeXML(title) + `</dc:title><dc:creator>PDT</dc:creator>` +
+		`<dcterms:created xsi:type="dcterms:W3CDTF">` + now + `</dcterms:created>` +
+		`</cp:coreProperties>`
+}
+
+func docxStyles(theme Theme) string {
+	font := "Calibri"
+	if theme.Serif {
+		font = "Georgia"
+	}
+	accent, text, muted, codeBg := docxColor(theme.Accent), docxColor(theme.Text), docxColor(theme.Muted), docxColor(theme.CodeBg)
+
+	heading := func(level, size int, color string, border bool) string {
+		var bdr string
+		if border {
+			bdr = `<w:pBdr><w:bottom w:val="single" w:sz="12" w:space="4" w:color="` + accent + `"/></w:pBdr>`
+		}
+		return fmt.Sprintf(`<w:style w:type="paragraph" w:styleId="Heading%d"><w:name w:val="heading %d"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>`+
+			`<w:pPr><w:keepNext/>%s<w:spacing w:before="%d" w:after="120"/><w:outlineLvl w:val="%d"/></w:pPr>`+
+			`<w:rPr><w:b/><w:color w:val="%s"/><w:sz w:val="%d"/></w:rPr></w:style>`,
+			level, level, bdr, 120+size*6, level-1, color, size)
+	}
+
+	return xml.Header + `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+		`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="` + font + `" w:hAnsi="` + font + `" w:cs="` + font + `"/><w:color w:val="` + text + `"/><w:sz w:val="21"/></w:rPr></w:rPrDefault>` +
+		`<w:pPrDefault><w:pPr><w:spacing w:after="120" w:line="276" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+		`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>` +
+		heading(1, 40, accent, true) +
+		heading(2, 30, accent, false) +
+		heading(3, 25, text, false) +
+		heading(4, 22, text, false) +
+		`<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="40"/></w:pPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Code"><w:name w:val="Code"/><w:basedOn w:val="Normal"/>` +
+		`<w:pPr><w:shd w:val="clear" w:color="auto" w:fill="` + codeBg + `"/><w:spacing w:after="160" w:line="240" w:lineRule="auto"/></w:pPr>` +
+		`<w:rPr><w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:cs="Consolas"/><w:sz w:val="18"/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/>` +
+		`<w:pPr><w:pBdr><w:left w:val="single" w:sz="18" w:space="8" w:color="` + accent + `"/></w:pBdr></w:pPr>` +
+		`<w:rPr><w:i/><w:color w:val="` + muted + `"/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Brand"><w:name w:val="Brand"/><w:basedOn w:val="Normal"/>` +
+		`<w:pPr><w:pBdr><w:top w:val="single" w:sz="24" w:space="4" w:color="` + accent + `"/></w:pBdr><w:jc w:val="right"/></w:pPr>` +
+		`<w:rPr><w:b/><w:color w:val="` + muted + `"/><w:sz w:val="18"/></w:rPr></w:style>` +
+		`<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/><w:rPr><w:color w:val="` + accent + `"/><w:u w:val="single"/></w:rPr></w:style>` +
+		`</w:styles>`
+}
+
+// docxColor returns a theme color as Word's RRGGBB, black if malformed.
+func docxColor(color string) string {
+	r, g, b := rgb(color)
+	return fmt.Sprintf("%02X%02X%02X", r, g, b)
+}
+
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
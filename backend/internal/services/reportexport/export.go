@@ -0,0 +1,178 @@
+// Package reportexport turns rendered Markdown reports into the formats people
+// share them in: sanitized HTML with a themeable stylesheet, PDF and DOCX. All
+// renderers are pure Go, so exporting needs no browser or office suite.
+package reportexport
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/cds-id/pdt/backend/internal/services/storage"
+)
+
+// Format is an export format, named by its file extension.
+type Format string
+
+const (
+	FormatMarkdown Format = "md"
+	FormatHTML     Format = "html"
+	FormatPDF      Format = "pdf"
+	FormatDOCX     Format = "docx"
+)
+
+// Formats lists every format, Markdown first.
+var Formats = []Format{FormatMarkdown, FormatHTML, FormatPDF, FormatDOCX}
+
+// ParseFormat returns the format named s; "markdown" is accepted for md.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatMarkdown, FormatHTML, FormatPDF, FormatDOCX:
+		return f, nil
+	case "markdown":
+		return FormatMarkdown, nil
+	}
+	return "", fmt.Errorf("format must be one of md, html, pdf or docx")
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatPDF:
+		return "application/pdf"
+	case FormatDOCX:
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	}
+	return "text/markdown; charset=utf-8"
+}
+
+// Theme styles exported reports. Colors are "#RRGGBB".
+type Theme struct {
+	Name       string `json:"name"`
+	BrandName  string `json:"brand_name"`
+	Accent     string `json:"accent"`
+	Text       string `json:"text"`
+	Muted      string `json:"muted"`
+	Background string `json:"background"`
+	CodeBg     string `json:"code_background"`
+	Serif      bool   `json:"serif"`
+}
+
+// Themes are the built-in themes by name.
+var Themes = map[string]Theme{
+	"default": {
+		Name:       "default",
+		Accent:     "#2563EB",
+		Text:       "#1F2937",
+		Muted:      "#6B7280",
+		Background: "#FFFFFF",
+		CodeBg:     "#F3F4F6",
+	},
+	"minimal": {
+		Name:       "minimal",
+		Accent:     "#111827",
+		Text:       "#111827",
+		Muted:      "#6B7280",
+		Background: "#FFFFFF",
+		CodeBg:     "#F5F5F4",
+		Serif:      true,
+	},
+}
+
+var hexColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+var (
+	defaultThemeName = "default"
+	brandName        string
+	brandColor       string
+)
+
+// Configure sets the theme used when none is named and the brand every theme
+// carries: its name in page headers and its color as the accent. Empty values
+// keep the built-in ones.
+func Configure(themeName, name, color string) {
+	if _, ok := Themes[themeName]; ok {
+		defaultThemeName = themeName
+	} else if themeName != "" {
+		log.Printf("[report-export] unknown theme %q, using %q", themeName, defaultThemeName)
+	}
+	brandName = name
+	if color != "" && !hexColor.MatchString(color) {
+		log.Printf("[report-export] ignoring brand color %q, expected #RRGGBB", color)
+		color = ""
+	}
+	brandColor = color
+}
+
+// ThemeByName returns the named theme with the brand applied, or the default
+// theme for an empty name.
+func ThemeByName(name string) (Theme, bool) {
+	if name == "" {
+		name = defaultThemeName
+	}
+	theme, ok := Themes[name]
+	if !ok {
+		return Theme{}, false
+	}
+	if brandName != "" {
+		theme.BrandName = brandName
+	}
+	if brandColor != "" {
+		theme.Accent = brandColor
+	}
+	return theme, true
+}
+
+// Render converts a Markdown report to format.
+func Render(format Format, title, markdown string, theme Theme) ([]byte, error) {
+	switch format {
+	case FormatMarkdown:
+		return []byte(markdown), nil
+	case FormatHTML:
+		return HTML(title, markdown, theme)
+	case FormatPDF:
+		return PDF(title, markdown, theme)
+	case FormatDOCX:
+		return DOCX(title, markdown, theme)
+	}
+	return nil, fmt.Errorf("unsupported format: %s", format)
+}
+
+// Upload stores the Markdown report at reports/<userID>/<name>.md, and every
+// other format next to it with the default theme, and returns the Markdown's
+// URL. A format that fails to render or upload is logged and skipped.
+func Upload(ctx context.Context, r2 *storage.R2Client, userID uint, name, title, markdown string) (string, error) {
+	theme, _ := ThemeByName("")
+	var mdURL string
+	for _, format := range Formats {
+		content, err := Render(format, title, markdown, theme)
+		if err != nil {
+			log.Printf("[report-export] render %s for %s failed: %v", format, name, err)
+			continue
+		}
+		key := fmt.Sprintf("reports/%d/%s.%s", userID, name, format)
+		url, err := r2.Upload(ctx, key, content, format.ContentType())
+		if format == FormatMarkdown {
+			if err != nil {
+				return "", err
+			}
+			mdURL = url
+		} else if err != nil {
+			log.Printf("[report-export] upload %s failed: %v", key, err)
+		}
+	}
+	return mdURL, nil
+}
+
+// rgb splits a "#RRGGBB" color into its components, black if malformed.
+func rgb(color string) (r, g, b int) {
+	if !hexColor.MatchString(color) {
+		return 0, 0, 0
+	}
+	fmt.Sscanf(color[1:], "%02x%02x%02x", &r, &g, &b)
+	return r, g, b
+}
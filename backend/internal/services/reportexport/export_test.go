@@ -0,0 +1,112 @@
+package reportexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+const sampleReport = "# Weekly Report — 12 Oct – 18 Oct 2026\n\n" +
+	"**Author:** ana@corp.com\n\n" +
+	"## Cards\n\n" +
+	"### CORE-1 — Login · *done*\n\n" +
+	"- `a1b2c3d4` Fix [login](https://jira.example.com/browse/CORE-1) ~~flow~~\n" +
+	"  1. nested step\n" +
+	"- [Bad link](javascript:alert(1)) <script>alert(1)</script>\n\n" +
+	"> Blocked on review 🚧\n\n" +
+	"```\ngo test ./...\n```\n\n" +
+	"| Repo | Commits |\n|------|---------|\n| org/app | 3 |\n| org/api & more | 1 |\n\n" +
+	"---\n"
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"pdf": FormatPDF, " DOCX ": FormatDOCX, "markdown": FormatMarkdown, "html": FormatHTML} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("odt"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestHTML_SanitizedAndThemed(t *testing.T) {
+	theme := Themes["default"]
+	theme.Accent = "#123456"
+	theme.BrandName = "Acme <Corp>"
+
+	out, err := HTML("Weekly <Report>", sampleReport, theme)
+	if err != nil {
+		t.Fatalf("HTML: %v", err)
+	}
+	html := string(out)
+	for _, bad := range []string{"<script", "javascript:", "<Corp>", "<Report>"} {
+		if strings.Contains(html, bad) {
+			t.Errorf("output contains %q", bad)
+		}
+	}
+	for _, want := range []string{"--accent: #123456", "Acme &lt;Corp&gt;", "<table>", `<a href="https://jira.example.com/browse/CORE-1"`, "<del>flow</del>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+}
+
+func TestPDF(t *testing.T) {
+	// Enough rows to break the table across pages.
+	long := sampleReport + "\n| Day | Commits |\n|---|---|\n" + strings.Repeat("| Monday | 12 |\n", 120)
+	out, err := PDF("Weekly Report", long, Themes["minimal"])
+	if err != nil {
+		t.Fatalf("PDF: %v", err)
+	}
+	if !bytes.HasPrefix(out, []byte("%PDF-")) {
+		t.Fatalf("not a PDF: %q", out[:min(len(out), 16)])
+	}
+	if pages := bytes.Count(out, []byte("/Type /Page\n")); pages < 2 {
+		t.Errorf("expected the long table to span pages, got %d", pages)
+	}
+}
+
+func TestDOCX(t *testing.T) {
+	out, err := DOCX("Weekly Report", sampleReport, Themes["default"])
+	if err != nil {
+		t.Fatalf("DOCX: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("not a zip: %v", err)
+	}
+
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(data)
+
+		dec := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed: %v", f.Name, err)
+			}
+		}
+	}
+
+	doc := parts["word/document.xml"]
+	for _, want := range []string{`w:val="Heading1"`, "CORE-1 — Login", "org/api &amp; more", `w:val="Code"`, `w:val="Quote"`, "<w:tbl>"} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("document lacks %q", want)
+		}
+	}
+	if strings.Contains(doc, "script") {
+		t.Error("raw HTML leaked into the document")
+	}
+	rels := parts["word/_rels/document.xml.rels"]
+	if !strings.Contains(rels, "https://jira.example.com/browse/CORE-1") || strings.Contains(rels, "javascript:") {
+		t.Errorf("unexpected hyperlinks: %s", rels)
+	}
+}
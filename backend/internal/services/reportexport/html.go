@@ -0,0 +1,95 @@
+package reportexport
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// stylesheet styles exported HTML. Themes only set the custom properties
+// declared by CSS.
+const stylesheet = `
+body { margin: 0; background: var(--background); color: var(--text); font: 15px/1.6 var(--font); }
+.brand { border-top: 6px solid var(--accent); padding: 12px 40px; color: var(--muted); font-size: 13px; text-align: right; }
+.report { max-width: 820px; margin: 0 auto; padding: 24px 40px 48px; }
+h1, h2, h3, h4 { line-height: 1.25; margin: 1.6em 0 0.6em; }
+h1 { color: var(--accent); font-size: 1.9em; border-bottom: 2px solid var(--accent); padding-bottom: 0.3em; margin-top: 0; }
+h2 { color: var(--accent); font-size: 1.4em; }
+h3 { font-size: 1.15em; }
+a { color: var(--accent); }
+code { font-family: var(--mono); font-size: 0.9em; background: var(--code-background); padding: 0.1em 0.35em; border-radius: 4px; }
+pre { background: var(--code-background); padding: 12px 16px; border-radius: 6px; overflow-x: auto; }
+pre code { background: none; padding: 0; }
+blockquote { margin: 1em 0; padding: 0 1em; border-left: 4px solid var(--accent); color: var(--muted); }
+table { border-collapse: collapse; width: 100%; margin: 1em 0; }
+th, td { border: 1px solid var(--code-background); padding: 6px 10px; text-align: left; }
+th { background: var(--accent); color: #FFFFFF; }
+hr { border: 0; border-top: 1px solid var(--muted); margin: 2em 0; }
+ul, ol { padding-left: 1.6em; }
+@media print { .brand { padding: 0 0 8px; } .report { padding: 0; } }
+`
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>{{.CSS}}</style>
+</head>
+<body>
+{{if .Brand}}<header class="brand">{{.Brand}}</header>
+{{end}}<main class="report">
+{{.Body}}</main>
+</body>
+</html>
+`))
+
+// CSS returns the stylesheet for exported HTML with the theme applied.
+func CSS(theme Theme) string {
+	font := `-apple-system, "Segoe UI", Helvetica, Arial, sans-serif`
+	if theme.Serif {
+		font = `Georgia, "Times New Roman", serif`
+	}
+	return fmt.Sprintf(`:root { --accent: %s; --text: %s; --muted: %s; --background: %s; --code-background: %s; --font: %s; --mono: SFMono-Regular, Consolas, "Liberation Mono", monospace; }`,
+		cssColor(theme.Accent), cssColor(theme.Text), cssColor(theme.Muted), cssColor(theme.Background), cssColor(theme.CodeBg), font) + stylesheet
+}
+
+func cssColor(color string) string {
+	if hexColor.MatchString(color) {
+		return color
+	}
+	return "inherit"
+}
+
+// policy allows the HTML that Markdown produces, including GFM task list
+// checkboxes, and nothing else.
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}()
+
+// HTML renders a Markdown report as a standalone, sanitized HTML page.
+func HTML(title, markdown string, theme Theme) ([]byte, error) {
+	var body bytes.Buffer
+	if err := newMarkdown().Convert([]byte(markdown), &body); err != nil {
+		return nil, fmt.Errorf("markdown: %w", err)
+	}
+
+	var out bytes.Buffer
+	err := page.Execute(&out, map[string]any{
+		"Title": title,
+		"Brand": theme.BrandName,
+		"CSS":   template.CSS(CSS(theme)),
+		"Body":  template.HTML(policy.SanitizeBytes(body.Bytes())),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,286 @@
+package reportexport
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	pdfMargin     = 20.0
+	pdfBodySize   = 10.5
+	pdfLineHeight = 5.4
+	pdfIndent     = 6.0
+)
+
+var pdfHeadingSizes = map[int]float64{1: 20, 2: 15, 3: 12.5}
+
+// PDF renders a Markdown report as an A4 PDF. It uses the standard PDF fonts,
+// so text is limited to the Windows-1252 character set; other characters are
+// printed as ".".
+func PDF(title, markdown string, theme Theme) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("PDT", true)
+	pdf.SetMargins(pdfMargin, pdfMargin+6, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.AliasNbPages("")
+
+	w := &pdfWriter{
+		pdf:   pdf,
+		tr:    pdf.UnicodeTranslatorFromDescriptor(""),
+		theme: theme,
+		font:  "Helvetica",
+	}
+	if theme.Serif {
+		w.font = "Times"
+	}
+
+	pageWidth, _ := pdf.GetPageSize()
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFillColor(rgb(theme.Accent))
+		pdf.Rect(0, 0, pageWidth, 3, "F")
+		if theme.BrandName != "" {
+			pdf.SetXY(pdfMargin, 8)
+			pdf.SetFont(w.font, "B", 9)
+			pdf.SetTextColor(rgb(theme.Muted))
+			pdf.CellFormat(0, 5, w.tr(theme.BrandName), "", 0, "R", false, 0, "")
+		}
+		pdf.SetXY(pdfMargin, pdfMargin+6)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-14)
+		pdf.SetFont(w.font, "", 8)
+		pdf.SetTextColor(rgb(theme.Muted))
+		pdf.CellFormat(0, 5, w.tr(title), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 5, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+	for i, b := range parseBlocks(markdown) {
+		w.block(b, i == 0)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("pdf: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+type pdfWriter struct {
+	pdf   *fpdf.Fpdf
+	tr    func(string) string
+	theme Theme
+	font  string
+}
+
+func (w *pdfWriter) block(b block, first bool) {
+	pdf := w.pdf
+	left := pdfMargin + float64(b.Quote)*pdfIndent + float64(b.Level)*pdfIndent
+	if b.Kind == blockHeading {
+		left = pdfMargin + float64(b.Quote)*pdfIndent
+	}
+	pdf.SetLeftMargin(left)
+	pdf.SetX(left)
+	defer pdf.SetLeftMargin(pdfMargin)
+
+	startY := pdf.GetY()
+	switch b.Kind {
+	case blockHeading:
+		size, ok := pdfHeadingSizes[b.Level]
+		if !ok {
+			size = 11
+		}
+		// Keep headings off the bottom of a page, away from their content.
+		if _, pageHeight := pdf.GetPageSize(); pdf.GetY() > pageHeight-pdfMargin-30 {
+			pdf.AddPage()
+		} else if !first {
+			pdf.Ln(size * 0.35)
+		}
+		pdf.SetTextColor(rgb(w.theme.Text))
+		if b.Level <= 2 {
+			pdf.SetTextColor(rgb(w.theme.Accent))
+		}
+		lh := size * 0.45
+		w.spans(b.Spans, size, lh, "B")
+		pdf.Ln(lh)
+		if b.Level == 1 {
+			pageWidth, _ := pdf.GetPageSize()
+			pdf.SetDrawColor(rgb(w.theme.Accent))
+			pdf.SetLineWidth(0.6)
+			pdf.Line(left, pdf.GetY()+1, pageWidth-pdfMargin, pdf.GetY()+1)
+			pdf.Ln(3)
+		}
+		pdf.Ln(1.5)
+
+	case blockParagraph:
+		w.spans(b.Spans, pdfBodySize, pdfLineHeight, "")
+		pdf.Ln(pdfLineHeight + 1.5)
+
+	case blockListItem:
+		pdf.SetFont(w.font, "", pdfBodySize)
+		pdf.SetTextColor(rgb(w.theme.Text))
+		pdf.CellFormat(pdfIndent, pdfLineHeight, w.tr(b.Marker), "", 0, "L", false, 0, "")
+		pdf.SetLeftMargin(left + pdfIndent)
+		w.spans(b.Spans, pdfBodySize, pdfLineHeight, "")
+		pdf.Ln(pdfLineHeight + 0.8)
+
+	case blockCode:
+		pdf.SetFont("Courier", "", 9)
+		pdf.SetTextColor(rgb(w.theme.Text))
+		pdf.SetFillColor(rgb(w.theme.CodeBg))
+		pdf.SetCellMargin(2)
+		pdf.MultiCell(0, 4.6, w.tr(b.Code), "", "L", true)
+		pdf.SetCellMargin(1)
+		pdf.Ln(2)
+
+	case blockRule:
+		pageWidth, _ := pdf.GetPageSize()
+		pdf.Ln(2)
+		pdf.SetDrawColor(rgb(w.theme.Muted))
+		pdf.SetLineWidth(0.2)
+		pdf.Line(left, pdf.GetY(), pageWidth-pdfMargin, pdf.GetY())
+		pdf.Ln(4)
+
+	case blockTable:
+		w.table(b, left)
+		pdf.Ln(2)
+	}
+
+	// Quotes get a bar beside them, on the page the block ended on.
+	if b.Quote > 0 {
+		top := startY
+		if pdf.GetY() < startY {
+			top = pdfMargin + 6
+		}
+		pdf.SetDrawColor(rgb(w.theme.Accent))
+		pdf.SetLineWidth(0.8)
+		x := pdfMargin + float64(b.Quote-1)*pdfIndent + 2
+		pdf.Line(x, top, x, pdf.GetY()-1)
+	}
+}
+
+// spans writes inline text that wraps at the right margin and returns to the
+// current left margin.
+func (w *pdfWriter) spans(spans []span, size, lh float64, base string) {
+	pdf := w.pdf
+	for _, s := range spans {
+		style := base
+		if s.Bold && !strings.Contains(style, "B") {
+			style += "B"
+		}
+		if s.Italic {
+			style += "I"
+		}
+		if s.Strike {
+			style += "S"
+		}
+		if s.Link != "" {
+			style += "U"
+		}
+		font := w.font
+		fontSize := size
+		if s.Code {
+			font, fontSize = "Courier", size*0.92
+		}
+		pdf.SetFont(font, style, fontSize)
+
+		switch {
+		case s.Link != "":
+			pdf.SetTextColor(rgb(w.theme.Accent))
+			pdf.WriteLinkString(lh, w.tr(s.Text), s.Link)
+		case s.Code:
+			pdf.SetTextColor(rgb(w.theme.Text))
+			pdf.Write(lh, w.tr(s.Text))
+		default:
+			if base == "" {
+				pdf.SetTextColor(rgb(w.theme.Text))
+			}
+			pdf.Write(lh, w.tr(s.Text))
+		}
+	}
+	if base != "" {
+		pdf.SetTextColor(rgb(w.theme.Text))
+	}
+}
+
+// table draws a table with equal-width columns and rows as tall as their
+// longest cell, repeating the header after page breaks.
+func (w *pdfWriter) table(b block, left float64) {
+	pdf := w.pdf
+	cols := 0
+	for _, row := range b.Rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return
+	}
+	pageWidth, pageHeight := pdf.GetPageSize()
+	colWidth := (pageWidth - pdfMargin - left) / float64(cols)
+	const lh = 4.8
+
+	layout := func(row [][]span, header bool) ([][]string, float64) {
+		style := ""
+		if header {
+			style = "B"
+		}
+		pdf.SetFont(w.font, style, 9.5)
+		cells := make([][]string, cols)
+		lines := 1
+		for i := range cells {
+			var txt string
+			if i < len(row) {
+				txt = plainText(row[i])
+			}
+			cells[i] = pdf.SplitText(w.tr(txt), colWidth-3)
+			lines = max(lines, len(cells[i]))
+		}
+		return cells, float64(lines)*lh + 2
+	}
+
+	draw := func(cells [][]string, height float64, header bool) {
+		if header {
+			pdf.SetFont(w.font, "B", 9.5)
+		} else {
+			pdf.SetFont(w.font, "", 9.5)
+		}
+		y := pdf.GetY()
+		pdf.SetDrawColor(rgb(w.theme.CodeBg))
+		pdf.SetLineWidth(0.2)
+		for i, lines := range cells {
+			x := left + float64(i)*colWidth
+			if header {
+				pdf.SetFillColor(rgb(w.theme.Accent))
+				pdf.Rect(x, y, colWidth, height, "FD")
+				pdf.SetTextColor(255, 255, 255)
+			} else {
+				pdf.Rect(x, y, colWidth, height, "D")
+				pdf.SetTextColor(rgb(w.theme.Text))
+			}
+			for j, line := range lines {
+				pdf.SetXY(x+1.5, y+1+float64(j)*lh)
+				pdf.CellFormat(colWidth-3, lh, line, "", 0, "L", false, 0, "")
+			}
+		}
+		pdf.SetXY(left, y+height)
+	}
+
+	headerCells, headerHeight := layout(b.Rows[0], true)
+	if pdf.GetY()+headerHeight > pageHeight-pdfMargin {
+		pdf.AddPage()
+	}
+	draw(headerCells, headerHeight, true)
+	for _, row := range b.Rows[1:] {
+		cells, height := layout(row, false)
+		if pdf.GetY()+height > pageHeight-pdfMargin {
+			pdf.AddPage()
+			pdf.SetX(left)
+			draw(headerCells, headerHeight, true)
+		}
+		draw(cells, height, false)
+	}
+	pdf.SetTextColor(rgb(w.theme.Text))
+}
@@ -0,0 +1,268 @@
+package sprintstats
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+// Commitment compares the scope a sprint started with to what it finished.
+type Commitment struct {
+	Committed     Totals   `json:"committed"`   // scope on the first day
+	Final         Totals   `json:"final_scope"` // scope on the last day
+	Completed     Totals   `json:"completed"`   // done within the final scope
+	Added         []string `json:"added"`
+	Removed       []string `json:"removed"`
+	CompletionPct float64  `json:"completion_pct"` // completed of committed; in points when there are any, else in cards
+	Estimated     bool     `json:"estimated"`      // the first or last day had no snapshot
+}
+
+// Commitment compares the first and last day of the series.
+func (s *Series) Commitment() Commitment {
+	c := Commitment{Added: []string{}, Removed: []string{}}
+	if len(s.Days) == 0 {
+		return c
+	}
+	first, last := s.Days[0], s.Days[len(s.Days)-1]
+	c.Committed = first.Scope
+	c.Final = last.Scope
+	c.Completed = last.Done
+	c.Estimated = first.Estimated || last.Estimated
+
+	start, end := s.scopes[0], s.scopes[len(s.scopes)-1]
+	for key := range end {
+		if _, ok := start[key]; !ok {
+			c.Added = append(c.Added, key)
+		}
+	}
+	for key := range start {
+		if _, ok := end[key]; !ok {
+			c.Removed = append(c.Removed, key)
+		}
+	}
+	sort.Strings(c.Added)
+	sort.Strings(c.Removed)
+
+	switch {
+	case c.Committed.Points > 0:
+		c.CompletionPct = round(c.Completed.Points / c.Committed.Points * 100)
+	case c.Committed.Cards > 0:
+		c.CompletionPct = round(float64(c.Completed.Cards) / float64(c.Committed.Cards) * 100)
+	}
+	return c
+}
+
+// CarryCard is a card that moved between sprints unfinished.
+type CarryCard struct {
+	Key         string   `json:"key"`
+	Summary     string   `json:"summary"`
+	Status      string   `json:"status"`
+	StoryPoints *float64 `json:"story_points"`
+	Sprints     []string `json:"sprints,omitempty"` // the other sprints it was in
+}
+
+// Carried lists the cards a sprint took over and the ones it did not finish.
+type Carried struct {
+	// In were already in an earlier sprint's scope.
+	In []CarryCard `json:"carried_in"`
+	// NotDone were unfinished on the sprint's last day. Once the sprint is
+	// closed they are carried out, into the sprints listed.
+	NotDone []CarryCard `json:"not_done"`
+}
+
+// CarryOver finds the cards of the series' final scope that came from an
+// earlier sprint or were left unfinished. Earlier sprints are only known
+// from their snapshots.
+func CarryOver(db *gorm.DB, s *Series) (*Carried, error) {
+	out := &Carried{In: []CarryCard{}, NotDone: []CarryCard{}}
+	if len(s.scopes) == 0 {
+		return out, nil
+	}
+	scope := s.scopes[len(s.scopes)-1]
+	keys := make([]string, 0, len(scope))
+	for key := range scope {
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return out, nil
+	}
+	sort.Strings(keys)
+
+	var cards []models.JiraCard
+	if err := db.Select("card_key, summary, status, story_points, sprint_id").
+		Where("user_id = ? AND card_key IN ?", s.Sprint.UserID, keys).
+		Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]models.JiraCard, len(cards))
+	for _, c := range cards {
+		byKey[c.Key] = c
+	}
+
+	var pairs []struct {
+		CardKey  string
+		SprintID uint
+	}
+	if err := db.Model(&models.SprintSnapshot{}).
+		Distinct("card_key", "sprint_id").
+		Where("user_id = ? AND card_key IN ? AND sprint_id <> ?", s.Sprint.UserID, keys, s.Sprint.ID).
+		Scan(&pairs).Error; err != nil {
+		return nil, err
+	}
+	sprintIDs := []uint{}
+	for _, p := range pairs {
+		sprintIDs = append(sprintIDs, p.SprintID)
+	}
+	for _, c := range cards {
+		if c.SprintID != nil && *c.SprintID != s.Sprint.ID {
+			sprintIDs = append(sprintIDs, *c.SprintID)
+		}
+	}
+	sprints := map[uint]models.Sprint{}
+	if len(sprintIDs) > 0 {
+		var list []models.Sprint
+		db.Where("id IN ?", sprintIDs).Find(&list)
+		for _, sp := range list {
+			sprints[sp.ID] = sp
+		}
+	}
+
+	earlier := map[string][]string{}
+	for _, p := range pairs {
+		sp, ok := sprints[p.SprintID]
+		if !ok || !startsBefore(sp, s.Sprint) {
+			continue
+		}
+		earlier[p.CardKey] = append(earlier[p.CardKey], sp.Name)
+	}
+
+	for _, key := range keys {
+		card := byKey[key]
+		cc := CarryCard{Key: key, Summary: card.Summary, Status: card.Status, StoryPoints: scope[key].points}
+		if names, ok := earlier[key]; ok {
+			in := cc
+			in.Sprints = slices.Compact(slices.Sorted(slices.Values(names)))
+			out.In = append(out.In, in)
+		}
+		if !scope[key].done {
+			if card.SprintID != nil && *card.SprintID != s.Sprint.ID {
+				cc.Sprints = []string{sprints[*card.SprintID].Name}
+			}
+			out.NotDone = append(out.NotDone, cc)
+		}
+	}
+	return out, nil
+}
+
+func startsBefore(a, b models.Sprint) bool {
+	if a.StartDate == nil || b.StartDate == nil {
+		return a.ID < b.ID
+	}
+	return a.StartDate.Before(*b.StartDate)
+}
+
+// SprintVelocity is what one closed sprint committed to and completed.
+type SprintVelocity struct {
+	SprintID  uint       `json:"sprint_id"`
+	Name      string     `json:"name"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	Committed Totals     `json:"committed"`
+	Completed Totals     `json:"completed"`
+	Estimated bool       `json:"estimated"`
+}
+
+// VelocityReport covers the last closed sprints, oldest first.
+type VelocityReport struct {
+	Sprints      []SprintVelocity `json:"sprints"`
+	AvgCommitted Totals           `json:"avg_committed"`
+	AvgCompleted Totals           `json:"avg_completed"`
+}
+
+// Velocity reports the last n closed sprints of the user, or of one
+// workspace when workspaceID is set.
+func Velocity(db *gorm.DB, userID uint, workspaceID *uint, n int, now time.Time) (*VelocityReport, error) {
+	query := db.Where("user_id = ? AND state = ?", userID, models.SprintClosed)
+	if workspaceID != nil {
+		query = query.Where("workspace_id = ?", *workspaceID)
+	}
+	var sprints []models.Sprint
+	if err := query.Order("COALESCE(end_date, created_at) DESC").Limit(n).Find(&sprints).Error; err != nil {
+		return nil, err
+	}
+	slices.Reverse(sprints)
+
+	report := &VelocityReport{Sprints: []SprintVelocity{}}
+	for _, sp := range sprints {
+		series, err := Build(db, userID, sp.ID, now)
+		if err != nil {
+			return nil, err
+		}
+		c := series.Commitment()
+		report.Sprints = append(report.Sprints, SprintVelocity{
+			SprintID:  sp.ID,
+			Name:      sp.Name,
+			StartDate: sp.StartDate,
+			EndDate:   sp.EndDate,
+			Committed: c.Committed,
+			Completed: c.Completed,
+			Estimated: c.Estimated,
+		})
+		report.AvgCommitted.Cards += c.Committed.Cards
+		report.AvgCommitted.Points += c.Committed.Points
+		report.AvgCompleted.Cards += c.Completed.Cards
+		report.AvgCompleted.Points += c.Completed.Points
+	}
+	if k := len(report.Sprints); k > 0 {
+		// Card averages are rounded down to whole cards.
+		report.AvgCommitted = Totals{Cards: report.AvgCommitted.Cards / k, Points: round(report.AvgCommitted.Points / float64(k))}
+		report.AvgCompleted = Totals{Cards: report.AvgCompleted.Cards / k, Points: round(report.AvgCompleted.Points / float64(k))}
+	}
+	return report, nil
+}
+
+// Summary is a sprint's state in brief, for briefings and reports.
+type Summary struct {
+	SprintID   uint               `json:"sprint_id"`
+	Name       string             `json:"name"`
+	State      models.SprintState `json:"state"`
+	StartDate  *time.Time         `json:"start_date"`
+	EndDate    *time.Time         `json:"end_date"`
+	Commitment Commitment         `json:"commitment"`
+	Remaining  Totals             `json:"remaining"`
+	Ideal      *float64           `json:"ideal_points"` // ideal remaining points today
+	CarriedIn  int                `json:"carried_in"`
+	NotDone    int                `json:"not_done"`
+}
+
+// Summarize builds the Summary of one of the user's sprints.
+func Summarize(db *gorm.DB, userID, sprintID uint, now time.Time) (*Summary, error) {
+	series, err := Build(db, userID, sprintID, now)
+	if err != nil {
+		return nil, err
+	}
+	carried, err := CarryOver(db, series)
+	if err != nil {
+		return nil, err
+	}
+	sum := &Summary{
+		SprintID:   series.Sprint.ID,
+		Name:       series.Sprint.Name,
+		State:      series.Sprint.State,
+		StartDate:  series.Sprint.StartDate,
+		EndDate:    series.Sprint.EndDate,
+		Commitment: series.Commitment(),
+		CarriedIn:  len(carried.In),
+		NotDone:    len(carried.NotDone),
+	}
+	if len(series.Days) > 0 {
+		last := series.Days[len(series.Days)-1]
+		sum.Remaining = last.Remaining
+		sum.Ideal = last.Ideal
+	}
+	return sum, nil
+}
@@ -0,0 +1,243 @@
+// Package sprintstats derives sprint analytics from the daily scope
+// snapshots the Jira sync takes: burndown and burnup series, committed
+// against completed points, carry-over between sprints and velocity.
+//
+// Days before a sprint's first snapshot are rebuilt from the cards now in the
+// sprint and their status transitions; days missing after it repeat the
+// previous snapshot. Both are marked as estimated.
+package sprintstats
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/flow"
+)
+
+// DateLayout is how snapshot and series dates are written.
+const DateLayout = "2006-01-02"
+
+// Totals counts cards and their story points. Cards without points count
+// towards Cards only.
+type Totals struct {
+	Cards  int     `json:"cards"`
+	Points float64 `json:"points"`
+}
+
+func (t *Totals) add(points *float64) {
+	t.Cards++
+	if points != nil {
+		t.Points += *points
+	}
+}
+
+// Day is a sprint's scope and progress at the end of one day.
+type Day struct {
+	Date      string   `json:"date"`
+	Scope     Totals   `json:"scope"`
+	Done      Totals   `json:"done"`
+	Remaining Totals   `json:"remaining"`
+	Ideal     *float64 `json:"ideal_points"` // remaining points on a straight line to zero at the sprint end
+	Added     []string `json:"added,omitempty"`
+	Removed   []string `json:"removed,omitempty"`
+	Estimated bool     `json:"estimated"` // rebuilt without a snapshot
+}
+
+// Series is a sprint's day-by-day progress, from its start until its end or
+// today, whichever comes first.
+type Series struct {
+	Sprint models.Sprint `json:"sprint"`
+	Days   []Day         `json:"days"`
+
+	scopes []map[string]cardState
+}
+
+type cardState struct {
+	points *float64
+	done   bool
+}
+
+// Snapshot rewrites the sprint's rows for day from the cards now in it.
+// categories maps lower-cased status names to Jira status categories.
+func Snapshot(db *gorm.DB, userID, sprintID uint, day time.Time, categories map[string]string) error {
+	date := day.In(time.Local).Format(DateLayout)
+
+	var cards []models.JiraCard
+	if err := db.Select("card_key, status, story_points").
+		Where("user_id = ? AND sprint_id = ?", userID, sprintID).
+		Find(&cards).Error; err != nil {
+		return err
+	}
+
+	rows := make([]models.SprintSnapshot, 0, len(cards))
+	for _, c := range cards {
+		rows = append(rows, models.SprintSnapshot{
+			UserID:         userID,
+			SprintID:       sprintID,
+			Date:           date,
+			CardKey:        c.Key,
+			Status:         c.Status,
+			StatusCategory: flow.Category(c.Status, categories),
+			StoryPoints:    c.StoryPoints,
+		})
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("sprint_id = ? AND date = ?", sprintID, date).
+			Delete(&models.SprintSnapshot{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// Build returns the series of one of the user's sprints up to now.
+func Build(db *gorm.DB, userID, sprintID uint, now time.Time) (*Series, error) {
+	var sprint models.Sprint
+	if err := db.Where("id = ? AND user_id = ?", sprintID, userID).First(&sprint).Error; err != nil {
+		return nil, err
+	}
+
+	var snapshots []models.SprintSnapshot
+	if err := db.Where("user_id = ? AND sprint_id = ?", userID, sprintID).
+		Order("date asc").Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	byDate := map[string]map[string]cardState{}
+	for _, s := range snapshots {
+		if byDate[s.Date] == nil {
+			byDate[s.Date] = map[string]cardState{}
+		}
+		byDate[s.Date][s.CardKey] = cardState{points: s.StoryPoints, done: s.StatusCategory == models.StatusCategoryDone}
+	}
+
+	var cards []models.JiraCard
+	if err := db.Select("card_key, status, story_points, jira_created_at").
+		Where("user_id = ? AND sprint_id = ?", userID, sprintID).
+		Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(cards))
+	for i, c := range cards {
+		keys[i] = c.Key
+	}
+	history, err := flow.Histories(db, userID, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	first := dayOf(now)
+	if sprint.StartDate != nil {
+		first = dayOf(*sprint.StartDate)
+	} else if len(snapshots) > 0 {
+		first, _ = time.ParseInLocation(DateLayout, snapshots[0].Date, time.Local)
+	}
+	last := dayOf(now)
+	if sprint.EndDate != nil && dayOf(*sprint.EndDate).Before(last) {
+		last = dayOf(*sprint.EndDate)
+	}
+
+	// The ideal line runs over the whole planned sprint, not just the days
+	// so far.
+	planned := 0
+	if sprint.EndDate != nil {
+		planned = int(dayOf(*sprint.EndDate).Sub(first).Hours()/24+0.5) + 1
+	}
+
+	series := &Series{Sprint: sprint, Days: []Day{}}
+	var prev, lastSnapshot map[string]cardState
+	for d, i := first, 0; !d.After(last); d, i = d.AddDate(0, 0, 1), i+1 {
+		date := d.Format(DateLayout)
+		scope, ok := byDate[date]
+		switch {
+		case ok:
+			lastSnapshot = scope
+		case lastSnapshot != nil:
+			// A gap between syncs, or the days after a sprint was closed
+			// and its cards moved on: the last snapshot still holds.
+			scope = lastSnapshot
+		default:
+			scope = rebuild(cards, history, d.AddDate(0, 0, 1))
+		}
+
+		day := Day{Date: date, Estimated: !ok}
+		for key, st := range scope {
+			day.Scope.add(st.points)
+			if st.done {
+				day.Done.add(st.points)
+			} else {
+				day.Remaining.add(st.points)
+			}
+			if prev != nil {
+				if _, was := prev[key]; !was {
+					day.Added = append(day.Added, key)
+				}
+			}
+		}
+		for key := range prev {
+			if _, still := scope[key]; !still {
+				day.Removed = append(day.Removed, key)
+			}
+		}
+		sort.Strings(day.Added)
+		sort.Strings(day.Removed)
+
+		if planned > 0 {
+			committed := round(day.Scope.Points)
+			if len(series.Days) > 0 {
+				committed = series.Days[0].Scope.Points
+			}
+			ideal := committed
+			if planned > 1 {
+				ideal = max(0, round(committed*(1-float64(i)/float64(planned-1))))
+			}
+			day.Ideal = &ideal
+		}
+		day.Scope.Points = round(day.Scope.Points)
+		day.Done.Points = round(day.Done.Points)
+		day.Remaining.Points = round(day.Remaining.Points)
+
+		series.Days = append(series.Days, day)
+		series.scopes = append(series.scopes, scope)
+		prev = scope
+	}
+	return series, nil
+}
+
+// rebuild guesses a day's scope from the cards now in the sprint that
+// existed before end, each done when its last transition before end went to
+// a done status.
+func rebuild(cards []models.JiraCard, history map[string][]models.JiraCardTransition, end time.Time) map[string]cardState {
+	scope := map[string]cardState{}
+	for _, c := range cards {
+		if c.JiraCreatedAt != nil && !c.JiraCreatedAt.Before(end) {
+			continue
+		}
+		rows := history[c.Key]
+		done := len(rows) == 0 && flow.Category(c.Status, nil) == models.StatusCategoryDone
+		for _, t := range rows {
+			if !t.TransitionedAt.Before(end) {
+				break
+			}
+			done = t.ToCategory == models.StatusCategoryDone
+		}
+		scope[c.Key] = cardState{points: c.StoryPoints, done: done}
+	}
+	return scope
+}
+
+func dayOf(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,92 @@
+package sprintstats
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+func TestSprintAnalytics(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Sprint{}, &models.JiraCard{}, &models.JiraCardTransition{}, &models.SprintSnapshot{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	day := func(d int) *time.Time {
+		t := time.Date(2026, 10, d, 9, 0, 0, 0, time.Local)
+		return &t
+	}
+	pts := func(p float64) *float64 { return &p }
+
+	closed := models.Sprint{UserID: 1, JiraSprintID: "1", Name: "S1", State: models.SprintClosed, StartDate: day(1), EndDate: day(3)}
+	active := models.Sprint{UserID: 1, JiraSprintID: "2", Name: "S2", State: models.SprintActive, StartDate: day(5), EndDate: day(9)}
+	db.Create(&closed)
+	db.Create(&active)
+
+	// S1 was only snapshotted on its first and last day; CORE-2 was left
+	// unfinished and moved on to S2.
+	db.Create(&[]models.SprintSnapshot{
+		{UserID: 1, SprintID: closed.ID, Date: "2026-10-01", CardKey: "CORE-1", StatusCategory: models.StatusCategoryNew, StoryPoints: pts(3)},
+		{UserID: 1, SprintID: closed.ID, Date: "2026-10-01", CardKey: "CORE-2", StatusCategory: models.StatusCategoryNew, StoryPoints: pts(5)},
+		{UserID: 1, SprintID: closed.ID, Date: "2026-10-03", CardKey: "CORE-1", StatusCategory: models.StatusCategoryDone, StoryPoints: pts(3)},
+		{UserID: 1, SprintID: closed.ID, Date: "2026-10-03", CardKey: "CORE-2", StatusCategory: models.StatusCategoryNew, StoryPoints: pts(5)},
+	})
+
+	db.Create(&models.JiraCard{UserID: 1, Key: "CORE-2", Status: "To Do", StoryPoints: pts(5), SprintID: &active.ID})
+	db.Create(&models.JiraCard{UserID: 1, Key: "CORE-3", Status: "To Do", StoryPoints: pts(2), SprintID: &active.ID})
+	if err := Snapshot(db, 1, active.ID, *day(5), nil); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	db.Model(&models.JiraCard{}).Where("card_key = ?", "CORE-2").Update("status", "Done")
+	db.Create(&models.JiraCard{UserID: 1, Key: "CORE-4", Status: "To Do", StoryPoints: pts(1), SprintID: &active.ID})
+	if err := Snapshot(db, 1, active.ID, *day(6), nil); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	series, err := Build(db, 1, active.ID, *day(7))
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(series.Days) != 3 {
+		t.Fatalf("days = %+v, want Oct 5 to 7", series.Days)
+	}
+	d1, d2 := series.Days[1], series.Days[2]
+	if d1.Scope.Points != 8 || d1.Done.Points != 5 || d1.Remaining.Cards != 2 || !slices.Equal(d1.Added, []string{"CORE-4"}) {
+		t.Errorf("Oct 6 = %+v", d1)
+	}
+	if !d2.Estimated || d2.Remaining.Points != 3 || *d2.Ideal != 3.5 {
+		t.Errorf("Oct 7 = %+v, want the Oct 6 snapshot carried forward and ideal 3.5", d2)
+	}
+
+	c := series.Commitment()
+	if c.Committed.Points != 7 || c.Completed.Points != 5 || c.CompletionPct != 71.43 || !slices.Equal(c.Added, []string{"CORE-4"}) {
+		t.Errorf("commitment = %+v", c)
+	}
+
+	carried, err := CarryOver(db, series)
+	if err != nil {
+		t.Fatalf("CarryOver: %v", err)
+	}
+	if len(carried.In) != 1 || carried.In[0].Key != "CORE-2" || carried.In[0].Sprints[0] != "S1" {
+		t.Errorf("carried in = %+v", carried.In)
+	}
+	if len(carried.NotDone) != 2 {
+		t.Errorf("not done = %+v, want CORE-3 and CORE-4", carried.NotDone)
+	}
+
+	velocity, err := Velocity(db, 1, nil, 5, *day(7))
+	if err != nil {
+		t.Fatalf("Velocity: %v", err)
+	}
+	if len(velocity.Sprints) != 1 || velocity.AvgCommitted.Points != 8 || velocity.AvgCompleted.Points != 3 {
+		t.Errorf("velocity = %+v", velocity)
+	}
+}
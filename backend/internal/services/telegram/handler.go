@@ -154,7 +154,7 @@ func (h *Handler) handleMessage(ctx context.Context, msg *tgbotapi.Message) {
 	orchestrator := agent.NewOrchestrator(
 		h.MiniMaxClient,
 		&agent.GitAgent{DB: h.DB, UserID: userID, Encryptor: h.Encryptor, Weaviate: h.WeaviateClient},
-		&agent.JiraAgent{DB: h.DB, UserID: userID, Weaviate: h.WeaviateClient},
+		&agent.JiraAgent{DB: h.DB, UserID: userID, Weaviate: h.WeaviateClient, Encryptor: h.Encryptor},
 		&agent.ReportAgent{DB: h.DB, UserID: userID, Generator: h.ReportGenerator, R2: h.R2},
 		&agent.ProofAgent{DB: h.DB, UserID: userID},
 		&agent.BriefingAgent{DB: h.DB, UserID: userID},
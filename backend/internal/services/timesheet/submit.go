@@ -0,0 +1,153 @@
+package timesheet
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/jiraoutbox"
+)
+
+// Save stores an entry as the user's version of a card's day. Submitted
+// entries cannot be changed. Zero seconds marks the entry as skipped.
+func Save(db *gorm.DB, userID uint, e Entry) (*models.TimesheetEntry, error) {
+	if e.CardKey == "" {
+		return nil, fmt.Errorf("card_key is required")
+	}
+	if _, err := time.ParseInLocation(DateLayout, e.Date, time.Local); err != nil {
+		return nil, fmt.Errorf("date must be YYYY-MM-DD")
+	}
+
+	var existing models.TimesheetEntry
+	if db.Where("user_id = ? AND card_key = ? AND date = ?", userID, e.CardKey, e.Date).First(&existing).Error == nil &&
+		(existing.Status == models.TimesheetSubmitted || existing.Status == models.TimesheetSubmitting) {
+		return nil, fmt.Errorf("%s on %s is already submitted", e.CardKey, e.Date)
+	}
+
+	status := models.TimesheetEdited
+	if e.Seconds <= 0 {
+		status = models.TimesheetSkipped
+	}
+	entry := models.TimesheetEntry{
+		UserID:           userID,
+		CardKey:          e.CardKey,
+		Date:             e.Date,
+		EstimatedSeconds: e.EstimatedSeconds,
+		Seconds:          max(e.Seconds, 0),
+		StartedAt:        e.StartedAt,
+		Comment:          e.Comment,
+		Status:           status,
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "card_key"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"estimated_seconds", "seconds", "started_at", "comment", "status", "error", "updated_at"}),
+	}).Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Submit logs each submittable entry as a Jira worklog. Approving the
+// timesheet is the user's approval, so each worklog goes through the Jira
+// outbox already approved and is sent right away. Each entry is first claimed
+// as submitting, so concurrent approvals of the same day post one worklog.
+// The entries come back with their new status.
+func Submit(db *gorm.DB, enc *crypto.Encryptor, userID uint, entries []Entry) []Entry {
+	out := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if !e.Submittable() {
+			out = append(out, e)
+			continue
+		}
+
+		started := e.StartedAt
+		if started.IsZero() {
+			day, _ := time.ParseInLocation(DateLayout, e.Date, time.Local)
+			started = day.Add(9 * time.Hour)
+		}
+		claimed, err := claim(db, userID, e, started)
+		if err != nil || !claimed {
+			e.Status = models.TimesheetSubmitting
+			e.Error = fmt.Sprintf("%s on %s is already being submitted", e.CardKey, e.Date)
+			if err != nil {
+				e.Status = models.TimesheetFailed
+				e.Error = err.Error()
+			}
+			out = append(out, e)
+			continue
+		}
+
+		item := models.JiraOutbox{
+			UserID:           userID,
+			CardKey:          e.CardKey,
+			Action:           models.JiraActionWorklog,
+			Body:             e.Comment,
+			TimeSpentSeconds: e.Seconds,
+			StartedAt:        &started,
+			RequestedBy:      "timesheet",
+			Context:          fmt.Sprintf("Timesheet for %s", e.Date),
+		}
+		err = jiraoutbox.Queue(db, &item)
+		if err == nil {
+			err = jiraoutbox.Approve(db, enc, &item)
+		}
+
+		e.Status = models.TimesheetSubmitted
+		e.Error = ""
+		if err != nil {
+			e.Status = models.TimesheetFailed
+			e.Error = err.Error()
+		}
+		if item.ID != 0 {
+			id := item.ID
+			e.OutboxID = &id
+		}
+		if err := db.Model(&models.TimesheetEntry{}).
+			Where("user_id = ? AND card_key = ? AND date = ?", userID, e.CardKey, e.Date).
+			Updates(map[string]any{"status": e.Status, "outbox_id": e.OutboxID, "error": e.Error}).Error; err != nil {
+			log.Printf("[timesheet] record %s %s for user %d: %v", e.CardKey, e.Date, userID, err)
+			e.Error = "saving the result: " + err.Error()
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// claim marks the entry as submitting, storing it if only the estimate
+// existed. It reports false when the entry is no longer submittable, for
+// example because another approval claimed it first.
+func claim(db *gorm.DB, userID uint, e Entry, started time.Time) (bool, error) {
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.TimesheetEntry{
+		UserID:           userID,
+		CardKey:          e.CardKey,
+		Date:             e.Date,
+		EstimatedSeconds: e.EstimatedSeconds,
+		Seconds:          e.Seconds,
+		StartedAt:        started,
+		Comment:          e.Comment,
+		Status:           models.TimesheetSubmitting,
+	})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return true, nil
+	}
+
+	res = db.Model(&models.TimesheetEntry{}).
+		Where("user_id = ? AND card_key = ? AND date = ? AND status IN ?", userID, e.CardKey, e.Date,
+			[]models.TimesheetStatus{models.TimesheetEdited, models.TimesheetFailed}).
+		Updates(map[string]any{
+			"seconds":    e.Seconds,
+			"started_at": started,
+			"comment":    e.Comment,
+			"status":     models.TimesheetSubmitting,
+			"error":      "",
+		})
+	return res.RowsAffected == 1, res.Error
+}
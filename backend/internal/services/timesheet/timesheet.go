@@ -0,0 +1,301 @@
+// Package timesheet estimates the time spent per Jira card per day from
+// commit timestamps, and keeps the user's edits to those estimates until they
+// are submitted as Jira worklogs.
+package timesheet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/cardlink"
+	"github.com/cds-id/pdt/backend/internal/services/identity"
+)
+
+// DateLayout is how days are keyed, in local time.
+const DateLayout = "2006-01-02"
+
+// Settings are the heuristics used to turn commit times into durations.
+type Settings struct {
+	SessionGap time.Duration // a longer pause between commits starts a new session
+	MinBlock   time.Duration // least time credited to a card on a day
+	LeadIn     time.Duration // work credited before the first commit of a session
+}
+
+// SettingsFor reads the user's heuristics. A session gap that is not
+// positive falls back to 90 minutes.
+func SettingsFor(user models.User) Settings {
+	s := Settings{
+		SessionGap: time.Duration(user.WorklogSessionGap) * time.Minute,
+		MinBlock:   time.Duration(max(user.WorklogMinBlock, 0)) * time.Minute,
+		LeadIn:     time.Duration(max(user.WorklogLeadIn, 0)) * time.Minute,
+	}
+	if s.SessionGap <= 0 {
+		s.SessionGap = 90 * time.Minute
+	}
+	return s
+}
+
+// CommitAt is a commit's time with the cards it is linked to.
+type CommitAt struct {
+	Time    time.Time
+	Cards   []string
+	Message string
+}
+
+// Block is the time estimated for one card on one day. An empty CardKey
+// holds time spent on commits without a card.
+type Block struct {
+	CardKey   string
+	Seconds   int
+	StartedAt time.Time
+	Commits   int
+	Messages  []string
+}
+
+// Estimate attributes one day's commits to cards. Commits are grouped into
+// sessions split at pauses longer than the session gap. Within a session the
+// time since the previous commit goes to the cards of the commit that ended
+// it, shared evenly when it has several; the first commit of a session is
+// credited the lead-in. Each card's total is rounded up to whole minutes and
+// to at least the minimum block.
+func Estimate(commits []CommitAt, s Settings) []Block {
+	sorted := append([]CommitAt(nil), commits...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	byCard := map[string]*Block{}
+	var order []string
+	credited := map[string]time.Duration{}
+	for i, c := range sorted {
+		span := s.LeadIn
+		if i > 0 {
+			if gap := c.Time.Sub(sorted[i-1].Time); gap <= s.SessionGap {
+				span = gap
+			}
+		}
+
+		cards := c.Cards
+		if len(cards) == 0 {
+			cards = []string{""}
+		}
+		share := span / time.Duration(len(cards))
+		for _, key := range cards {
+			b, ok := byCard[key]
+			if !ok {
+				b = &Block{CardKey: key, StartedAt: c.Time.Add(-span)}
+				byCard[key] = b
+				order = append(order, key)
+			}
+			credited[key] += share
+			b.Commits++
+			if msg := firstLine(c.Message); msg != "" {
+				b.Messages = append(b.Messages, msg)
+			}
+		}
+	}
+
+	blocks := make([]Block, 0, len(order))
+	for _, key := range order {
+		b := byCard[key]
+		d := max(credited[key], s.MinBlock)
+		b.Seconds = int((d + time.Minute - 1) / time.Minute * 60)
+		blocks = append(blocks, *b)
+	}
+	return blocks
+}
+
+// Entry is a card's time on one day as shown on the timesheet.
+type Entry struct {
+	Date             string                 `json:"date"`
+	CardKey          string                 `json:"card_key"`
+	Summary          string                 `json:"summary,omitempty"`
+	Commits          int                    `json:"commits"`
+	EstimatedSeconds int                    `json:"estimated_seconds"`
+	Seconds          int                    `json:"seconds"` // what will be logged
+	StartedAt        time.Time              `json:"started_at"`
+	Comment          string                 `json:"comment"`
+	Status           models.TimesheetStatus `json:"status"`
+	OutboxID         *uint                  `json:"outbox_id,omitempty"`
+	Error            string                 `json:"error,omitempty"`
+}
+
+// Submittable reports whether the entry can still be sent to Jira.
+func (e Entry) Submittable() bool {
+	return e.CardKey != "" && e.Seconds >= 60 &&
+		(e.Status == models.TimesheetSuggested || e.Status == models.TimesheetEdited || e.Status == models.TimesheetFailed)
+}
+
+// DayTotal is time on commits without a card, which cannot be logged.
+type DayTotal struct {
+	Date    string `json:"date"`
+	Seconds int    `json:"seconds"`
+	Commits int    `json:"commits"`
+}
+
+// Week is the timesheet of the seven days from Start, a Monday.
+type Week struct {
+	Start          string     `json:"start"`
+	End            string     `json:"end"`
+	SessionGap     int        `json:"session_gap"` // minutes
+	MinBlock       int        `json:"min_block"`
+	LeadIn         int        `json:"lead_in"`
+	Entries        []Entry    `json:"entries"`
+	Unlinked       []DayTotal `json:"unlinked"`
+	TotalSeconds   int        `json:"total_seconds"`
+	SubmittedCount int        `json:"submitted_count"`
+	PendingSeconds int        `json:"pending_seconds"` // not submitted yet
+}
+
+// WeekStart returns the Monday, at local midnight, of the week containing t.
+func WeekStart(t time.Time) time.Time {
+	t = t.In(time.Local)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// ParseWeek reads an ISO week ("2026-W42") or any date in the week
+// ("2026-10-14"). An empty value is the current week.
+func ParseWeek(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return WeekStart(time.Now()), nil
+	}
+	var year, week int
+	if n, _ := fmt.Sscanf(s, "%d-W%d", &year, &week); n == 2 {
+		if week < 1 || week > 53 {
+			return time.Time{}, fmt.Errorf("invalid week: %s", s)
+		}
+		// January 4th is always in week 1.
+		return WeekStart(time.Date(year, 1, 4, 0, 0, 0, 0, time.Local)).AddDate(0, 0, 7*(week-1)), nil
+	}
+	day, err := time.ParseInLocation(DateLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("week must be YYYY-Www or YYYY-MM-DD")
+	}
+	return WeekStart(day), nil
+}
+
+// BuildWeek estimates the user's week from their own commits and overlays
+// the entries they have edited or submitted.
+func BuildWeek(db *gorm.DB, user models.User, start time.Time) (*Week, error) {
+	start = WeekStart(start)
+	end := start.AddDate(0, 0, 7)
+	s := SettingsFor(user)
+
+	query := db.Joins("JOIN repositories ON repositories.id = commits.repo_id").
+		Where("repositories.user_id = ? AND commits.date >= ? AND commits.date < ?", user.ID, start, end)
+	if mine, ok := identity.AuthorFilter(db, user); ok {
+		query = mine.ScopeCommits(query)
+	}
+	var commits []models.Commit
+	if err := query.Order("commits.date asc").Find(&commits).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(commits))
+	for i, c := range commits {
+		ids[i] = c.ID
+	}
+	linked := cardlink.Keys(db, ids)
+
+	days := map[string][]CommitAt{}
+	for _, c := range commits {
+		date := c.Date.In(time.Local).Format(DateLayout)
+		days[date] = append(days[date], CommitAt{Time: c.Date, Cards: linked[c.ID], Message: c.Message})
+	}
+
+	week := &Week{
+		Start:      start.Format(DateLayout),
+		End:        end.AddDate(0, 0, -1).Format(DateLayout),
+		SessionGap: int(s.SessionGap / time.Minute),
+		MinBlock:   int(s.MinBlock / time.Minute),
+		LeadIn:     int(s.LeadIn / time.Minute),
+		Entries:    []Entry{},
+		Unlinked:   []DayTotal{},
+	}
+
+	entries := map[string]*Entry{}
+	var keys []string
+	for date, dayCommits := range days {
+		for _, b := range Estimate(dayCommits, s) {
+			if b.CardKey == "" {
+				week.Unlinked = append(week.Unlinked, DayTotal{Date: date, Seconds: b.Seconds, Commits: b.Commits})
+				continue
+			}
+			id := date + "|" + b.CardKey
+			entries[id] = &Entry{
+				Date:             date,
+				CardKey:          b.CardKey,
+				Commits:          b.Commits,
+				EstimatedSeconds: b.Seconds,
+				Seconds:          b.Seconds,
+				StartedAt:        b.StartedAt,
+				Comment:          strings.Join(b.Messages, "\n"),
+				Status:           models.TimesheetSuggested,
+			}
+			keys = append(keys, id)
+		}
+	}
+
+	var stored []models.TimesheetEntry
+	db.Where("user_id = ? AND date >= ? AND date <= ?", user.ID, week.Start, week.End).Find(&stored)
+	for _, st := range stored {
+		id := st.Date + "|" + st.CardKey
+		e, ok := entries[id]
+		if !ok {
+			e = &Entry{Date: st.Date, CardKey: st.CardKey}
+			entries[id] = e
+			keys = append(keys, id)
+		}
+		e.Seconds = st.Seconds
+		e.StartedAt = st.StartedAt
+		e.Comment = st.Comment
+		e.Status = st.Status
+		e.OutboxID = st.OutboxID
+		e.Error = st.Error
+	}
+
+	var cardKeys []string
+	for _, id := range keys {
+		cardKeys = append(cardKeys, entries[id].CardKey)
+	}
+	summaries := map[string]string{}
+	if len(cardKeys) > 0 {
+		var cards []models.JiraCard
+		db.Select("card_key, summary").Where("user_id = ? AND card_key IN ?", user.ID, cardKeys).Find(&cards)
+		for _, c := range cards {
+			summaries[c.Key] = c.Summary
+		}
+	}
+
+	sort.Strings(keys)
+	for _, id := range keys {
+		e := entries[id]
+		e.Summary = summaries[e.CardKey]
+		week.Entries = append(week.Entries, *e)
+		if e.Status == models.TimesheetSkipped {
+			continue
+		}
+		week.TotalSeconds += e.Seconds
+		if e.Status == models.TimesheetSubmitted {
+			week.SubmittedCount++
+		}
+		if e.Submittable() {
+			week.PendingSeconds += e.Seconds
+		}
+	}
+	sort.Slice(week.Unlinked, func(i, j int) bool { return week.Unlinked[i].Date < week.Unlinked[j].Date })
+	return week, nil
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,140 @@
+package timesheet
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+)
+
+func TestEstimate(t *testing.T) {
+	at := func(hhmm string) time.Time {
+		tm, _ := time.ParseInLocation("2006-01-02 15:04", "2026-10-12 "+hhmm, time.Local)
+		return tm
+	}
+	s := Settings{SessionGap: 90 * time.Minute, MinBlock: 15 * time.Minute, LeadIn: 30 * time.Minute}
+	blocks := Estimate([]CommitAt{
+		{Time: at("10:00"), Cards: []string{"CORE-1"}, Message: "start\n\nbody"},
+		{Time: at("09:00"), Cards: []string{"CORE-1"}, Message: "first"},
+		{Time: at("10:40"), Cards: []string{"CORE-1", "CORE-2"}},
+		{Time: at("15:00"), Cards: nil},                // new session: lead-in only
+		{Time: at("15:05"), Cards: []string{"CORE-3"}}, // 5 minutes, raised to the minimum block
+	}, s)
+
+	got := map[string]Block{}
+	for _, b := range blocks {
+		got[b.CardKey] = b
+	}
+	// CORE-1: 30m lead-in + 60m + half of 40m.
+	if b := got["CORE-1"]; b.Seconds != 110*60 || b.Commits != 3 || !b.StartedAt.Equal(at("08:30")) || b.Messages[1] != "start" {
+		t.Errorf("CORE-1 = %+v", b)
+	}
+	if b := got["CORE-2"]; b.Seconds != 20*60 {
+		t.Errorf("CORE-2 = %+v", b)
+	}
+	if b := got[""]; b.Seconds != 30*60 || b.Commits != 1 {
+		t.Errorf("unlinked = %+v", b)
+	}
+	if b := got["CORE-3"]; b.Seconds != 15*60 {
+		t.Errorf("CORE-3 = %+v", b)
+	}
+}
+
+func TestParseWeek(t *testing.T) {
+	for in, want := range map[string]string{
+		"2026-W42":   "2026-10-12",
+		"2026-10-18": "2026-10-12",
+		"2026-10-12": "2026-10-12",
+		"2027-W01":   "2027-01-04",
+	} {
+		got, err := ParseWeek(in)
+		if err != nil || got.Format(DateLayout) != want {
+			t.Errorf("ParseWeek(%q) = %s, %v; want %s", in, got.Format(DateLayout), err, want)
+		}
+	}
+	if _, err := ParseWeek("last week"); err == nil {
+		t.Error("invalid week accepted")
+	}
+}
+
+func TestBuildWeek_OverlaysSavedEntries(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Repository{}, &models.Commit{}, &models.CommitCardLink{},
+		&models.JiraCard{}, &models.TimesheetEntry{}, &models.ProviderAccount{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	user := models.User{Email: "me@x.io", IncludeAllAuthors: true, WorklogSessionGap: 90, WorklogMinBlock: 15, WorklogLeadIn: 30}
+	db.Create(&user)
+	repo := models.Repository{UserID: user.ID, Name: "api", Owner: "acme"}
+	db.Create(&repo)
+	day := time.Date(2026, 10, 13, 9, 0, 0, 0, time.Local)
+	for i, key := range []string{"CORE-1", "CORE-2"} {
+		c := models.Commit{RepoID: repo.ID, SHA: key, Message: key + " work", Date: day.Add(time.Duration(i) * time.Hour)}
+		db.Create(&c)
+		db.Create(&models.CommitCardLink{CommitID: c.ID, JiraCardKey: key, LinkedAt: day})
+	}
+
+	if _, err := Save(db, user.ID, Entry{Date: "2026-10-13", CardKey: "CORE-2", Seconds: 0}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	week, err := BuildWeek(db, user, day)
+	if err != nil {
+		t.Fatalf("BuildWeek: %v", err)
+	}
+	if week.Start != "2026-10-12" || len(week.Entries) != 2 {
+		t.Fatalf("week = %+v", week)
+	}
+	first, second := week.Entries[0], week.Entries[1]
+	if first.CardKey != "CORE-1" || first.Status != models.TimesheetSuggested || first.Seconds != 30*60 || !first.Submittable() {
+		t.Errorf("CORE-1 = %+v", first)
+	}
+	if second.Status != models.TimesheetSkipped || second.EstimatedSeconds != 60*60 || second.Submittable() {
+		t.Errorf("CORE-2 = %+v", second)
+	}
+	if week.TotalSeconds != 30*60 || week.PendingSeconds != 30*60 {
+		t.Errorf("totals = %d, %d", week.TotalSeconds, week.PendingSeconds)
+	}
+}
+
+func TestClaim_OnlyOnce(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.TimesheetEntry{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	user := models.User{Email: "me@x.io"}
+	db.Create(&user)
+
+	started := time.Date(2026, 10, 13, 9, 0, 0, 0, time.Local)
+	suggested := Entry{CardKey: "CORE-1", Date: "2026-10-13", Seconds: 1800, Status: models.TimesheetSuggested}
+	if ok, err := claim(db, user.ID, suggested, started); !ok || err != nil {
+		t.Fatalf("first claim = %v, %v", ok, err)
+	}
+	if ok, err := claim(db, user.ID, suggested, started); ok || err != nil {
+		t.Errorf("second claim of a suggested entry = %v, %v, want false", ok, err)
+	}
+
+	edited := Entry{CardKey: "CORE-2", Date: "2026-10-13", Seconds: 1800, Status: models.TimesheetEdited}
+	if _, err := Save(db, user.ID, edited); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if ok, _ := claim(db, user.ID, edited, started); !ok {
+		t.Error("edited entry was not claimed")
+	}
+	if ok, _ := claim(db, user.ID, edited, started); ok {
+		t.Error("edited entry was claimed twice")
+	}
+	if _, err := Save(db, user.ID, edited); err == nil {
+		t.Error("Save changed an entry that is being submitted")
+	}
+}
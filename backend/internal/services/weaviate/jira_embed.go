@@ -56,6 +56,16 @@ func jiraUUID(prefix string, id string) string {
 		h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
 }
 
+// JiraCardContent is the text embedded for a card: its summary, then its
+// description rendered as Markdown, so lists, tables and code keep their
+// structure.
+func JiraCardContent(summary, description string) string {
+	if description == "" {
+		return summary
+	}
+	return summary + "\n\n" + description
+}
+
 // UpsertJiraCard embeds a Jira card's summary + description.
 func (c *Client) UpsertJiraCard(ctx context.Context, cardKey string, userID, workspaceID int, content, status, assignee string) error {
 	if !c.available || content == "" {
@@ -9,20 +9,31 @@ import (
 	"github.com/cds-id/pdt/backend/internal/crypto"
 	"github.com/cds-id/pdt/backend/internal/models"
 	"github.com/cds-id/pdt/backend/internal/services"
-	"github.com/cds-id/pdt/backend/internal/services/github"
-	"github.com/cds-id/pdt/backend/internal/services/gitlab"
+	"github.com/cds-id/pdt/backend/internal/services/cardlink"
+	"github.com/cds-id/pdt/backend/internal/services/gitprovider"
 	wvClient "github.com/cds-id/pdt/backend/internal/services/weaviate"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// CommitBackfillDays is how far back the first sync of a branch reaches when
+// it has no cursor yet. Overridden from config at startup.
+var CommitBackfillDays = 30
+
+// CursorOverlap is how far before a branch cursor each sync reaches again.
+// Cursors hold author dates, while providers filter on when a commit landed,
+// so a commit authored before the cursor but pushed, rebased or merged later
+// is only found by looking back.
+var CursorOverlap = 7 * 24 * time.Hour
+
 type CommitSyncResult struct {
-	RepoID   uint   `json:"repo_id"`
-	RepoName string `json:"repo_name"`
-	Provider string `json:"provider"`
-	New      int    `json:"new_commits"`
-	Total    int    `json:"total_fetched"`
-	Error    string `json:"error,omitempty"`
+	RepoID     uint   `json:"repo_id"`
+	RepoName   string `json:"repo_name"`
+	Provider   string `json:"provider"`
+	New        int    `json:"new_commits"`
+	Total      int    `json:"total_fetched"`
+	Error      string `json:"error,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"`
 }
 
 func SyncUserCommits(db *gorm.DB, enc *crypto.Encryptor, userID uint, wv ...*wvClient.Client) ([]CommitSyncResult, error) {
@@ -40,8 +51,8 @@ func SyncUserCommits(db *gorm.DB, enc *crypto.Encryptor, userID uint, wv ...*wvC
 		return nil, nil
 	}
 
-	since := time.Now().AddDate(0, 0, -30)
 	var results []CommitSyncResult
+	projects := cardlink.ProjectKeys(db, userID)
 
 	for _, repo := range repos {
 		result := CommitSyncResult{
@@ -50,83 +61,56 @@ func SyncUserCommits(db *gorm.DB, enc *crypto.Encryptor, userID uint, wv ...*wvC
 			Provider: string(repo.Provider),
 		}
 
-		var provider services.CommitProvider
-		var token string
-
-		switch repo.Provider {
-		case models.ProviderGitHub:
-			provider = github.New()
-			decrypted, err := enc.Decrypt(user.GithubToken)
-			if err != nil {
-				result.Error = "failed to decrypt github token"
-				results = append(results, result)
-				continue
-			}
-			token = decrypted
-		case models.ProviderGitLab:
-			provider = gitlab.New(user.GitlabURL)
-			decrypted, err := enc.Decrypt(user.GitlabToken)
-			if err != nil {
-				result.Error = "failed to decrypt gitlab token"
-				results = append(results, result)
-				continue
-			}
-			token = decrypted
-		}
-
-		if token == "" {
-			result.Error = fmt.Sprintf("no %s token configured", repo.Provider)
+		provider, token, err := gitprovider.ForRepository(db, enc, user, repo)
+		if err != nil {
+			result.Error = err.Error()
 			results = append(results, result)
 			continue
 		}
 
-		commits, err := provider.FetchCommits(repo.Owner, repo.Name, token, since)
-		if err != nil {
+		commits, fetched, err := syncRepoBranches(db, provider, repo, token)
+		if err != nil && fetched == 0 {
+			health := recordSyncFailure(db, repo, err)
 			result.Error = err.Error()
-			db.Model(&repo).Update("is_valid", false)
+			result.ErrorClass = health.LastErrorClass
 			results = append(results, result)
 			continue
 		}
 
 		result.Total = len(commits)
 
+		shas := make([]string, 0, len(commits))
 		for _, ci := range commits {
-			jiraKey := services.ExtractJiraKey(ci.Message)
-			commit := models.Commit{
-				RepoID:      repo.ID,
-				SHA:         ci.SHA,
-				Message:     ci.Message,
-				Author:      ci.Author,
-				AuthorEmail: ci.AuthorEmail,
-				Branch:      ci.Branch,
-				Date:        ci.Date,
-				JiraCardKey: jiraKey,
-				HasLink:     jiraKey != "",
+			shas = append(shas, ci.SHA)
+			commit, created := StoreCommit(db, repo, ci, projects)
+			if !created {
+				continue
 			}
 
-			res := db.Clauses(clause.OnConflict{
-				Columns:   []clause.Column{{Name: "sha"}},
-				DoNothing: true,
-			}).Create(&commit)
-
-			if res.RowsAffected > 0 {
-				result.New++
-
-				// Embed new commits in Weaviate
-				if len(wv) > 0 && wv[0] != nil {
-					repoName := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
-					if err := wv[0].UpsertCommit(context.Background(), int(commit.ID), int(userID), ci.SHA, ci.Message, repoName, ci.Author, ci.Date); err != nil {
-						log.Printf("[commit-sync] embed commit %s error: %v", ci.SHA[:7], err)
-					}
-				}
+			result.New++
+
+			// Embed new commits in Weaviate
+			if len(wv) > 0 && wv[0] != nil {
+				EmbedCommit(wv[0], repo, commit)
 			}
 		}
 
-		now := time.Now()
-		db.Model(&repo).Updates(map[string]interface{}{
-			"is_valid":       true,
-			"last_synced_at": &now,
-		})
+		LinkBranchCommits(db, repo, shas, projects)
+		if err != nil {
+			health := recordPartialFailure(db, repo, err)
+			result.Error = err.Error()
+			result.ErrorClass = health.LastErrorClass
+		} else {
+			recordSyncSuccess(db, repo)
+		}
+
+		if prProvider, ok := provider.(services.PullRequestProvider); ok {
+			if n, err := syncRepoPullRequests(db, prProvider, repo, token, projects); err != nil {
+				log.Printf("[commit-sync] repo=%s pull request sync error: %v", result.RepoName, err)
+			} else if n > 0 {
+				log.Printf("[commit-sync] repo=%s pull requests updated=%d", result.RepoName, n)
+			}
+		}
 
 		results = append(results, result)
 	}
@@ -134,6 +118,277 @@ func SyncUserCommits(db *gorm.DB, enc *crypto.Encryptor, userID uint, wv ...*wvC
 	return results, nil
 }
 
+// StoreCommit inserts a fetched commit for repo and links it to every Jira
+// key in its message that belongs to one of projects (any project when
+// empty), and records the branch it was seen on. When the SHA is already
+// stored it only records the branch and reports created=false.
+func StoreCommit(db *gorm.DB, repo models.Repository, ci services.CommitInfo, projects []string) (models.Commit, bool) {
+	keys := services.ExtractJiraKeys(ci.Message, projects)
+	var jiraKey string
+	if len(keys) > 0 {
+		jiraKey = keys[0]
+	}
+	commit := models.Commit{
+		RepoID:      repo.ID,
+		SHA:         ci.SHA,
+		Message:     ci.Message,
+		Author:      ci.Author,
+		AuthorEmail: ci.AuthorEmail,
+		Branch:      ci.Branch,
+		Date:        ci.Date,
+		JiraCardKey: jiraKey,
+		HasLink:     jiraKey != "",
+	}
+
+	res := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "sha"}},
+		DoNothing: true,
+	}).Create(&commit)
+
+	if res.RowsAffected == 0 {
+		var existing models.Commit
+		if db.Where("sha = ?", ci.SHA).First(&existing).Error == nil {
+			recordBranch(db, existing.ID, ci.Branch)
+		}
+		return existing, false
+	}
+	recordBranch(db, commit.ID, ci.Branch)
+
+	if _, err := cardlink.Link(db, &commit, keys, models.LinkSourceMessage); err != nil {
+		log.Printf("[commit-sync] link commit %s error: %v", commit.SHA, err)
+	}
+	return commit, true
+}
+
+// recordBranch notes that a commit was seen on branch.
+func recordBranch(db *gorm.DB, commitID uint, branch string) {
+	if branch == "" {
+		return
+	}
+	db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.CommitBranch{CommitID: commitID, Branch: branch, SeenAt: time.Now()})
+}
+
+// LinkBranchCommits links the given commits of repo to the Jira keys in the
+// name of their branch. Only commits seen on a single branch are linked: one
+// also reachable from main or another branch was not necessarily made for the
+// branch's card.
+func LinkBranchCommits(db *gorm.DB, repo models.Repository, shas []string, projects []string) {
+	if len(shas) == 0 {
+		return
+	}
+	var commits []models.Commit
+	db.Where("repo_id = ? AND sha IN ? AND branch <> ''", repo.ID, shas).
+		Where("(SELECT COUNT(*) FROM commit_branches WHERE commit_branches.commit_id = commits.id) = 1").
+		Find(&commits)
+	for i := range commits {
+		keys := services.ExtractBranchJiraKeys(commits[i].Branch, projects)
+		if _, err := cardlink.Link(db, &commits[i], keys, models.LinkSourceBranch); err != nil {
+			log.Printf("[commit-sync] link commit %s error: %v", commits[i].SHA, err)
+		}
+	}
+}
+
+// EmbedCommit upserts a stored commit into Weaviate.
+func EmbedCommit(wv *wvClient.Client, repo models.Repository, commit models.Commit) {
+	repoName := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
+	if err := wv.UpsertCommit(context.Background(), int(commit.ID), int(repo.UserID), commit.SHA, commit.Message, repoName, commit.Author, commit.Date); err != nil {
+		log.Printf("[commit-sync] embed commit %s error: %v", commit.SHA[:7], err)
+	}
+}
+
+// syncRepoBranches walks every branch of a repository, fetching commits from
+// CursorOverlap before that branch's cursor (or the backfill window on first
+// sync), and advances the cursors. Commits already recorded on the branch are
+// skipped; one reachable from several branches is returned once per branch
+// so the caller can record all of them. fetched counts the
+// branches read successfully; when some fail, the first error is returned
+// along with the commits of the others.
+func syncRepoBranches(db *gorm.DB, provider services.CommitProvider, repo models.Repository, token string) (all []services.CommitInfo, fetched int, err error) {
+	branches, err := provider.FetchBranches(repo.Owner, repo.Name, token)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch branches: %w", err)
+	}
+
+	var cursors []models.RepoSyncCursor
+	db.Where("repo_id = ?", repo.ID).Find(&cursors)
+	cursorByBranch := make(map[string]models.RepoSyncCursor, len(cursors))
+	for _, c := range cursors {
+		cursorByBranch[c.Branch] = c
+	}
+
+	backfillSince := time.Now().AddDate(0, 0, -CommitBackfillDays)
+	active := make(map[string]bool, len(branches))
+	var failed int
+	var firstErr error
+
+	for _, branch := range branches {
+		active[branch] = true
+
+		cursor, ok := cursorByBranch[branch]
+		since := backfillSince
+		if ok && cursor.LastCommitAt != nil {
+			since = cursor.LastCommitAt.Add(-CursorOverlap)
+		}
+
+		commits, err := provider.FetchBranchCommits(repo.Owner, repo.Name, branch, token, since)
+		if err != nil {
+			log.Printf("[commit-sync] repo=%s/%s branch=%s fetch error: %v", repo.Owner, repo.Name, branch, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("branch %s: %w", branch, err)
+			}
+			failed++
+			continue
+		}
+		fetched++
+
+		if !ok {
+			cursor = models.RepoSyncCursor{RepoID: repo.ID, Branch: branch}
+		}
+		seen := seenOnBranch(db, repo.ID, branch, commits)
+		for _, ci := range commits {
+			if seen[ci.SHA] {
+				continue
+			}
+			all = append(all, ci)
+			if cursor.LastCommitAt == nil || ci.Date.After(*cursor.LastCommitAt) {
+				date := ci.Date
+				cursor.LastCommitAt = &date
+				cursor.LastSHA = ci.SHA
+			}
+		}
+		cursor.SyncedAt = time.Now()
+		db.Save(&cursor)
+	}
+
+	// Drop cursors for branches that were deleted upstream.
+	for branch, cursor := range cursorByBranch {
+		if !active[branch] {
+			db.Delete(&cursor)
+		}
+	}
+
+	if firstErr != nil {
+		return all, fetched, fmt.Errorf("%d of %d branches failed to fetch, first %w", failed, len(branches), firstErr)
+	}
+	return all, fetched, nil
+}
+
+// seenOnBranch returns which of commits are already recorded on branch.
+func seenOnBranch(db *gorm.DB, repoID uint, branch string, commits []services.CommitInfo) map[string]bool {
+	if len(commits) == 0 {
+		return nil
+	}
+	shas := make([]string, len(commits))
+	for i, ci := range commits {
+		shas[i] = ci.SHA
+	}
+	var stored []string
+	db.Model(&models.Commit{}).
+		Joins("JOIN commit_branches ON commit_branches.commit_id = commits.id").
+		Where("commits.repo_id = ? AND commits.sha IN ? AND commit_branches.branch = ?", repoID, shas, branch).
+		Pluck("commits.sha", &stored)
+	seen := make(map[string]bool, len(stored))
+	for _, sha := range stored {
+		seen[sha] = true
+	}
+	return seen
+}
+
+// syncRepoPullRequests upserts pull/merge requests updated since the repo's
+// last PR sync (or the backfill window) along with their reviews, and
+// returns how many were stored.
+func syncRepoPullRequests(db *gorm.DB, provider services.PullRequestProvider, repo models.Repository, token string, projects []string) (int, error) {
+	since := time.Now().AddDate(0, 0, -CommitBackfillDays)
+	if repo.PRSyncedAt != nil {
+		since = *repo.PRSyncedAt
+	}
+	startedAt := time.Now()
+
+	prs, err := provider.FetchPullRequests(repo.Owner, repo.Name, token, since)
+	if err != nil {
+		return 0, err
+	}
+
+	stored := 0
+	for _, info := range prs {
+		titleKeys := services.ExtractJiraKeys(info.Title, projects)
+		branchKeys := services.ExtractBranchJiraKeys(info.SourceBranch, projects)
+		var jiraKey string
+		if keys := append(append([]string{}, titleKeys...), branchKeys...); len(keys) > 0 {
+			jiraKey = keys[0]
+		}
+
+		pr := models.PullRequest{RepoID: repo.ID, Number: info.Number}
+		db.Where("repo_id = ? AND number = ?", repo.ID, info.Number).First(&pr)
+
+		pr.Title = info.Title
+		pr.State = models.PullRequestState(info.State)
+		pr.Author = info.Author
+		pr.SourceBranch = info.SourceBranch
+		pr.TargetBranch = info.TargetBranch
+		pr.URL = info.URL
+		pr.Reviewers = joinReviewers(info.Reviewers)
+		pr.JiraCardKey = jiraKey
+		pr.OpenedAt = info.CreatedAt
+		pr.MergedAt = info.MergedAt
+		pr.ClosedAt = info.ClosedAt
+		pr.ActivityAt = info.UpdatedAt
+		if err := db.Omit("Reviews").Save(&pr).Error; err != nil {
+			log.Printf("[commit-sync] repo=%s/%s save pull request #%d error: %v", repo.Owner, repo.Name, info.Number, err)
+			continue
+		}
+		stored++
+
+		for _, r := range info.Reviews {
+			review := models.PullRequestReview{
+				PullRequestID: pr.ID,
+				ExternalID:    r.ExternalID,
+				Author:        r.Author,
+				State:         r.State,
+				Body:          r.Body,
+				SubmittedAt:   r.SubmittedAt,
+			}
+			db.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "pull_request_id"}, {Name: "external_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"state", "body"}),
+			}).Create(&review)
+		}
+
+		if len(info.CommitSHAs) > 0 && (len(titleKeys) > 0 || len(branchKeys) > 0) {
+			var commits []models.Commit
+			db.Where("repo_id = ? AND sha IN ?", repo.ID, info.CommitSHAs).Find(&commits)
+			for i := range commits {
+				cardlink.Link(db, &commits[i], titleKeys, models.LinkSourcePullRequest)
+				cardlink.Link(db, &commits[i], branchKeys, models.LinkSourceBranch)
+			}
+		}
+	}
+
+	db.Model(&repo).Update("pr_synced_at", &startedAt)
+	return stored, nil
+}
+
+// maxReviewersLen is the size of the pull_requests.reviewers column.
+const maxReviewersLen = 1000
+
+// joinReviewers joins reviewer logins with commas, dropping the ones that
+// would not fit the reviewers column.
+func joinReviewers(reviewers []string) string {
+	var joined string
+	for _, r := range reviewers {
+		next := r
+		if joined != "" {
+			next = joined + "," + r
+		}
+		if len(next) > maxReviewersLen {
+			break
+		}
+		joined = next
+	}
+	return joined
+}
+
 func SyncAllUsersCommits(db *gorm.DB, enc *crypto.Encryptor) {
 	var userIDs []uint
 	db.Model(&models.Repository{}).Distinct("user_id").Pluck("user_id", &userIDs)
@@ -152,4 +407,5 @@ func SyncAllUsersCommits(db *gorm.DB, enc *crypto.Encryptor) {
 			}
 		}
 	}
+	DiscoverPeople(db, userIDs...)
 }
@@ -0,0 +1,288 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services"
+)
+
+type fakeCommitProvider struct {
+	branches []string
+	commits  map[string][]services.CommitInfo
+	sinces   map[string]time.Time
+	errs     map[string]error
+}
+
+func (f *fakeCommitProvider) FetchCommits(owner, repo, token string, since time.Time) ([]services.CommitInfo, error) {
+	return nil, nil
+}
+
+func (f *fakeCommitProvider) FetchBranches(owner, repo, token string) ([]string, error) {
+	return f.branches, nil
+}
+
+func (f *fakeCommitProvider) FetchBranchCommits(owner, repo, branch, token string, since time.Time) ([]services.CommitInfo, error) {
+	f.sinces[branch] = since
+	if err := f.errs[branch]; err != nil {
+		return nil, err
+	}
+	var out []services.CommitInfo
+	for _, c := range f.commits[branch] {
+		if !c.Date.Before(since) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeCommitProvider) ValidateAccess(owner, repo, token string) error { return nil }
+
+func setupWorkerDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Repository{}, &models.Commit{}, &models.CommitBranch{}, &models.RepoSyncCursor{}, &models.RepoSyncHealth{},
+		&models.PullRequest{}, &models.PullRequestReview{}, &models.CommitCardLink{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestSyncRepoBranches_ResumesFromCursor(t *testing.T) {
+	db := setupWorkerDB(t)
+	repo := models.Repository{UserID: 1, Owner: "o", Name: "r", Provider: models.ProviderGitHub, URL: "https://github.com/o/r"}
+	db.Create(&repo)
+
+	t1 := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	t2 := t1.Add(time.Hour)
+	p := &fakeCommitProvider{
+		branches: []string{"main", "feature/x"},
+		commits: map[string][]services.CommitInfo{
+			"main":      {{SHA: "a1", Branch: "main", Date: t1}},
+			"feature/x": {{SHA: "a1", Branch: "feature/x", Date: t1}, {SHA: "b2", Branch: "feature/x", Date: t2}},
+		},
+		sinces: map[string]time.Time{},
+	}
+
+	got, _, err := syncRepoBranches(db, p, repo, "tok")
+	if err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("first sync returned %d commits, want 3", len(got))
+	}
+
+	var cursor models.RepoSyncCursor
+	db.Where("repo_id = ? AND branch = ?", repo.ID, "feature/x").First(&cursor)
+	if cursor.LastSHA != "b2" || cursor.LastCommitAt == nil || !cursor.LastCommitAt.Equal(t2) {
+		t.Fatalf("cursor = %+v, want b2 at %v", cursor, t2)
+	}
+
+	for _, ci := range got {
+		StoreCommit(db, repo, ci, nil)
+	}
+
+	// Second run looks back from each branch cursor and skips the commits
+	// already recorded on the branch.
+	p.branches = []string{"main"}
+	got, _, err = syncRepoBranches(db, p, repo, "tok")
+	if err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("second sync returned %d commits, want 0", len(got))
+	}
+	if want := t1.Add(-CursorOverlap); !p.sinces["main"].Equal(want) {
+		t.Errorf("main since = %v, want %v", p.sinces["main"], want)
+	}
+
+	var count int64
+	db.Model(&models.RepoSyncCursor{}).Where("repo_id = ?", repo.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("cursor count = %d, want 1 after branch deletion", count)
+	}
+}
+
+func TestSyncRepoBranches_FindsCommitsAuthoredBeforeTheCursor(t *testing.T) {
+	db := setupWorkerDB(t)
+	repo := models.Repository{UserID: 1, Owner: "o", Name: "r", Provider: models.ProviderGitHub, URL: "https://github.com/o/r"}
+	db.Create(&repo)
+
+	t1 := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	p := &fakeCommitProvider{
+		branches: []string{"main"},
+		commits:  map[string][]services.CommitInfo{"main": {{SHA: "a1", Branch: "main", Date: t1}}},
+		sinces:   map[string]time.Time{},
+	}
+	got, _, _ := syncRepoBranches(db, p, repo, "tok")
+	for _, ci := range got {
+		StoreCommit(db, repo, ci, nil)
+	}
+
+	// A commit authored a day before the cursor is merged into main later.
+	p.commits["main"] = append(p.commits["main"], services.CommitInfo{SHA: "old1", Branch: "main", Date: t1.Add(-24 * time.Hour)})
+	got, _, err := syncRepoBranches(db, p, repo, "tok")
+	if err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if len(got) != 1 || got[0].SHA != "old1" {
+		t.Errorf("second sync returned %+v, want only old1", got)
+	}
+
+	var cursor models.RepoSyncCursor
+	db.Where("repo_id = ? AND branch = ?", repo.ID, "main").First(&cursor)
+	if !cursor.LastCommitAt.Equal(t1) {
+		t.Errorf("cursor moved back to %v, want %v", cursor.LastCommitAt, t1)
+	}
+}
+
+func TestSyncRepoBranches_ReportsBranchErrors(t *testing.T) {
+	db := setupWorkerDB(t)
+	repo := models.Repository{UserID: 1, Owner: "o", Name: "r", Provider: models.ProviderGitHub, URL: "https://github.com/o/r"}
+	db.Create(&repo)
+
+	p := &fakeCommitProvider{
+		branches: []string{"main", "broken"},
+		commits:  map[string][]services.CommitInfo{"main": {{SHA: "a1", Branch: "main", Date: time.Now()}}},
+		sinces:   map[string]time.Time{},
+		errs:     map[string]error{"broken": &services.ProviderError{Class: services.ErrServer, Message: "server error: status 502"}},
+	}
+
+	got, fetched, err := syncRepoBranches(db, p, repo, "tok")
+	if len(got) != 1 || fetched != 1 {
+		t.Errorf("got %d commits from %d branches, want the main branch's", len(got), fetched)
+	}
+	if err == nil || services.ClassifyError(err) != services.ErrServer || !strings.Contains(err.Error(), "1 of 2 branches") {
+		t.Errorf("err = %v, want the broken branch's server error", err)
+	}
+}
+
+func TestStoreCommit_LinksMessageAndBranchKeys(t *testing.T) {
+	db := setupWorkerDB(t)
+	repo := models.Repository{UserID: 1, Owner: "o", Name: "r", Provider: models.ProviderGitHub, URL: "https://github.com/o/r"}
+	db.Create(&repo)
+	projects := []string{"CORE", "OPS"}
+
+	now := time.Now()
+	StoreCommit(db, repo, services.CommitInfo{SHA: "c1", Message: "fix login\n\nRefs CORE-12, OPS-3 and UTF-8 handling", Branch: "main", Date: now}, projects)
+	StoreCommit(db, repo, services.CommitInfo{SHA: "c2", Message: "wip", Branch: "feature/core-40-export", Date: now}, projects)
+	StoreCommit(db, repo, services.CommitInfo{SHA: "c3", Message: "shared", Branch: "main", Date: now}, projects)
+	StoreCommit(db, repo, services.CommitInfo{SHA: "c3", Message: "shared", Branch: "feature/core-40-export", Date: now}, projects)
+	other := models.Repository{UserID: 2, Owner: "fork", Name: "r", Provider: models.ProviderGitHub, URL: "https://github.com/fork/r"}
+	db.Create(&other)
+	StoreCommit(db, other, services.CommitInfo{SHA: "c4", Message: "theirs", Branch: "feature/core-41-import", Date: now}, nil)
+	LinkBranchCommits(db, repo, []string{"c1", "c2", "c3", "c4"}, projects)
+
+	links := map[string][]string{}
+	var rows []struct {
+		SHA         string
+		JiraCardKey string
+		Source      string
+	}
+	db.Table("commit_card_links").Select("commits.sha, commit_card_links.jira_card_key, commit_card_links.source").
+		Joins("JOIN commits ON commits.id = commit_card_links.commit_id").Order("commit_card_links.id").Scan(&rows)
+	for _, r := range rows {
+		links[r.SHA] = append(links[r.SHA], r.JiraCardKey+"/"+r.Source)
+	}
+
+	if got := strings.Join(links["c1"], ","); got != "CORE-12/message,OPS-3/message" {
+		t.Errorf("c1 links = %s", got)
+	}
+	if got := strings.Join(links["c2"], ","); got != "CORE-40/branch" {
+		t.Errorf("c2 links = %s", got)
+	}
+	if len(links["c3"]) != 0 {
+		t.Errorf("c3 is on main too and must not take the branch key, got %v", links["c3"])
+	}
+	if len(links["c4"]) != 0 {
+		t.Errorf("c4 belongs to another repository, got %v", links["c4"])
+	}
+
+	var c3 models.Commit
+	db.Where("sha = ?", "c3").First(&c3)
+	var branches []string
+	db.Model(&models.CommitBranch{}).Where("commit_id = ?", c3.ID).Order("id").Pluck("branch", &branches)
+	if c3.Branch != "main" || strings.Join(branches, ",") != "main,feature/core-40-export" {
+		t.Errorf("c3 branch=%q branches=%v, want main first and both recorded", c3.Branch, branches)
+	}
+
+	var c1 models.Commit
+	db.Where("sha = ?", "c1").First(&c1)
+	if c1.JiraCardKey != "CORE-12" || !c1.HasLink {
+		t.Errorf("c1 jira_card_key=%q has_link=%v", c1.JiraCardKey, c1.HasLink)
+	}
+}
+
+type fakePullRequestProvider struct {
+	prs   []services.PullRequestInfo
+	since time.Time
+}
+
+func (f *fakePullRequestProvider) FetchPullRequests(owner, repo, token string, since time.Time) ([]services.PullRequestInfo, error) {
+	f.since = since
+	return f.prs, nil
+}
+
+func TestSyncRepoPullRequests_UpsertsAndAdvances(t *testing.T) {
+	db := setupWorkerDB(t)
+	repo := models.Repository{UserID: 1, Owner: "o", Name: "r", Provider: models.ProviderGitHub, URL: "https://github.com/o/r"}
+	db.Create(&repo)
+
+	opened := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	p := &fakePullRequestProvider{prs: []services.PullRequestInfo{{
+		Number:       42,
+		Title:        "Add webhook support",
+		State:        "open",
+		Author:       "ana",
+		SourceBranch: "feature/CORE-123-webhooks",
+		CreatedAt:    opened,
+		UpdatedAt:    opened,
+		Reviews:      []services.ReviewInfo{{ExternalID: "review-1", Author: "bob", State: "commented", SubmittedAt: opened}},
+		CommitSHAs:   []string{"p1"},
+	}}}
+	db.Create(&models.Commit{RepoID: repo.ID, SHA: "p1", Message: "wire up handler", Branch: "feature/CORE-123-webhooks,main"})
+
+	if _, err := syncRepoPullRequests(db, p, repo, "tok", nil); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	var pr models.PullRequest
+	db.Where("repo_id = ? AND number = ?", repo.ID, 42).First(&pr)
+	if pr.JiraCardKey != "CORE-123" {
+		t.Errorf("jira key = %q, want key from branch", pr.JiraCardKey)
+	}
+	var link models.CommitCardLink
+	if db.Where("jira_card_key = ?", "CORE-123").First(&link).Error != nil || link.Source != models.LinkSourceBranch {
+		t.Errorf("PR commit must be linked to the branch key, got %+v", link)
+	}
+
+	// The PR is merged and the review is re-delivered with an approval.
+	merged := opened.Add(time.Hour)
+	p.prs[0].State = "merged"
+	p.prs[0].MergedAt = &merged
+	p.prs[0].Reviews = append(p.prs[0].Reviews, services.ReviewInfo{ExternalID: "review-2", Author: "bob", State: "approved", SubmittedAt: merged})
+
+	db.First(&repo, repo.ID)
+	if _, err := syncRepoPullRequests(db, p, repo, "tok", nil); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if repo.PRSyncedAt == nil || !p.since.Equal(*repo.PRSyncedAt) {
+		t.Errorf("second sync since = %v, want repo pr_synced_at %v", p.since, repo.PRSyncedAt)
+	}
+
+	var prCount, reviewCount int64
+	db.Model(&models.PullRequest{}).Count(&prCount)
+	db.Model(&models.PullRequestReview{}).Count(&reviewCount)
+	db.First(&pr, pr.ID)
+	if prCount != 1 || reviewCount != 2 || pr.State != models.PullRequestMerged {
+		t.Errorf("prs=%d reviews=%d state=%s, want 1, 2, merged", prCount, reviewCount, pr.State)
+	}
+}
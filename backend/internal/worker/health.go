@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// InvalidateAfterFailures is how many not-found/unauthorized sync failures
+// in a row mark a repository invalid. Other failure classes never do.
+var InvalidateAfterFailures = 3
+
+func loadHealth(db *gorm.DB, repoID uint) models.RepoSyncHealth {
+	health := models.RepoSyncHealth{RepoID: repoID}
+	db.Where("repo_id = ?", repoID).First(&health)
+	return health
+}
+
+// recordSyncSuccess resets the failure counters and marks the repository valid.
+func recordSyncSuccess(db *gorm.DB, repo models.Repository) {
+	health := loadHealth(db, repo.ID)
+	now := time.Now()
+	health.ConsecutiveFailures = 0
+	health.ConsecutiveAccessFailures = 0
+	health.LastSuccessAt = &now
+	db.Save(&health)
+
+	db.Model(&repo).Updates(map[string]interface{}{
+		"is_valid":       true,
+		"last_synced_at": &now,
+	})
+}
+
+// recordSyncFailure classifies err, updates the health record and only
+// invalidates the repository after repeated access failures.
+func recordSyncFailure(db *gorm.DB, repo models.Repository, err error) models.RepoSyncHealth {
+	return recordFailure(db, repo, err, false)
+}
+
+// recordPartialFailure records an error from a sync that still reached the
+// repository, such as one branch failing to fetch. It counts as a failure but
+// never toward invalidation, since access evidently works.
+func recordPartialFailure(db *gorm.DB, repo models.Repository, err error) models.RepoSyncHealth {
+	return recordFailure(db, repo, err, true)
+}
+
+func recordFailure(db *gorm.DB, repo models.Repository, err error, reachable bool) models.RepoSyncHealth {
+	class := services.ClassifyError(err)
+	health := loadHealth(db, repo.ID)
+	now := time.Now()
+
+	health.LastErrorClass = string(class)
+	health.LastError = err.Error()
+	health.LastFailureAt = &now
+	health.ConsecutiveFailures++
+	if class.IsAccessError() && !reachable {
+		health.ConsecutiveAccessFailures++
+	} else {
+		health.ConsecutiveAccessFailures = 0
+	}
+	db.Save(&health)
+
+	if health.ConsecutiveAccessFailures >= InvalidateAfterFailures && repo.IsValid {
+		db.Model(&repo).Update("is_valid", false)
+		log.Printf("[commit-sync] repo=%s/%s marked invalid after %d %s failures",
+			repo.Owner, repo.Name, health.ConsecutiveAccessFailures, class)
+	}
+
+	return health
+}
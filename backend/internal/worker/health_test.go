@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services"
+)
+
+func TestRecordSyncFailure_OnlyRepeatedAccessFailuresInvalidate(t *testing.T) {
+	db := setupWorkerDB(t)
+	repo := models.Repository{UserID: 1, Owner: "o", Name: "r", Provider: models.ProviderGitHub, URL: "https://github.com/o/r", IsValid: true}
+	db.Create(&repo)
+
+	isValid := func() bool {
+		var r models.Repository
+		db.First(&r, repo.ID)
+		repo = r
+		return r.IsValid
+	}
+
+	network := fmt.Errorf("failed to fetch branches: %w", &services.ProviderError{Class: services.ErrNetwork, Message: "request failed"})
+	for i := 0; i < 5; i++ {
+		recordSyncFailure(db, repo, network)
+	}
+	if !isValid() {
+		t.Fatal("network failures must not invalidate the repository")
+	}
+
+	notFound := &services.ProviderError{Class: services.ErrNotFound, Message: "repository not found"}
+	recordSyncFailure(db, repo, notFound)
+	recordSyncFailure(db, repo, notFound)
+	if !isValid() {
+		t.Fatal("invalidated before the threshold")
+	}
+
+	health := recordSyncFailure(db, repo, notFound)
+	if isValid() {
+		t.Fatal("expected repository to be invalid after repeated not-found failures")
+	}
+	if health.ConsecutiveFailures != 8 || health.LastErrorClass != string(services.ErrNotFound) {
+		t.Errorf("health = %+v", health)
+	}
+
+	recordSyncSuccess(db, repo)
+	health = loadHealth(db, repo.ID)
+	if !isValid() || health.ConsecutiveFailures != 0 || health.LastSuccessAt == nil {
+		t.Errorf("after success: valid=%v health=%+v", repo.IsValid, health)
+	}
+}
+
+func TestRecordPartialFailure_NeverInvalidates(t *testing.T) {
+	db := setupWorkerDB(t)
+	repo := models.Repository{UserID: 1, Owner: "o", Name: "r", Provider: models.ProviderGitHub, URL: "https://github.com/o/r", IsValid: true}
+	db.Create(&repo)
+
+	notFound := fmt.Errorf("1 of 2 branches failed to fetch, first branch gone: %w", &services.ProviderError{Class: services.ErrNotFound, Message: "repository not found"})
+	var health models.RepoSyncHealth
+	for i := 0; i < InvalidateAfterFailures+1; i++ {
+		health = recordPartialFailure(db, repo, notFound)
+	}
+	db.First(&repo, repo.ID)
+	if !repo.IsValid || health.ConsecutiveFailures != 4 || health.ConsecutiveAccessFailures != 0 || health.LastErrorClass != string(services.ErrNotFound) {
+		t.Errorf("valid=%v health=%+v", repo.IsValid, health)
+	}
+}
@@ -0,0 +1,18 @@
+package worker
+
+import (
+	"log"
+
+	"github.com/cds-id/pdt/backend/internal/services/identity"
+	"gorm.io/gorm"
+)
+
+// DiscoverPeople runs identity discovery for each user once a sync batch is
+// done, rather than after every repository or workspace.
+func DiscoverPeople(db *gorm.DB, userIDs ...uint) {
+	for _, uid := range userIDs {
+		if _, err := identity.Discover(db, uid); err != nil {
+			log.Printf("[identity] user=%d discovery error: %v", uid, err)
+		}
+	}
+}
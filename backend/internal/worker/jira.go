@@ -5,12 +5,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cds-id/pdt/backend/internal/crypto"
 	"github.com/cds-id/pdt/backend/internal/helpers"
 	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services"
+	"github.com/cds-id/pdt/backend/internal/services/flow"
 	"github.com/cds-id/pdt/backend/internal/services/jira"
+	"github.com/cds-id/pdt/backend/internal/services/sprintstats"
 	wvClient "github.com/cds-id/pdt/backend/internal/services/weaviate"
 	"gorm.io/gorm"
 )
@@ -22,15 +28,6 @@ func SyncUserJira(db *gorm.DB, enc *crypto.Encryptor, userID uint, wv ...*wvClie
 		return fmt.Errorf("user not found: %w", err)
 	}
 
-	if user.JiraToken == "" || user.JiraEmail == "" {
-		return nil
-	}
-
-	token, err := enc.Decrypt(user.JiraToken)
-	if err != nil {
-		return fmt.Errorf("failed to decrypt jira token: %w", err)
-	}
-
 	// Get all active workspaces for this user
 	var workspaces []models.JiraWorkspaceConfig
 	db.Where("user_id = ? AND is_active = ?", userID, true).Find(&workspaces)
@@ -47,8 +44,8 @@ func SyncUserJira(db *gorm.DB, enc *crypto.Encryptor, userID uint, wv ...*wvClie
 
 	for _, ws := range workspaces {
 		log.Printf("[jira-sync] user=%d workspace=%s starting sync", userID, ws.Workspace)
-		if err := syncWorkspace(db, user, token, ws, wvC); err != nil {
-			log.Printf("[jira-sync] user=%d workspace=%s sync failed: %v", userID, ws.Workspace, err)
+		if err := syncWorkspace(db, enc, user, ws, wvC); err != nil {
+			log.Printf("[jira-sync] user=%d workspace=%s sync failed (%s): %v", userID, ws.Workspace, services.ClassifyError(err), err)
 		} else {
 			log.Printf("[jira-sync] user=%d workspace=%s sync completed", userID, ws.Workspace)
 		}
@@ -57,11 +54,20 @@ func SyncUserJira(db *gorm.DB, enc *crypto.Encryptor, userID uint, wv ...*wvClie
 	return nil
 }
 
-func syncWorkspace(db *gorm.DB, user models.User, token string, ws models.JiraWorkspaceConfig, wvC *wvClient.Client) error {
-	client := jira.New(ws.Workspace, user.JiraEmail, token)
+// syncWorkspace refreshes the workspace's sprints from its Scrum boards,
+// then searches for cards whose updated time moved past the workspace's
+// high-water mark: cards in sprints, on Kanban boards and, when the
+// workspace opts in, in backlogs. Only those cards have their details,
+// changelog and comments refetched.
+func syncWorkspace(db *gorm.DB, enc *crypto.Encryptor, user models.User, ws models.JiraWorkspaceConfig, wvC *wvClient.Client) error {
+	client, err := jira.ClientFor(ws, user, enc)
+	if err != nil {
+		return err
+	}
 	userID := user.ID
-	wsID := ws.ID
 
+	// Sprint state changes do not bump the updated time of their issues,
+	// so sprints are still listed from every board on each run.
 	boards, err := client.FetchBoards()
 	if err != nil {
 		return fmt.Errorf("failed to fetch boards: %w", err)
@@ -69,109 +75,297 @@ func syncWorkspace(db *gorm.DB, user models.User, token string, ws models.JiraWo
 
 	log.Printf("[jira-sync] user=%d ws=%s found %d boards", userID, ws.Workspace, len(boards))
 
-	for _, boardID := range boards {
-		sprints, err := client.FetchSprints(boardID)
+	sprintIDs := map[int]uint{}
+	var kanbanBoards, scrumBoards []int
+	for _, board := range boards {
+		if board.Type == jira.BoardKanban {
+			kanbanBoards = append(kanbanBoards, board.ID)
+			continue
+		}
+		sprints, err := client.FetchSprints(board.ID)
 		if err != nil {
-			log.Printf("[jira-sync] user=%d ws=%s board=%d sprint fetch error: %v", userID, ws.Workspace, boardID, err)
+			if board.Type == jira.BoardSimple {
+				// A team-managed board without sprints works like Kanban.
+				kanbanBoards = append(kanbanBoards, board.ID)
+				continue
+			}
+			log.Printf("[jira-sync] user=%d ws=%s board=%d sprint fetch error: %v", userID, ws.Workspace, board.ID, err)
 			continue
 		}
-
+		scrumBoards = append(scrumBoards, board.ID)
 		for _, s := range sprints {
-			sprint := models.Sprint{
-				UserID:       userID,
-				WorkspaceID:  &wsID,
-				JiraSprintID: strconv.Itoa(s.ID),
-				Name:         s.Name,
-				State:        models.SprintState(s.State),
-				StartDate:    s.StartDate,
-				EndDate:      s.EndDate,
+			sprintIDs[s.ID] = UpsertSprint(db, userID, ws.ID, s)
+		}
+	}
+
+	// Without the field ids, issues sync without sprint or story points data.
+	fieldIDs, err := client.FetchFieldIDs()
+	if err != nil {
+		log.Printf("[jira-sync] user=%d ws=%s field ids error: %v", userID, ws.Workspace, err)
+	}
+	sprintField := fieldIDs.Sprint
+
+	if ws.StoryPointsField == "" && fieldIDs.StoryPoints != "" {
+		// Cards synced before the field was known are refetched once so
+		// their points fill in.
+		db.Model(&ws).Update("story_points_field", fieldIDs.StoryPoints)
+		ResetJiraSync(db, userID, ws.ID)
+		ws.IssuesSyncedAt = nil
+	}
+	client.StoryPointsField = ws.StoryPointsField
+
+	// Without the categories, transitions fall back to guessing them from
+	// status names.
+	categories, err := client.FetchStatusCategories()
+	if err != nil {
+		log.Printf("[jira-sync] user=%d ws=%s status categories error: %v", userID, ws.Workspace, err)
+	}
+
+	var projectKeys []string
+	for _, k := range strings.Split(ws.ProjectKeys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			projectKeys = append(projectKeys, k)
+		}
+	}
+	issues, err := client.SearchIssues(jira.UpdatedSinceJQL(projectKeys, ws.IssuesSyncedAt), sprintField)
+	if err != nil {
+		return err
+	}
+
+	// Kanban and backlog cards have no sprint the search above could match,
+	// so they are listed from their boards.
+	boardJQL := jira.BoardUpdatedSinceJQL(ws.IssuesSyncedAt)
+	seen := map[string]bool{}
+	for _, issue := range issues {
+		seen[issue.Key] = true
+	}
+	unsprinted := map[string]bool{}
+	backlog := map[string]bool{}
+	addBoardIssues := func(found []jira.IssueUpdate, inBacklog bool) {
+		for _, issue := range found {
+			unsprinted[issue.Key] = true
+			if inBacklog {
+				backlog[issue.Key] = true
+			}
+			if !seen[issue.Key] {
+				seen[issue.Key] = true
+				issues = append(issues, issue)
+			}
+		}
+	}
+	for _, boardID := range kanbanBoards {
+		found, err := client.FetchBoardIssues(boardID, boardJQL, sprintField)
+		if err != nil {
+			return fmt.Errorf("failed to fetch issues of board %d: %w", boardID, err)
+		}
+		addBoardIssues(found, false)
+	}
+	if ws.SyncBacklog {
+		for _, boardID := range scrumBoards {
+			found, err := client.FetchBacklogIssues(boardID, boardJQL, sprintField)
+			if err != nil {
+				return fmt.Errorf("failed to fetch backlog of board %d: %w", boardID, err)
+			}
+			addBoardIssues(found, true)
+		}
+	}
+	// The high-water mark below relies on the oldest changes coming first.
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Updated.Before(issues[j].Updated) })
+
+	mark := ws.IssuesSyncedAt
+	failed := false
+	updated, unchanged := 0, 0
+	for _, issue := range issues {
+		if !helpers.FilterByProjectKeys(issue.Key, ws.ProjectKeys) {
+			continue
+		}
+		var sprintID *uint
+		if sprint := cardSprint(issue.Sprints, ws.SyncFutureSprints); sprint != nil {
+			id, ok := sprintIDs[sprint.ID]
+			if !ok {
+				id = UpsertSprint(db, userID, ws.ID, *sprint)
+				sprintIDs[sprint.ID] = id
+			}
+			sprintID = &id
+		} else if !unsprinted[issue.Key] {
+			continue
+		}
+
+		var existing models.JiraCard
+		db.Where("user_id = ? AND card_key = ?", userID, issue.Key).Limit(1).Find(&existing)
+		if existing.JiraUpdatedAt != nil && existing.JiraUpdatedAt.Equal(issue.Updated) {
+			unchanged++
+		} else if err := syncCard(db, client, userID, ws, issue, sprintID, backlog[issue.Key], categories, wvC); err != nil {
+			log.Printf("[jira-sync] user=%d ws=%s card=%s sync error: %v", userID, ws.Workspace, issue.Key, err)
+			failed = true
+			continue
+		} else {
+			updated++
+		}
+
+		// Results come oldest first; the mark stops before the first failed
+		// card so the next run retries it.
+		if !failed && !issue.Updated.IsZero() && (mark == nil || issue.Updated.After(*mark)) {
+			t := issue.Updated
+			mark = &t
+		}
+	}
+
+	if mark != nil && (ws.IssuesSyncedAt == nil || !mark.Equal(*ws.IssuesSyncedAt)) {
+		db.Model(&ws).Update("issues_synced_at", mark)
+	}
+
+	// Snapshot the scope of the active sprints once the cards are current.
+	var active []models.Sprint
+	db.Where("user_id = ? AND workspace_id = ? AND state = ?", userID, ws.ID, models.SprintActive).Find(&active)
+	for _, sp := range active {
+		if err := sprintstats.Snapshot(db, userID, sp.ID, time.Now(), categories); err != nil {
+			log.Printf("[jira-sync] user=%d ws=%s sprint=%d snapshot error: %v", userID, ws.Workspace, sp.ID, err)
+		}
+	}
+
+	log.Printf("[jira-sync] user=%d ws=%s %d issues matched, %d updated, %d unchanged",
+		userID, ws.Workspace, len(issues), updated, unchanged)
+	return nil
+}
+
+// UpsertSprint stores a sprint of the workspace by its Jira id and returns
+// the row id.
+func UpsertSprint(db *gorm.DB, userID, wsID uint, s jira.SprintInfo) uint {
+	sprint := models.Sprint{
+		UserID:       userID,
+		WorkspaceID:  &wsID,
+		JiraSprintID: strconv.Itoa(s.ID),
+		Name:         s.Name,
+		State:        models.SprintState(s.State),
+		StartDate:    s.StartDate,
+		EndDate:      s.EndDate,
+	}
+	db.Where("user_id = ? AND workspace_id = ? AND jira_sprint_id = ?", userID, wsID, sprint.JiraSprintID).
+		Assign(sprint).FirstOrCreate(&sprint)
+	return sprint.ID
+}
+
+// cardSprint picks the sprint a card is shown under: its active sprint,
+// else the future sprint it is planned for when future is set, else the
+// last closed one. Cards only in future sprints get none unless future is
+// set.
+func cardSprint(sprints []jira.SprintInfo, future bool) *jira.SprintInfo {
+	var planned, closed *jira.SprintInfo
+	for i := range sprints {
+		switch models.SprintState(sprints[i].State) {
+		case models.SprintActive:
+			return &sprints[i]
+		case models.SprintFuture:
+			if future && planned == nil {
+				planned = &sprints[i]
 			}
-			db.Where("jira_sprint_id = ?", sprint.JiraSprintID).
-				Assign(sprint).FirstOrCreate(&sprint)
-
-			state := models.SprintState(s.State)
-			if state == models.SprintActive || state == models.SprintClosed {
-				cards, err := client.FetchSprintIssues(s.ID)
-				if err != nil {
-					log.Printf("[jira-sync] user=%d ws=%s sprint=%d issue fetch error: %v", userID, ws.Workspace, s.ID, err)
-					continue
-				}
-
-				savedCount := 0
-				for _, card := range cards {
-					if !helpers.FilterByProjectKeys(card.Key, ws.ProjectKeys) {
-						continue
-					}
-
-					jiraCard := models.JiraCard{
-						UserID:      userID,
-						WorkspaceID: &wsID,
-						Key:         card.Key,
-						Summary:     card.Summary,
-						Status:      card.Status,
-						Assignee:    card.Assignee,
-						SprintID:    &sprint.ID,
-					}
-
-					detail, err := client.FetchIssue(card.Key)
-					if err == nil && detail != nil {
-						detailJSON, _ := json.Marshal(detail)
-						jiraCard.DetailsJSON = string(detailJSON)
-					}
-
-					db.Where("user_id = ? AND card_key = ?", userID, card.Key).
-						Assign(jiraCard).FirstOrCreate(&jiraCard)
-
-					// Embed card in Weaviate
-					if wvC != nil {
-						embedContent := card.Summary
-						if detail != nil && detail.Description != "" {
-							embedContent = card.Summary + "\n" + detail.Description
-						}
-						if err := wvC.UpsertJiraCard(context.Background(), card.Key, int(userID), int(wsID), embedContent, card.Status, card.Assignee); err != nil {
-							log.Printf("[jira-sync] embed card %s error: %v", card.Key, err)
-						}
-					}
-
-					comments, err := client.FetchIssueComments(card.Key)
-					if err != nil {
-						log.Printf("[jira-sync] user=%d ws=%s card=%s fetch comments error: %v", userID, ws.Workspace, card.Key, err)
-					} else {
-						for _, comment := range comments {
-							jiraComment := models.JiraComment{
-								UserID:      userID,
-								WorkspaceID: &wsID,
-								CardKey:     card.Key,
-								CommentID:   comment.ID,
-								Author:      comment.Author,
-								AuthorEmail: comment.AuthorEmail,
-								Body:        comment.Body,
-								CommentedAt: comment.Created,
-							}
-							db.Where("comment_id = ?", comment.ID).
-								Assign(jiraComment).FirstOrCreate(&jiraComment)
-
-							// Embed comment in Weaviate
-							if wvC != nil {
-								if err := wvC.UpsertJiraComment(context.Background(), comment.ID, card.Key, int(userID), int(wsID), comment.Body, comment.Author); err != nil {
-									log.Printf("[jira-sync] embed comment %s error: %v", comment.ID, err)
-								}
-							}
-						}
-					}
-
-					savedCount++
-				}
-
-				log.Printf("[jira-sync] user=%d ws=%s sprint=%d (%s) found %d cards, %d after filter",
-					userID, ws.Workspace, s.ID, s.State, len(cards), savedCount)
+		case models.SprintClosed:
+			closed = &sprints[i]
+		}
+	}
+	if planned != nil {
+		return planned
+	}
+	return closed
+}
+
+// syncCard stores one changed card with its details and changelog, replaces
+// its status transitions, embeds it, and refetches its comments. The card's
+// JiraUpdatedAt is only recorded once all of that succeeded.
+func syncCard(db *gorm.DB, client *jira.Client, userID uint, ws models.JiraWorkspaceConfig, issue jira.IssueUpdate, sprintID *uint, inBacklog bool, categories map[string]string, wvC *wvClient.Client) error {
+	wsID := ws.ID
+	jiraCard := models.JiraCard{
+		UserID:      userID,
+		WorkspaceID: &wsID,
+		Key:         issue.Key,
+		Summary:     issue.Summary,
+		Status:      issue.Status,
+		Assignee:    issue.Assignee,
+		SprintID:    sprintID,
+		InBacklog:   inBacklog,
+	}
+	if !issue.Created.IsZero() {
+		created := issue.Created
+		jiraCard.JiraCreatedAt = &created
+	}
+
+	detail, err := client.FetchIssue(issue.Key)
+	if err != nil {
+		return fmt.Errorf("fetch issue: %w", err)
+	}
+	detailJSON, _ := json.Marshal(detail)
+	jiraCard.DetailsJSON = string(detailJSON)
+
+	db.Where("user_id = ? AND card_key = ?", userID, issue.Key).
+		Assign(jiraCard).FirstOrCreate(&jiraCard)
+	// Assign skips nil and false, so leaving a sprint or the backlog is
+	// written explicitly.
+	db.Model(&jiraCard).Updates(map[string]interface{}{"sprint_id": sprintID, "in_backlog": inBacklog})
+	if client.StoryPointsField != "" {
+		// Assign skips nil, so cleared points are written separately.
+		db.Model(&jiraCard).Update("story_points", detail.StoryPoints)
+	}
+
+	if err := flow.Replace(db, userID, issue.Key, flow.FromChangelog(jiraCard, detail.Changelog, categories)); err != nil {
+		return fmt.Errorf("store transitions: %w", err)
+	}
+
+	// Embed card in Weaviate
+	if wvC != nil {
+		embedContent := wvClient.JiraCardContent(issue.Summary, detail.Description)
+		if err := wvC.UpsertJiraCard(context.Background(), issue.Key, int(userID), int(wsID), embedContent, issue.Status, issue.Assignee); err != nil {
+			log.Printf("[jira-sync] embed card %s error: %v", issue.Key, err)
+		}
+	}
+
+	comments, err := client.FetchIssueComments(issue.Key)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		jiraComment := models.JiraComment{
+			UserID:      userID,
+			WorkspaceID: &wsID,
+			CardKey:     issue.Key,
+			CommentID:   comment.ID,
+			Author:      comment.Author,
+			AuthorEmail: comment.AuthorEmail,
+			Body:        comment.Body,
+			BodyADF:     string(comment.BodyADF),
+			CommentedAt: comment.Created,
+		}
+		db.Where("user_id = ? AND workspace_id = ? AND comment_id = ?", userID, wsID, comment.ID).
+			Assign(jiraComment).FirstOrCreate(&jiraComment)
+
+		// Embed comment in Weaviate
+		if wvC != nil {
+			if err := wvC.UpsertJiraComment(context.Background(), comment.ID, issue.Key, int(userID), int(wsID), comment.Body, comment.Author); err != nil {
+				log.Printf("[jira-sync] embed comment %s error: %v", comment.ID, err)
 			}
 		}
 	}
 
+	if !issue.Updated.IsZero() {
+		updatedAt := issue.Updated
+		db.Model(&jiraCard).Update("jira_updated_at", &updatedAt)
+	}
 	return nil
 }
 
+// ResetJiraSync clears the high-water marks of the user's workspaces (or
+// only workspaceID when non-zero) so the next sync refetches every card.
+func ResetJiraSync(db *gorm.DB, userID, workspaceID uint) {
+	wsQuery := db.Model(&models.JiraWorkspaceConfig{}).Where("user_id = ?", userID)
+	cardQuery := db.Model(&models.JiraCard{}).Where("user_id = ?", userID)
+	if workspaceID != 0 {
+		wsQuery = wsQuery.Where("id = ?", workspaceID)
+		cardQuery = cardQuery.Where("workspace_id = ?", workspaceID)
+	}
+	wsQuery.Update("issues_synced_at", nil)
+	cardQuery.Update("jira_updated_at", nil)
+}
+
 // SyncUserJiraWorkspace syncs a single workspace for a user.
 func SyncUserJiraWorkspace(db *gorm.DB, enc *crypto.Encryptor, userID uint, workspaceID uint, wv ...*wvClient.Client) error {
 	var user models.User
@@ -179,15 +373,6 @@ func SyncUserJiraWorkspace(db *gorm.DB, enc *crypto.Encryptor, userID uint, work
 		return fmt.Errorf("user not found: %w", err)
 	}
 
-	if user.JiraToken == "" || user.JiraEmail == "" {
-		return fmt.Errorf("jira credentials not configured")
-	}
-
-	token, err := enc.Decrypt(user.JiraToken)
-	if err != nil {
-		return fmt.Errorf("failed to decrypt jira token: %w", err)
-	}
-
 	var ws models.JiraWorkspaceConfig
 	if err := db.Where("id = ? AND user_id = ?", workspaceID, userID).First(&ws).Error; err != nil {
 		return fmt.Errorf("workspace not found")
@@ -197,7 +382,7 @@ func SyncUserJiraWorkspace(db *gorm.DB, enc *crypto.Encryptor, userID uint, work
 	if len(wv) > 0 {
 		wvC = wv[0]
 	}
-	return syncWorkspace(db, user, token, ws, wvC)
+	return syncWorkspace(db, enc, user, ws, wvC)
 }
 
 func SyncAllUsersJira(db *gorm.DB, enc *crypto.Encryptor) {
@@ -213,4 +398,5 @@ func SyncAllUsersJira(db *gorm.DB, enc *crypto.Encryptor) {
 			log.Printf("[jira-sync] user=%d sync failed: %v", userID, err)
 		}
 	}
+	DiscoverPeople(db, userIDs...)
 }
@@ -0,0 +1,256 @@
+package worker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/cds-id/pdt/backend/internal/crypto"
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/httpclient"
+)
+
+func TestSyncWorkspace_OnlyRefetchesMovedCards(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.JiraWorkspaceConfig{}, &models.Sprint{}, &models.JiraCard{}, &models.JiraComment{}, &models.JiraCardTransition{}, &models.SprintSnapshot{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	updated := map[string]string{"CORE-1": "2026-10-12T09:00:00.000+0000", "CORE-2": "2026-10-12T10:00:00.000+0000"}
+	var jqls []string
+	issueFetches := map[string]int{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/agile/1.0/board", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[{"id":1}],"isLast":true}`)
+	})
+	mux.HandleFunc("/rest/agile/1.0/board/1/sprint", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[{"id":5,"name":"S5","state":"active"},{"id":6,"name":"S6","state":"future"}],"isLast":true}`)
+	})
+	mux.HandleFunc("/rest/api/3/field", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":"summary","schema":{}},{"id":"customfield_10020","schema":{"custom":"com.pyxis.greenhopper.jira:gh-sprint"}},
+			{"id":"customfield_10016","name":"Story Points","schema":{"custom":"com.atlassian.jira.plugin.system.customfieldtypes:float"}}]`)
+	})
+	mux.HandleFunc("/rest/api/3/search", func(w http.ResponseWriter, r *http.Request) {
+		jqls = append(jqls, r.URL.Query().Get("jql"))
+		var issues []string
+		for _, key := range []string{"CORE-1", "CORE-2"} {
+			issues = append(issues, fmt.Sprintf(`{"key":%q,"fields":{"summary":"s","status":{"name":"In Progress"},"updated":%q,
+				"customfield_10020":[{"id":5,"name":"S5","state":"active"}]}}`, key, updated[key]))
+		}
+		fmt.Fprintf(w, `{"total":%d,"issues":[%s]}`, len(issues), strings.Join(issues, ","))
+	})
+	mux.HandleFunc("/rest/api/3/issue/", func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/rest/api/3/issue/")
+		if r.URL.Query().Get("fields") == "comment" {
+			fmt.Fprintf(w, `{"fields":{"comment":{"comments":[{"id":"c-%s","author":{"displayName":"Bob"},
+				"body":{"type":"doc","version":1,"content":[{"type":"paragraph","content":[{"type":"text","text":"hi","marks":[{"type":"strong"}]}]}]},"created":"2026-10-12T09:00:00.000+0000"}]}}}`, key)
+			return
+		}
+		issueFetches[key]++
+		fmt.Fprintf(w, `{"key":%q,"fields":{"summary":"s","status":{"name":"In Progress"},"customfield_10016":3},"changelog":{"histories":[
+			{"author":{"displayName":"Ana"},"created":"2026-10-12T08:00:00.000+0000","items":[{"field":"status","fromString":"To Do","toString":"In Progress"}]}]}}`, key)
+	})
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+	orig := httpclient.Client
+	httpclient.Client = srv.Client()
+	defer func() { httpclient.Client = orig }()
+
+	enc, err := crypto.NewEncryptor(strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatalf("encryptor: %v", err)
+	}
+	token, _ := enc.Encrypt("tok")
+	user := models.User{Email: "ana@corp.com", Password: "x", JiraEmail: "ana@corp.com", JiraToken: token}
+	db.Create(&user)
+	ws := models.JiraWorkspaceConfig{UserID: user.ID, Workspace: srv.Listener.Addr().String(), ProjectKeys: "CORE", IsActive: true}
+	db.Create(&ws)
+
+	if err := syncWorkspace(db, enc, user, ws, nil); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if strings.Contains(jqls[0], "updated >=") {
+		t.Errorf("first sync must not be bounded: %q", jqls[0])
+	}
+	db.First(&ws, ws.ID)
+	if ws.IssuesSyncedAt == nil || ws.IssuesSyncedAt.Hour() != 10 {
+		t.Fatalf("high-water mark = %v, want CORE-2's updated time", ws.IssuesSyncedAt)
+	}
+
+	// Only CORE-1 moved; CORE-2 comes back from the overlap unchanged.
+	updated["CORE-1"] = "2026-10-12T11:00:00.000+0000"
+	if err := syncWorkspace(db, enc, user, ws, nil); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if !strings.Contains(jqls[1], `project in ("CORE")`) || !strings.Contains(jqls[1], "updated >= -") {
+		t.Errorf("second sync jql = %q", jqls[1])
+	}
+	if issueFetches["CORE-1"] != 2 || issueFetches["CORE-2"] != 1 {
+		t.Errorf("issue fetches = %v, want CORE-1 twice and CORE-2 once", issueFetches)
+	}
+
+	var cards, comments, sprints, transitions int64
+	db.Model(&models.JiraCard{}).Count(&cards)
+	db.Model(&models.JiraComment{}).Count(&comments)
+	db.Model(&models.Sprint{}).Count(&sprints)
+	db.Model(&models.JiraCardTransition{}).Count(&transitions)
+	if cards != 2 || comments != 2 || sprints != 2 || transitions != 2 {
+		t.Errorf("cards=%d comments=%d sprints=%d transitions=%d, want 2 of each", cards, comments, sprints, transitions)
+	}
+	var comment models.JiraComment
+	db.First(&comment)
+	if comment.Body != "**hi**" || !strings.Contains(comment.BodyADF, `"type":"doc"`) {
+		t.Errorf("comment = %q / %q, want Markdown with the raw ADF kept", comment.Body, comment.BodyADF)
+	}
+
+	db.First(&ws, ws.ID)
+	if ws.StoryPointsField != "customfield_10016" {
+		t.Errorf("story points field = %q", ws.StoryPointsField)
+	}
+	var snapshots []models.SprintSnapshot
+	db.Find(&snapshots)
+	if len(snapshots) != 2 || snapshots[0].StoryPoints == nil || *snapshots[0].StoryPoints != 3 {
+		t.Errorf("snapshots = %+v, want both cards with 3 points", snapshots)
+	}
+}
+
+func TestSyncWorkspace_KanbanAndBacklog(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("sqlite open: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.JiraWorkspaceConfig{}, &models.Sprint{}, &models.JiraCard{}, &models.JiraComment{}, &models.JiraCardTransition{}, &models.SprintSnapshot{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	issue := func(key, updated, sprints string) string {
+		return fmt.Sprintf(`{"key":%q,"fields":{"summary":"s","status":{"name":"To Do"},"updated":%q,"customfield_10020":%s}}`, key, updated, sprints)
+	}
+	var backlogFetched bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/agile/1.0/board", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[{"id":1,"type":"scrum"},{"id":2,"type":"kanban"}],"isLast":true}`)
+	})
+	mux.HandleFunc("/rest/agile/1.0/board/1/sprint", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[{"id":6,"name":"S6","state":"future"}],"isLast":true}`)
+	})
+	mux.HandleFunc("/rest/agile/1.0/board/2/sprint", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("sprints fetched for a Kanban board")
+	})
+	mux.HandleFunc("/rest/agile/1.0/board/2/issue", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"total":1,"issues":[%s]}`, issue("OPS-1", "2026-10-12T08:00:00.000+0000", "null"))
+	})
+	mux.HandleFunc("/rest/agile/1.0/board/1/backlog", func(w http.ResponseWriter, r *http.Request) {
+		backlogFetched = true
+		fmt.Fprintf(w, `{"total":1,"issues":[%s]}`, issue("CORE-2", "2026-10-12T07:00:00.000+0000", "null"))
+	})
+	mux.HandleFunc("/rest/api/3/field", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":"customfield_10020","schema":{"custom":"com.pyxis.greenhopper.jira:gh-sprint"}}]`)
+	})
+	mux.HandleFunc("/rest/api/3/search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"total":1,"issues":[%s]}`, issue("CORE-1", "2026-10-12T09:00:00.000+0000", `[{"id":6,"name":"S6","state":"future"}]`))
+	})
+	mux.HandleFunc("/rest/api/3/issue/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fields") == "comment" {
+			fmt.Fprint(w, `{"fields":{"comment":{"comments":[]}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"key":"X","fields":{"summary":"s","status":{"name":"To Do"}}}`)
+	})
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+	orig := httpclient.Client
+	httpclient.Client = srv.Client()
+	defer func() { httpclient.Client = orig }()
+
+	enc, err := crypto.NewEncryptor(strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatalf("encryptor: %v", err)
+	}
+	token, _ := enc.Encrypt("tok")
+	user := models.User{Email: "ana@corp.com", Password: "x", JiraEmail: "ana@corp.com", JiraToken: token}
+	db.Create(&user)
+	ws := models.JiraWorkspaceConfig{UserID: user.ID, Workspace: srv.Listener.Addr().String(), IsActive: true}
+	db.Create(&ws)
+
+	if err := syncWorkspace(db, enc, user, ws, nil); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	var keys []string
+	db.Model(&models.JiraCard{}).Order("card_key").Pluck("card_key", &keys)
+	if strings.Join(keys, ",") != "OPS-1" || backlogFetched {
+		t.Fatalf("cards = %v, backlog fetched = %v; want only the Kanban card", keys, backlogFetched)
+	}
+
+	db.Model(&ws).Updates(map[string]interface{}{"sync_backlog": true, "sync_future_sprints": true})
+	ResetJiraSync(db, user.ID, ws.ID)
+	db.First(&ws, ws.ID)
+	if err := syncWorkspace(db, enc, user, ws, nil); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	var cards []models.JiraCard
+	db.Order("card_key").Find(&cards)
+	if len(cards) != 3 {
+		t.Fatalf("cards = %+v, want CORE-1, CORE-2 and OPS-1", cards)
+	}
+	if cards[0].SprintID == nil || cards[0].InBacklog {
+		t.Errorf("CORE-1 = %+v, want it in the future sprint", cards[0])
+	}
+	if cards[1].SprintID != nil || !cards[1].InBacklog {
+		t.Errorf("CORE-2 = %+v, want a backlog card without sprint", cards[1])
+	}
+	if cards[2].SprintID != nil || cards[2].InBacklog {
+		t.Errorf("OPS-1 = %+v, want a Kanban card outside the backlog", cards[2])
+	}
+	db.First(&ws, ws.ID)
+	if ws.IssuesSyncedAt == nil || ws.IssuesSyncedAt.Hour() != 9 {
+		t.Errorf("high-water mark = %v, want the newest card's updated time", ws.IssuesSyncedAt)
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	db := setupWorkerDB(t)
+	if err := db.AutoMigrate(&models.DataMigration{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	runs := 0
+	for range 2 {
+		runOnce(db, "test_job", func(*gorm.DB) { runs++ })
+	}
+	if runs != 1 {
+		t.Errorf("job ran %d times, want 1", runs)
+	}
+}
+
+func TestRunDataMigrations_DoesNotRestoreDeletedCardLinks(t *testing.T) {
+	db := setupWorkerDB(t)
+	if err := db.AutoMigrate(&models.DataMigration{}, &models.JiraCard{}, &models.JiraCardTransition{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	commit := models.Commit{RepoID: 1, SHA: "a1", Message: "CORE-1 fix", JiraCardKey: "CORE-1"}
+	db.Create(&commit)
+
+	RunDataMigrations(db)
+	var links []models.CommitCardLink
+	db.Find(&links)
+	if len(links) != 1 || links[0].JiraCardKey != "CORE-1" || links[0].Source != models.LinkSourceMessage {
+		t.Fatalf("links = %+v, want CORE-1 copied from the commit", links)
+	}
+
+	db.Delete(&links[0])
+	RunDataMigrations(db)
+	var count int64
+	db.Model(&models.CommitCardLink{}).Count(&count)
+	if count != 0 {
+		t.Errorf("links = %d after the second run, want the deleted link to stay deleted", count)
+	}
+}
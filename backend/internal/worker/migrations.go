@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"github.com/cds-id/pdt/backend/internal/models"
+	"github.com/cds-id/pdt/backend/internal/services/cardlink"
+	"github.com/cds-id/pdt/backend/internal/services/flow"
+	"gorm.io/gorm"
+)
+
+// RunDataMigrations runs the one-off data jobs that have not run yet and
+// records each once it finished.
+func RunDataMigrations(db *gorm.DB) {
+	runOnce(db, "jira_card_transitions_backfill", flow.Backfill)
+	runOnce(db, "commit_card_links_backfill", cardlink.Backfill)
+}
+
+func runOnce(db *gorm.DB, name string, job func(*gorm.DB)) {
+	var count int64
+	db.Model(&models.DataMigration{}).Where("name = ?", name).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	start := time.Now()
+	job(db)
+	if err := db.Create(&models.DataMigration{Name: name, RanAt: time.Now()}).Error; err != nil {
+		log.Printf("[migrations] record %s: %v", name, err)
+		return
+	}
+	log.Printf("[migrations] %s done in %s", name, time.Since(start).Round(time.Millisecond))
+}
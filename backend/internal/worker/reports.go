@@ -9,13 +9,27 @@ import (
 	"github.com/cds-id/pdt/backend/internal/crypto"
 	"github.com/cds-id/pdt/backend/internal/models"
 	"github.com/cds-id/pdt/backend/internal/services/report"
+	"github.com/cds-id/pdt/backend/internal/services/reportexport"
 	"github.com/cds-id/pdt/backend/internal/services/storage"
 	"gorm.io/gorm"
 )
 
+// ReportNarrativeLLM, when set, adds an AI-written narrative to the daily and
+// monthly reports generated here, written by ReportNarrativeModel.
+var (
+	ReportNarrativeLLM   report.NarrativeLLM
+	ReportNarrativeModel string
+)
+
+func newReportGenerator(db *gorm.DB, enc *crypto.Encryptor) *report.Generator {
+	gen := report.NewGenerator(db, enc)
+	gen.LLM, gen.LLMModel = ReportNarrativeLLM, ReportNarrativeModel
+	return gen
+}
+
 // GenerateMonthlyReportForUser generates a monthly report for a single user for the given month/year.
 func GenerateMonthlyReportForUser(db *gorm.DB, enc *crypto.Encryptor, r2 *storage.R2Client, userID uint, month, year int) error {
-	generator := report.NewGenerator(db, enc)
+	generator := newReportGenerator(db, enc)
 	data, err := generator.BuildMonthlyReportData(userID, month, year)
 	if err != nil {
 		return fmt.Errorf("build monthly data: %w", err)
@@ -26,6 +40,15 @@ func GenerateMonthlyReportForUser(db *gorm.DB, enc *crypto.Encryptor, r2 *storag
 		return nil
 	}
 
+	var existing models.Report
+	db.Where("user_id = ? AND report_type = ? AND month = ? AND year = ?", userID, "monthly", month, year).First(&existing)
+	existing.UserID = userID
+	if generator.LLM != nil {
+		if err := generator.NarrateMonthly(&existing, data); err != nil {
+			log.Printf("[report-worker] user=%d %v", userID, err)
+		}
+	}
+
 	templateContent := generator.GetMonthlyTemplateContent(userID)
 	rendered, err := generator.RenderMonthly(templateContent, data)
 	if err != nil {
@@ -35,26 +58,60 @@ func GenerateMonthlyReportForUser(db *gorm.DB, enc *crypto.Encryptor, r2 *storag
 	m := month
 	y := year
 	dateStr := fmt.Sprintf("%04d-%02d", year, month)
+	rpt := models.Report{
+		UserID:        userID,
+		Date:          dateStr,
+		Title:         fmt.Sprintf("Monthly Report — %s %d", time.Month(month).String(), year),
+		Content:       rendered,
+		ReportType:    "monthly",
+		Month:         &m,
+		Year:          &y,
+		Narrative:     existing.Narrative,
+		NarrativeHash: existing.NarrativeHash,
+	}
+	if _, err := report.Save(db, &rpt, data); err != nil {
+		return fmt.Errorf("save monthly: %w", err)
+	}
+
+	log.Printf("[report-worker] user=%d monthly report version %d generated for %d-%02d", userID, rpt.Version, year, month)
+	return nil
+}
+
+// GenerateWeeklyReportForUser generates the weekly report for a single user for
+// the Monday-to-Sunday week containing day.
+func GenerateWeeklyReportForUser(db *gorm.DB, enc *crypto.Encryptor, r2 *storage.R2Client, userID uint, day time.Time) error {
+	generator := report.NewGenerator(db, enc)
+	data, err := generator.BuildWeeklyReportData(userID, day)
+	if err != nil {
+		return fmt.Errorf("build weekly data: %w", err)
+	}
+
+	if !data.HasActivity() {
+		log.Printf("[report-worker] user=%d no activity in week of %s, skipping weekly report", userID, data.StartDate)
+		return nil
+	}
+
+	templateContent, templateID := generator.GetWeeklyTemplateContent(userID, nil)
+	rendered, err := generator.RenderRange(templateContent, data)
+	if err != nil {
+		return fmt.Errorf("render weekly: %w", err)
+	}
+
 	rpt := models.Report{
 		UserID:     userID,
-		Date:       dateStr,
-		Title:      fmt.Sprintf("Monthly Report — %s %d", time.Month(month).String(), year),
+		TemplateID: templateID,
+		Date:       data.StartDate,
+		EndDate:    data.EndDate,
+		Title:      data.Title,
 		Content:    rendered,
-		ReportType: "monthly",
-		Month:      &m,
-		Year:       &y,
+		ReportType: "weekly",
 	}
-
-	var existing models.Report
-	if db.Where("user_id = ? AND report_type = ? AND month = ? AND year = ?", userID, "monthly", month, year).First(&existing).Error == nil {
-		existing.Content = rendered
-		existing.Title = rpt.Title
-		db.Save(&existing)
-	} else {
-		db.Create(&rpt)
+	if _, err := report.Save(db, &rpt, data); err != nil {
+		return fmt.Errorf("save weekly: %w", err)
 	}
+	uploadReport(db, r2, &rpt)
 
-	log.Printf("[report-worker] user=%d monthly report generated for %d-%02d", userID, year, month)
+	log.Printf("[report-worker] user=%d weekly report generated for week of %s", userID, data.StartDate)
 	return nil
 }
 
@@ -65,14 +122,16 @@ func AutoGenerateReports(db *gorm.DB, enc *crypto.Encryptor, r2 *storage.R2Clien
 	var users []models.User
 	db.Find(&users)
 
-	gen := report.NewGenerator(db, enc)
+	gen := newReportGenerator(db, enc)
 
 	for _, user := range users {
-		var count int64
-		db.Model(&models.Report{}).Where("user_id = ? AND date = ?", user.ID, today).Count(&count)
-		if count > 0 {
+		// Users who already have today's report keep it.
+		var rpt models.Report
+		db.Where("user_id = ? AND date = ? AND report_type = ?", user.ID, today, "daily").First(&rpt)
+		if rpt.ID != 0 {
 			continue
 		}
+		rpt.UserID = user.ID
 
 		date := time.Now()
 		data, err := gen.BuildReportData(user.ID, date)
@@ -81,10 +140,17 @@ func AutoGenerateReports(db *gorm.DB, enc *crypto.Encryptor, r2 *storage.R2Clien
 			continue
 		}
 
-		if data.Stats.TotalCommits == 0 {
+		if !data.HasActivity() {
 			continue
 		}
 
+		// Days with only PR activity are not worth a model call.
+		if gen.LLM != nil && data.Stats.TotalCommits > 0 {
+			if err := gen.Narrate(&rpt, data); err != nil {
+				log.Printf("[worker] report narrative failed for user %d: %v", user.ID, err)
+			}
+		}
+
 		templateContent, templateID := gen.GetTemplateContent(user.ID, nil)
 
 		rendered, err := gen.Render(templateContent, data)
@@ -93,28 +159,33 @@ func AutoGenerateReports(db *gorm.DB, enc *crypto.Encryptor, r2 *storage.R2Clien
 			continue
 		}
 
-		var fileURL string
-		if r2 != nil {
-			key := fmt.Sprintf("reports/%d/%s.md", user.ID, today)
-			url, err := r2.Upload(context.Background(), key, []byte(rendered), "text/markdown; charset=utf-8")
-			if err != nil {
-				log.Printf("[worker] R2 upload failed for user %d: %v", user.ID, err)
-			} else {
-				fileURL = url
-			}
-		}
-
-		rpt := models.Report{
-			UserID:     user.ID,
-			TemplateID: templateID,
-			Date:       today,
-			Title:      "Daily Report — " + date.Format("Monday, 02 January 2006"),
-			Content:    rendered,
-			FileURL:    fileURL,
+		rpt.TemplateID = templateID
+		rpt.Date = today
+		rpt.Title = "Daily Report — " + date.Format("Monday, 02 January 2006")
+		rpt.Content = rendered
+		if _, err := report.Save(db, &rpt, data); err != nil {
+			log.Printf("[worker] report save failed for user %d: %v", user.ID, err)
+			continue
 		}
-		db.Create(&rpt)
+		uploadReport(db, r2, &rpt)
 
 		log.Printf("[worker] report generated for user %d: %d commits, %d cards, url=%s",
-			user.ID, data.Stats.TotalCommits, data.Stats.TotalCards, fileURL)
+			user.ID, data.Stats.TotalCommits, data.Stats.TotalCards, rpt.FileURL)
+	}
+}
+
+// uploadReport uploads the exports of rpt's latest version to R2, if
+// configured, and records where.
+func uploadReport(db *gorm.DB, r2 *storage.R2Client, rpt *models.Report) {
+	if r2 == nil {
+		return
+	}
+	url, err := reportexport.Upload(context.Background(), r2, rpt.UserID, report.FileName(*rpt), rpt.Title, rpt.Content)
+	if err != nil {
+		log.Printf("[report-worker] R2 upload failed for user %d: %v", rpt.UserID, err)
+		return
+	}
+	if err := report.SetFileURL(db, rpt, url); err != nil {
+		log.Printf("[report-worker] record file URL of report %d: %v", rpt.ID, err)
 	}
 }
@@ -23,6 +23,7 @@ type Scheduler struct {
 	ReportAutoGenerate    bool
 	ReportAutoTime        string
 	ReportMonthlyAutoTime string
+	ReportWeeklyAutoTime  string
 	R2                    *storage.R2Client
 	Weaviate              *wvClient.Client
 	EventBus              *eventbus.Bus
@@ -32,9 +33,11 @@ type Scheduler struct {
 	lastReportDate        string
 	lastMonthlyReport     string
 	monthlyRunning        atomic.Bool
+	lastWeeklyReport      string
+	weeklyRunning         atomic.Bool
 }
 
-func NewScheduler(db *gorm.DB, enc *crypto.Encryptor, commitInterval, jiraInterval time.Duration, reportAutoGen bool, reportAutoTime string, reportMonthlyAutoTime string, r2 *storage.R2Client, wv *wvClient.Client) *Scheduler {
+func NewScheduler(db *gorm.DB, enc *crypto.Encryptor, commitInterval, jiraInterval time.Duration, reportAutoGen bool, reportAutoTime string, reportMonthlyAutoTime, reportWeeklyAutoTime string, r2 *storage.R2Client, wv *wvClient.Client) *Scheduler {
 	return &Scheduler{
 		DB:                    db,
 		Encryptor:             enc,
@@ -44,6 +47,7 @@ func NewScheduler(db *gorm.DB, enc *crypto.Encryptor, commitInterval, jiraInterv
 		ReportAutoGenerate:    reportAutoGen,
 		ReportAutoTime:        reportAutoTime,
 		ReportMonthlyAutoTime: reportMonthlyAutoTime,
+		ReportWeeklyAutoTime:  reportWeeklyAutoTime,
 		R2:                    r2,
 		Weaviate:              wv,
 	}
@@ -58,6 +62,7 @@ func (s *Scheduler) Start(ctx context.Context) {
 	if s.ReportAutoGenerate {
 		go s.reportLoop(ctx)
 		go s.monthlyReportLoop(ctx)
+		go s.weeklyReportLoop(ctx)
 	}
 }
 
@@ -109,6 +114,7 @@ func (s *Scheduler) runCommitSync() {
 		}
 		s.Status.SetCommitDone(uid, nextSync, nil)
 	}
+	DiscoverPeople(s.DB, userIDs...)
 
 	if s.EventBus != nil {
 		for _, uid := range userIDs {
@@ -147,8 +153,10 @@ func (s *Scheduler) runJiraSync() {
 
 	log.Println("[worker] jira sync starting")
 
+	// Workspaces may carry their own credentials, so every user with an
+	// active workspace is synced.
 	var users []models.User
-	s.DB.Where("jira_token != '' AND jira_workspace != '' AND jira_email != ''").Find(&users)
+	s.DB.Where("id IN (?)", s.DB.Model(&models.JiraWorkspaceConfig{}).Select("user_id").Where("is_active = ?", true)).Find(&users)
 
 	nextSync := time.Now().Add(s.JiraInterval)
 
@@ -163,6 +171,9 @@ func (s *Scheduler) runJiraSync() {
 			log.Printf("[worker] jira sync completed for user %d", user.ID)
 		}
 	}
+	for _, user := range users {
+		DiscoverPeople(s.DB, user.ID)
+	}
 
 	if s.EventBus != nil {
 		for _, user := range users {
@@ -231,6 +242,47 @@ func (s *Scheduler) monthlyReportLoop(ctx context.Context) {
 	}
 }
 
+// weeklyReportLoop generates last week's reports on Mondays once
+// ReportWeeklyAutoTime has passed.
+func (s *Scheduler) weeklyReportLoop(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if now.Weekday() != time.Monday {
+				continue
+			}
+			currentKey := now.Format("2006-01-02")
+			if currentKey == s.lastWeeklyReport {
+				continue
+			}
+			if now.Format("15:04") < s.ReportWeeklyAutoTime {
+				continue
+			}
+			if !s.weeklyRunning.CompareAndSwap(false, true) {
+				continue
+			}
+
+			lastWeek := now.AddDate(0, 0, -7)
+
+			var userIDs []uint
+			s.DB.Model(&models.User{}).Pluck("id", &userIDs)
+			for _, uid := range userIDs {
+				if err := GenerateWeeklyReportForUser(s.DB, s.Encryptor, s.R2, uid, lastWeek); err != nil {
+					log.Printf("[weekly-report] user=%d error: %v", uid, err)
+				}
+			}
+			s.lastWeeklyReport = currentKey
+			s.weeklyRunning.Store(false)
+		}
+	}
+}
+
 func (s *Scheduler) checkAndGenerateReport() {
 	now := time.Now()
 	today := now.Format("2006-01-02")
@@ -63,7 +63,12 @@ func TestJiraService_FetchSprints(t *testing.T) {
 		t.Skip("no boards found")
 	}
 
-	for _, boardID := range boards {
+	for _, board := range boards {
+		boardID := board.ID
+		if board.Type == jira.BoardKanban {
+			t.Logf("Board %d: kanban, no sprints", boardID)
+			continue
+		}
 		sprints, err := client.FetchSprints(boardID)
 		if err != nil {
 			t.Logf("Board %d: error fetching sprints: %v", boardID, err)
@@ -123,7 +128,12 @@ func TestJiraService_FetchActiveSprintIssues(t *testing.T) {
 	}
 
 	var activeSprintID int
-	for _, boardID := range boards {
+	for _, board := range boards {
+		boardID := board.ID
+		if board.Type == jira.BoardKanban {
+			t.Logf("Board %d: kanban, no sprints", boardID)
+			continue
+		}
 		sprints, err := client.FetchSprints(boardID)
 		if err != nil {
 			continue
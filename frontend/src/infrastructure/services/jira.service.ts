@@ -42,6 +42,7 @@ export interface JiraComment {
   author: string
   author_email: string
   body: string
+  body_adf?: unknown
   commented_at: string
 }
 
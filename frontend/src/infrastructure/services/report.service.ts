@@ -6,10 +6,12 @@ export interface Report {
   user_id: number
   template_id?: number
   date: string
+  end_date?: string
   title: string
   content: string
   file_url: string
   report_type?: string
+  version?: number
   created_at: string
 }
 
@@ -17,6 +19,7 @@ export interface ReportTemplate {
   id: number
   name: string
   content: string
+  report_type: 'daily' | 'weekly' | 'monthly'
   is_default: boolean
   created_at: string
   updated_at: string
@@ -34,6 +34,8 @@ export function JiraCardDetailPage() {
 
   let subtasks: { key: string; summary: string; status: string; type: string }[] = (card as any).subtasks || []
   let description = (card as any).description || ''
+  // Descriptions converted from ADF arrive as Markdown already
+  let descriptionIsMarkdown = !!(card as any).description_adf
   let parent: { key: string; summary: string; status: string; type: string } | null = (card as any).parent || null
   let issueType = (card as any).issue_type || ''
 
@@ -43,6 +45,7 @@ export function JiraCardDetailPage() {
       const details = JSON.parse(card.details_json)
       subtasks = subtasks.length ? subtasks : (details.subtasks || [])
       description = details.description || ''
+      descriptionIsMarkdown = !!details.description_adf
       issueType = issueType || details.issue_type || ''
       if (!parent && details.parent) {
         parent = details.parent
@@ -104,7 +107,7 @@ export function JiraCardDetailPage() {
       {description && (
         <DataCard title="Description">
           <div className="text-sm overflow-hidden">
-            <MessageResponse>{jiraToMarkdown(description)}</MessageResponse>
+            <MessageResponse>{descriptionIsMarkdown ? description : jiraToMarkdown(description)}</MessageResponse>
           </div>
         </DataCard>
       )}
@@ -210,7 +213,7 @@ export function JiraCardDetailPage() {
                   </span>
                 </div>
                 <div className="text-sm pl-5 overflow-hidden">
-                  <MessageResponse>{jiraToMarkdown(comment.body)}</MessageResponse>
+                  <MessageResponse>{comment.body_adf ? comment.body : jiraToMarkdown(comment.body)}</MessageResponse>
                 </div>
               </div>
             ))}